@@ -0,0 +1,72 @@
+// Command ebnfgen prints random sentences of an EBNF grammar, one per line,
+// e.g. to fuzz the parser by hand or to see what a grammar change allows:
+//
+//	go run ./_tool/ebnfgen -grammar grammar/a.ebnf -start expression -n 10
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/bagaswh/rottenlang/pkg/ebnf"
+)
+
+func main() {
+	grammarPath := flag.String("grammar", "grammar/a.ebnf", "file defining the grammar")
+	start := flag.String("start", "", "rule to generate sentences of, by default the first")
+	n := flag.Int("n", 1, "number of sentences")
+	seed := flag.Int64("seed", 0, "random seed, by default the current time")
+	maxSymbols := flag.Int("max-symbols", 40, "length sentences are kept close to")
+	flag.Parse()
+
+	if err := run(*grammarPath, *start, *n, *seed, *maxSymbols); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// classes writes the token classes of the rottenlang grammar.
+var classes = map[string][]string{
+	"NUMBER":     {"0", "1", "2.5", "42"},
+	"STRING":     {`""`, `"rotten"`, `"no cap"`},
+	"IDENTIFIER": {"a", "b", "x", "rizzler", "_tmp"},
+}
+
+func run(grammarPath, start string, n int, seed int64, maxSymbols int) error {
+	f, err := os.Open(grammarPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	grammar, err := ebnf.Parse(f, grammarPath)
+	if err != nil {
+		return err
+	}
+	if start == "" {
+		start = grammar.Rules[0].Name
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	generator := ebnf.NewGenerator(grammar, rand.New(rand.NewSource(seed)))
+	generator.MaxSymbols = maxSymbols
+	for class, choices := range classes {
+		generator.Classes[class] = func(r *rand.Rand) string {
+			return choices[r.Intn(len(choices))]
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		symbols, err := generator.Generate(start)
+		if err != nil {
+			return err
+		}
+		fmt.Println(ebnf.Sentence(symbols))
+	}
+	return nil
+}
@@ -1,226 +1,143 @@
+// Command genexpr generates the syntax tree of pkg/ast from a spec file:
+// the node types with their constructors and getters, the visitor
+// interfaces and Accept functions, a no-op BaseVisitor, Equal, Clone and
+// the traversal used by ast.Walk.
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
+	"go/format"
 	"os"
 	"path/filepath"
 	"strings"
 	"text/template"
 )
 
-type AstField struct {
-	Type      string
-	Name      string
-	ClassName string
-}
-
-type AstClass struct {
-	Name   string
-	Fields []AstField
-}
-
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: go run astgen.go <output_directory>")
-		os.Exit(1)
-	}
+	specPath := flag.String("spec", "ast.spec", "file defining the nodes")
+	outputDir := flag.String("out", ".", "directory the Go files are written to")
+	packageName := flag.String("package", "ast", "package of the generated files")
+	flag.Parse()
 
-	outputDir := os.Args[1]
-	packageName := "ast"
-
-	exprs := []string{
-		"Binary   : Expr left, Token operator, Expr right",
-		"Grouping : Expr expression",
-		"Literal  : any value",
-		"Unary    : Token operator, Expr right",
-	}
-
-	err := defineAst(outputDir, packageName, "Expr", exprs)
-	if err != nil {
+	if err := run(*specPath, *outputDir, *packageName); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func defineAst(outputDir, packageName, baseName string, types []string) error {
-	outputPath := filepath.Join(outputDir, strings.ToLower(baseName)+".go")
-	file, err := os.Create(outputPath)
+func run(specPath, outputDir, packageName string) error {
+	f, err := os.Open(specPath)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer f.Close()
 
-	classes := parseTypes(types, []string{})
-
-	// First collect all unique fields across all types
-	allFields := make(map[string]string) // map[fieldName]fieldType
-	for _, class := range classes {
-		for _, field := range class.Fields {
-			allFields[field.Name] = field.Type
-		}
+	bases, err := parseSpec(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w", specPath, err)
 	}
 
-	// Template for the generated code
-	tmpl := `package {{.PackageName}}
-
-{{makeImports .Imports}}
-
-// {{.BaseName}} is the interface for all expression types
-type {{.BaseName}} interface {
-{{range .InterfaceMethods}}	{{.}}
-{{end}}}
-
-{{range .Classes}}// {{.Name}}Expr represents a {{.Name}} expression
-type {{.Name}}Expr struct {
-{{range .Fields}}	{{.Name}} {{.Type}}
-{{end}}}
+	data := struct {
+		Spec        string
+		PackageName string
+		Bases       []*AstBase
+	}{
+		Spec:        filepath.Base(specPath),
+		PackageName: packageName,
+		Bases:       bases,
+	}
 
-{{end}}// Method implementations for all expression types
-{{range $className, $classFields := .ClassFieldMap}}{{range $fieldName, $fieldType := $.AllFields}}func (e *{{$className}}Expr) {{makeGetter $fieldName}}() {{$fieldType}} {
-{{if hasField $classFields $fieldName}}	return e.{{$fieldName}}
-{{else}}	return nil // This expression type doesn't have this field
-{{end}}}
+	t, err := template.New("ast").Funcs(newFuncMap(bases)).Parse(templates)
+	if err != nil {
+		return err
+	}
 
-{{end}}{{end}}// New{{.BaseName}} functions for creating expression instances
-{{range .Classes}}func New{{.Name}}Expr({{makeParams .Fields}}) *{{.Name}}Expr {
-	return &{{.Name}}Expr{
-{{makeInitializers .Fields}}
+	for _, base := range bases {
+		err := generate(t, "base", filepath.Join(outputDir, strings.ToLower(base.Name)+".go"), struct {
+			Spec        string
+			PackageName string
+			*AstBase
+		}{data.Spec, packageName, base})
+		if err != nil {
+			return err
+		}
 	}
+	return generate(t, "node", filepath.Join(outputDir, "node.go"), data)
 }
 
-{{end}}`
-
-	// Determine interface methods based on all unique fields
-	methods := make([]string, 0, len(allFields))
-	for fieldName, fieldType := range allFields {
-		methodName := makeGetterName(fieldName)
-		methods = append(methods, methodName+"() "+fieldType)
+func generate(t *template.Template, name, outputPath string, data any) error {
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
 	}
-
-	// Create a map for class fields lookup
-	classFieldMap := make(map[string][]AstField)
-	for _, class := range classes {
-		classFieldMap[class.Name] = class.Fields
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("formatting %s: %w", outputPath, err)
 	}
+	return os.WriteFile(outputPath, source, 0644)
+}
 
-	// Remove imports if we're in the same package
-	imports := []string{}
-	if packageName != "ast" {
-		imports = append(imports, "github.com/bagaswh/rottenlang/pkg/ast")
+func newFuncMap(bases []*AstBase) template.FuncMap {
+	isBase := make(map[string]bool)
+	for _, base := range bases {
+		isBase[base.Name] = true
 	}
 
-	data := struct {
-		PackageName      string
-		BaseName         string
-		Classes          []AstClass
-		InterfaceMethods []string
-		Imports          []string
-		AllFields        map[string]string
-		ClassFieldMap    map[string][]AstField
-	}{
-		PackageName:      packageName,
-		BaseName:         baseName,
-		Classes:          classes,
-		InterfaceMethods: methods,
-		Imports:          imports,
-		AllFields:        allFields,
-		ClassFieldMap:    classFieldMap,
+	// kind classifies a field type for Equal, Clone and Walk
+	kind := func(fieldType string) string {
+		switch {
+		case isBase[fieldType]:
+			return "node"
+		case strings.HasPrefix(fieldType, "[]") && isBase[fieldType[2:]]:
+			return "nodes"
+		case fieldType == "*Token":
+			return "token"
+		case fieldType == "[]*Token":
+			return "tokens"
+		}
+		return "value"
 	}
 
-	funcMap := template.FuncMap{
-		"makeGetter": makeGetterName,
-		"makeParams": func(fields []AstField) string {
+	return template.FuncMap{
+		"kind":   kind,
+		"getter": makeGetterName,
+		"elem": func(fieldType string) string {
+			return strings.TrimPrefix(fieldType, "[]")
+		},
+		// param is the parameter name used for a node, e.g. expr
+		"param": strings.ToLower,
+		"noun": func(baseName string) string {
+			switch baseName {
+			case "Expr":
+				return "expression"
+			case "Stmt":
+				return "statement"
+			}
+			return strings.ToLower(baseName)
+		},
+		"receiver": func(baseName string) string {
+			return strings.ToLower(baseName[:1])
+		},
+		"params": func(fields []AstField) string {
 			params := make([]string, len(fields))
 			for i, field := range fields {
 				params[i] = field.Name + " " + field.Type
 			}
 			return strings.Join(params, ", ")
 		},
-		"makeInitializers": func(fields []AstField) string {
-			inits := make([]string, len(fields))
-			for i, field := range fields {
-				inits[i] = "\t\t" + field.Name + ": " + field.Name + ","
+		"visitors": func() string {
+			visitors := make([]string, len(bases))
+			for i, base := range bases {
+				visitors[i] = base.Visitor + "[R]"
 			}
-			return strings.Join(inits, "\n")
-		},
-		"makeImports": func(imports []string) string {
-			if len(imports) == 0 {
-				return ""
+			if len(visitors) < 3 {
+				return strings.Join(visitors, " and ")
 			}
-
-			str := "import (\n"
-			for _, imp := range imports {
-				str += fmt.Sprintf("\t\"%s\"\n", imp)
-			}
-			return str + ")"
-		},
-		"hasField": func(fields []AstField, fieldName string) bool {
-			for _, field := range fields {
-				if field.Name == fieldName {
-					return true
-				}
-			}
-			return false
+			return strings.Join(visitors[:len(visitors)-1], ", ") + " and " + visitors[len(visitors)-1]
 		},
 	}
-
-	t, err := template.New("ast").Funcs(funcMap).Parse(tmpl)
-	if err != nil {
-		return err
-	}
-
-	return t.Execute(file, data)
-}
-
-func parseTypes(types []string, interfaceMethods []string) []AstClass {
-	var classes []AstClass
-
-	for _, typeSpec := range types {
-		// Split class name and fields
-		parts := strings.Split(typeSpec, ":")
-		className := strings.TrimSpace(parts[0])
-
-		var fields []AstField
-		if len(parts) > 1 {
-			fieldSpecs := strings.Split(strings.TrimSpace(parts[1]), ",")
-
-			for _, fieldSpec := range fieldSpecs {
-				fieldSpec = strings.TrimSpace(fieldSpec)
-				if fieldSpec == "" {
-					continue
-				}
-
-				fieldParts := strings.Fields(fieldSpec)
-
-				// Ensure we have type and name
-				if len(fieldParts) >= 2 {
-					fieldType := strings.TrimSpace(fieldParts[0])
-					fieldName := strings.TrimSpace(fieldParts[1])
-
-					// Handle pointer types like *Token
-					if fieldType != "Expr" && fieldType != "any" {
-						// Don't add * if it's already a pointer type
-						if !strings.HasPrefix(fieldType, "*") {
-							fieldType = "*" + fieldType
-						}
-					}
-
-					fields = append(fields, AstField{
-						Type:      fieldType,
-						Name:      fieldName,
-						ClassName: className,
-					})
-				}
-			}
-		}
-
-		classes = append(classes, AstClass{
-			Name:   className,
-			Fields: fields,
-		})
-	}
-
-	return classes
 }
 
 func makeGetterName(fieldName string) string {
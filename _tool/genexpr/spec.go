@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type AstField struct {
+	Type string
+	Name string
+	// Doc documents the field's getter
+	Doc string
+}
+
+type AstClass struct {
+	// Name is the name of the node's type, e.g. BinaryExpr
+	Name   string
+	Doc    []string
+	Fields []AstField
+}
+
+// AstBase is an interface, such as Expr, and the nodes implementing it.
+type AstBase struct {
+	Name    string
+	Visitor string
+	Accept  string
+	Classes []AstClass
+}
+
+// parseSpec reads node definitions, in the format described at the top of
+// pkg/ast/ast.spec.
+func parseSpec(r io.Reader) ([]*AstBase, error) {
+	var (
+		bases []*AstBase
+		base  *AstBase
+		class *AstClass
+		doc   []string
+	)
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", lineNo, fmt.Sprintf(format, args...))
+		}
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			class = nil
+
+		case raw != line && class != nil:
+			// an indented field of the node above
+			field, err := parseField(line)
+			if err != nil {
+				return nil, fail("%v", err)
+			}
+			class.Fields = append(class.Fields, field)
+
+		case strings.HasPrefix(line, "["):
+			parts := strings.Fields(strings.Trim(line, "[]"))
+			if len(parts) != 3 {
+				return nil, fail("expected [Base Visitor Accept], got %s", line)
+			}
+			base = &AstBase{Name: parts[0], Visitor: parts[1], Accept: parts[2]}
+			bases = append(bases, base)
+			class = nil
+			doc = nil
+
+		case strings.HasPrefix(line, "//"):
+			doc = append(doc, line)
+
+		default:
+			if base == nil {
+				return nil, fail("node outside of a [Base Visitor Accept] section")
+			}
+			name, fields, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fail("expected Name : fields, got %s", line)
+			}
+			base.Classes = append(base.Classes, AstClass{
+				Name: strings.TrimSpace(name) + base.Name,
+				Doc:  doc,
+			})
+			class = &base.Classes[len(base.Classes)-1]
+			doc = nil
+
+			for _, fieldSpec := range strings.Split(fields, ",") {
+				if strings.TrimSpace(fieldSpec) == "" {
+					continue
+				}
+				field, err := parseField(fieldSpec)
+				if err != nil {
+					return nil, fail("%v", err)
+				}
+				class.Fields = append(class.Fields, field)
+			}
+		}
+	}
+	return bases, scanner.Err()
+}
+
+// parseField parses "Type name", optionally followed by a "//" comment.
+func parseField(spec string) (AstField, error) {
+	spec, doc, _ := strings.Cut(spec, "//")
+	parts := strings.Fields(spec)
+	if len(parts) != 2 {
+		return AstField{}, fmt.Errorf("expected Type name, got %s", strings.TrimSpace(spec))
+	}
+	field := AstField{Type: parts[0], Name: parts[1]}
+	if doc = strings.TrimSpace(doc); doc != "" {
+		field.Doc = "// " + doc
+	}
+	return field, nil
+}
@@ -0,0 +1,166 @@
+package main
+
+const templates = `
+{{define "header"}}// Code generated by genexpr from {{.Spec}}; DO NOT EDIT.
+
+package {{.PackageName}}
+{{end}}
+
+{{define "base"}}{{template "header" .}}
+{{$base := .Name}}{{$param := param .Name}}{{$recv := receiver .Name}}
+import "fmt"
+
+// {{.Visitor}} is implemented by operations on {{noun .Name}}s, each producing an R.
+// {{.Accept}} calls the method matching the type of the node.
+type {{.Visitor}}[R any] interface {
+{{range .Classes}}	Visit{{.Name}}({{$param}} *{{.Name}}) R
+{{end}}}
+
+type {{.Name}} interface {
+	Node
+	{{$param}}Node()
+}
+
+// {{.Accept}} calls the method of visitor for the type of {{$param}}.
+func {{.Accept}}[R any]({{$param}} {{.Name}}, visitor {{.Visitor}}[R]) R {
+	switch {{$param}} := {{$param}}.(type) {
+{{range .Classes}}	case *{{.Name}}:
+		return visitor.Visit{{.Name}}({{$param}})
+{{end}}	}
+	panic(fmt.Sprintf("ast: unexpected {{noun .Name}} %T", {{$param}}))
+}
+{{range .Classes}}{{$class := .Name}}
+{{range .Doc}}{{.}}
+{{end}}type {{.Name}} struct {
+{{range .Fields}}	{{.Name}} {{.Type}}
+{{end}}}
+
+func (*{{.Name}}) node() {}
+func (*{{.Name}}) {{$param}}Node() {}
+{{range .Fields}}
+{{with .Doc}}{{.}}
+{{end}}func ({{$recv}} *{{$class}}) {{getter .Name}}() {{.Type}} {
+	return {{$recv}}.{{.Name}}
+}
+{{end}}
+func New{{.Name}}({{params .Fields}}) *{{.Name}} {
+	return &{{.Name}}{
+{{range .Fields}}		{{.Name}}: {{.Name}},
+{{end}}	}
+}
+{{end}}{{end}}
+
+{{define "node"}}{{template "header" .}}
+import (
+	"fmt"
+	"slices"
+)
+
+// BaseVisitor implements {{visitors}}
+// with methods that do nothing and return the zero R. Embed it in a visitor
+// to implement only the methods an operation needs.
+type BaseVisitor[R any] struct{}
+{{range .Bases}}{{$param := param .Name}}{{range .Classes}}
+func (BaseVisitor[R]) Visit{{.Name}}({{$param}} *{{.Name}}) R {
+	var zero R
+	return zero
+}
+{{end}}{{end}}
+// Equal reports whether a and b are the same tree. Tokens are compared by
+// type, lexeme and literal but not position, so the trees of differently
+// formatted sources are equal.
+func Equal(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	switch a := a.(type) {
+{{range .Bases}}{{range .Classes}}	case *{{.Name}}:
+		b, ok := b.(*{{.Name}})
+		return ok{{range .Fields}} &&
+			{{- $kind := kind .Type}}
+			{{- if eq $kind "node"}} Equal(a.{{.Name}}, b.{{.Name}})
+			{{- else if eq $kind "nodes"}} equal{{elem .Type}}s(a.{{.Name}}, b.{{.Name}})
+			{{- else if eq $kind "token"}} equalToken(a.{{.Name}}, b.{{.Name}})
+			{{- else if eq $kind "tokens"}} slices.EqualFunc(a.{{.Name}}, b.{{.Name}}, equalToken)
+			{{- else}} a.{{.Name}} == b.{{.Name}}{{end}}{{end}}
+{{end}}{{end}}	}
+	panic(fmt.Sprintf("ast.Equal: unexpected node type %T", a))
+}
+
+func equalToken(a, b *Token) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Type == b.Type && *a.Lexeme == *b.Lexeme && a.Literal == b.Literal
+}
+{{range .Bases}}
+func equal{{.Name}}s(a, b []{{.Name}}) bool {
+	return slices.EqualFunc(a, b, func(a, b {{.Name}}) bool {
+		return Equal(a, b)
+	})
+}
+{{end}}{{range .Bases}}{{$base := .Name}}{{$param := param .Name}}
+// Clone{{.Name}} returns a deep copy of {{$param}}. Tokens are immutable and shared
+// with the copy.
+func Clone{{.Name}}({{$param}} {{.Name}}) {{.Name}} {
+	switch {{$param}} := {{$param}}.(type) {
+	case nil:
+		return nil
+{{range .Classes}}	case *{{.Name}}:
+		return &{{.Name}}{
+{{range .Fields}}			{{.Name}}:
+			{{- $kind := kind .Type}}
+			{{- if eq $kind "node"}} Clone{{.Type}}({{$param}}.{{.Name}})
+			{{- else if eq $kind "nodes"}} clone{{elem .Type}}s({{$param}}.{{.Name}})
+			{{- else if eq $kind "tokens"}} slices.Clone({{$param}}.{{.Name}})
+			{{- else}} {{$param}}.{{.Name}}{{end}},
+{{end}}		}
+{{end}}	}
+	panic(fmt.Sprintf("ast.Clone{{.Name}}: unexpected {{noun .Name}} %T", {{$param}}))
+}
+
+func clone{{.Name}}s(list []{{.Name}}) []{{.Name}} {
+	if list == nil {
+		return nil
+	}
+	cloned := make([]{{.Name}}, len(list))
+	for i, {{$param}} := range list {
+		cloned[i] = Clone{{.Name}}({{$param}})
+	}
+	return cloned
+}
+{{end}}
+// Field is a field of a node, named as in {{.Spec}}. Its value is a child
+// node, a slice of them, a *Token, a slice of tokens or a plain value.
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Fields returns the fields of node, in the order {{.Spec}} declares them.
+func Fields(node Node) []Field {
+	switch n := node.(type) {
+{{range .Bases}}{{range .Classes}}	case *{{.Name}}:
+		return []Field{ {{- range $i, $f := .Fields}}{{if $i}}, {{end}}{"{{$f.Name}}", n.{{$f.Name}}}{{end -}} }
+{{end}}{{end}}	}
+	panic(fmt.Sprintf("ast.Fields: unexpected node type %T", node))
+}
+
+// walkChildren walks each non-nil child of node with w.
+func walkChildren(w Walker, node Node) {
+	switch n := node.(type) {
+{{range .Bases}}{{range .Classes}}	case *{{.Name}}:
+{{range .Fields}}{{$kind := kind .Type}}{{if eq $kind "node"}}		if n.{{.Name}} != nil {
+			Walk(w, n.{{.Name}})
+		}
+{{else if eq $kind "nodes"}}		for _, child := range n.{{.Name}} {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+{{end}}{{end}}{{end}}{{end}}	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+}
+{{end}}
+`
@@ -0,0 +1,62 @@
+//go:build js && wasm
+
+// Command rottenlang-wasm runs rottenlang in a browser. Build it with
+//
+//	GOOS=js GOARCH=wasm go build -o rottenlang.wasm ./cmd/rottenlang-wasm
+//
+// and load it with the wasm_exec.js of the Go distribution, found in
+// $(go env GOROOT)/lib/wasm. Once running, it defines a global rottenlang
+// object with the functions
+//
+//	rottenlang.tokenize(source)
+//	rottenlang.parse(source)
+//	rottenlang.format(source)
+//	rottenlang.run(source, {engine: "tree", maxSteps: 0, optimize: false})
+//
+// each returning a JSON string encoding a playground.Result: the tokens,
+// the syntax tree, the formatted source or what the program printed, along
+// with the diagnostics and what would have been written to stderr. The
+// options of run may be left out.
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+
+	"github.com/bagaswh/rottenlang/pkg/playground"
+)
+
+func main() {
+	js.Global().Set("rottenlang", js.ValueOf(map[string]any{
+		"tokenize": stage(playground.Tokenize),
+		"parse":    stage(playground.Parse),
+		"format":   stage(playground.Format),
+		"run": js.FuncOf(func(this js.Value, args []js.Value) any {
+			var options playground.Options
+			if len(args) > 1 && args[1].Type() == js.TypeObject {
+				encoded := js.Global().Get("JSON").Call("stringify", args[1]).String()
+				if err := json.Unmarshal([]byte(encoded), &options); err != nil {
+					return (&playground.Result{Error: err.Error()}).JSON()
+				}
+			}
+			return playground.Run(source(args), options).JSON()
+		}),
+	}))
+	// the functions are called back for as long as the page lives
+	select {}
+}
+
+// stage returns a JavaScript function calling fn with the source it's
+// given.
+func stage(fn func(string) *playground.Result) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		return fn(source(args)).JSON()
+	})
+}
+
+func source(args []js.Value) string {
+	if len(args) == 0 || args[0].Type() != js.TypeString {
+		return ""
+	}
+	return args[0].String()
+}
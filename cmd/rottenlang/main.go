@@ -1,47 +1,409 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/bagaswh/rottenlang/pkg/dap"
 	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/lsp"
+	"github.com/bagaswh/rottenlang/pkg/parser"
+	"github.com/bagaswh/rottenlang/pkg/profiler"
+	"github.com/bagaswh/rottenlang/pkg/repl"
 	"github.com/bagaswh/rottenlang/pkg/rottenlang"
+	"github.com/bagaswh/rottenlang/pkg/rottentest"
+	"github.com/bagaswh/rottenlang/pkg/value"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+var (
+	engine      string
+	historyFile string
+	limits      value.Limits
+	timeout     time.Duration
+	keywordPack string
+	runPattern  string
+	verbose     bool
+	modulePath  string
+	optimize    bool
+	dumpTree    bool
+	target      string
+	outDir      string
+	packageName string
+	cpuProfile  string
+	stats       bool
+)
+
 var rootCmd = &cobra.Command{
-	Use:   "app [file]",
-	Short: "A simple application that processes a file",
+	Use:   "rottenlang [file]",
+	Short: "Run a rottenlang program, or start the REPL when no file is given",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		selected := selectedEngine()
+		if len(args) == 0 {
+			runREPL(selected)
+			return
+		}
+		runFile(args[0], selected)
+	},
+}
+
+var runCmd = &cobra.Command{
+	Use:   "run [file]",
+	Short: "Run a rottenlang program",
+	Long: `Run a rottenlang program. With --cpuprofile, the steps, allocations and
+time of each line of each function are written as a pprof profile, to view
+with go tool pprof; --stats prints the number of steps, allocations and
+calls of each function once the program ends.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runFile(args[0], selectedEngine())
+	},
+}
+
+var disasmCmd = &cobra.Command{
+	Use:   "disasm [file]",
+	Short: "Print the bytecode a program compiles to",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		filename := args[0]
+		rottenlang := newRottenlang(readSource(args[0]))
+		if err := rottenlang.Disassemble(os.Stdout); err != nil {
+			os.Exit(1)
+		}
+	},
+}
+
+var buildCmd = &cobra.Command{
+	Use:   "build [file]",
+	Short: "Translate a program into another language",
+	Long: `Translate a program into another language, writing the files of the
+translation to the output directory. The go target writes a Go package,
+holding the program and the runtime support library it needs; package main
+runs the program, other packages run it by calling Run. The js target
+writes an ES2020 module exporting run, call and main, its source map and
+the runtime support module it imports.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		selected, err := rottenlang.ParseTarget(target)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
 
-		if _, err := os.Stat(filename); os.IsNotExist(err) {
-			fmt.Printf("Error: File '%s' does not exist\n", filename)
+		options := rottenlang.BuildOptions{
+			Package: packageName,
+			Source:  sourceFrom(outDir, args[0]),
+		}
+		rottenlang := newRottenlang(readSource(args[0]))
+		rottenlang.Path = args[0]
+		files, err := rottenlang.Build(selected, options)
+		if err != nil {
 			os.Exit(1)
 		}
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			fmt.Printf("Error: Failed creating directory '%s': %v\n", outDir, err)
+			os.Exit(1)
+		}
+		for name, content := range files {
+			path := filepath.Join(outDir, name)
+			if err := os.WriteFile(path, content, 0644); err != nil {
+				fmt.Printf("Error: Failed writing file '%s': %v\n", path, err)
+				os.Exit(1)
+			}
+		}
+	},
+}
 
-		f, err := os.OpenFile(filename, os.O_RDONLY, 0400)
+var testCmd = &cobra.Command{
+	Use:   "test [dir]",
+	Short: "Run the tests of the *_test.rot files in a directory",
+	Long: `Run the tests of the *_test.rot files in a directory, by default the
+current one, and its subdirectories. Every top-level function whose name
+starts with "test" is a test; it fails when it stops with a runtime error,
+such as one raised by assert(condition), assert_eq(got, want) or
+assert_throws(fn).`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "."
+		if len(args) > 0 {
+			dir = args[0]
+		}
+		files, err := rottentest.FindFiles(dir)
 		if err != nil {
-			fmt.Printf("Error: Failed opening file '%s': %v", filename, err.Error())
+			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
-		source, err := io.ReadAll(f)
+
+		runner := rottentest.NewRunner(selectedEngine(), os.Stdout)
+		runner.Verbose = verbose
+		if runPattern != "" {
+			runner.Match, err = regexp.Compile(runPattern)
+			if err != nil {
+				fmt.Printf("Error: Invalid --run pattern: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		pack := loadKeywordPack()
+		searchPath := moduleSearchPath()
+		runner.Prepare = func(r *rottenlang.Rottenlang) {
+			r.KeywordPack = pack
+			r.SearchPath = searchPath
+		}
+
+		passed, err := runner.RunFiles(files)
 		if err != nil {
-			fmt.Printf("Error: Failed reading file '%s': %v", filename, err.Error())
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !passed {
+			os.Exit(1)
+		}
+	},
+}
+
+var replCmd = &cobra.Command{
+	Use:   "repl",
+	Short: "Start an interactive session",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		runREPL(selectedEngine())
+	},
+}
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Start a Language Server Protocol server on stdio",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := lsp.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
-		rottenlang := rottenlang.NewRottenlang(string(source), &errorreporter.StderrErrorReporter{})
-		rottenlang.Scan()
 	},
 }
 
+var dapCmd = &cobra.Command{
+	Use:   "dap",
+	Short: "Start a Debug Adapter Protocol server on stdio",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := dap.NewServer(os.Stdin, os.Stdout).Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// sourceFrom returns the path of the source file relative to dir, which is
+// how the files written to dir refer to it.
+func sourceFrom(dir, file string) string {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return file
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	rel, err := filepath.Rel(absDir, absFile)
+	if err != nil {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+// runFile runs the program in filename with the engine and flags given on
+// the command line.
+func runFile(filename string, engine rottenlang.Engine) {
+	rottenlang := newRottenlang(readSource(filename))
+	rottenlang.Path = filename
+	rottenlang.Limits = limits
+	if dumpTree {
+		rottenlang.Optimize = true
+		if err := rottenlang.Dump(os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		return
+	}
+	if cpuProfile != "" || stats {
+		rottenlang.Profiler = profiler.NewProfiler()
+	}
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	err := rottenlang.ExecuteContext(ctx, engine)
+	// a program stopped by an error is profiled up to where it stopped
+	if rottenlang.Profiler != nil {
+		writeProfile(rottenlang.Profiler)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// writeProfile writes what profile recorded where --cpuprofile and --stats
+// ask for it.
+func writeProfile(profile *profiler.Profiler) {
+	if stats {
+		profile.WriteStats(os.Stderr)
+	}
+	if cpuProfile == "" {
+		return
+	}
+	f, err := os.Create(cpuProfile)
+	if err != nil {
+		fmt.Printf("Error: Failed creating profile '%s': %v\n", cpuProfile, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	if err := profile.WriteProfile(f); err != nil {
+		fmt.Printf("Error: Failed writing profile '%s': %v\n", cpuProfile, err)
+		os.Exit(1)
+	}
+}
+
+// addRunFlags adds the flags controlling how programs run to cmd.
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&limits.MaxSteps, "max-steps", 0, "stop the program after this many steps, 0 for no limit")
+	cmd.Flags().IntVar(&limits.MaxCallDepth, "max-call-depth", 0, fmt.Sprintf("maximum number of nested calls, 0 for the default of %d", value.MaxCallDepth))
+	cmd.Flags().IntVar(&limits.MaxStringLength, "max-string-length", 0, "maximum length of strings in bytes, 0 for no limit")
+	cmd.Flags().IntVar(&limits.MaxListLength, "max-list-length", 0, "maximum number of elements of lists and entries of maps, 0 for no limit")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "stop the program after this long, 0 for no limit")
+	cmd.Flags().StringVar(&keywordPack, "keyword-pack", "", "JSON file of extra keywords and operators")
+	cmd.Flags().BoolVarP(&optimize, "optimize", "O", false, "optimize the program before running it")
+	cmd.Flags().BoolVar(&dumpTree, "dump-optimized", false, "print the optimized program instead of running it")
+	cmd.Flags().StringVar(&cpuProfile, "cpuprofile", "", "write a pprof profile of the program's steps, allocations and time to this file")
+	cmd.Flags().BoolVar(&stats, "stats", false, "print the number of steps, allocations and calls of each function once the program ends")
+}
+
+func selectedEngine() rottenlang.Engine {
+	selected, err := rottenlang.ParseEngine(engine)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return selected
+}
+
+// newRottenlang prepares source to run, with the keyword pack given on the
+// command line, if any.
+func newRottenlang(source string) *rottenlang.Rottenlang {
+	r := rottenlang.NewRottenlang(source, &errorreporter.StderrErrorReporter{})
+	r.KeywordPack = loadKeywordPack()
+	r.SearchPath = moduleSearchPath()
+	r.Optimize = optimize
+	return r
+}
+
+// moduleSearchPath returns the directories given by --module-path, or by
+// the ROTTENLANG_PATH environment variable, to look up imported modules in.
+func moduleSearchPath() []string {
+	if modulePath == "" {
+		return nil
+	}
+	return filepath.SplitList(modulePath)
+}
+
+// loadKeywordPack loads the keyword pack given on the command line, or
+// returns nil if there is none.
+func loadKeywordPack() *parser.KeywordPack {
+	if keywordPack == "" {
+		return nil
+	}
+
+	f, err := os.Open(keywordPack)
+	if err != nil {
+		fmt.Printf("Error: Failed opening keyword pack '%s': %v\n", keywordPack, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	pack, err := parser.LoadKeywordPack(f)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return pack
+}
+
+func runREPL(engine rottenlang.Engine) {
+	history, err := repl.LoadHistory(historyFile)
+	if err != nil {
+		fmt.Printf("Error: Failed loading history '%s': %v\n", historyFile, err)
+		os.Exit(1)
+	}
+
+	session := repl.NewREPL(os.Stdin, os.Stdout, &errorreporter.StderrErrorReporter{}, engine, history)
+	if err := session.Run(); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func defaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".rottenlang_history")
+}
+
+func readSource(filename string) string {
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		fmt.Printf("Error: File '%s' does not exist\n", filename)
+		os.Exit(1)
+	}
+
+	f, err := os.OpenFile(filename, os.O_RDONLY, 0400)
+	if err != nil {
+		fmt.Printf("Error: Failed opening file '%s': %v\n", filename, err.Error())
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	source, err := io.ReadAll(f)
+	if err != nil {
+		fmt.Printf("Error: Failed reading file '%s': %v\n", filename, err.Error())
+		os.Exit(1)
+	}
+	return string(source)
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	// Example: rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.app.yaml)")
+	rootCmd.PersistentFlags().StringVar(&engine, "engine", string(rottenlang.EngineTree), "execution engine, 'tree' or 'vm'")
+	rootCmd.PersistentFlags().StringVar(&historyFile, "history", defaultHistoryFile(), "file the REPL keeps its history in")
+	addRunFlags(rootCmd)
+	addRunFlags(runCmd)
+	rootCmd.PersistentFlags().StringVar(&modulePath, "module-path", os.Getenv("ROTTENLANG_PATH"), "directories to look up imported modules in, separated like PATH")
+	disasmCmd.Flags().StringVar(&keywordPack, "keyword-pack", "", "JSON file of extra keywords and operators")
+	disasmCmd.Flags().BoolVarP(&optimize, "optimize", "O", false, "optimize the program before compiling it")
+	buildCmd.Flags().StringVar(&target, "target", string(rottenlang.TargetGo), "language to translate the program into, 'go' or 'js'")
+	buildCmd.Flags().StringVarP(&outDir, "out", "o", ".", "directory to write the translation to")
+	buildCmd.Flags().StringVar(&packageName, "package", "main", "name of the generated Go package")
+	buildCmd.Flags().StringVar(&keywordPack, "keyword-pack", "", "JSON file of extra keywords and operators")
+	buildCmd.Flags().BoolVarP(&optimize, "optimize", "O", false, "optimize the program before translating it")
+	testCmd.Flags().StringVar(&runPattern, "run", "", "run only the tests whose name matches this regular expression, also accepted as -run")
+	testCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "report every test, not only those that fail")
+	testCmd.Flags().StringVar(&keywordPack, "keyword-pack", "", "JSON file of extra keywords and operators")
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(disasmCmd)
+	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(replCmd)
+	rootCmd.AddCommand(lspCmd)
+	rootCmd.AddCommand(dapCmd)
 }
 
 func initConfig() {
@@ -51,7 +413,31 @@ func initConfig() {
 	viper.AutomaticEnv()
 }
 
+// singleDashFlags are the long flags also accepted with a single dash, the
+// way go test spells them.
+var singleDashFlags = []string{"run"}
+
+// normalizeArgs rewrites the flags of singleDashFlags given with a single
+// dash, such as -run, into their long form.
+func normalizeArgs(args []string) []string {
+	normalized := make([]string, len(args))
+	for i, arg := range args {
+		normalized[i] = arg
+		if arg == "--" {
+			copy(normalized[i:], args[i:])
+			break
+		}
+		for _, name := range singleDashFlags {
+			if arg == "-"+name || strings.HasPrefix(arg, "-"+name+"=") {
+				normalized[i] = "-" + arg
+			}
+		}
+	}
+	return normalized
+}
+
 func main() {
+	rootCmd.SetArgs(normalizeArgs(os.Args[1:]))
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
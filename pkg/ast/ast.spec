@@ -0,0 +1,259 @@
+# Syntax tree nodes, generated into Go by _tool/genexpr (see generate.go).
+#
+# A section "[Base Visitor Accept]" starts the nodes implementing the Base
+# interface, visited by Visitor[R] through the Accept function. Each node is
+# "Name : Type field, Type field", or "Name :" followed by one indented
+# field per line, which may end with a "//" comment documenting its getter.
+# "//" lines before a node document its type.
+
+[Expr Visitor Accept]
+
+// BinaryExpr is an arithmetic, comparison or equality operation.
+Binary   : Expr left, *Token operator, Expr right
+
+// UnaryExpr is a prefix operation such as negation.
+Unary    : *Token operator, Expr right
+
+// LiteralExpr is a number, string, boolean or nil written in the source.
+Literal  : any value
+
+// GroupingExpr is a parenthesized expression.
+Grouping : Expr expr
+
+// VariableExpr reads a variable.
+Variable : *Token name
+
+// AssignExpr assigns to an existing variable.
+Assign   : *Token name, Expr value
+
+// CompoundAssignExpr applies an operator to a variable and assigns the
+// result, as in "x += 1".
+CompoundAssign :
+    *Token name
+    *Token operator   // Operator is a compound operator, see CompoundOperator.
+    Expr   value
+
+// UpdateExpr increments or decrements a variable with "++" or "--". A prefix
+// update evaluates to the new value, a postfix one to the old value.
+Update   : *Token name, *Token operator, bool prefix
+
+// LogicalExpr is a short-circuiting "and"/"or" expression.
+Logical  : Expr left, *Token operator, Expr right
+
+// ConditionalExpr is "condition ? thenBranch : elseBranch", evaluating only
+// the branch selected by the condition.
+Conditional :
+    Expr   condition
+    *Token question
+    Expr   thenBranch
+    Expr   elseBranch
+
+// CoalesceExpr is "left ?? right", evaluating right only when left is nil.
+Coalesce : Expr left, *Token operator, Expr right
+
+// GetExpr reads a property of an object, "object.name".
+Get      : Expr object, *Token name
+
+// OptionalGetExpr is "object?.name", which is nil instead of an error when
+// the object is nil. A nil object skips the rest of the chain of property
+// accesses and calls the expression is part of, so "a?.b.c" is nil too.
+OptionalGet : Expr object, *Token name
+
+// CallExpr calls a function.
+Call :
+    Expr   callee
+    *Token paren      // Paren is the closing parenthesis, used to report call errors.
+    []Expr arguments
+
+// IndexExpr reads an element of a list or an entry of a map, "object[index]".
+Index :
+    Expr   object
+    *Token bracket    // Bracket is the opening bracket, used to report indexing errors.
+    Expr   index
+
+// SetExpr assigns to a property of an object, "object.name = value".
+Set : Expr object, *Token name, Expr value
+
+// SetIndexExpr assigns to an element of a list or an entry of a map,
+// "object[index] = value".
+SetIndex :
+    Expr   object
+    *Token bracket
+    Expr   index
+    Expr   value
+
+// CompoundSetExpr applies an operator to a property of an object and
+// assigns the result, as in "object.name += 1".
+CompoundSet :
+    Expr   object
+    *Token name
+    *Token operator   // Operator is a compound operator, see CompoundOperator.
+    Expr   value
+
+// CompoundSetIndexExpr applies an operator to an element of a list or an
+// entry of a map and assigns the result, as in "object[index] += 1".
+CompoundSetIndex :
+    Expr   object
+    *Token bracket
+    Expr   index
+    *Token operator   // Operator is a compound operator, see CompoundOperator.
+    Expr   value
+
+// UpdatePropertyExpr increments or decrements a property of an object, as
+// UpdateExpr does a variable.
+UpdateProperty : Expr object, *Token name, *Token operator, bool prefix
+
+// UpdateIndexExpr increments or decrements an element of a list or an entry
+// of a map, as UpdateExpr does a variable.
+UpdateIndex :
+    Expr   object
+    *Token bracket
+    Expr   index
+    *Token operator
+    bool   prefix
+
+// ThisExpr is the instance a method was called on.
+This : *Token keyword
+
+// ListExpr makes a list of the values of elements, "[a, b]".
+List : *Token bracket, []Expr elements
+
+// MapExpr makes a map from each of keys to the entry of values at the same
+// index, "{name: value}". A key written as a name is the string of the name.
+Map :
+    *Token brace
+    []Expr keys
+    []Expr values
+
+// MatchExpr evaluates to the value of the first arm whose pattern matches
+// the subject and whose guard, if it has one, is true. The arms are the
+// entries of patterns, guards and values at the same index.
+Match :
+    *Token    keyword
+    Expr      subject
+    []Pattern patterns
+    []Expr    guards     // Guards has a nil entry for each arm without a guard.
+    []Expr    values
+
+// SpawnExpr runs a call as a task of its own, evaluating to the task. The
+// callee and arguments are evaluated before the task starts.
+Spawn : *Token keyword, Expr call
+
+[Stmt StmtVisitor AcceptStmt]
+
+// ExpressionStmt evaluates an expression for its side effects.
+Expression : Expr expression
+
+// VarStmt declares a variable with "vibes" or a constant with "slay".
+Var        : *Token name, Expr initializer, bool constant
+
+// BlockStmt is a list of statements in a scope of their own.
+Block      : []Stmt statements
+
+// IfStmt is a "chat is this real" statement.
+If :
+    Expr condition
+    Stmt thenBranch
+    Stmt elseBranch   // ElseBranch returns nil when the statement has no else branch.
+
+// WhileStmt also represents desugared "for" loops.
+While :
+    *Token keyword    // Keyword is the "skibidi" or "for" token that starts the loop.
+    Expr   condition
+    Stmt   body
+
+// FunctionStmt declares a function.
+Function :
+    *Token   name
+    []*Token params
+    []Stmt   body
+    bool     generator   // Generator reports whether the body yields, making calls return a generator.
+
+// ClassStmt declares a class. Calling it makes an instance, which its
+// methods, all FunctionStmt, are called on.
+Class : *Token name, []Stmt methods
+
+// ForInStmt runs body once for each value of iterable, with the value
+// bound to name in a scope of its own.
+ForIn :
+    *Token keyword
+    *Token name
+    Expr   iterable
+    Stmt   body
+
+// ReturnStmt is a "purrr" statement.
+Return :
+    *Token keyword
+    Expr   value      // Value returns nil for a bare "purrr;".
+
+// YieldStmt suspends a generator, handing value to the code resuming it.
+Yield : *Token keyword, Expr value
+
+// ImportStmt is "import path as name", binding the module at path to name.
+Import :
+    *Token keyword
+    *Token path       // Path is the string token naming the module file.
+    *Token name
+
+// ThrowStmt raises an error, "throw value".
+Throw :
+    *Token keyword
+    Expr   value
+
+// TryStmt runs body, handing the errors it raises to the catch clause and
+// running the finally clause however body or the catch clause ends.
+Try :
+    *Token keyword
+    []Stmt body
+    *Token catchName    // CatchName returns nil when the statement has no catch clause.
+    []Stmt catchBody
+    *Token finally      // Finally returns nil when the statement has no finally clause.
+    []Stmt finallyBody
+
+// SelectStmt waits for the first of several channel operations to proceed
+// and runs its body. Each case is a call of a channel's send or receive
+// method, at the same index as its body and as the name of the value it
+// receives.
+Select :
+    *Token   keyword
+    []Expr   cases
+    []*Token names        // Names has a nil entry for each case that doesn't name a value.
+    []Stmt   bodies
+    Stmt     elseBranch   // ElseBranch returns nil when the statement has no else branch.
+
+[Pattern PatternVisitor AcceptPattern]
+
+// LiteralPattern matches values equal to a number, string, boolean or nil.
+Literal :
+    *Token token      // Token is the literal's token, without the sign of a negative number.
+    any    value
+
+// WildcardPattern is "_", matching any value.
+Wildcard : *Token underscore
+
+// BindingPattern matches any value, binding it to name in the arm.
+Binding : *Token name
+
+// ObjectPattern matches objects having every property in names, each
+// matching the pattern at the same index of patterns.
+Object :
+    *Token    brace
+    []*Token  names
+    []Pattern patterns
+
+// ListPattern matches lists whose elements match patterns, pattern by
+// pattern. Without a rest the list must have as many elements as there are
+// patterns; with one it may have more, which rest binds as a list unless it
+// is "_".
+List :
+    *Token    bracket
+    []Pattern patterns
+    *Token    rest       // Rest returns nil when the pattern doesn't end with "...name".
+
+// ClassPattern matches instances of the class called name whose properties
+// match the patterns, as an ObjectPattern does.
+Class :
+    *Token    name
+    *Token    brace
+    []*Token  names
+    []Pattern patterns
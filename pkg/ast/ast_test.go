@@ -0,0 +1,69 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/types"
+)
+
+// sampleProgram builds "func f(a) { purrr a + 1; } f(arg);" on the given
+// line.
+func sampleProgram(line int, arg float64) *BlockStmt {
+	name := func(s string) *Token { return NewToken(TokenIdentifier, types.StrPtr(s), nil, line, 0) }
+	plus := NewToken(TokenPlus, types.StrPtr("+"), nil, line, 0)
+
+	return NewBlockStmt([]Stmt{
+		NewFunctionStmt(name("f"), []*Token{name("a")}, []Stmt{
+			NewReturnStmt(name("purrr"), NewBinaryExpr(NewVariableExpr(name("a")), plus, NewLiteralExpr(1.0))),
+		}, false),
+		NewExpressionStmt(NewCallExpr(NewVariableExpr(name("f")), name(")"), []Expr{NewLiteralExpr(arg)})),
+	})
+}
+
+func TestInspect(t *testing.T) {
+	program := sampleProgram(1, 2)
+
+	var visited []string
+	Inspect(program, func(node Node) bool {
+		if node == nil {
+			return false
+		}
+		visited = append(visited, fmt.Sprintf("%T", node))
+		// skip the operands of binary expressions
+		_, binary := node.(*BinaryExpr)
+		return !binary
+	})
+
+	want := []string{
+		"*ast.BlockStmt",
+		"*ast.FunctionStmt", "*ast.ReturnStmt", "*ast.BinaryExpr",
+		"*ast.ExpressionStmt", "*ast.CallExpr", "*ast.VariableExpr", "*ast.LiteralExpr",
+	}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited %v, want %v", visited, want)
+	}
+}
+
+func TestEqualAndClone(t *testing.T) {
+	program := sampleProgram(1, 2)
+
+	if !Equal(program, sampleProgram(7, 2)) {
+		t.Error("trees differing only in token positions are not equal")
+	}
+	if Equal(program, sampleProgram(1, 3)) {
+		t.Error("trees with different literals are equal")
+	}
+	if Equal(program.Statements()[0], program.Statements()[1]) {
+		t.Error("different statements are equal")
+	}
+
+	clone := CloneStmt(program).(*BlockStmt)
+	if !Equal(program, clone) {
+		t.Error("clone is not equal to the original")
+	}
+	if clone.Statements()[1] == program.Statements()[1] {
+		t.Error("clone shares nodes with the original")
+	}
+}
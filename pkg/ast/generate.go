@@ -0,0 +1,3 @@
+package ast
+
+//go:generate go run ../../_tool/genexpr -spec ast.spec -out .
@@ -0,0 +1,1136 @@
+// Code generated by genexpr from ast.spec; DO NOT EDIT.
+
+package ast
+
+import (
+	"fmt"
+	"slices"
+)
+
+// BaseVisitor implements Visitor[R], StmtVisitor[R] and PatternVisitor[R]
+// with methods that do nothing and return the zero R. Embed it in a visitor
+// to implement only the methods an operation needs.
+type BaseVisitor[R any] struct{}
+
+func (BaseVisitor[R]) VisitBinaryExpr(expr *BinaryExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitUnaryExpr(expr *UnaryExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitLiteralExpr(expr *LiteralExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitGroupingExpr(expr *GroupingExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitVariableExpr(expr *VariableExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitAssignExpr(expr *AssignExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitCompoundAssignExpr(expr *CompoundAssignExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitUpdateExpr(expr *UpdateExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitLogicalExpr(expr *LogicalExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitConditionalExpr(expr *ConditionalExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitCoalesceExpr(expr *CoalesceExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitGetExpr(expr *GetExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitOptionalGetExpr(expr *OptionalGetExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitCallExpr(expr *CallExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitIndexExpr(expr *IndexExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitSetExpr(expr *SetExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitSetIndexExpr(expr *SetIndexExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitCompoundSetExpr(expr *CompoundSetExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitCompoundSetIndexExpr(expr *CompoundSetIndexExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitUpdatePropertyExpr(expr *UpdatePropertyExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitUpdateIndexExpr(expr *UpdateIndexExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitThisExpr(expr *ThisExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitListExpr(expr *ListExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitMapExpr(expr *MapExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitMatchExpr(expr *MatchExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitSpawnExpr(expr *SpawnExpr) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitExpressionStmt(stmt *ExpressionStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitVarStmt(stmt *VarStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitBlockStmt(stmt *BlockStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitIfStmt(stmt *IfStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitWhileStmt(stmt *WhileStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitFunctionStmt(stmt *FunctionStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitClassStmt(stmt *ClassStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitForInStmt(stmt *ForInStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitReturnStmt(stmt *ReturnStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitYieldStmt(stmt *YieldStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitImportStmt(stmt *ImportStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitThrowStmt(stmt *ThrowStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitTryStmt(stmt *TryStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitSelectStmt(stmt *SelectStmt) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitLiteralPattern(pattern *LiteralPattern) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitWildcardPattern(pattern *WildcardPattern) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitBindingPattern(pattern *BindingPattern) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitObjectPattern(pattern *ObjectPattern) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitListPattern(pattern *ListPattern) R {
+	var zero R
+	return zero
+}
+
+func (BaseVisitor[R]) VisitClassPattern(pattern *ClassPattern) R {
+	var zero R
+	return zero
+}
+
+// Equal reports whether a and b are the same tree. Tokens are compared by
+// type, lexeme and literal but not position, so the trees of differently
+// formatted sources are equal.
+func Equal(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	switch a := a.(type) {
+	case *BinaryExpr:
+		b, ok := b.(*BinaryExpr)
+		return ok && Equal(a.left, b.left) && equalToken(a.operator, b.operator) && Equal(a.right, b.right)
+	case *UnaryExpr:
+		b, ok := b.(*UnaryExpr)
+		return ok && equalToken(a.operator, b.operator) && Equal(a.right, b.right)
+	case *LiteralExpr:
+		b, ok := b.(*LiteralExpr)
+		return ok && a.value == b.value
+	case *GroupingExpr:
+		b, ok := b.(*GroupingExpr)
+		return ok && Equal(a.expr, b.expr)
+	case *VariableExpr:
+		b, ok := b.(*VariableExpr)
+		return ok && equalToken(a.name, b.name)
+	case *AssignExpr:
+		b, ok := b.(*AssignExpr)
+		return ok && equalToken(a.name, b.name) && Equal(a.value, b.value)
+	case *CompoundAssignExpr:
+		b, ok := b.(*CompoundAssignExpr)
+		return ok && equalToken(a.name, b.name) && equalToken(a.operator, b.operator) && Equal(a.value, b.value)
+	case *UpdateExpr:
+		b, ok := b.(*UpdateExpr)
+		return ok && equalToken(a.name, b.name) && equalToken(a.operator, b.operator) && a.prefix == b.prefix
+	case *LogicalExpr:
+		b, ok := b.(*LogicalExpr)
+		return ok && Equal(a.left, b.left) && equalToken(a.operator, b.operator) && Equal(a.right, b.right)
+	case *ConditionalExpr:
+		b, ok := b.(*ConditionalExpr)
+		return ok && Equal(a.condition, b.condition) && equalToken(a.question, b.question) && Equal(a.thenBranch, b.thenBranch) && Equal(a.elseBranch, b.elseBranch)
+	case *CoalesceExpr:
+		b, ok := b.(*CoalesceExpr)
+		return ok && Equal(a.left, b.left) && equalToken(a.operator, b.operator) && Equal(a.right, b.right)
+	case *GetExpr:
+		b, ok := b.(*GetExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.name, b.name)
+	case *OptionalGetExpr:
+		b, ok := b.(*OptionalGetExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.name, b.name)
+	case *CallExpr:
+		b, ok := b.(*CallExpr)
+		return ok && Equal(a.callee, b.callee) && equalToken(a.paren, b.paren) && equalExprs(a.arguments, b.arguments)
+	case *IndexExpr:
+		b, ok := b.(*IndexExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.bracket, b.bracket) && Equal(a.index, b.index)
+	case *SetExpr:
+		b, ok := b.(*SetExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.name, b.name) && Equal(a.value, b.value)
+	case *SetIndexExpr:
+		b, ok := b.(*SetIndexExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.bracket, b.bracket) && Equal(a.index, b.index) && Equal(a.value, b.value)
+	case *CompoundSetExpr:
+		b, ok := b.(*CompoundSetExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.name, b.name) && equalToken(a.operator, b.operator) && Equal(a.value, b.value)
+	case *CompoundSetIndexExpr:
+		b, ok := b.(*CompoundSetIndexExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.bracket, b.bracket) && Equal(a.index, b.index) && equalToken(a.operator, b.operator) && Equal(a.value, b.value)
+	case *UpdatePropertyExpr:
+		b, ok := b.(*UpdatePropertyExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.name, b.name) && equalToken(a.operator, b.operator) && a.prefix == b.prefix
+	case *UpdateIndexExpr:
+		b, ok := b.(*UpdateIndexExpr)
+		return ok && Equal(a.object, b.object) && equalToken(a.bracket, b.bracket) && Equal(a.index, b.index) && equalToken(a.operator, b.operator) && a.prefix == b.prefix
+	case *ThisExpr:
+		b, ok := b.(*ThisExpr)
+		return ok && equalToken(a.keyword, b.keyword)
+	case *ListExpr:
+		b, ok := b.(*ListExpr)
+		return ok && equalToken(a.bracket, b.bracket) && equalExprs(a.elements, b.elements)
+	case *MapExpr:
+		b, ok := b.(*MapExpr)
+		return ok && equalToken(a.brace, b.brace) && equalExprs(a.keys, b.keys) && equalExprs(a.values, b.values)
+	case *MatchExpr:
+		b, ok := b.(*MatchExpr)
+		return ok && equalToken(a.keyword, b.keyword) && Equal(a.subject, b.subject) && equalPatterns(a.patterns, b.patterns) && equalExprs(a.guards, b.guards) && equalExprs(a.values, b.values)
+	case *SpawnExpr:
+		b, ok := b.(*SpawnExpr)
+		return ok && equalToken(a.keyword, b.keyword) && Equal(a.call, b.call)
+	case *ExpressionStmt:
+		b, ok := b.(*ExpressionStmt)
+		return ok && Equal(a.expression, b.expression)
+	case *VarStmt:
+		b, ok := b.(*VarStmt)
+		return ok && equalToken(a.name, b.name) && Equal(a.initializer, b.initializer) && a.constant == b.constant
+	case *BlockStmt:
+		b, ok := b.(*BlockStmt)
+		return ok && equalStmts(a.statements, b.statements)
+	case *IfStmt:
+		b, ok := b.(*IfStmt)
+		return ok && Equal(a.condition, b.condition) && Equal(a.thenBranch, b.thenBranch) && Equal(a.elseBranch, b.elseBranch)
+	case *WhileStmt:
+		b, ok := b.(*WhileStmt)
+		return ok && equalToken(a.keyword, b.keyword) && Equal(a.condition, b.condition) && Equal(a.body, b.body)
+	case *FunctionStmt:
+		b, ok := b.(*FunctionStmt)
+		return ok && equalToken(a.name, b.name) && slices.EqualFunc(a.params, b.params, equalToken) && equalStmts(a.body, b.body) && a.generator == b.generator
+	case *ClassStmt:
+		b, ok := b.(*ClassStmt)
+		return ok && equalToken(a.name, b.name) && equalStmts(a.methods, b.methods)
+	case *ForInStmt:
+		b, ok := b.(*ForInStmt)
+		return ok && equalToken(a.keyword, b.keyword) && equalToken(a.name, b.name) && Equal(a.iterable, b.iterable) && Equal(a.body, b.body)
+	case *ReturnStmt:
+		b, ok := b.(*ReturnStmt)
+		return ok && equalToken(a.keyword, b.keyword) && Equal(a.value, b.value)
+	case *YieldStmt:
+		b, ok := b.(*YieldStmt)
+		return ok && equalToken(a.keyword, b.keyword) && Equal(a.value, b.value)
+	case *ImportStmt:
+		b, ok := b.(*ImportStmt)
+		return ok && equalToken(a.keyword, b.keyword) && equalToken(a.path, b.path) && equalToken(a.name, b.name)
+	case *ThrowStmt:
+		b, ok := b.(*ThrowStmt)
+		return ok && equalToken(a.keyword, b.keyword) && Equal(a.value, b.value)
+	case *TryStmt:
+		b, ok := b.(*TryStmt)
+		return ok && equalToken(a.keyword, b.keyword) && equalStmts(a.body, b.body) && equalToken(a.catchName, b.catchName) && equalStmts(a.catchBody, b.catchBody) && equalToken(a.finally, b.finally) && equalStmts(a.finallyBody, b.finallyBody)
+	case *SelectStmt:
+		b, ok := b.(*SelectStmt)
+		return ok && equalToken(a.keyword, b.keyword) && equalExprs(a.cases, b.cases) && slices.EqualFunc(a.names, b.names, equalToken) && equalStmts(a.bodies, b.bodies) && Equal(a.elseBranch, b.elseBranch)
+	case *LiteralPattern:
+		b, ok := b.(*LiteralPattern)
+		return ok && equalToken(a.token, b.token) && a.value == b.value
+	case *WildcardPattern:
+		b, ok := b.(*WildcardPattern)
+		return ok && equalToken(a.underscore, b.underscore)
+	case *BindingPattern:
+		b, ok := b.(*BindingPattern)
+		return ok && equalToken(a.name, b.name)
+	case *ObjectPattern:
+		b, ok := b.(*ObjectPattern)
+		return ok && equalToken(a.brace, b.brace) && slices.EqualFunc(a.names, b.names, equalToken) && equalPatterns(a.patterns, b.patterns)
+	case *ListPattern:
+		b, ok := b.(*ListPattern)
+		return ok && equalToken(a.bracket, b.bracket) && equalPatterns(a.patterns, b.patterns) && equalToken(a.rest, b.rest)
+	case *ClassPattern:
+		b, ok := b.(*ClassPattern)
+		return ok && equalToken(a.name, b.name) && equalToken(a.brace, b.brace) && slices.EqualFunc(a.names, b.names, equalToken) && equalPatterns(a.patterns, b.patterns)
+	}
+	panic(fmt.Sprintf("ast.Equal: unexpected node type %T", a))
+}
+
+func equalToken(a, b *Token) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Type == b.Type && *a.Lexeme == *b.Lexeme && a.Literal == b.Literal
+}
+
+func equalExprs(a, b []Expr) bool {
+	return slices.EqualFunc(a, b, func(a, b Expr) bool {
+		return Equal(a, b)
+	})
+}
+
+func equalStmts(a, b []Stmt) bool {
+	return slices.EqualFunc(a, b, func(a, b Stmt) bool {
+		return Equal(a, b)
+	})
+}
+
+func equalPatterns(a, b []Pattern) bool {
+	return slices.EqualFunc(a, b, func(a, b Pattern) bool {
+		return Equal(a, b)
+	})
+}
+
+// CloneExpr returns a deep copy of expr. Tokens are immutable and shared
+// with the copy.
+func CloneExpr(expr Expr) Expr {
+	switch expr := expr.(type) {
+	case nil:
+		return nil
+	case *BinaryExpr:
+		return &BinaryExpr{
+			left:     CloneExpr(expr.left),
+			operator: expr.operator,
+			right:    CloneExpr(expr.right),
+		}
+	case *UnaryExpr:
+		return &UnaryExpr{
+			operator: expr.operator,
+			right:    CloneExpr(expr.right),
+		}
+	case *LiteralExpr:
+		return &LiteralExpr{
+			value: expr.value,
+		}
+	case *GroupingExpr:
+		return &GroupingExpr{
+			expr: CloneExpr(expr.expr),
+		}
+	case *VariableExpr:
+		return &VariableExpr{
+			name: expr.name,
+		}
+	case *AssignExpr:
+		return &AssignExpr{
+			name:  expr.name,
+			value: CloneExpr(expr.value),
+		}
+	case *CompoundAssignExpr:
+		return &CompoundAssignExpr{
+			name:     expr.name,
+			operator: expr.operator,
+			value:    CloneExpr(expr.value),
+		}
+	case *UpdateExpr:
+		return &UpdateExpr{
+			name:     expr.name,
+			operator: expr.operator,
+			prefix:   expr.prefix,
+		}
+	case *LogicalExpr:
+		return &LogicalExpr{
+			left:     CloneExpr(expr.left),
+			operator: expr.operator,
+			right:    CloneExpr(expr.right),
+		}
+	case *ConditionalExpr:
+		return &ConditionalExpr{
+			condition:  CloneExpr(expr.condition),
+			question:   expr.question,
+			thenBranch: CloneExpr(expr.thenBranch),
+			elseBranch: CloneExpr(expr.elseBranch),
+		}
+	case *CoalesceExpr:
+		return &CoalesceExpr{
+			left:     CloneExpr(expr.left),
+			operator: expr.operator,
+			right:    CloneExpr(expr.right),
+		}
+	case *GetExpr:
+		return &GetExpr{
+			object: CloneExpr(expr.object),
+			name:   expr.name,
+		}
+	case *OptionalGetExpr:
+		return &OptionalGetExpr{
+			object: CloneExpr(expr.object),
+			name:   expr.name,
+		}
+	case *CallExpr:
+		return &CallExpr{
+			callee:    CloneExpr(expr.callee),
+			paren:     expr.paren,
+			arguments: cloneExprs(expr.arguments),
+		}
+	case *IndexExpr:
+		return &IndexExpr{
+			object:  CloneExpr(expr.object),
+			bracket: expr.bracket,
+			index:   CloneExpr(expr.index),
+		}
+	case *SetExpr:
+		return &SetExpr{
+			object: CloneExpr(expr.object),
+			name:   expr.name,
+			value:  CloneExpr(expr.value),
+		}
+	case *SetIndexExpr:
+		return &SetIndexExpr{
+			object:  CloneExpr(expr.object),
+			bracket: expr.bracket,
+			index:   CloneExpr(expr.index),
+			value:   CloneExpr(expr.value),
+		}
+	case *CompoundSetExpr:
+		return &CompoundSetExpr{
+			object:   CloneExpr(expr.object),
+			name:     expr.name,
+			operator: expr.operator,
+			value:    CloneExpr(expr.value),
+		}
+	case *CompoundSetIndexExpr:
+		return &CompoundSetIndexExpr{
+			object:   CloneExpr(expr.object),
+			bracket:  expr.bracket,
+			index:    CloneExpr(expr.index),
+			operator: expr.operator,
+			value:    CloneExpr(expr.value),
+		}
+	case *UpdatePropertyExpr:
+		return &UpdatePropertyExpr{
+			object:   CloneExpr(expr.object),
+			name:     expr.name,
+			operator: expr.operator,
+			prefix:   expr.prefix,
+		}
+	case *UpdateIndexExpr:
+		return &UpdateIndexExpr{
+			object:   CloneExpr(expr.object),
+			bracket:  expr.bracket,
+			index:    CloneExpr(expr.index),
+			operator: expr.operator,
+			prefix:   expr.prefix,
+		}
+	case *ThisExpr:
+		return &ThisExpr{
+			keyword: expr.keyword,
+		}
+	case *ListExpr:
+		return &ListExpr{
+			bracket:  expr.bracket,
+			elements: cloneExprs(expr.elements),
+		}
+	case *MapExpr:
+		return &MapExpr{
+			brace:  expr.brace,
+			keys:   cloneExprs(expr.keys),
+			values: cloneExprs(expr.values),
+		}
+	case *MatchExpr:
+		return &MatchExpr{
+			keyword:  expr.keyword,
+			subject:  CloneExpr(expr.subject),
+			patterns: clonePatterns(expr.patterns),
+			guards:   cloneExprs(expr.guards),
+			values:   cloneExprs(expr.values),
+		}
+	case *SpawnExpr:
+		return &SpawnExpr{
+			keyword: expr.keyword,
+			call:    CloneExpr(expr.call),
+		}
+	}
+	panic(fmt.Sprintf("ast.CloneExpr: unexpected expression %T", expr))
+}
+
+func cloneExprs(list []Expr) []Expr {
+	if list == nil {
+		return nil
+	}
+	cloned := make([]Expr, len(list))
+	for i, expr := range list {
+		cloned[i] = CloneExpr(expr)
+	}
+	return cloned
+}
+
+// CloneStmt returns a deep copy of stmt. Tokens are immutable and shared
+// with the copy.
+func CloneStmt(stmt Stmt) Stmt {
+	switch stmt := stmt.(type) {
+	case nil:
+		return nil
+	case *ExpressionStmt:
+		return &ExpressionStmt{
+			expression: CloneExpr(stmt.expression),
+		}
+	case *VarStmt:
+		return &VarStmt{
+			name:        stmt.name,
+			initializer: CloneExpr(stmt.initializer),
+			constant:    stmt.constant,
+		}
+	case *BlockStmt:
+		return &BlockStmt{
+			statements: cloneStmts(stmt.statements),
+		}
+	case *IfStmt:
+		return &IfStmt{
+			condition:  CloneExpr(stmt.condition),
+			thenBranch: CloneStmt(stmt.thenBranch),
+			elseBranch: CloneStmt(stmt.elseBranch),
+		}
+	case *WhileStmt:
+		return &WhileStmt{
+			keyword:   stmt.keyword,
+			condition: CloneExpr(stmt.condition),
+			body:      CloneStmt(stmt.body),
+		}
+	case *FunctionStmt:
+		return &FunctionStmt{
+			name:      stmt.name,
+			params:    slices.Clone(stmt.params),
+			body:      cloneStmts(stmt.body),
+			generator: stmt.generator,
+		}
+	case *ClassStmt:
+		return &ClassStmt{
+			name:    stmt.name,
+			methods: cloneStmts(stmt.methods),
+		}
+	case *ForInStmt:
+		return &ForInStmt{
+			keyword:  stmt.keyword,
+			name:     stmt.name,
+			iterable: CloneExpr(stmt.iterable),
+			body:     CloneStmt(stmt.body),
+		}
+	case *ReturnStmt:
+		return &ReturnStmt{
+			keyword: stmt.keyword,
+			value:   CloneExpr(stmt.value),
+		}
+	case *YieldStmt:
+		return &YieldStmt{
+			keyword: stmt.keyword,
+			value:   CloneExpr(stmt.value),
+		}
+	case *ImportStmt:
+		return &ImportStmt{
+			keyword: stmt.keyword,
+			path:    stmt.path,
+			name:    stmt.name,
+		}
+	case *ThrowStmt:
+		return &ThrowStmt{
+			keyword: stmt.keyword,
+			value:   CloneExpr(stmt.value),
+		}
+	case *TryStmt:
+		return &TryStmt{
+			keyword:     stmt.keyword,
+			body:        cloneStmts(stmt.body),
+			catchName:   stmt.catchName,
+			catchBody:   cloneStmts(stmt.catchBody),
+			finally:     stmt.finally,
+			finallyBody: cloneStmts(stmt.finallyBody),
+		}
+	case *SelectStmt:
+		return &SelectStmt{
+			keyword:    stmt.keyword,
+			cases:      cloneExprs(stmt.cases),
+			names:      slices.Clone(stmt.names),
+			bodies:     cloneStmts(stmt.bodies),
+			elseBranch: CloneStmt(stmt.elseBranch),
+		}
+	}
+	panic(fmt.Sprintf("ast.CloneStmt: unexpected statement %T", stmt))
+}
+
+func cloneStmts(list []Stmt) []Stmt {
+	if list == nil {
+		return nil
+	}
+	cloned := make([]Stmt, len(list))
+	for i, stmt := range list {
+		cloned[i] = CloneStmt(stmt)
+	}
+	return cloned
+}
+
+// ClonePattern returns a deep copy of pattern. Tokens are immutable and shared
+// with the copy.
+func ClonePattern(pattern Pattern) Pattern {
+	switch pattern := pattern.(type) {
+	case nil:
+		return nil
+	case *LiteralPattern:
+		return &LiteralPattern{
+			token: pattern.token,
+			value: pattern.value,
+		}
+	case *WildcardPattern:
+		return &WildcardPattern{
+			underscore: pattern.underscore,
+		}
+	case *BindingPattern:
+		return &BindingPattern{
+			name: pattern.name,
+		}
+	case *ObjectPattern:
+		return &ObjectPattern{
+			brace:    pattern.brace,
+			names:    slices.Clone(pattern.names),
+			patterns: clonePatterns(pattern.patterns),
+		}
+	case *ListPattern:
+		return &ListPattern{
+			bracket:  pattern.bracket,
+			patterns: clonePatterns(pattern.patterns),
+			rest:     pattern.rest,
+		}
+	case *ClassPattern:
+		return &ClassPattern{
+			name:     pattern.name,
+			brace:    pattern.brace,
+			names:    slices.Clone(pattern.names),
+			patterns: clonePatterns(pattern.patterns),
+		}
+	}
+	panic(fmt.Sprintf("ast.ClonePattern: unexpected pattern %T", pattern))
+}
+
+func clonePatterns(list []Pattern) []Pattern {
+	if list == nil {
+		return nil
+	}
+	cloned := make([]Pattern, len(list))
+	for i, pattern := range list {
+		cloned[i] = ClonePattern(pattern)
+	}
+	return cloned
+}
+
+// Field is a field of a node, named as in ast.spec. Its value is a child
+// node, a slice of them, a *Token, a slice of tokens or a plain value.
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Fields returns the fields of node, in the order ast.spec declares them.
+func Fields(node Node) []Field {
+	switch n := node.(type) {
+	case *BinaryExpr:
+		return []Field{{"left", n.left}, {"operator", n.operator}, {"right", n.right}}
+	case *UnaryExpr:
+		return []Field{{"operator", n.operator}, {"right", n.right}}
+	case *LiteralExpr:
+		return []Field{{"value", n.value}}
+	case *GroupingExpr:
+		return []Field{{"expr", n.expr}}
+	case *VariableExpr:
+		return []Field{{"name", n.name}}
+	case *AssignExpr:
+		return []Field{{"name", n.name}, {"value", n.value}}
+	case *CompoundAssignExpr:
+		return []Field{{"name", n.name}, {"operator", n.operator}, {"value", n.value}}
+	case *UpdateExpr:
+		return []Field{{"name", n.name}, {"operator", n.operator}, {"prefix", n.prefix}}
+	case *LogicalExpr:
+		return []Field{{"left", n.left}, {"operator", n.operator}, {"right", n.right}}
+	case *ConditionalExpr:
+		return []Field{{"condition", n.condition}, {"question", n.question}, {"thenBranch", n.thenBranch}, {"elseBranch", n.elseBranch}}
+	case *CoalesceExpr:
+		return []Field{{"left", n.left}, {"operator", n.operator}, {"right", n.right}}
+	case *GetExpr:
+		return []Field{{"object", n.object}, {"name", n.name}}
+	case *OptionalGetExpr:
+		return []Field{{"object", n.object}, {"name", n.name}}
+	case *CallExpr:
+		return []Field{{"callee", n.callee}, {"paren", n.paren}, {"arguments", n.arguments}}
+	case *IndexExpr:
+		return []Field{{"object", n.object}, {"bracket", n.bracket}, {"index", n.index}}
+	case *SetExpr:
+		return []Field{{"object", n.object}, {"name", n.name}, {"value", n.value}}
+	case *SetIndexExpr:
+		return []Field{{"object", n.object}, {"bracket", n.bracket}, {"index", n.index}, {"value", n.value}}
+	case *CompoundSetExpr:
+		return []Field{{"object", n.object}, {"name", n.name}, {"operator", n.operator}, {"value", n.value}}
+	case *CompoundSetIndexExpr:
+		return []Field{{"object", n.object}, {"bracket", n.bracket}, {"index", n.index}, {"operator", n.operator}, {"value", n.value}}
+	case *UpdatePropertyExpr:
+		return []Field{{"object", n.object}, {"name", n.name}, {"operator", n.operator}, {"prefix", n.prefix}}
+	case *UpdateIndexExpr:
+		return []Field{{"object", n.object}, {"bracket", n.bracket}, {"index", n.index}, {"operator", n.operator}, {"prefix", n.prefix}}
+	case *ThisExpr:
+		return []Field{{"keyword", n.keyword}}
+	case *ListExpr:
+		return []Field{{"bracket", n.bracket}, {"elements", n.elements}}
+	case *MapExpr:
+		return []Field{{"brace", n.brace}, {"keys", n.keys}, {"values", n.values}}
+	case *MatchExpr:
+		return []Field{{"keyword", n.keyword}, {"subject", n.subject}, {"patterns", n.patterns}, {"guards", n.guards}, {"values", n.values}}
+	case *SpawnExpr:
+		return []Field{{"keyword", n.keyword}, {"call", n.call}}
+	case *ExpressionStmt:
+		return []Field{{"expression", n.expression}}
+	case *VarStmt:
+		return []Field{{"name", n.name}, {"initializer", n.initializer}, {"constant", n.constant}}
+	case *BlockStmt:
+		return []Field{{"statements", n.statements}}
+	case *IfStmt:
+		return []Field{{"condition", n.condition}, {"thenBranch", n.thenBranch}, {"elseBranch", n.elseBranch}}
+	case *WhileStmt:
+		return []Field{{"keyword", n.keyword}, {"condition", n.condition}, {"body", n.body}}
+	case *FunctionStmt:
+		return []Field{{"name", n.name}, {"params", n.params}, {"body", n.body}, {"generator", n.generator}}
+	case *ClassStmt:
+		return []Field{{"name", n.name}, {"methods", n.methods}}
+	case *ForInStmt:
+		return []Field{{"keyword", n.keyword}, {"name", n.name}, {"iterable", n.iterable}, {"body", n.body}}
+	case *ReturnStmt:
+		return []Field{{"keyword", n.keyword}, {"value", n.value}}
+	case *YieldStmt:
+		return []Field{{"keyword", n.keyword}, {"value", n.value}}
+	case *ImportStmt:
+		return []Field{{"keyword", n.keyword}, {"path", n.path}, {"name", n.name}}
+	case *ThrowStmt:
+		return []Field{{"keyword", n.keyword}, {"value", n.value}}
+	case *TryStmt:
+		return []Field{{"keyword", n.keyword}, {"body", n.body}, {"catchName", n.catchName}, {"catchBody", n.catchBody}, {"finally", n.finally}, {"finallyBody", n.finallyBody}}
+	case *SelectStmt:
+		return []Field{{"keyword", n.keyword}, {"cases", n.cases}, {"names", n.names}, {"bodies", n.bodies}, {"elseBranch", n.elseBranch}}
+	case *LiteralPattern:
+		return []Field{{"token", n.token}, {"value", n.value}}
+	case *WildcardPattern:
+		return []Field{{"underscore", n.underscore}}
+	case *BindingPattern:
+		return []Field{{"name", n.name}}
+	case *ObjectPattern:
+		return []Field{{"brace", n.brace}, {"names", n.names}, {"patterns", n.patterns}}
+	case *ListPattern:
+		return []Field{{"bracket", n.bracket}, {"patterns", n.patterns}, {"rest", n.rest}}
+	case *ClassPattern:
+		return []Field{{"name", n.name}, {"brace", n.brace}, {"names", n.names}, {"patterns", n.patterns}}
+	}
+	panic(fmt.Sprintf("ast.Fields: unexpected node type %T", node))
+}
+
+// walkChildren walks each non-nil child of node with w.
+func walkChildren(w Walker, node Node) {
+	switch n := node.(type) {
+	case *BinaryExpr:
+		if n.left != nil {
+			Walk(w, n.left)
+		}
+		if n.right != nil {
+			Walk(w, n.right)
+		}
+	case *UnaryExpr:
+		if n.right != nil {
+			Walk(w, n.right)
+		}
+	case *LiteralExpr:
+	case *GroupingExpr:
+		if n.expr != nil {
+			Walk(w, n.expr)
+		}
+	case *VariableExpr:
+	case *AssignExpr:
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *CompoundAssignExpr:
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *UpdateExpr:
+	case *LogicalExpr:
+		if n.left != nil {
+			Walk(w, n.left)
+		}
+		if n.right != nil {
+			Walk(w, n.right)
+		}
+	case *ConditionalExpr:
+		if n.condition != nil {
+			Walk(w, n.condition)
+		}
+		if n.thenBranch != nil {
+			Walk(w, n.thenBranch)
+		}
+		if n.elseBranch != nil {
+			Walk(w, n.elseBranch)
+		}
+	case *CoalesceExpr:
+		if n.left != nil {
+			Walk(w, n.left)
+		}
+		if n.right != nil {
+			Walk(w, n.right)
+		}
+	case *GetExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+	case *OptionalGetExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+	case *CallExpr:
+		if n.callee != nil {
+			Walk(w, n.callee)
+		}
+		for _, child := range n.arguments {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *IndexExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+		if n.index != nil {
+			Walk(w, n.index)
+		}
+	case *SetExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *SetIndexExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+		if n.index != nil {
+			Walk(w, n.index)
+		}
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *CompoundSetExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *CompoundSetIndexExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+		if n.index != nil {
+			Walk(w, n.index)
+		}
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *UpdatePropertyExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+	case *UpdateIndexExpr:
+		if n.object != nil {
+			Walk(w, n.object)
+		}
+		if n.index != nil {
+			Walk(w, n.index)
+		}
+	case *ThisExpr:
+	case *ListExpr:
+		for _, child := range n.elements {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *MapExpr:
+		for _, child := range n.keys {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+		for _, child := range n.values {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *MatchExpr:
+		if n.subject != nil {
+			Walk(w, n.subject)
+		}
+		for _, child := range n.patterns {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+		for _, child := range n.guards {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+		for _, child := range n.values {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *SpawnExpr:
+		if n.call != nil {
+			Walk(w, n.call)
+		}
+	case *ExpressionStmt:
+		if n.expression != nil {
+			Walk(w, n.expression)
+		}
+	case *VarStmt:
+		if n.initializer != nil {
+			Walk(w, n.initializer)
+		}
+	case *BlockStmt:
+		for _, child := range n.statements {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *IfStmt:
+		if n.condition != nil {
+			Walk(w, n.condition)
+		}
+		if n.thenBranch != nil {
+			Walk(w, n.thenBranch)
+		}
+		if n.elseBranch != nil {
+			Walk(w, n.elseBranch)
+		}
+	case *WhileStmt:
+		if n.condition != nil {
+			Walk(w, n.condition)
+		}
+		if n.body != nil {
+			Walk(w, n.body)
+		}
+	case *FunctionStmt:
+		for _, child := range n.body {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *ClassStmt:
+		for _, child := range n.methods {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *ForInStmt:
+		if n.iterable != nil {
+			Walk(w, n.iterable)
+		}
+		if n.body != nil {
+			Walk(w, n.body)
+		}
+	case *ReturnStmt:
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *YieldStmt:
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *ImportStmt:
+	case *ThrowStmt:
+		if n.value != nil {
+			Walk(w, n.value)
+		}
+	case *TryStmt:
+		for _, child := range n.body {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+		for _, child := range n.catchBody {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+		for _, child := range n.finallyBody {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *SelectStmt:
+		for _, child := range n.cases {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+		for _, child := range n.bodies {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+		if n.elseBranch != nil {
+			Walk(w, n.elseBranch)
+		}
+	case *LiteralPattern:
+	case *WildcardPattern:
+	case *BindingPattern:
+	case *ObjectPattern:
+		for _, child := range n.patterns {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *ListPattern:
+		for _, child := range n.patterns {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	case *ClassPattern:
+		for _, child := range n.patterns {
+			if child != nil {
+				Walk(w, child)
+			}
+		}
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+}
@@ -0,0 +1,203 @@
+// Code generated by genexpr from ast.spec; DO NOT EDIT.
+
+package ast
+
+import "fmt"
+
+// PatternVisitor is implemented by operations on patterns, each producing an R.
+// AcceptPattern calls the method matching the type of the node.
+type PatternVisitor[R any] interface {
+	VisitLiteralPattern(pattern *LiteralPattern) R
+	VisitWildcardPattern(pattern *WildcardPattern) R
+	VisitBindingPattern(pattern *BindingPattern) R
+	VisitObjectPattern(pattern *ObjectPattern) R
+	VisitListPattern(pattern *ListPattern) R
+	VisitClassPattern(pattern *ClassPattern) R
+}
+
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// AcceptPattern calls the method of visitor for the type of pattern.
+func AcceptPattern[R any](pattern Pattern, visitor PatternVisitor[R]) R {
+	switch pattern := pattern.(type) {
+	case *LiteralPattern:
+		return visitor.VisitLiteralPattern(pattern)
+	case *WildcardPattern:
+		return visitor.VisitWildcardPattern(pattern)
+	case *BindingPattern:
+		return visitor.VisitBindingPattern(pattern)
+	case *ObjectPattern:
+		return visitor.VisitObjectPattern(pattern)
+	case *ListPattern:
+		return visitor.VisitListPattern(pattern)
+	case *ClassPattern:
+		return visitor.VisitClassPattern(pattern)
+	}
+	panic(fmt.Sprintf("ast: unexpected pattern %T", pattern))
+}
+
+// LiteralPattern matches values equal to a number, string, boolean or nil.
+type LiteralPattern struct {
+	token *Token
+	value any
+}
+
+func (*LiteralPattern) node()        {}
+func (*LiteralPattern) patternNode() {}
+
+// Token is the literal's token, without the sign of a negative number.
+func (p *LiteralPattern) Token() *Token {
+	return p.token
+}
+
+func (p *LiteralPattern) Value() any {
+	return p.value
+}
+
+func NewLiteralPattern(token *Token, value any) *LiteralPattern {
+	return &LiteralPattern{
+		token: token,
+		value: value,
+	}
+}
+
+// WildcardPattern is "_", matching any value.
+type WildcardPattern struct {
+	underscore *Token
+}
+
+func (*WildcardPattern) node()        {}
+func (*WildcardPattern) patternNode() {}
+
+func (p *WildcardPattern) Underscore() *Token {
+	return p.underscore
+}
+
+func NewWildcardPattern(underscore *Token) *WildcardPattern {
+	return &WildcardPattern{
+		underscore: underscore,
+	}
+}
+
+// BindingPattern matches any value, binding it to name in the arm.
+type BindingPattern struct {
+	name *Token
+}
+
+func (*BindingPattern) node()        {}
+func (*BindingPattern) patternNode() {}
+
+func (p *BindingPattern) Name() *Token {
+	return p.name
+}
+
+func NewBindingPattern(name *Token) *BindingPattern {
+	return &BindingPattern{
+		name: name,
+	}
+}
+
+// ObjectPattern matches objects having every property in names, each
+// matching the pattern at the same index of patterns.
+type ObjectPattern struct {
+	brace    *Token
+	names    []*Token
+	patterns []Pattern
+}
+
+func (*ObjectPattern) node()        {}
+func (*ObjectPattern) patternNode() {}
+
+func (p *ObjectPattern) Brace() *Token {
+	return p.brace
+}
+
+func (p *ObjectPattern) Names() []*Token {
+	return p.names
+}
+
+func (p *ObjectPattern) Patterns() []Pattern {
+	return p.patterns
+}
+
+func NewObjectPattern(brace *Token, names []*Token, patterns []Pattern) *ObjectPattern {
+	return &ObjectPattern{
+		brace:    brace,
+		names:    names,
+		patterns: patterns,
+	}
+}
+
+// ListPattern matches lists whose elements match patterns, pattern by
+// pattern. Without a rest the list must have as many elements as there are
+// patterns; with one it may have more, which rest binds as a list unless it
+// is "_".
+type ListPattern struct {
+	bracket  *Token
+	patterns []Pattern
+	rest     *Token
+}
+
+func (*ListPattern) node()        {}
+func (*ListPattern) patternNode() {}
+
+func (p *ListPattern) Bracket() *Token {
+	return p.bracket
+}
+
+func (p *ListPattern) Patterns() []Pattern {
+	return p.patterns
+}
+
+// Rest returns nil when the pattern doesn't end with "...name".
+func (p *ListPattern) Rest() *Token {
+	return p.rest
+}
+
+func NewListPattern(bracket *Token, patterns []Pattern, rest *Token) *ListPattern {
+	return &ListPattern{
+		bracket:  bracket,
+		patterns: patterns,
+		rest:     rest,
+	}
+}
+
+// ClassPattern matches instances of the class called name whose properties
+// match the patterns, as an ObjectPattern does.
+type ClassPattern struct {
+	name     *Token
+	brace    *Token
+	names    []*Token
+	patterns []Pattern
+}
+
+func (*ClassPattern) node()        {}
+func (*ClassPattern) patternNode() {}
+
+func (p *ClassPattern) Name() *Token {
+	return p.name
+}
+
+func (p *ClassPattern) Brace() *Token {
+	return p.brace
+}
+
+func (p *ClassPattern) Names() []*Token {
+	return p.names
+}
+
+func (p *ClassPattern) Patterns() []Pattern {
+	return p.patterns
+}
+
+func NewClassPattern(name *Token, brace *Token, names []*Token, patterns []Pattern) *ClassPattern {
+	return &ClassPattern{
+		name:     name,
+		brace:    brace,
+		names:    names,
+		patterns: patterns,
+	}
+}
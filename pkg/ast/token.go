@@ -1,6 +1,9 @@
 package ast
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/bagaswh/rottenlang/pkg/types"
 )
 
@@ -15,11 +18,18 @@ const (
 	TokenRightBracket
 	TokenComma
 	TokenDot
+	TokenDotDotDot
 	TokenMinus
 	TokenPlus
 	TokenSemicolon
 	TokenSlash
 	TokenStar
+	TokenColon
+	TokenPercent
+	TokenAmpersand
+	TokenPipe
+	TokenCaret
+	TokenTilde
 
 	TokenEqual
 	TokenEqualEqual
@@ -29,6 +39,20 @@ const (
 	TokenGreaterEqual
 	TokenLess
 	TokenLessEqual
+	TokenQuestion
+	TokenQuestionQuestion
+	TokenQuestionDot
+	TokenPlusEqual
+	TokenMinusEqual
+	TokenStarEqual
+	TokenSlashEqual
+	TokenPercentEqual
+	TokenPlusPlus
+	TokenMinusMinus
+	TokenStarStar
+	TokenLessLess
+	TokenGreaterGreater
+	TokenEqualGreater
 
 	TokenIdentifier
 	TokenString
@@ -47,6 +71,19 @@ const (
 	TokenNil
 	TokenVar
 	TokenConst
+	TokenImport
+	TokenAs
+	TokenThrow
+	TokenTry
+	TokenCatch
+	TokenFinally
+	TokenMatch
+	TokenSpawn
+	TokenSelect
+	TokenIn
+	TokenYield
+	TokenClass
+	TokenThis
 
 	TokenComment
 	TokenCStyleComment
@@ -54,36 +91,142 @@ const (
 	TokenEOF
 )
 
-var keywords = map[string]TokenType{
-	"and":               TokenAnd,
-	"or":                TokenOr,
-	"chat is this real": TokenIf,
-	"else":              TokenElse,
-	"cap":               TokenFalse,
-	"nocap":             TokenTrue,
-	"for":               TokenFor,
-	"func":              TokenFunc,
-	"nil":               TokenNil,
+// Keyword is one entry of the keywords table: a spelling, the token it
+// scans to and what it means.
+type Keyword struct {
+	Spelling string
+	Type     TokenType
+	Meaning  string
+}
+
+var keywordTable = []Keyword{
+	{"and", TokenAnd, "Logical and"},
+	{"or", TokenOr, "Logical or"},
+	{"chat is this real", TokenIf, "If statement"},
+	{"else", TokenElse, "Else branch of an if statement"},
+	{"cap", TokenFalse, "Boolean false"},
+	{"nocap", TokenTrue, "Boolean true"},
+	{"for", TokenFor, "For loop"},
+	{"func", TokenFunc, "Function declaration"},
+	{"nil", TokenNil, "The absence of a value"},
+	{"import", TokenImport, "Import a module"},
+	{"as", TokenAs, "Name an imported module"},
+	{"throw", TokenThrow, "Raise an error"},
+	{"try", TokenTry, "Run a block, handling its errors"},
+	{"catch", TokenCatch, "Handle the errors of a try block"},
+	{"finally", TokenFinally, "Run a block however a try block ends"},
+	{"match", TokenMatch, "Match a value against patterns"},
+	{"spawn", TokenSpawn, "Run a function call as a task"},
+	{"select", TokenSelect, "Wait for the first of several channel operations"},
+	{"in", TokenIn, "Name the values of a for loop"},
+	{"yield", TokenYield, "Produce the next value of a generator"},
+	{"class", TokenClass, "Class declaration"},
+	{"this", TokenThis, "The instance a method is called on"},
 
 	// Additional Gen Alpha keywords
-	"vibes":           TokenVar,          // For variable declaration
-	"purrr":           TokenReturn,       // Return keyword
-	"slay":            TokenConst,        // Constant declaration
-	"skibidi":         TokenWhile,        // While loop
-	"rizz":            TokenAnd,          // Alternative for "and"
-	"mid":             TokenLess,         // Less than
-	"bussin":          TokenGreater,      // Greater than
-	"no_tea_no_shade": TokenEqualEqual,   // Equality check
-	"fr_fr":           TokenBangEqual,    // Not equal
-	"based":           TokenPlus,         // Addition
-	"cringe":          TokenMinus,        // Subtraction
-	"yeet":            TokenSlash,        // Division
-	"ong":             TokenStar,         // Multiplication
-	"lowkey":          TokenLessEqual,    // Less than or equal
-	"highkey":         TokenGreaterEqual, // Greater than or equal
-	"deadass":         TokenBang,         // Logical NOT
-	"iykyk":           TokenLeftBrace,    // Start block
-	"periodt":         TokenRightBrace,   // End block
+	{"vibes", TokenVar, "Variable declaration"},
+	{"purrr", TokenReturn, "Return from a function"},
+	{"slay", TokenConst, "Constant declaration"},
+	{"skibidi", TokenWhile, "While loop"},
+	{"rizz", TokenAnd, "Alternative for \"and\""},
+	{"mid", TokenLess, "Less than"},
+	{"bussin", TokenGreater, "Greater than"},
+	{"no_tea_no_shade", TokenEqualEqual, "Equality check"},
+	{"fr_fr", TokenBangEqual, "Not equal"},
+	{"based", TokenPlus, "Addition"},
+	{"cringe", TokenMinus, "Subtraction"},
+	{"yeet", TokenSlash, "Division"},
+	{"ong", TokenStar, "Multiplication"},
+	{"lowkey", TokenLessEqual, "Less than or equal"},
+	{"highkey", TokenGreaterEqual, "Greater than or equal"},
+	{"deadass", TokenBang, "Logical NOT"},
+	{"iykyk", TokenLeftBrace, "Start block"},
+	{"periodt", TokenRightBrace, "End block"},
+	{"ratio", TokenPercent, "Remainder"},
+	{"glowup", TokenStarStar, "Exponentiation"},
+	{"collab", TokenAmpersand, "Bitwise and"},
+	{"squad", TokenPipe, "Bitwise or"},
+	{"beef", TokenCaret, "Bitwise xor"},
+	{"flip", TokenTilde, "Bitwise not"},
+	{"up_only", TokenLessLess, "Shift left"},
+	{"down_bad", TokenGreaterGreater, "Shift right"},
+	{"stacks", TokenPlusEqual, "Add and assign"},
+	{"drains", TokenMinusEqual, "Subtract and assign"},
+	{"hypes", TokenStarEqual, "Multiply and assign"},
+	{"splits", TokenSlashEqual, "Divide and assign"},
+	{"ratios", TokenPercentEqual, "Remainder and assign"},
+	{"stonks", TokenPlusPlus, "Increment"},
+	{"flopped", TokenMinusMinus, "Decrement"},
+	{"throw_hands", TokenThrow, "Alternative for \"throw\""},
+	{"fafo", TokenTry, "Alternative for \"try\""},
+	{"caught_in_4k", TokenCatch, "Alternative for \"catch\""},
+	{"anyways", TokenFinally, "Alternative for \"finally\""},
+	{"vibe_check", TokenMatch, "Alternative for \"match\""},
+	{"let_him_cook", TokenSpawn, "Alternative for \"spawn\""},
+	{"pick_me", TokenSelect, "Alternative for \"select\""},
+	{"its_giving", TokenYield, "Alternative for \"yield\""},
+	{"clique", TokenClass, "Alternative for \"class\""},
+	{"main_character", TokenThis, "Alternative for \"this\""},
+}
+
+// compoundOperators maps compound assignment operators to the binary
+// operator they apply.
+var compoundOperators = map[TokenType]TokenType{
+	TokenPlusEqual:    TokenPlus,
+	TokenMinusEqual:   TokenMinus,
+	TokenStarEqual:    TokenStar,
+	TokenSlashEqual:   TokenSlash,
+	TokenPercentEqual: TokenPercent,
+}
+
+// CompoundOperator returns the binary operator applied by the compound
+// assignment operator compound, such as TokenPlus for TokenPlusEqual.
+func CompoundOperator(compound TokenType) (TokenType, bool) {
+	operator, ok := compoundOperators[compound]
+	return operator, ok
+}
+
+var keywords = func() map[string]TokenType {
+	m := make(map[string]TokenType, len(keywordTable))
+	for _, keyword := range keywordTable {
+		m[keyword.Spelling] = keyword.Type
+	}
+	return m
+}()
+
+// Keywords returns every entry of the keywords table, in table order.
+func Keywords() []Keyword {
+	return keywordTable
+}
+
+// multiWordKeywords lists the keywords spelled with more than one word,
+// longest first so that the scanner prefers the most specific match.
+var multiWordKeywords = func() []string {
+	words := make([]string, 0)
+	for keyword := range keywords {
+		if strings.Contains(keyword, " ") {
+			words = append(words, keyword)
+		}
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if len(words[i]) != len(words[j]) {
+			return len(words[i]) > len(words[j])
+		}
+		return words[i] < words[j]
+	})
+	return words
+}()
+
+// LookupKeyword returns the token type of keyword, if it is one.
+func LookupKeyword(keyword string) (TokenType, bool) {
+	tokenType, ok := keywords[keyword]
+	return tokenType, ok
+}
+
+// MultiWordKeywords returns the keywords made of several space separated
+// words, such as "chat is this real".
+func MultiWordKeywords() []string {
+	return multiWordKeywords
 }
 
 type Token struct {
@@ -91,6 +234,8 @@ type Token struct {
 	Lexeme       *string
 	Literal      any
 	Line, Column int
+	// Offset is the byte offset of the token's first character in the source
+	Offset int
 }
 
 func NewToken(tokenType TokenType, lexeme *string, literal any, line, column int) *Token {
@@ -121,6 +266,8 @@ func (t *Token) Name() string {
 		return "COMMA"
 	case TokenDot:
 		return "DOT"
+	case TokenDotDotDot:
+		return "DOT_DOT_DOT"
 	case TokenMinus:
 		return "MINUS"
 	case TokenPlus:
@@ -131,6 +278,18 @@ func (t *Token) Name() string {
 		return "SLASH"
 	case TokenStar:
 		return "STAR"
+	case TokenColon:
+		return "COLON"
+	case TokenPercent:
+		return "PERCENT"
+	case TokenAmpersand:
+		return "AMPERSAND"
+	case TokenPipe:
+		return "PIPE"
+	case TokenCaret:
+		return "CARET"
+	case TokenTilde:
+		return "TILDE"
 	case TokenEqual:
 		return "EQUAL"
 	case TokenEqualEqual:
@@ -147,6 +306,34 @@ func (t *Token) Name() string {
 		return "LESS"
 	case TokenLessEqual:
 		return "LESS_EQUAL"
+	case TokenQuestion:
+		return "QUESTION"
+	case TokenQuestionQuestion:
+		return "QUESTION_QUESTION"
+	case TokenQuestionDot:
+		return "QUESTION_DOT"
+	case TokenPlusEqual:
+		return "PLUS_EQUAL"
+	case TokenMinusEqual:
+		return "MINUS_EQUAL"
+	case TokenStarEqual:
+		return "STAR_EQUAL"
+	case TokenSlashEqual:
+		return "SLASH_EQUAL"
+	case TokenPercentEqual:
+		return "PERCENT_EQUAL"
+	case TokenPlusPlus:
+		return "PLUS_PLUS"
+	case TokenMinusMinus:
+		return "MINUS_MINUS"
+	case TokenStarStar:
+		return "STAR_STAR"
+	case TokenLessLess:
+		return "LESS_LESS"
+	case TokenGreaterGreater:
+		return "GREATER_GREATER"
+	case TokenEqualGreater:
+		return "EQUAL_GREATER"
 	case TokenIdentifier:
 		return "IDENTIFIER"
 	case TokenString:
@@ -179,6 +366,32 @@ func (t *Token) Name() string {
 		return "VAR"
 	case TokenConst:
 		return "CONST"
+	case TokenImport:
+		return "IMPORT"
+	case TokenAs:
+		return "AS"
+	case TokenThrow:
+		return "THROW"
+	case TokenTry:
+		return "TRY"
+	case TokenCatch:
+		return "CATCH"
+	case TokenFinally:
+		return "FINALLY"
+	case TokenMatch:
+		return "MATCH"
+	case TokenSpawn:
+		return "SPAWN"
+	case TokenSelect:
+		return "SELECT"
+	case TokenIn:
+		return "IN"
+	case TokenYield:
+		return "YIELD"
+	case TokenClass:
+		return "CLASS"
+	case TokenThis:
+		return "THIS"
 	case TokenComment:
 		return "COMMENT"
 	case TokenCStyleComment:
@@ -190,4 +403,21 @@ func (t *Token) Name() string {
 	}
 }
 
+var tokenNames = func() map[string]TokenType {
+	m := make(map[string]TokenType)
+	for tokenType := TokenType(0); tokenType <= TokenEOF; tokenType++ {
+		if name := (&Token{Type: tokenType}).Name(); name != "UNKNOWN" {
+			m[name] = tokenType
+		}
+	}
+	return m
+}()
+
+// LookupTokenName returns the token type called name, as returned by
+// Token.Name, such as TokenPlus for "PLUS".
+func LookupTokenName(name string) (TokenType, bool) {
+	tokenType, ok := tokenNames[name]
+	return tokenType, ok
+}
+
 var EOF = NewToken(TokenEOF, types.StrPtr(""), nil, 0, 0)
@@ -0,0 +1,192 @@
+package ast
+
+// Node is an expression, a statement or a pattern.
+type Node interface {
+	node()
+}
+
+// A Walker's Visit method is called by Walk for every node. When it returns
+// a non-nil Walker w, Walk visits the children of node with w, then calls
+// w.Visit(nil).
+type Walker interface {
+	Visit(node Node) (w Walker)
+}
+
+// Walk traverses the syntax tree rooted at node in depth-first order,
+// calling w.Visit for node and then walking each of its non-nil children.
+func Walk(w Walker, node Node) {
+	if w = w.Visit(node); w == nil {
+		return
+	}
+
+	walkChildren(w, node)
+	w.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Walker {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the syntax tree rooted at node in depth-first order,
+// calling f for node and each of its children. When f returns false, the
+// children of that node are skipped. After the children, f(nil) is called.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// PatternBindings returns the names bound by pattern, in the order the
+// pattern matches them.
+func PatternBindings(pattern Pattern) []*Token {
+	var names []*Token
+	Inspect(pattern, func(node Node) bool {
+		switch node := node.(type) {
+		case *BindingPattern:
+			names = append(names, node.Name())
+		case *ListPattern:
+			// the rest is matched after the elements
+			for _, element := range node.Patterns() {
+				names = append(names, PatternBindings(element)...)
+			}
+			if node.Rest() != nil && *node.Rest().Lexeme != "_" {
+				names = append(names, node.Rest())
+			}
+			return false
+		}
+		return true
+	})
+	return names
+}
+
+// SelectCase splits a case of a select statement, a call of a channel's
+// send or receive method, into the channel and, for a send, the value
+// sent. ok is false when expr isn't such a call.
+func SelectCase(expr Expr) (channel Expr, send bool, value Expr, ok bool) {
+	call, isCall := expr.(*CallExpr)
+	if !isCall {
+		return nil, false, nil, false
+	}
+	get, isGet := call.Callee().(*GetExpr)
+	if !isGet {
+		return nil, false, nil, false
+	}
+	switch {
+	case *get.Name().Lexeme == "send" && len(call.Arguments()) == 1:
+		return get.Object(), true, call.Arguments()[0], true
+	case *get.Name().Lexeme == "receive" && len(call.Arguments()) == 0:
+		return get.Object(), false, nil, true
+	}
+	return nil, false, nil, false
+}
+
+// LeadingToken returns the first token of node the tree keeps, which
+// locates it in the source, or nil if it keeps none, as for a literal.
+func LeadingToken(node Node) *Token {
+	switch node := node.(type) {
+	case *ExpressionStmt:
+		return LeadingToken(node.Expression())
+	case *VarStmt:
+		return node.Name()
+	case *BlockStmt:
+		for _, stmt := range node.Statements() {
+			if token := LeadingToken(stmt); token != nil {
+				return token
+			}
+		}
+	case *IfStmt:
+		return LeadingToken(node.Condition())
+	case *WhileStmt:
+		return node.Keyword()
+	case *FunctionStmt:
+		return node.Name()
+	case *ClassStmt:
+		return node.Name()
+	case *ForInStmt:
+		return node.Keyword()
+	case *ReturnStmt:
+		return node.Keyword()
+	case *YieldStmt:
+		return node.Keyword()
+	case *ImportStmt:
+		return node.Keyword()
+	case *ThrowStmt:
+		return node.Keyword()
+	case *TryStmt:
+		return node.Keyword()
+	case *SelectStmt:
+		return node.Keyword()
+	case *BinaryExpr:
+		return leadingTokenOr(node.Left(), node.Operator())
+	case *UnaryExpr:
+		return node.Operator()
+	case *GroupingExpr:
+		return LeadingToken(node.Expr())
+	case *VariableExpr:
+		return node.Name()
+	case *AssignExpr:
+		return node.Name()
+	case *CompoundAssignExpr:
+		return node.Name()
+	case *UpdateExpr:
+		if node.Prefix() {
+			return node.Operator()
+		}
+		return node.Name()
+	case *LogicalExpr:
+		return leadingTokenOr(node.Left(), node.Operator())
+	case *ConditionalExpr:
+		return leadingTokenOr(node.Condition(), node.Question())
+	case *CoalesceExpr:
+		return leadingTokenOr(node.Left(), node.Operator())
+	case *GetExpr:
+		return leadingTokenOr(node.Object(), node.Name())
+	case *OptionalGetExpr:
+		return leadingTokenOr(node.Object(), node.Name())
+	case *CallExpr:
+		return leadingTokenOr(node.Callee(), node.Paren())
+	case *IndexExpr:
+		return leadingTokenOr(node.Object(), node.Bracket())
+	case *SetExpr:
+		return leadingTokenOr(node.Object(), node.Name())
+	case *SetIndexExpr:
+		return leadingTokenOr(node.Object(), node.Bracket())
+	case *CompoundSetExpr:
+		return leadingTokenOr(node.Object(), node.Name())
+	case *CompoundSetIndexExpr:
+		return leadingTokenOr(node.Object(), node.Bracket())
+	case *UpdatePropertyExpr:
+		if node.Prefix() {
+			return node.Operator()
+		}
+		return leadingTokenOr(node.Object(), node.Name())
+	case *UpdateIndexExpr:
+		if node.Prefix() {
+			return node.Operator()
+		}
+		return leadingTokenOr(node.Object(), node.Bracket())
+	case *ThisExpr:
+		return node.Keyword()
+	case *ListExpr:
+		return node.Bracket()
+	case *MapExpr:
+		return node.Brace()
+	case *MatchExpr:
+		return node.Keyword()
+	case *SpawnExpr:
+		return node.Keyword()
+	}
+	return nil
+}
+
+// leadingTokenOr returns the leading token of expr, the first operand of
+// an operation, or else token.
+func leadingTokenOr(expr Expr, token *Token) *Token {
+	if first := LeadingToken(expr); first != nil {
+		return first
+	}
+	return token
+}
@@ -0,0 +1,284 @@
+package compiler
+
+import (
+	"fmt"
+)
+
+type OpCode byte
+
+const (
+	// OpConstant pushes Constants[u16].
+	OpConstant OpCode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+
+	// OpGetLocal and OpSetLocal take a u8 stack slot relative to the frame.
+	OpGetLocal
+	OpSetLocal
+	// The global opcodes take a u16 index of the variable name constant.
+	OpGetGlobal
+	OpDefineGlobal
+	OpDefineGlobalConst
+	OpSetGlobal
+	// OpGetUpvalue and OpSetUpvalue take a u8 index into the closure's upvalues.
+	OpGetUpvalue
+	OpSetUpvalue
+	// OpGetProperty takes the u16 index of the property name constant.
+	OpGetProperty
+	// OpImport takes the u16 index of the module path constant and pushes
+	// the module.
+	OpImport
+
+	OpEqual
+	OpNotEqual
+	OpGreater
+	OpGreaterEqual
+	OpLess
+	OpLessEqual
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpModulo
+	OpPower
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpNot
+	OpNegate
+	OpPositive
+	OpBitNot
+
+	// The jump opcodes take a u16 byte offset; OpLoop jumps backwards.
+	// Conditional jumps leave the value they test on the stack.
+	OpJump
+	OpJumpIfFalse
+	OpJumpIfNil
+	OpJumpIfNotNil
+	OpLoop
+
+	// OpCall takes the u8 argument count.
+	OpCall
+	// OpClosure takes the u16 index of a *Function constant, followed by
+	// a (isLocal u8, index u8) pair for each of its upvalues.
+	OpClosure
+	OpCloseUpvalue
+	OpReturn
+
+	// OpTry takes a u16 byte offset to a handler, which errors raised until
+	// the matching OpEndTry jump to with the caught error pushed.
+	OpTry
+	OpEndTry
+	// OpThrow raises the error on top of the stack.
+	OpThrow
+
+	// OpMatch takes the u16 index of an ast.Pattern constant and the u8
+	// number of variables it binds. It pops the value to match and pushes
+	// the bound values, nil if the pattern doesn't match, then whether it
+	// matched.
+	OpMatch
+	// OpNoMatch raises the error of a match with no arm for the value on
+	// top of the stack.
+	OpNoMatch
+
+	// OpSpawn takes the u8 argument count. It pops the callee and the
+	// arguments, like OpCall, and pushes the task calling it.
+	OpSpawn
+	// OpSelect takes the u8 number of cases, a u8 that is 1 if the select
+	// has an else branch, then a u8 for each case that is 1 for a send.
+	// It pops a channel and the value sent, nil for a receive, for each
+	// case, and pushes the value received then the index of the case that
+	// proceeded, -1 for the else branch.
+	OpSelect
+
+	// OpIterate pops a value and pushes the iterator a for-in loop over it
+	// goes through.
+	OpIterate
+	// OpForNext takes a u16 byte offset. It pushes the next value of the
+	// iterator on top of the stack, or jumps once there are none left.
+	OpForNext
+	// OpYield pops a value and yields it from the running generator.
+	OpYield
+
+	// OpList takes the u16 number of elements. It pops them and pushes the
+	// list holding them.
+	OpList
+	// OpMap takes the u16 number of entries. It pops a key then a value for
+	// each and pushes the map holding them.
+	OpMap
+	// OpIndex pops an index and an object and pushes object[index].
+	OpIndex
+	// OpSetIndex pops a value, an index and an object, assigns the value to
+	// object[index] and pushes it back.
+	OpSetIndex
+	// OpSetProperty takes the u16 index of the property name constant. It
+	// pops a value and an object, assigns the value to the property and
+	// pushes it back.
+	OpSetProperty
+	// OpClass takes the u16 index of the class name constant and the u8
+	// number of methods. It pops the closures of the methods and pushes
+	// the class.
+	OpClass
+	// OpDup takes a u8 depth and pushes a copy of the value that many slots
+	// below the top: 0 duplicates the top.
+	OpDup
+	// OpBury takes a u8 depth. It pops a value and inserts it that many
+	// slots below the top of the rest.
+	OpBury
+)
+
+var opNames = [...]string{
+	OpConstant:          "OP_CONSTANT",
+	OpNil:               "OP_NIL",
+	OpTrue:              "OP_TRUE",
+	OpFalse:             "OP_FALSE",
+	OpPop:               "OP_POP",
+	OpGetLocal:          "OP_GET_LOCAL",
+	OpSetLocal:          "OP_SET_LOCAL",
+	OpGetGlobal:         "OP_GET_GLOBAL",
+	OpDefineGlobal:      "OP_DEFINE_GLOBAL",
+	OpDefineGlobalConst: "OP_DEFINE_GLOBAL_CONST",
+	OpSetGlobal:         "OP_SET_GLOBAL",
+	OpGetUpvalue:        "OP_GET_UPVALUE",
+	OpSetUpvalue:        "OP_SET_UPVALUE",
+	OpGetProperty:       "OP_GET_PROPERTY",
+	OpImport:            "OP_IMPORT",
+	OpEqual:             "OP_EQUAL",
+	OpNotEqual:          "OP_NOT_EQUAL",
+	OpGreater:           "OP_GREATER",
+	OpGreaterEqual:      "OP_GREATER_EQUAL",
+	OpLess:              "OP_LESS",
+	OpLessEqual:         "OP_LESS_EQUAL",
+	OpAdd:               "OP_ADD",
+	OpSubtract:          "OP_SUBTRACT",
+	OpMultiply:          "OP_MULTIPLY",
+	OpDivide:            "OP_DIVIDE",
+	OpModulo:            "OP_MODULO",
+	OpPower:             "OP_POWER",
+	OpBitAnd:            "OP_BIT_AND",
+	OpBitOr:             "OP_BIT_OR",
+	OpBitXor:            "OP_BIT_XOR",
+	OpShiftLeft:         "OP_SHIFT_LEFT",
+	OpShiftRight:        "OP_SHIFT_RIGHT",
+	OpNot:               "OP_NOT",
+	OpNegate:            "OP_NEGATE",
+	OpPositive:          "OP_POSITIVE",
+	OpBitNot:            "OP_BIT_NOT",
+	OpJump:              "OP_JUMP",
+	OpJumpIfFalse:       "OP_JUMP_IF_FALSE",
+	OpJumpIfNil:         "OP_JUMP_IF_NIL",
+	OpJumpIfNotNil:      "OP_JUMP_IF_NOT_NIL",
+	OpLoop:              "OP_LOOP",
+	OpCall:              "OP_CALL",
+	OpClosure:           "OP_CLOSURE",
+	OpCloseUpvalue:      "OP_CLOSE_UPVALUE",
+	OpReturn:            "OP_RETURN",
+	OpTry:               "OP_TRY",
+	OpEndTry:            "OP_END_TRY",
+	OpThrow:             "OP_THROW",
+	OpMatch:             "OP_MATCH",
+	OpNoMatch:           "OP_NO_MATCH",
+	OpSpawn:             "OP_SPAWN",
+	OpSelect:            "OP_SELECT",
+	OpIterate:           "OP_ITERATE",
+	OpForNext:           "OP_FOR_NEXT",
+	OpYield:             "OP_YIELD",
+	OpList:              "OP_LIST",
+	OpMap:               "OP_MAP",
+	OpIndex:             "OP_INDEX",
+	OpSetIndex:          "OP_SET_INDEX",
+	OpSetProperty:       "OP_SET_PROPERTY",
+	OpClass:             "OP_CLASS",
+	OpDup:               "OP_DUP",
+	OpBury:              "OP_BURY",
+}
+
+func (op OpCode) String() string {
+	if int(op) < len(opNames) && opNames[op] != "" {
+		return opNames[op]
+	}
+	return fmt.Sprintf("OP_UNKNOWN(%d)", byte(op))
+}
+
+// Position is a source location.
+type Position struct {
+	Line, Column int
+}
+
+// positionRun records that the instructions starting at offset, up to the
+// next run, come from pos. Runs keep the line table small since consecutive
+// instructions usually share a position.
+type positionRun struct {
+	offset int
+	pos    Position
+}
+
+// Chunk is a sequence of bytecode together with its constant pool and a
+// table mapping instruction offsets back to source positions.
+type Chunk struct {
+	Code      []byte
+	Constants []any
+	positions []positionRun
+}
+
+func NewChunk() *Chunk {
+	return &Chunk{
+		Code:      make([]byte, 0),
+		Constants: make([]any, 0),
+	}
+}
+
+func (c *Chunk) Write(b byte, pos Position) {
+	if len(c.positions) == 0 || c.positions[len(c.positions)-1].pos != pos {
+		c.positions = append(c.positions, positionRun{offset: len(c.Code), pos: pos})
+	}
+	c.Code = append(c.Code, b)
+}
+
+// AddConstant appends v to the constant pool and returns its index.
+func (c *Chunk) AddConstant(v any) int {
+	c.Constants = append(c.Constants, v)
+	return len(c.Constants) - 1
+}
+
+// Position returns the source position of the instruction byte at offset.
+func (c *Chunk) Position(offset int) Position {
+	lo, hi := 0, len(c.positions)-1
+	pos := Position{}
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		if c.positions[mid].offset <= offset {
+			pos = c.positions[mid].pos
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return pos
+}
+
+// Function is a compiled function, or the top level script when Name is
+// empty.
+type Function struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	Chunk        *Chunk
+	// Inline is set for functions the compiler makes out of an
+	// expression, such as a match. Stack traces leave them out.
+	Inline bool
+	// Generator is set for generator functions, whose calls return a
+	// generator running the body.
+	Generator bool
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+	return fmt.Sprintf("<fn %s>", f.Name)
+}
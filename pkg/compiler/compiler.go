@@ -0,0 +1,1155 @@
+// Package compiler lowers the syntax tree to bytecode for the vm package.
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+var ErrCompile = errors.New("compile error")
+
+const (
+	maxLocals    = math.MaxUint8 + 1
+	maxUpvalues  = math.MaxUint8 + 1
+	maxConstants = math.MaxUint16 + 1
+	maxJump      = math.MaxUint16
+	maxMethods   = math.MaxUint8
+)
+
+// thisName names the local in slot zero of a method, which holds the
+// instance it is called on. No variable can have it since it is a keyword.
+const thisName = "this"
+
+type local struct {
+	name string
+	// depth is the scope depth the local was declared at
+	depth    int
+	constant bool
+	// captured is set when a closure refers to the local, so leaving its
+	// scope must move it off the stack
+	captured bool
+}
+
+type upvalue struct {
+	index    byte
+	isLocal  bool
+	constant bool
+}
+
+// tryBlock is a try statement whose body or catch clause is being
+// compiled.
+type tryBlock struct {
+	stmt *ast.TryStmt
+	// handlers counts the handlers of the statement in effect
+	handlers int
+}
+
+// functionScope holds the compilation state of one function body. Nested
+// function declarations push a new scope linked to the enclosing one.
+type functionScope struct {
+	enclosing  *functionScope
+	function   *Function
+	locals     []local
+	upvalues   []upvalue
+	scopeDepth int
+	// tries are the try statements enclosing the code being compiled,
+	// innermost last
+	tries []*tryBlock
+	// initializer is set for the init method of a class, which returns
+	// the instance
+	initializer bool
+}
+
+func newFunctionScope(enclosing *functionScope, name string, arity int) *functionScope {
+	scope := &functionScope{
+		enclosing: enclosing,
+		function: &Function{
+			Name:  name,
+			Arity: arity,
+			Chunk: NewChunk(),
+		},
+	}
+	// slot zero holds the function being called
+	scope.locals = append(scope.locals, local{name: "", depth: 0})
+	return scope
+}
+
+type Compiler struct {
+	scope         *functionScope
+	pos           Position
+	errorReporter errorreporter.ErrorReporter
+	errors        []*CompileError
+}
+
+func NewCompiler(errorReporter errorreporter.ErrorReporter) *Compiler {
+	return &Compiler{
+		errorReporter: errorReporter,
+	}
+}
+
+// Compile compiles a program into the function for its top level code.
+// Errors are reported to the error reporter; ErrCompile is returned if there
+// were any.
+func (c *Compiler) Compile(statements []ast.Stmt) (*Function, error) {
+	c.errors = nil
+	c.scope = newFunctionScope(nil, "", 0)
+	for _, stmt := range statements {
+		c.statement(stmt)
+	}
+	function := c.endFunction()
+
+	if len(c.errors) > 0 {
+		return nil, ErrCompile
+	}
+	return function, nil
+}
+
+func (c *Compiler) statement(stmt ast.Stmt) {
+	ast.AcceptStmt[any](stmt, c)
+}
+
+func (c *Compiler) expression(expr ast.Expr) {
+	ast.Accept[any](expr, c)
+}
+
+// CompileInteractive compiles like Compile, except that when the program
+// ends with an expression statement its value is returned instead of
+// discarded. The REPL uses it to show results.
+func (c *Compiler) CompileInteractive(statements []ast.Stmt) (*Function, error) {
+	if len(statements) == 0 {
+		return c.Compile(statements)
+	}
+	last, ok := statements[len(statements)-1].(*ast.ExpressionStmt)
+	if !ok {
+		return c.Compile(statements)
+	}
+
+	c.errors = nil
+	c.scope = newFunctionScope(nil, "", 0)
+	for _, stmt := range statements[:len(statements)-1] {
+		c.statement(stmt)
+	}
+	c.expression(last.Expression())
+	c.emitOp(OpReturn)
+	function := c.endFunction()
+
+	if len(c.errors) > 0 {
+		return nil, ErrCompile
+	}
+	return function, nil
+}
+
+func (c *Compiler) Errors() []*CompileError {
+	return c.errors
+}
+
+func (c *Compiler) error(token *ast.Token, message string) {
+	err := NewCompileError(token, message)
+	c.errors = append(c.errors, err)
+	c.errorReporter.ReportParserError(token.Line, token.Column, fmt.Sprintf("at '%s'", *token.Lexeme), message)
+}
+
+func (c *Compiler) at(token *ast.Token) {
+	c.pos = Position{Line: token.Line, Column: token.Column}
+}
+
+func (c *Compiler) chunk() *Chunk {
+	return c.scope.function.Chunk
+}
+
+func (c *Compiler) emit(bytes ...byte) {
+	for _, b := range bytes {
+		c.chunk().Write(b, c.pos)
+	}
+}
+
+func (c *Compiler) emitOp(op OpCode, operands ...byte) {
+	c.emit(byte(op))
+	c.emit(operands...)
+}
+
+func (c *Compiler) emitShort(op OpCode, operand int) {
+	c.emit(byte(op), byte(operand>>8), byte(operand))
+}
+
+func (c *Compiler) makeConstant(token *ast.Token, v any) int {
+	index := c.chunk().AddConstant(v)
+	if index >= maxConstants {
+		c.error(token, "Too many constants in one chunk")
+		return 0
+	}
+	return index
+}
+
+func (c *Compiler) emitJump(op OpCode) int {
+	c.emit(byte(op), 0xff, 0xff)
+	return len(c.chunk().Code) - 2
+}
+
+func (c *Compiler) patchJump(token *ast.Token, offset int) {
+	jump := len(c.chunk().Code) - offset - 2
+	if jump > maxJump {
+		c.error(token, "Too much code to jump over")
+	}
+	c.chunk().Code[offset] = byte(jump >> 8)
+	c.chunk().Code[offset+1] = byte(jump)
+}
+
+func (c *Compiler) emitLoop(token *ast.Token, loopStart int) {
+	c.emit(byte(OpLoop))
+	offset := len(c.chunk().Code) - loopStart + 2
+	if offset > maxJump {
+		c.error(token, "Loop body too large")
+	}
+	c.emit(byte(offset>>8), byte(offset))
+}
+
+func (c *Compiler) endFunction() *Function {
+	c.emitReturnNil()
+	c.emitOp(OpReturn)
+	function := c.scope.function
+	function.UpvalueCount = len(c.scope.upvalues)
+	c.scope = c.scope.enclosing
+	return function
+}
+
+// emitReturnNil pushes the value of a return without one: nil, or the
+// instance in an initializer.
+func (c *Compiler) emitReturnNil() {
+	if c.scope.initializer {
+		c.emitOp(OpGetLocal, 0)
+	} else {
+		c.emitOp(OpNil)
+	}
+}
+
+func (c *Compiler) beginScope() {
+	c.scope.scopeDepth++
+}
+
+func (c *Compiler) endScope() {
+	scope := c.scope
+	scope.scopeDepth--
+	for len(scope.locals) > 0 && scope.locals[len(scope.locals)-1].depth > scope.scopeDepth {
+		if scope.locals[len(scope.locals)-1].captured {
+			c.emitOp(OpCloseUpvalue)
+		} else {
+			c.emitOp(OpPop)
+		}
+		scope.locals = scope.locals[:len(scope.locals)-1]
+	}
+}
+
+// addHiddenLocal claims the stack slot of the value on top of the stack
+// for a local the program can't name, needed by the statement at token. It
+// returns the slot.
+func (c *Compiler) addHiddenLocal(token *ast.Token) int {
+	if len(c.scope.locals) >= maxLocals {
+		c.error(token, "Too many local variables in function")
+	}
+	c.scope.locals = append(c.scope.locals, local{depth: c.scope.scopeDepth})
+	return len(c.scope.locals) - 1
+}
+
+// addLocal claims the stack slot of the value on top of the stack for name.
+func (c *Compiler) addLocal(name *ast.Token, constant bool) {
+	if len(c.scope.locals) >= maxLocals {
+		c.error(name, "Too many local variables in function")
+		return
+	}
+	c.scope.locals = append(c.scope.locals, local{
+		name:     *name.Lexeme,
+		depth:    c.scope.scopeDepth,
+		constant: constant,
+	})
+}
+
+func resolveLocal(scope *functionScope, name string) int {
+	for i := len(scope.locals) - 1; i >= 0; i-- {
+		if scope.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Compiler) resolveUpvalue(scope *functionScope, name *ast.Token) int {
+	if scope.enclosing == nil {
+		return -1
+	}
+
+	if index := resolveLocal(scope.enclosing, *name.Lexeme); index != -1 {
+		scope.enclosing.locals[index].captured = true
+		return c.addUpvalue(scope, name, byte(index), true, scope.enclosing.locals[index].constant)
+	}
+
+	if index := c.resolveUpvalue(scope.enclosing, name); index != -1 {
+		return c.addUpvalue(scope, name, byte(index), false, scope.enclosing.upvalues[index].constant)
+	}
+
+	return -1
+}
+
+func (c *Compiler) addUpvalue(scope *functionScope, name *ast.Token, index byte, isLocal, constant bool) int {
+	for i, upvalue := range scope.upvalues {
+		if upvalue.index == index && upvalue.isLocal == isLocal {
+			return i
+		}
+	}
+
+	if len(scope.upvalues) >= maxUpvalues {
+		c.error(name, "Too many closure variables in function")
+		return 0
+	}
+	scope.upvalues = append(scope.upvalues, upvalue{index: index, isLocal: isLocal, constant: constant})
+	return len(scope.upvalues) - 1
+}
+
+// function emits a closure of the function stmt declares. The slot zero of
+// a method holds the instance it is called on, which "this" refers to.
+func (c *Compiler) function(stmt *ast.FunctionStmt, method bool) {
+	c.scope = newFunctionScope(c.scope, *stmt.Name().Lexeme, len(stmt.Params()))
+	c.scope.function.Generator = stmt.Generator()
+	if method {
+		c.scope.locals[0].name = thisName
+		c.scope.initializer = *stmt.Name().Lexeme == value.InitMethod
+	}
+	c.beginScope()
+	for _, param := range stmt.Params() {
+		c.addLocal(param, false)
+	}
+	for _, bodyStmt := range stmt.Body() {
+		c.statement(bodyStmt)
+	}
+	upvalues := c.scope.upvalues
+	function := c.endFunction()
+
+	c.at(stmt.Name())
+	c.closure(stmt.Name(), function, upvalues)
+}
+
+// closure emits the creation of a closure of function, which captures
+// upvalues from the scope being compiled.
+func (c *Compiler) closure(token *ast.Token, function *Function, upvalues []upvalue) {
+	c.emitShort(OpClosure, c.makeConstant(token, function))
+	for _, upvalue := range upvalues {
+		isLocal := byte(0)
+		if upvalue.isLocal {
+			isLocal = 1
+		}
+		c.emit(isLocal, upvalue.index)
+	}
+}
+
+// Statements
+
+func (c *Compiler) VisitExpressionStmt(stmt *ast.ExpressionStmt) any {
+	c.expression(stmt.Expression())
+	c.emitOp(OpPop)
+	return nil
+}
+
+func (c *Compiler) VisitVarStmt(stmt *ast.VarStmt) any {
+	c.at(stmt.Name())
+	if stmt.Initializer() != nil {
+		c.expression(stmt.Initializer())
+	} else {
+		c.emitOp(OpNil)
+	}
+
+	c.at(stmt.Name())
+	if c.scope.scopeDepth > 0 {
+		// the initializer's value becomes the local's slot
+		c.addLocal(stmt.Name(), stmt.Constant())
+		return nil
+	}
+
+	op := OpDefineGlobal
+	if stmt.Constant() {
+		op = OpDefineGlobalConst
+	}
+	c.emitShort(op, c.makeConstant(stmt.Name(), *stmt.Name().Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitImportStmt(stmt *ast.ImportStmt) any {
+	// the parser only allows imports at the top level, so the module is
+	// always bound to a global
+	c.at(stmt.Keyword())
+	c.emitShort(OpImport, c.makeConstant(stmt.Path(), stmt.Path().Literal.(string)))
+	c.at(stmt.Name())
+	c.emitShort(OpDefineGlobalConst, c.makeConstant(stmt.Name(), *stmt.Name().Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitBlockStmt(stmt *ast.BlockStmt) any {
+	c.block(stmt.Statements())
+	return nil
+}
+
+func (c *Compiler) block(statements []ast.Stmt) {
+	c.beginScope()
+	for _, stmt := range statements {
+		c.statement(stmt)
+	}
+	c.endScope()
+}
+
+func (c *Compiler) VisitIfStmt(stmt *ast.IfStmt) any {
+	c.expression(stmt.Condition())
+
+	thenJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.statement(stmt.ThenBranch())
+
+	elseJump := c.emitJump(OpJump)
+	c.patchJump(ast.EOF, thenJump)
+	c.emitOp(OpPop)
+	if stmt.ElseBranch() != nil {
+		c.statement(stmt.ElseBranch())
+	}
+	c.patchJump(ast.EOF, elseJump)
+	return nil
+}
+
+func (c *Compiler) VisitWhileStmt(stmt *ast.WhileStmt) any {
+	loopStart := len(c.chunk().Code)
+	c.at(stmt.Keyword())
+	c.expression(stmt.Condition())
+
+	exitJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.statement(stmt.Body())
+	c.at(stmt.Keyword())
+	c.emitLoop(stmt.Keyword(), loopStart)
+
+	c.patchJump(stmt.Keyword(), exitJump)
+	c.emitOp(OpPop)
+	return nil
+}
+
+// VisitForInStmt keeps the iterator in a hidden local, below the local
+// holding each value, which is made anew for every run of the body so that
+// closures capture the value of their own run.
+func (c *Compiler) VisitForInStmt(stmt *ast.ForInStmt) any {
+	keyword := stmt.Keyword()
+	c.expression(stmt.Iterable())
+	c.at(keyword)
+	c.emitOp(OpIterate)
+	c.beginScope()
+	c.addHiddenLocal(keyword)
+
+	loopStart := len(c.chunk().Code)
+	exitJump := c.emitJump(OpForNext)
+	c.beginScope()
+	c.addLocal(stmt.Name(), false)
+	c.statement(stmt.Body())
+	c.endScope()
+	c.at(keyword)
+	c.emitLoop(keyword, loopStart)
+
+	c.patchJump(keyword, exitJump)
+	c.endScope()
+	return nil
+}
+
+func (c *Compiler) VisitYieldStmt(stmt *ast.YieldStmt) any {
+	c.expression(stmt.Value())
+	c.at(stmt.Keyword())
+	c.emitOp(OpYield)
+	return nil
+}
+
+func (c *Compiler) VisitFunctionStmt(stmt *ast.FunctionStmt) any {
+	if c.scope.scopeDepth > 0 {
+		// declare the local first so the function can call itself
+		c.emitOp(OpNil)
+		c.addLocal(stmt.Name(), false)
+		slot := len(c.scope.locals) - 1
+		c.function(stmt, false)
+		c.emitOp(OpSetLocal, byte(slot))
+		c.emitOp(OpPop)
+		return nil
+	}
+
+	c.function(stmt, false)
+	c.emitShort(OpDefineGlobal, c.makeConstant(stmt.Name(), *stmt.Name().Lexeme))
+	return nil
+}
+
+// VisitClassStmt declares the class like a function, so that its methods
+// can refer to it.
+func (c *Compiler) VisitClassStmt(stmt *ast.ClassStmt) any {
+	if c.scope.scopeDepth > 0 {
+		c.emitOp(OpNil)
+		c.addLocal(stmt.Name(), false)
+		slot := len(c.scope.locals) - 1
+		c.class(stmt)
+		c.emitOp(OpSetLocal, byte(slot))
+		c.emitOp(OpPop)
+		return nil
+	}
+
+	c.class(stmt)
+	c.emitShort(OpDefineGlobal, c.makeConstant(stmt.Name(), *stmt.Name().Lexeme))
+	return nil
+}
+
+// class emits the closures of the methods of stmt, then the class made of
+// them.
+func (c *Compiler) class(stmt *ast.ClassStmt) {
+	if len(stmt.Methods()) > maxMethods {
+		c.error(stmt.Name(), fmt.Sprintf("Can't have more than %d methods", maxMethods))
+		return
+	}
+	for _, method := range stmt.Methods() {
+		c.function(method.(*ast.FunctionStmt), true)
+	}
+	c.at(stmt.Name())
+	c.emitShort(OpClass, c.makeConstant(stmt.Name(), *stmt.Name().Lexeme))
+	c.emit(byte(len(stmt.Methods())))
+}
+
+func (c *Compiler) VisitReturnStmt(stmt *ast.ReturnStmt) any {
+	if stmt.Value() != nil {
+		c.expression(stmt.Value())
+	} else {
+		c.emitReturnNil()
+	}
+	c.leaveTries(stmt.Keyword())
+	c.at(stmt.Keyword())
+	c.emitOp(OpReturn)
+	return nil
+}
+
+// leaveTries ends the try statements a return leaves, running their
+// finally clauses, with the value returned on top of the stack.
+func (c *Compiler) leaveTries(keyword *ast.Token) {
+	tries := c.scope.tries
+	if len(tries) == 0 {
+		return
+	}
+	c.beginScope()
+	slot := c.addHiddenLocal(keyword)
+	for i := len(tries) - 1; i >= 0; i-- {
+		for j := 0; j < tries[i].handlers; j++ {
+			c.emitOp(OpEndTry)
+		}
+		if tries[i].stmt.Finally() != nil {
+			// a return in the finally clause leaves only the outer
+			// statements
+			c.scope.tries = tries[:i]
+			c.block(tries[i].stmt.FinallyBody())
+		}
+	}
+	c.scope.tries = tries
+	c.emitOp(OpGetLocal, byte(slot))
+
+	// the return drops the locals, so there's nothing to pop
+	c.scope.scopeDepth--
+	c.scope.locals = c.scope.locals[:slot]
+}
+
+func (c *Compiler) VisitThrowStmt(stmt *ast.ThrowStmt) any {
+	c.expression(stmt.Value())
+	c.at(stmt.Keyword())
+	c.emitOp(OpThrow)
+	return nil
+}
+
+// VisitTryStmt compiles the body of a try statement between OpTry and
+// OpEndTry, with one handler for the catch clause and an outer one for the
+// finally clause. The finally clause is compiled after the body and catch
+// clause, in the handler that raises the error again, and before every
+// return leaving the statement.
+func (c *Compiler) VisitTryStmt(stmt *ast.TryStmt) any {
+	c.at(stmt.Keyword())
+	try := &tryBlock{stmt: stmt}
+	c.scope.tries = append(c.scope.tries, try)
+
+	var finallyHandler int
+	if stmt.Finally() != nil {
+		finallyHandler = c.emitJump(OpTry)
+		try.handlers++
+	}
+	if stmt.CatchName() != nil {
+		catchHandler := c.emitJump(OpTry)
+		try.handlers++
+		c.block(stmt.Body())
+		c.at(stmt.Keyword())
+		c.emitOp(OpEndTry)
+		try.handlers--
+		skip := c.emitJump(OpJump)
+
+		// the handler pushes the error, which becomes the catch variable
+		c.patchJump(stmt.Keyword(), catchHandler)
+		c.beginScope()
+		c.addLocal(stmt.CatchName(), false)
+		for _, catchStmt := range stmt.CatchBody() {
+			c.statement(catchStmt)
+		}
+		c.endScope()
+		c.patchJump(stmt.Keyword(), skip)
+	} else {
+		c.block(stmt.Body())
+	}
+	c.scope.tries = c.scope.tries[:len(c.scope.tries)-1]
+
+	if stmt.Finally() != nil {
+		c.at(stmt.Finally())
+		c.emitOp(OpEndTry)
+		c.block(stmt.FinallyBody())
+		end := c.emitJump(OpJump)
+
+		c.patchJump(stmt.Finally(), finallyHandler)
+		c.beginScope()
+		slot := c.addHiddenLocal(stmt.Finally())
+		c.block(stmt.FinallyBody())
+		c.at(stmt.Finally())
+		c.emitOp(OpGetLocal, byte(slot))
+		c.emitOp(OpThrow)
+		c.endScope()
+		c.patchJump(stmt.Finally(), end)
+	}
+	return nil
+}
+
+// VisitSelectStmt compiles the operands of the cases followed by
+// OpSelect, whose results are kept in hidden locals, then a body for each
+// case run when the index of the case that proceeded is its own.
+func (c *Compiler) VisitSelectStmt(stmt *ast.SelectStmt) any {
+	keyword := stmt.Keyword()
+	if len(stmt.Cases()) > math.MaxUint8 {
+		c.error(keyword, fmt.Sprintf("Can't have more than %d select cases", math.MaxUint8))
+		return nil
+	}
+	sends := make([]byte, len(stmt.Cases()))
+	for i, selectCase := range stmt.Cases() {
+		channel, send, value, _ := ast.SelectCase(selectCase)
+		c.expression(channel)
+		if send {
+			c.expression(value)
+			sends[i] = 1
+		} else {
+			c.emitOp(OpNil)
+		}
+	}
+	hasElse := byte(0)
+	if stmt.ElseBranch() != nil {
+		hasElse = 1
+	}
+	c.at(keyword)
+	c.emitOp(OpSelect, byte(len(stmt.Cases())), hasElse)
+	c.emit(sends...)
+
+	c.beginScope()
+	received := c.addHiddenLocal(keyword)
+	index := c.addHiddenLocal(keyword)
+	var endJumps []int
+	for i, body := range stmt.Bodies() {
+		c.at(keyword)
+		c.emitOp(OpGetLocal, byte(index))
+		c.emitShort(OpConstant, c.makeConstant(keyword, float64(i)))
+		c.emitOp(OpEqual)
+		nextJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+		c.beginScope()
+		if name := stmt.Names()[i]; name != nil {
+			c.emitOp(OpGetLocal, byte(received))
+			c.addLocal(name, false)
+		}
+		c.statement(body)
+		c.endScope()
+		endJumps = append(endJumps, c.emitJump(OpJump))
+		c.patchJump(keyword, nextJump)
+		c.emitOp(OpPop)
+	}
+	if stmt.ElseBranch() != nil {
+		// only reached when no case proceeded
+		c.statement(stmt.ElseBranch())
+	}
+	for _, jump := range endJumps {
+		c.patchJump(keyword, jump)
+	}
+	c.endScope()
+	return nil
+}
+
+// Expressions
+
+var binaryOps = map[ast.TokenType]OpCode{
+	ast.TokenEqualEqual:     OpEqual,
+	ast.TokenBangEqual:      OpNotEqual,
+	ast.TokenGreater:        OpGreater,
+	ast.TokenGreaterEqual:   OpGreaterEqual,
+	ast.TokenLess:           OpLess,
+	ast.TokenLessEqual:      OpLessEqual,
+	ast.TokenPlus:           OpAdd,
+	ast.TokenMinus:          OpSubtract,
+	ast.TokenStar:           OpMultiply,
+	ast.TokenSlash:          OpDivide,
+	ast.TokenPercent:        OpModulo,
+	ast.TokenStarStar:       OpPower,
+	ast.TokenAmpersand:      OpBitAnd,
+	ast.TokenPipe:           OpBitOr,
+	ast.TokenCaret:          OpBitXor,
+	ast.TokenLessLess:       OpShiftLeft,
+	ast.TokenGreaterGreater: OpShiftRight,
+}
+
+var unaryOps = map[ast.TokenType]OpCode{
+	ast.TokenBang:  OpNot,
+	ast.TokenMinus: OpNegate,
+	ast.TokenPlus:  OpPositive,
+	ast.TokenTilde: OpBitNot,
+}
+
+func (c *Compiler) VisitBinaryExpr(expr *ast.BinaryExpr) any {
+	c.expression(expr.Left())
+	c.expression(expr.Right())
+
+	c.at(expr.Operator())
+	op, ok := binaryOps[expr.Operator().Type]
+	if !ok {
+		c.error(expr.Operator(), "Unsupported binary operator")
+		return nil
+	}
+	c.emitOp(op)
+	return nil
+}
+
+func (c *Compiler) VisitUnaryExpr(expr *ast.UnaryExpr) any {
+	c.expression(expr.Right())
+
+	c.at(expr.Operator())
+	op, ok := unaryOps[expr.Operator().Type]
+	if !ok {
+		c.error(expr.Operator(), "Unsupported unary operator")
+		return nil
+	}
+	c.emitOp(op)
+	return nil
+}
+
+func (c *Compiler) VisitLiteralExpr(expr *ast.LiteralExpr) any {
+	switch v := expr.Value().(type) {
+	case nil:
+		c.emitOp(OpNil)
+	case bool:
+		if v {
+			c.emitOp(OpTrue)
+		} else {
+			c.emitOp(OpFalse)
+		}
+	default:
+		c.emitShort(OpConstant, c.makeConstant(ast.EOF, v))
+	}
+	return nil
+}
+
+func (c *Compiler) VisitGroupingExpr(expr *ast.GroupingExpr) any {
+	c.expression(expr.Expr())
+	return nil
+}
+
+func (c *Compiler) VisitVariableExpr(expr *ast.VariableExpr) any {
+	c.getVariable(expr.Name())
+	return nil
+}
+
+func (c *Compiler) VisitAssignExpr(expr *ast.AssignExpr) any {
+	c.expression(expr.Value())
+	c.setVariable(expr.Name())
+	return nil
+}
+
+func (c *Compiler) VisitCompoundAssignExpr(expr *ast.CompoundAssignExpr) any {
+	c.getVariable(expr.Name())
+	c.expression(expr.Value())
+
+	c.at(expr.Operator())
+	operator, _ := ast.CompoundOperator(expr.Operator().Type)
+	c.emitOp(binaryOps[operator])
+	c.setVariable(expr.Name())
+	return nil
+}
+
+// VisitUpdateExpr compiles ++ and --. The postfix forms keep a copy of the
+// old value below the new one, and pop the new one once it's stored.
+func (c *Compiler) VisitUpdateExpr(expr *ast.UpdateExpr) any {
+	c.getVariable(expr.Name())
+	if !expr.Prefix() {
+		c.getVariable(expr.Name())
+	}
+
+	c.at(expr.Operator())
+	c.emitShort(OpConstant, c.makeConstant(expr.Operator(), 1.0))
+	if expr.Operator().Type == ast.TokenPlusPlus {
+		c.emitOp(OpAdd)
+	} else {
+		c.emitOp(OpSubtract)
+	}
+	c.setVariable(expr.Name())
+	if !expr.Prefix() {
+		c.emitOp(OpPop)
+	}
+	return nil
+}
+
+// getVariable pushes the value of the variable name.
+func (c *Compiler) getVariable(name *ast.Token) {
+	c.at(name)
+	lexeme := *name.Lexeme
+	if slot := resolveLocal(c.scope, lexeme); slot != -1 {
+		c.emitOp(OpGetLocal, byte(slot))
+	} else if index := c.resolveUpvalue(c.scope, name); index != -1 {
+		c.emitOp(OpGetUpvalue, byte(index))
+	} else {
+		c.emitShort(OpGetGlobal, c.makeConstant(name, lexeme))
+	}
+}
+
+// setVariable stores the value on top of the stack in the variable name,
+// leaving it on the stack.
+func (c *Compiler) setVariable(name *ast.Token) {
+	c.at(name)
+	lexeme := *name.Lexeme
+	if slot := resolveLocal(c.scope, lexeme); slot != -1 {
+		if c.scope.locals[slot].constant {
+			c.error(name, fmt.Sprintf("Cannot assign to constant '%s'", lexeme))
+		}
+		c.emitOp(OpSetLocal, byte(slot))
+	} else if index := c.resolveUpvalue(c.scope, name); index != -1 {
+		if c.scope.upvalues[index].constant {
+			c.error(name, fmt.Sprintf("Cannot assign to constant '%s'", lexeme))
+		}
+		c.emitOp(OpSetUpvalue, byte(index))
+	} else {
+		c.emitShort(OpSetGlobal, c.makeConstant(name, lexeme))
+	}
+}
+
+func (c *Compiler) VisitLogicalExpr(expr *ast.LogicalExpr) any {
+	c.expression(expr.Left())
+
+	c.at(expr.Operator())
+	if expr.Operator().Type == ast.TokenOr {
+		elseJump := c.emitJump(OpJumpIfFalse)
+		endJump := c.emitJump(OpJump)
+		c.patchJump(expr.Operator(), elseJump)
+		c.emitOp(OpPop)
+		c.expression(expr.Right())
+		c.patchJump(expr.Operator(), endJump)
+		return nil
+	}
+
+	endJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.expression(expr.Right())
+	c.patchJump(expr.Operator(), endJump)
+	return nil
+}
+
+func (c *Compiler) VisitConditionalExpr(expr *ast.ConditionalExpr) any {
+	c.expression(expr.Condition())
+
+	c.at(expr.Question())
+	elseJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	c.expression(expr.ThenBranch())
+	endJump := c.emitJump(OpJump)
+
+	c.patchJump(expr.Question(), elseJump)
+	c.emitOp(OpPop)
+	c.expression(expr.ElseBranch())
+	c.patchJump(expr.Question(), endJump)
+	return nil
+}
+
+func (c *Compiler) VisitCoalesceExpr(expr *ast.CoalesceExpr) any {
+	c.expression(expr.Left())
+
+	c.at(expr.Operator())
+	endJump := c.emitJump(OpJumpIfNotNil)
+	c.emitOp(OpPop)
+	c.expression(expr.Right())
+	c.patchJump(expr.Operator(), endJump)
+	return nil
+}
+
+func (c *Compiler) VisitGetExpr(expr *ast.GetExpr) any {
+	c.chain(expr)
+	return nil
+}
+
+func (c *Compiler) VisitOptionalGetExpr(expr *ast.OptionalGetExpr) any {
+	c.chain(expr)
+	return nil
+}
+
+func (c *Compiler) VisitCallExpr(expr *ast.CallExpr) any {
+	c.chain(expr)
+	return nil
+}
+
+func (c *Compiler) VisitIndexExpr(expr *ast.IndexExpr) any {
+	c.chain(expr)
+	return nil
+}
+
+func (c *Compiler) VisitSetExpr(expr *ast.SetExpr) any {
+	c.expression(expr.Object())
+	c.expression(expr.Value())
+	c.at(expr.Name())
+	c.emitShort(OpSetProperty, c.makeConstant(expr.Name(), *expr.Name().Lexeme))
+	return nil
+}
+
+func (c *Compiler) VisitSetIndexExpr(expr *ast.SetIndexExpr) any {
+	c.expression(expr.Object())
+	c.expression(expr.Index())
+	c.expression(expr.Value())
+	c.at(expr.Bracket())
+	c.emitOp(OpSetIndex)
+	return nil
+}
+
+// The compound assignments and updates of properties and indexes evaluate
+// the object, and the index, once: they read the old value through copies
+// of them, and leave the originals for the assignment.
+
+func (c *Compiler) VisitCompoundSetExpr(expr *ast.CompoundSetExpr) any {
+	c.expression(expr.Object())
+	name := c.makeConstant(expr.Name(), *expr.Name().Lexeme)
+	c.at(expr.Name())
+	c.emitOp(OpDup, 0)
+	c.emitShort(OpGetProperty, name)
+	c.expression(expr.Value())
+
+	c.at(expr.Operator())
+	operator, _ := ast.CompoundOperator(expr.Operator().Type)
+	c.emitOp(binaryOps[operator])
+	c.at(expr.Name())
+	c.emitShort(OpSetProperty, name)
+	return nil
+}
+
+func (c *Compiler) VisitCompoundSetIndexExpr(expr *ast.CompoundSetIndexExpr) any {
+	c.expression(expr.Object())
+	c.expression(expr.Index())
+	c.at(expr.Bracket())
+	c.emitOp(OpDup, 1)
+	c.emitOp(OpDup, 1)
+	c.emitOp(OpIndex)
+	c.expression(expr.Value())
+
+	c.at(expr.Operator())
+	operator, _ := ast.CompoundOperator(expr.Operator().Type)
+	c.emitOp(binaryOps[operator])
+	c.at(expr.Bracket())
+	c.emitOp(OpSetIndex)
+	return nil
+}
+
+func (c *Compiler) VisitUpdatePropertyExpr(expr *ast.UpdatePropertyExpr) any {
+	c.expression(expr.Object())
+	name := c.makeConstant(expr.Name(), *expr.Name().Lexeme)
+	c.at(expr.Name())
+	c.emitOp(OpDup, 0)
+	c.emitShort(OpGetProperty, name)
+	c.update(expr.Operator(), expr.Prefix(), 1)
+	c.at(expr.Name())
+	c.emitShort(OpSetProperty, name)
+	if !expr.Prefix() {
+		c.emitOp(OpPop)
+	}
+	return nil
+}
+
+func (c *Compiler) VisitUpdateIndexExpr(expr *ast.UpdateIndexExpr) any {
+	c.expression(expr.Object())
+	c.expression(expr.Index())
+	c.at(expr.Bracket())
+	c.emitOp(OpDup, 1)
+	c.emitOp(OpDup, 1)
+	c.emitOp(OpIndex)
+	c.update(expr.Operator(), expr.Prefix(), 2)
+	c.at(expr.Bracket())
+	c.emitOp(OpSetIndex)
+	if !expr.Prefix() {
+		c.emitOp(OpPop)
+	}
+	return nil
+}
+
+// update applies "++" or "--" to the value on top of the stack, above the
+// depth values its assignment takes. A postfix update buries a copy of the
+// old value below them, for the new value to be popped off once assigned.
+func (c *Compiler) update(operator *ast.Token, prefix bool, depth int) {
+	c.at(operator)
+	if !prefix {
+		c.emitOp(OpDup, 0)
+		c.emitOp(OpBury, byte(depth+1))
+	}
+	c.emitShort(OpConstant, c.makeConstant(operator, 1.0))
+	if operator.Type == ast.TokenPlusPlus {
+		c.emitOp(OpAdd)
+	} else {
+		c.emitOp(OpSubtract)
+	}
+}
+
+// VisitThisExpr reads slot zero of the method, which functions nested in
+// it capture like any other local.
+func (c *Compiler) VisitThisExpr(expr *ast.ThisExpr) any {
+	name := *expr.Keyword()
+	lexeme := thisName
+	name.Lexeme = &lexeme
+	c.getVariable(&name)
+	return nil
+}
+
+func (c *Compiler) VisitListExpr(expr *ast.ListExpr) any {
+	if len(expr.Elements()) > math.MaxUint16 {
+		c.error(expr.Bracket(), fmt.Sprintf("Can't have more than %d list elements", math.MaxUint16))
+		return nil
+	}
+	for _, element := range expr.Elements() {
+		c.expression(element)
+	}
+	c.at(expr.Bracket())
+	c.emitShort(OpList, len(expr.Elements()))
+	return nil
+}
+
+func (c *Compiler) VisitMapExpr(expr *ast.MapExpr) any {
+	if len(expr.Keys()) > math.MaxUint16 {
+		c.error(expr.Brace(), fmt.Sprintf("Can't have more than %d map entries", math.MaxUint16))
+		return nil
+	}
+	for n, key := range expr.Keys() {
+		c.expression(key)
+		c.expression(expr.Values()[n])
+	}
+	c.at(expr.Brace())
+	c.emitShort(OpMap, len(expr.Keys()))
+	return nil
+}
+
+// chainJump is the jump an optional access takes past the rest of its
+// chain when the object is nil.
+type chainJump struct {
+	name   *ast.Token
+	offset int
+}
+
+// chain compiles expr, a property access, index or call ending a chain of them,
+// such as a?.b.c. An optional access finding nil jumps to the end of the
+// chain, leaving the nil as its value.
+func (c *Compiler) chain(expr ast.Expr) {
+	var jumps []chainJump
+	c.link(expr, &jumps)
+	for _, jump := range jumps {
+		c.patchJump(jump.name, jump.offset)
+	}
+}
+
+// link compiles expr, a link of a chain, adding the jumps of its optional
+// accesses to jumps.
+func (c *Compiler) link(expr ast.Expr, jumps *[]chainJump) {
+	switch expr := expr.(type) {
+	case *ast.GetExpr:
+		c.link(expr.Object(), jumps)
+		c.at(expr.Name())
+		c.emitShort(OpGetProperty, c.makeConstant(expr.Name(), *expr.Name().Lexeme))
+	case *ast.OptionalGetExpr:
+		c.link(expr.Object(), jumps)
+		c.at(expr.Name())
+		*jumps = append(*jumps, chainJump{name: expr.Name(), offset: c.emitJump(OpJumpIfNil)})
+		c.emitShort(OpGetProperty, c.makeConstant(expr.Name(), *expr.Name().Lexeme))
+	case *ast.IndexExpr:
+		c.link(expr.Object(), jumps)
+		c.expression(expr.Index())
+		c.at(expr.Bracket())
+		c.emitOp(OpIndex)
+	case *ast.CallExpr:
+		c.link(expr.Callee(), jumps)
+		for _, argument := range expr.Arguments() {
+			c.expression(argument)
+		}
+		c.at(expr.Paren())
+		c.emitOp(OpCall, byte(len(expr.Arguments())))
+	default:
+		c.expression(expr)
+	}
+}
+
+// VisitMatchExpr compiles a match into an inline function called with the
+// subject, returning the value of the arm chosen. Being a function of its
+// own gives the variables the arms bind stack slots, whatever temporaries
+// the enclosing expression keeps on the stack.
+func (c *Compiler) VisitMatchExpr(expr *ast.MatchExpr) any {
+	keyword := expr.Keyword()
+	c.scope = newFunctionScope(c.scope, "match", 1)
+	c.scope.function.Inline = true
+	c.beginScope()
+	subject := c.addHiddenLocal(keyword)
+
+	for arm, pattern := range expr.Patterns() {
+		bindings := ast.PatternBindings(pattern)
+		c.at(keyword)
+		c.beginScope()
+		c.emitOp(OpGetLocal, byte(subject))
+		c.emitShort(OpMatch, c.makeConstant(keyword, pattern))
+		c.emit(byte(len(bindings)))
+		for _, name := range bindings {
+			c.addLocal(name, false)
+		}
+
+		// a failed match leaves whether it matched on the stack, a failed
+		// guard its value, popped along with the bindings
+		failJumps := []int{c.emitJump(OpJumpIfFalse)}
+		c.emitOp(OpPop)
+		if guard := expr.Guards()[arm]; guard != nil {
+			c.expression(guard)
+			failJumps = append(failJumps, c.emitJump(OpJumpIfFalse))
+			c.emitOp(OpPop)
+		}
+		c.expression(expr.Values()[arm])
+		c.emitOp(OpReturn)
+
+		for _, jump := range failJumps {
+			c.patchJump(keyword, jump)
+		}
+		c.emitOp(OpPop)
+		c.endScope()
+	}
+	c.at(keyword)
+	c.emitOp(OpGetLocal, byte(subject))
+	c.emitOp(OpNoMatch)
+	upvalues := c.scope.upvalues
+	function := c.endFunction()
+
+	c.at(keyword)
+	c.closure(keyword, function, upvalues)
+	c.expression(expr.Subject())
+	c.at(keyword)
+	c.emitOp(OpCall, 1)
+	return nil
+}
+
+func (c *Compiler) VisitSpawnExpr(expr *ast.SpawnExpr) any {
+	call := expr.Call().(*ast.CallExpr)
+	c.expression(call.Callee())
+	for _, argument := range call.Arguments() {
+		c.expression(argument)
+	}
+	c.at(call.Paren())
+	c.emitOp(OpSpawn, byte(len(call.Arguments())))
+	return nil
+}
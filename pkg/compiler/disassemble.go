@@ -0,0 +1,112 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// Disassemble writes a human readable listing of function's bytecode to w,
+// followed by the listings of the functions it declares.
+func Disassemble(w io.Writer, function *Function) {
+	chunk := function.Chunk
+	fmt.Fprintf(w, "== %s ==\n", function)
+	for offset := 0; offset < len(chunk.Code); {
+		offset = DisassembleInstruction(w, chunk, offset)
+	}
+
+	for _, constant := range chunk.Constants {
+		if nested, ok := constant.(*Function); ok {
+			fmt.Fprintln(w)
+			Disassemble(w, nested)
+		}
+	}
+}
+
+// DisassembleInstruction writes the instruction at offset and returns the
+// offset of the next one.
+func DisassembleInstruction(w io.Writer, chunk *Chunk, offset int) int {
+	fmt.Fprintf(w, "%04d ", offset)
+	pos := chunk.Position(offset)
+	if offset > 0 && pos.Line == chunk.Position(offset-1).Line {
+		fmt.Fprint(w, "   | ")
+	} else {
+		fmt.Fprintf(w, "%4d ", pos.Line)
+	}
+
+	op := OpCode(chunk.Code[offset])
+	switch op {
+	case OpConstant, OpGetGlobal, OpDefineGlobal, OpDefineGlobalConst, OpSetGlobal, OpGetProperty, OpSetProperty, OpImport:
+		index := readShort(chunk, offset+1)
+		fmt.Fprintf(w, "%-22s %4d '%s'\n", op, index, formatConstant(chunk.Constants[index]))
+		return offset + 3
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall, OpSpawn, OpDup, OpBury:
+		fmt.Fprintf(w, "%-22s %4d\n", op, chunk.Code[offset+1])
+		return offset + 2
+	case OpJump, OpJumpIfFalse, OpJumpIfNil, OpJumpIfNotNil, OpTry, OpForNext:
+		jump := readShort(chunk, offset+1)
+		fmt.Fprintf(w, "%-22s %4d -> %d\n", op, offset, offset+3+jump)
+		return offset + 3
+	case OpList, OpMap:
+		fmt.Fprintf(w, "%-22s %4d\n", op, readShort(chunk, offset+1))
+		return offset + 3
+	case OpClass:
+		index := readShort(chunk, offset+1)
+		fmt.Fprintf(w, "%-22s %4d '%s' (%d methods)\n", op, index, formatConstant(chunk.Constants[index]), chunk.Code[offset+3])
+		return offset + 4
+	case OpMatch:
+		index := readShort(chunk, offset+1)
+		fmt.Fprintf(w, "%-22s %4d (%d bound)\n", op, index, chunk.Code[offset+3])
+		return offset + 4
+	case OpSelect:
+		count := int(chunk.Code[offset+1])
+		fmt.Fprintf(w, "%-22s %4d", op, count)
+		if chunk.Code[offset+2] == 1 {
+			fmt.Fprint(w, " else")
+		}
+		fmt.Fprintln(w)
+		offset += 3
+		for i := 0; i < count; i++ {
+			kind := "receive"
+			if chunk.Code[offset] == 1 {
+				kind = "send"
+			}
+			fmt.Fprintf(w, "%04d    |                        %s\n", offset, kind)
+			offset++
+		}
+		return offset
+	case OpLoop:
+		jump := readShort(chunk, offset+1)
+		fmt.Fprintf(w, "%-22s %4d -> %d\n", op, offset, offset+3-jump)
+		return offset + 3
+	case OpClosure:
+		index := readShort(chunk, offset+1)
+		function := chunk.Constants[index].(*Function)
+		fmt.Fprintf(w, "%-22s %4d %s\n", op, index, function)
+		offset += 3
+		for i := 0; i < function.UpvalueCount; i++ {
+			kind := "upvalue"
+			if chunk.Code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(w, "%04d    |                        %s %d\n", offset, kind, chunk.Code[offset+1])
+			offset += 2
+		}
+		return offset
+	default:
+		fmt.Fprintf(w, "%s\n", op)
+		return offset + 1
+	}
+}
+
+func readShort(chunk *Chunk, offset int) int {
+	return int(chunk.Code[offset])<<8 | int(chunk.Code[offset+1])
+}
+
+func formatConstant(v any) string {
+	if function, ok := v.(*Function); ok {
+		return function.String()
+	}
+	return value.Stringify(v)
+}
@@ -0,0 +1,31 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+type CompileError struct {
+	token   *ast.Token
+	message string
+}
+
+func NewCompileError(token *ast.Token, message string) *CompileError {
+	return &CompileError{
+		token:   token,
+		message: message,
+	}
+}
+
+func (err *CompileError) Error() string {
+	return fmt.Sprintf("compile error: line=%d col=%d at '%s': %s", err.token.Line, err.token.Column, *err.token.Lexeme, err.message)
+}
+
+func (err *CompileError) Token() *ast.Token {
+	return err.token
+}
+
+func (err *CompileError) Message() string {
+	return err.message
+}
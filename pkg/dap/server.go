@@ -0,0 +1,430 @@
+// Package dap implements a Debug Adapter Protocol server for rottenlang
+// over stdio. It launches one program on the tree engine, under the
+// debugger.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/debugger"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/rottenlang"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+type handler func(s *Server, arguments json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":              (*Server).initialize,
+	"launch":                  (*Server).launch,
+	"setBreakpoints":          (*Server).setBreakpoints,
+	"setExceptionBreakpoints": (*Server).setExceptionBreakpoints,
+	"configurationDone":       (*Server).configurationDone,
+	"threads":                 (*Server).threads,
+	"stackTrace":              (*Server).stackTrace,
+	"scopes":                  (*Server).scopes,
+	"variables":               (*Server).variables,
+	"continue":                resume((*debugger.Debugger).Continue),
+	"next":                    resume((*debugger.Debugger).StepOver),
+	"stepIn":                  resume((*debugger.Debugger).StepIn),
+	"stepOut":                 resume((*debugger.Debugger).StepOut),
+	"pause":                   (*Server).pause,
+	"terminate":               (*Server).terminate,
+	"disconnect":              (*Server).disconnect,
+}
+
+// threadID identifies the only thread the server shows. The tasks of a
+// program take turns running, so they share it.
+const threadID = 1
+
+// Server serves one debugging session. Lines and columns count from 1, as
+// clients ask for by default.
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+	// writeMu serializes writes to out and guards seq
+	writeMu sync.Mutex
+	seq     int
+
+	debugger *debugger.Debugger
+	// program is the launched program, started once the client is done
+	// configuring the session
+	program    *rottenlang.Rottenlang
+	configured bool
+	// done is closed when the program ends
+	done chan struct{}
+	// afterReply runs once the reply to the current request is sent
+	afterReply   func()
+	disconnected bool
+
+	// mu guards the stop the program is in, nil while it runs, and the
+	// variables of its frames: scopeRefs holds the variables references of
+	// each frame's scopes, indexes in scopeVariables plus one
+	mu             sync.Mutex
+	stop           *debugger.Stop
+	scopeRefs      [][]int
+	scopeVariables [][]debugger.Variable
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	s := &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		done: make(chan struct{}),
+	}
+	s.debugger = debugger.NewDebugger(s.stopped)
+	return s
+}
+
+// Run serves requests until the client disconnects or closes the input. A
+// program still running is terminated then.
+func (s *Server) Run() error {
+	defer s.debugger.Terminate()
+	for !s.disconnected {
+		body, err := readMessage(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %w", err)
+		}
+		if req.Type == "request" {
+			s.handle(&req)
+		}
+	}
+	return nil
+}
+
+func (s *Server) handle(req *request) {
+	h, ok := handlers[req.Command]
+	if !ok {
+		s.reply(req, nil, fmt.Errorf("unknown command '%s'", req.Command))
+		return
+	}
+	body, err := h(s, req.Arguments)
+	s.reply(req, body, err)
+	if s.afterReply != nil {
+		after := s.afterReply
+		s.afterReply = nil
+		after()
+	}
+}
+
+func (s *Server) reply(req *request, body any, err error) {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		resp.Message = err.Error()
+	}
+	s.send(func(seq int) any {
+		resp.Seq = seq
+		return resp
+	})
+}
+
+func (s *Server) event(name string, body any) {
+	s.send(func(seq int) any {
+		return &event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// send writes the message made for the next sequence number.
+func (s *Server) send(message func(seq int) any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	// nothing sensible to do when the client went away
+	_ = writeMessage(s.out, message(s.seq))
+}
+
+func decode(arguments json.RawMessage, v any) error {
+	if len(arguments) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(arguments, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// output writes what the program writes to a category of output events.
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.s.event("output", OutputEventBody{Category: o.category, Output: string(p)})
+	return len(p), nil
+}
+
+// Session
+
+func (s *Server) initialize(arguments json.RawMessage) (any, error) {
+	s.afterReply = func() {
+		s.event("initialized", nil)
+	}
+	return Capabilities{SupportsConfigurationDoneRequest: true, SupportsTerminateRequest: true}, nil
+}
+
+func (s *Server) launch(arguments json.RawMessage) (any, error) {
+	var args LaunchArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+	if s.program != nil {
+		return nil, errors.New("a program is already launched")
+	}
+	source, err := os.ReadFile(args.Program)
+	if err != nil {
+		return nil, err
+	}
+
+	program := rottenlang.NewRottenlang(string(source), errorreporter.NewWriterErrorReporter(&output{s: s, category: "stderr"}))
+	program.Out = &output{s: s, category: "stdout"}
+	program.Path = args.Program
+	if !args.NoDebug {
+		program.Debugger = s.debugger
+		if args.StopOnEntry {
+			s.debugger.StopOnEntry()
+		}
+	}
+	s.program = program
+	if s.configured {
+		s.afterReply = s.start
+	}
+	return nil, nil
+}
+
+func (s *Server) configurationDone(arguments json.RawMessage) (any, error) {
+	s.configured = true
+	if s.program != nil {
+		s.afterReply = s.start
+	}
+	return nil, nil
+}
+
+// start runs the launched program, reporting its end with the exited and
+// terminated events.
+func (s *Server) start() {
+	go func() {
+		defer close(s.done)
+		exitCode := 0
+		if err := s.program.Execute(rottenlang.EngineTree); err != nil {
+			exitCode = 1
+		}
+		s.event("exited", ExitedEventBody{ExitCode: exitCode})
+		s.event("terminated", nil)
+	}()
+}
+
+func (s *Server) terminate(arguments json.RawMessage) (any, error) {
+	s.debugger.Terminate()
+	return nil, nil
+}
+
+func (s *Server) disconnect(arguments json.RawMessage) (any, error) {
+	s.debugger.Terminate()
+	if s.program != nil && s.configured {
+		<-s.done
+	}
+	s.disconnected = true
+	return nil, nil
+}
+
+// Breakpoints
+
+func (s *Server) setBreakpoints(arguments json.RawMessage) (any, error) {
+	var args SetBreakpointsArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+	path, err := filepath.Abs(args.Source.Path)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := statementLines(path)
+
+	breakpoints := make([]Breakpoint, 0, len(args.Breakpoints))
+	var verified []int
+	for _, b := range args.Breakpoints {
+		breakpoint := Breakpoint{Line: b.Line, Source: &args.Source}
+		switch {
+		case err != nil:
+			breakpoint.Message = err.Error()
+		case !lines[b.Line]:
+			breakpoint.Message = "No statement starts on this line"
+		default:
+			breakpoint.Verified = true
+			verified = append(verified, b.Line)
+		}
+		breakpoints = append(breakpoints, breakpoint)
+	}
+	s.debugger.SetBreakpoints(path, verified)
+	return SetBreakpointsResponseBody{Breakpoints: breakpoints}, nil
+}
+
+// statementLines returns the lines of the file at path that statements
+// start on, where the program can stop.
+func statementLines(path string) (map[int]bool, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := rottenlang.NewRottenlang(string(source), &errorreporter.NopErrorReporter{}).Parse()
+	if err != nil {
+		return nil, fmt.Errorf("%s doesn't parse", filepath.Base(path))
+	}
+	lines := make(map[int]bool)
+	for _, stmt := range statements {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			if stmt, ok := node.(ast.Stmt); ok {
+				if _, ok := stmt.(*ast.BlockStmt); !ok {
+					if token := ast.LeadingToken(stmt); token != nil {
+						lines[token.Line] = true
+					}
+				}
+			}
+			return true
+		})
+	}
+	return lines, nil
+}
+
+// Exceptions don't stop the program, so there are no filters to set.
+func (s *Server) setExceptionBreakpoints(arguments json.RawMessage) (any, error) {
+	return SetBreakpointsResponseBody{Breakpoints: []Breakpoint{}}, nil
+}
+
+// Execution
+
+// stopped shows the client the stop the program is in.
+func (s *Server) stopped(stop debugger.Stop) {
+	s.mu.Lock()
+	s.stop = &stop
+	s.scopeRefs = make([][]int, len(stop.Stack))
+	s.scopeVariables = nil
+	for i, frame := range stop.Stack {
+		for _, scope := range frame.Scopes {
+			s.scopeVariables = append(s.scopeVariables, scope.Variables)
+			s.scopeRefs[i] = append(s.scopeRefs[i], len(s.scopeVariables))
+		}
+	}
+	s.mu.Unlock()
+	s.event("stopped", StoppedEventBody{Reason: stop.Reason, ThreadID: threadID, AllThreadsStopped: true})
+}
+
+// resume returns the handler of a request ending the stop of the program
+// with action. The program goes on once the reply is sent, so it's sent
+// before the next stop is shown.
+func resume(action func(*debugger.Debugger) bool) handler {
+	return func(s *Server, arguments json.RawMessage) (any, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.stop == nil {
+			return nil, errors.New("the program isn't stopped")
+		}
+		s.stop = nil
+		s.afterReply = func() {
+			action(s.debugger)
+		}
+		return ContinueResponseBody{AllThreadsContinued: true}, nil
+	}
+}
+
+func (s *Server) pause(arguments json.RawMessage) (any, error) {
+	s.debugger.Pause()
+	return nil, nil
+}
+
+// Inspection
+
+func (s *Server) threads(arguments json.RawMessage) (any, error) {
+	return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+func (s *Server) stackTrace(arguments json.RawMessage) (any, error) {
+	var args StackTraceArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil {
+		return nil, errors.New("the program isn't stopped")
+	}
+
+	stack := s.stop.Stack
+	end := len(stack)
+	if args.Levels > 0 {
+		end = min(args.StartFrame+args.Levels, end)
+	}
+	frames := make([]StackFrame, 0, max(end-args.StartFrame, 0))
+	for i := args.StartFrame; i < end; i++ {
+		frame := StackFrame{ID: i + 1, Name: stack[i].Function, Line: stack[i].Line, Column: stack[i].Column}
+		if stack[i].File != "" {
+			frame.Source = &Source{Name: filepath.Base(stack[i].File), Path: stack[i].File}
+		}
+		frames = append(frames, frame)
+	}
+	return StackTraceResponseBody{StackFrames: frames, TotalFrames: len(stack)}, nil
+}
+
+func (s *Server) scopes(arguments json.RawMessage) (any, error) {
+	var args ScopesArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil || args.FrameID < 1 || args.FrameID > len(s.stop.Stack) {
+		return nil, fmt.Errorf("unknown frame %d", args.FrameID)
+	}
+
+	frame := s.stop.Stack[args.FrameID-1]
+	scopes := make([]Scope, len(frame.Scopes))
+	for i, scope := range frame.Scopes {
+		scopes[i] = Scope{Name: scope.Name, VariablesReference: s.scopeRefs[args.FrameID-1][i]}
+	}
+	return ScopesResponseBody{Scopes: scopes}, nil
+}
+
+func (s *Server) variables(arguments json.RawMessage) (any, error) {
+	var args VariablesArguments
+	if err := decode(arguments, &args); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop == nil || args.VariablesReference < 1 || args.VariablesReference > len(s.scopeVariables) {
+		return nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+
+	variables := make([]Variable, 0)
+	for _, v := range s.scopeVariables[args.VariablesReference-1] {
+		variables = append(variables, Variable{Name: v.Name, Value: show(v.Value), Type: value.TypeName(v.Value)})
+	}
+	return VariablesResponseBody{Variables: variables}, nil
+}
+
+// show formats v as the value of a variable, quoting strings so they can be
+// told apart from the values they spell.
+func show(v any) string {
+	if s, ok := v.(string); ok {
+		return strconv.Quote(s)
+	}
+	return value.Stringify(v)
+}
@@ -0,0 +1,282 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const source = `func add(a, b) {
+  vibes sum = a + b;
+  purrr sum;
+}
+vibes x = 1;
+vibes y = add(x, 2);
+print(y);
+`
+
+// message is a response or an event sent by the server.
+type message struct {
+	Type       string          `json:"type"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client talks to a server running over pipes.
+type client struct {
+	t   *testing.T
+	w   io.Writer
+	r   *bufio.Reader
+	seq int
+	// events are the events read while waiting for responses
+	events []message
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{t: t, w: inW, r: bufio.NewReader(outR), done: make(chan error, 1)}
+	go func() {
+		c.done <- NewServer(inR, outW).Run()
+		outW.Close()
+	}()
+	t.Cleanup(func() {
+		inW.Close()
+		select {
+		case err := <-c.done:
+			if err != nil {
+				t.Errorf("Run() = %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("server didn't stop")
+		}
+	})
+	return c
+}
+
+func (c *client) read() message {
+	c.t.Helper()
+	body, err := readMessage(c.r)
+	if err != nil {
+		c.t.Fatalf("reading message: %v", err)
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		c.t.Fatal(err)
+	}
+	return msg
+}
+
+// request sends a request and decodes the body of its response into body,
+// failing unless it succeeded.
+func (c *client) request(command string, arguments any, body any) {
+	c.t.Helper()
+	c.seq++
+	if err := writeMessage(c.w, map[string]any{"seq": c.seq, "type": "request", "command": command, "arguments": arguments}); err != nil {
+		c.t.Fatal(err)
+	}
+	for {
+		msg := c.read()
+		if msg.Type == "event" {
+			c.events = append(c.events, msg)
+			continue
+		}
+		if msg.RequestSeq != c.seq || !msg.Success {
+			c.t.Fatalf("%s failed: %+v", command, msg)
+		}
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return
+	}
+}
+
+// event waits for the event called name, decoding its body into body, and
+// returns the output events sent before it.
+func (c *client) event(name string, body any) string {
+	c.t.Helper()
+	var output string
+	for {
+		var msg message
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.read()
+		}
+		if msg.Event == "output" {
+			var o OutputEventBody
+			json.Unmarshal(msg.Body, &o)
+			output += o.Output
+		}
+		if msg.Event != name {
+			continue
+		}
+		if body != nil {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+		return output
+	}
+}
+
+// stopped waits for the program to stop for one of reasons, returning its
+// stack.
+func (c *client) stopped(reasons ...string) []StackFrame {
+	c.t.Helper()
+	var stop StoppedEventBody
+	c.event("stopped", &stop)
+	if !slices.Contains(reasons, stop.Reason) {
+		c.t.Fatalf("stopped for %s, want %s", stop.Reason, strings.Join(reasons, " or "))
+	}
+	var trace StackTraceResponseBody
+	c.request("stackTrace", StackTraceArguments{ThreadID: threadID}, &trace)
+	return trace.StackFrames
+}
+
+// variables returns the variables of a scope of a frame, by name.
+func (c *client) variables(frameID int, scope string) map[string]string {
+	c.t.Helper()
+	var scopes ScopesResponseBody
+	c.request("scopes", ScopesArguments{FrameID: frameID}, &scopes)
+	for _, s := range scopes.Scopes {
+		if s.Name != scope {
+			continue
+		}
+		var variables VariablesResponseBody
+		c.request("variables", VariablesArguments{VariablesReference: s.VariablesReference}, &variables)
+		values := make(map[string]string)
+		for _, v := range variables.Variables {
+			values[v.Name] = v.Value
+		}
+		return values
+	}
+	c.t.Fatalf("frame %d has no scope %s", frameID, scope)
+	return nil
+}
+
+func (c *client) launch(program string, stopOnEntry bool, breakpoints ...int) []Breakpoint {
+	c.t.Helper()
+	c.request("initialize", map[string]any{"adapterID": "rottenlang"}, nil)
+	c.event("initialized", nil)
+	c.request("launch", LaunchArguments{Program: program, StopOnEntry: stopOnEntry}, nil)
+	var set SetBreakpointsResponseBody
+	lines := make([]SourceBreakpoint, len(breakpoints))
+	for i, line := range breakpoints {
+		lines[i] = SourceBreakpoint{Line: line}
+	}
+	c.request("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: program}, Breakpoints: lines}, &set)
+	c.request("configurationDone", nil, nil)
+	return set.Breakpoints
+}
+
+func writeProgram(t *testing.T, source string) string {
+	t.Helper()
+	program := filepath.Join(t.TempDir(), "add.rot")
+	if err := os.WriteFile(program, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return program
+}
+
+func checkFrame(t *testing.T, frame StackFrame, name string, line int) {
+	t.Helper()
+	if frame.Name != name || frame.Line != line || frame.Source == nil || frame.Source.Name != "add.rot" {
+		t.Errorf("got frame %+v, want %s at line %d of add.rot", frame, name, line)
+	}
+}
+
+// TestSession stops a program on entry and at a breakpoint, steps through
+// it and inspects its variables.
+func TestSession(t *testing.T) {
+	program := writeProgram(t, source)
+	c := newClient(t)
+	breakpoints := c.launch(program, true, 2, 4)
+	if len(breakpoints) != 2 || !breakpoints[0].Verified || breakpoints[1].Verified {
+		t.Errorf("got breakpoints %+v, want the one on line 4 unverified", breakpoints)
+	}
+
+	checkFrame(t, c.stopped("entry")[0], "<script>", 1)
+
+	c.request("continue", nil, nil)
+	stack := c.stopped("breakpoint")
+	if len(stack) != 2 {
+		t.Fatalf("got stack %+v", stack)
+	}
+	checkFrame(t, stack[0], "add", 2)
+	checkFrame(t, stack[1], "<script>", 6)
+	if locals := c.variables(stack[0].ID, "Locals"); len(locals) != 2 || locals["a"] != "1" || locals["b"] != "2" {
+		t.Errorf("got locals %v", locals)
+	}
+
+	c.request("next", nil, nil)
+	stack = c.stopped("step")
+	checkFrame(t, stack[0], "add", 3)
+	if sum := c.variables(stack[0].ID, "Locals")["sum"]; sum != "3" {
+		t.Errorf("got sum %s", sum)
+	}
+
+	c.request("stepOut", nil, nil)
+	stack = c.stopped("step")
+	checkFrame(t, stack[0], "<script>", 7)
+	if globals := c.variables(stack[0].ID, "Globals"); globals["x"] != "1" || globals["y"] != "3" || globals["add"] != "<fn add>" || globals["print"] != "" {
+		t.Errorf("got globals %v", globals)
+	}
+
+	c.request("continue", nil, nil)
+	var exited ExitedEventBody
+	if output := c.event("exited", &exited); output != "3\n" || exited.ExitCode != 0 {
+		t.Errorf("got output %q, exit code %d", output, exited.ExitCode)
+	}
+	c.event("terminated", nil)
+	c.request("disconnect", nil, nil)
+}
+
+// TestPauseAndDisconnect pauses programs running forever, then ends the
+// session, terminating them. A breakpoint stops each program once at its
+// loop first, so that the pause has to stop it while it runs the loop. The
+// loop with a body comes back to the breakpoint after each iteration, so
+// it may stop there again before the pause arrives.
+func TestPauseAndDisconnect(t *testing.T) {
+	for name, test := range map[string]struct {
+		loop    string
+		reasons []string
+	}{
+		"loop":       {"skibidi (1) {\n  n = n + 1;\n}\n", []string{"pause", "breakpoint"}},
+		"empty loop": {"skibidi (nocap) {}\n", []string{"pause"}},
+		"empty for":  {"for (vibes i = 0; nocap; ) {}\n", []string{"pause"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			program := writeProgram(t, "vibes n = 0;\n"+test.loop)
+			c := newClient(t)
+			c.launch(program, false, 2)
+			c.stopped("breakpoint")
+
+			c.request("continue", nil, nil)
+			c.request("pause", nil, nil)
+			if frame := c.stopped(test.reasons...)[0]; frame.Name != "<script>" || frame.Source == nil || frame.Source.Path != program {
+				t.Errorf("got frame %+v", frame)
+			}
+
+			c.request("disconnect", nil, nil)
+			var exited ExitedEventBody
+			c.event("exited", &exited)
+			if exited.ExitCode != 1 {
+				t.Errorf("got exit code %d", exited.ExitCode)
+			}
+		})
+	}
+}
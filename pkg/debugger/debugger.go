@@ -0,0 +1,247 @@
+// Package debugger stops running programs at breakpoints and steps through
+// them. The engine running a program calls Statement before each statement
+// it runs, and the debugger holds it there for as long as the program is
+// stopped, while a front end, such as the Debug Adapter Protocol server,
+// inspects the program and tells it how to go on.
+package debugger
+
+import (
+	"path/filepath"
+	"sync"
+
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// Reasons the program stops for, named as the Debug Adapter Protocol names
+// them.
+const (
+	ReasonEntry      = "entry"
+	ReasonBreakpoint = "breakpoint"
+	ReasonStep       = "step"
+	ReasonPause      = "pause"
+)
+
+// Position is where a statement starts. Lines and columns count from 1.
+type Position struct {
+	File         string
+	Line, Column int
+}
+
+// Frame is a call in progress, of a function or of the top level code of a
+// program or module.
+type Frame struct {
+	Function string
+	// Position is where the statement running in the call starts
+	Position
+	Scopes []Scope
+}
+
+// Scope is a group of the variables seen by a frame, sorted by name.
+type Scope struct {
+	Name      string
+	Variables []Variable
+}
+
+type Variable struct {
+	Name  string
+	Value any
+}
+
+// Program shows the debugger the program calling Statement.
+type Program interface {
+	// Depth returns the number of calls in progress.
+	Depth() int
+	// Stack returns the calls in progress, innermost first.
+	Stack() []Frame
+}
+
+// Stop is a stop of the program, before the statement at the top of its
+// stack.
+type Stop struct {
+	Reason string
+	Stack  []Frame
+}
+
+// action is what the program does until it stops next.
+type action int
+
+const (
+	actionContinue action = iota
+	actionStepIn
+	actionStepOver
+	actionStepOut
+	actionTerminate
+)
+
+// Debugger debugs one program at a time. Its methods may be called from
+// any goroutine.
+type Debugger struct {
+	// stopped is called on the goroutine running the program each time it
+	// stops
+	stopped func(stop Stop)
+	// resume hands the stopped program the action ending its stop
+	resume chan action
+
+	mu sync.Mutex
+	// breakpoints holds the lines of the breakpoints of each file
+	breakpoints map[string]map[int]bool
+	action      action
+	// depth is the depth of the stop the latest step started from
+	depth int
+	// entry and pause make the program stop at the next statement
+	entry, pause bool
+	// isStopped is set while the program is held in Statement
+	isStopped  bool
+	terminated bool
+	// last is where the latest statement run starts
+	last Position
+}
+
+// NewDebugger creates a debugger calling stopped each time the program
+// stops. The program stays stopped until a call to Continue, a step or
+// Terminate.
+func NewDebugger(stopped func(stop Stop)) *Debugger {
+	return &Debugger{
+		stopped:     stopped,
+		resume:      make(chan action, 1),
+		breakpoints: make(map[string]map[int]bool),
+	}
+}
+
+// StopOnEntry makes the program stop before its first statement.
+func (d *Debugger) StopOnEntry() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entry = true
+}
+
+// SetBreakpoints replaces the breakpoints of file with breakpoints at
+// lines.
+func (d *Debugger) SetBreakpoints(file string, lines []int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file = filepath.Clean(file)
+	if len(lines) == 0 {
+		delete(d.breakpoints, file)
+		return
+	}
+	d.breakpoints[file] = make(map[int]bool, len(lines))
+	for _, line := range lines {
+		d.breakpoints[file][line] = true
+	}
+}
+
+// Statement is called by the engine running the program before it runs the
+// statement at position, and returns once the program may run it. It
+// returns value.ErrTerminated once the program has been terminated.
+//
+// A breakpoint stops the program at a statement of its line only when the
+// statement run before was on another line, so that a line holding several
+// statements stops it once.
+func (d *Debugger) Statement(position Position, program Program) error {
+	d.mu.Lock()
+	if d.terminated {
+		d.mu.Unlock()
+		return value.ErrTerminated
+	}
+	reason := d.reason(position, program)
+	d.last = position
+	if reason == "" {
+		d.mu.Unlock()
+		return nil
+	}
+	d.entry, d.pause = false, false
+	d.isStopped = true
+	d.mu.Unlock()
+
+	d.stopped(Stop{Reason: reason, Stack: program.Stack()})
+	action := <-d.resume
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if action == actionTerminate {
+		return value.ErrTerminated
+	}
+	d.action = action
+	d.depth = program.Depth()
+	return nil
+}
+
+// reason returns why the program stops before the statement at position,
+// or "" when it doesn't.
+func (d *Debugger) reason(position Position, program Program) string {
+	switch {
+	case d.entry:
+		return ReasonEntry
+	case d.pause:
+		return ReasonPause
+	}
+	switch {
+	case d.action == actionStepIn,
+		d.action == actionStepOver && program.Depth() <= d.depth,
+		d.action == actionStepOut && program.Depth() < d.depth:
+		return ReasonStep
+	}
+	if len(d.breakpoints) > 0 && d.breakpoints[filepath.Clean(position.File)][position.Line] &&
+		(position.Line != d.last.Line || position.File != d.last.File) {
+		return ReasonBreakpoint
+	}
+	return ""
+}
+
+// Continue runs the stopped program until it stops at a breakpoint or is
+// paused. It reports whether the program was stopped.
+func (d *Debugger) Continue() bool {
+	return d.resumeWith(actionContinue)
+}
+
+// StepIn runs the stopped program until the next statement.
+func (d *Debugger) StepIn() bool {
+	return d.resumeWith(actionStepIn)
+}
+
+// StepOver runs the stopped program until the next statement outside of
+// the calls it makes.
+func (d *Debugger) StepOver() bool {
+	return d.resumeWith(actionStepOver)
+}
+
+// StepOut runs the stopped program until the next statement after the
+// call it is stopped in returns.
+func (d *Debugger) StepOut() bool {
+	return d.resumeWith(actionStepOut)
+}
+
+// resumeWith ends the stop of the program with action, reporting whether
+// it was stopped.
+func (d *Debugger) resumeWith(action action) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.isStopped {
+		return false
+	}
+	d.isStopped = false
+	d.resume <- action
+	return true
+}
+
+// Pause stops the running program before the next statement it runs.
+func (d *Debugger) Pause() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.isStopped {
+		d.pause = true
+	}
+}
+
+// Terminate ends the program with value.ErrTerminated: at once when it is
+// stopped, or else before the next statement it runs.
+func (d *Debugger) Terminate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.terminated = true
+	if d.isStopped {
+		d.isStopped = false
+		d.resume <- actionTerminate
+	}
+}
@@ -0,0 +1,332 @@
+// Package ebnf reads the EBNF dialect the rottenlang grammar is written in,
+// generates random sentences from a grammar and recognizes them.
+//
+// A grammar is a list of rules "name -> alternatives ;". Alternatives are
+// separated by "|" and are sequences of items: quoted terminals, names of
+// rules, names of token classes written in capitals, and parenthesized
+// alternatives. An item followed by "*", "+" or "?" repeats any number of
+// times, at least once, or at most once. An item "!" followed by a terminal
+// matches no tokens, and only where the next token isn't that terminal; it
+// can't end a sequence. Comments are written (* ... *).
+package ebnf
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Grammar is a parsed grammar.
+type Grammar struct {
+	// Rules lists the rules in the order they are written
+	Rules []*Rule
+	rules map[string]*Rule
+}
+
+type Rule struct {
+	Name string
+	Expr Expr
+	Line int
+}
+
+// Expr is the right hand side of a rule, or a part of it.
+type Expr interface {
+	String() string
+}
+
+type (
+	// Alternation matches any one of its alternatives.
+	Alternation []Expr
+	// Sequence matches its items one after the other.
+	Sequence []Expr
+	// Terminal matches the token written as its text.
+	Terminal string
+	// Token matches any token of a class, such as NUMBER.
+	Token string
+	// Nonterminal matches the rule it names.
+	Nonterminal string
+	// Not matches no tokens, where the next token isn't the terminal
+	// written as its text.
+	Not string
+)
+
+// Repetition matches Expr at least Min times, and at most once if Optional.
+type Repetition struct {
+	Expr     Expr
+	Min      int
+	Optional bool
+}
+
+func (a Alternation) String() string {
+	alternatives := make([]string, len(a))
+	for i, alternative := range a {
+		alternatives[i] = alternative.String()
+	}
+	return strings.Join(alternatives, " | ")
+}
+
+func (s Sequence) String() string {
+	items := make([]string, len(s))
+	for i, item := range s {
+		if _, ok := item.(Alternation); ok {
+			items[i] = "( " + item.String() + " )"
+		} else {
+			items[i] = item.String()
+		}
+	}
+	return strings.Join(items, " ")
+}
+
+func (t Terminal) String() string    { return fmt.Sprintf("%q", string(t)) }
+func (t Token) String() string       { return string(t) }
+func (n Nonterminal) String() string { return string(n) }
+func (n Not) String() string         { return fmt.Sprintf("!%q", string(n)) }
+
+func (r *Repetition) String() string {
+	operator := "*"
+	if r.Optional {
+		operator = "?"
+	} else if r.Min == 1 {
+		operator = "+"
+	}
+	switch r.Expr.(type) {
+	case Alternation, Sequence:
+		return "( " + r.Expr.String() + " )" + operator
+	}
+	return r.Expr.String() + operator
+}
+
+// Rule returns the rule called name.
+func (g *Grammar) Rule(name string) (*Rule, bool) {
+	rule, ok := g.rules[name]
+	return rule, ok
+}
+
+// Terminals returns the text of every terminal of the grammar, sorted.
+func (g *Grammar) Terminals() []string {
+	seen := make(map[string]bool)
+	for _, rule := range g.Rules {
+		walk(rule.Expr, func(expr Expr) {
+			if terminal, ok := expr.(Terminal); ok {
+				seen[string(terminal)] = true
+			}
+		})
+	}
+	terminals := make([]string, 0, len(seen))
+	for terminal := range seen {
+		terminals = append(terminals, terminal)
+	}
+	sort.Strings(terminals)
+	return terminals
+}
+
+func walk(expr Expr, visit func(Expr)) {
+	visit(expr)
+	switch expr := expr.(type) {
+	case Alternation:
+		for _, alternative := range expr {
+			walk(alternative, visit)
+		}
+	case Sequence:
+		for _, item := range expr {
+			walk(item, visit)
+		}
+	case *Repetition:
+		walk(expr.Expr, visit)
+	}
+}
+
+// Parse reads a grammar. The name of the source is used in error messages.
+func Parse(r io.Reader, name string) (*Grammar, error) {
+	source, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{lexer: lexer{name: name, source: string(source), line: 1, column: 1}}
+	grammar, err := p.grammar()
+	if err != nil {
+		return nil, err
+	}
+	if err := grammar.check(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return grammar, nil
+}
+
+// check rejects grammars with undefined or unused rules, rules that can
+// never finish, and left recursion.
+func (g *Grammar) check() error {
+	used := make(map[string]bool)
+	for _, rule := range g.Rules {
+		var err error
+		walk(rule.Expr, func(expr Expr) {
+			if name, ok := expr.(Nonterminal); ok && err == nil {
+				if _, ok := g.rules[string(name)]; !ok {
+					err = fmt.Errorf("line %d: rule '%s' uses undefined rule '%s'", rule.Line, rule.Name, name)
+				}
+				used[string(name)] = true
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, rule := range g.Rules[1:] {
+		if !used[rule.Name] {
+			return fmt.Errorf("line %d: rule '%s' is never used", rule.Line, rule.Name)
+		}
+	}
+
+	costs := g.costs()
+	for _, rule := range g.Rules {
+		if costs[rule.Name] == infinite {
+			return fmt.Errorf("line %d: rule '%s' never finishes", rule.Line, rule.Name)
+		}
+	}
+
+	nullable := g.nullable()
+	for _, rule := range g.Rules {
+		if path := g.leftRecursion(rule.Name, nullable); path != nil {
+			return fmt.Errorf("line %d: rule '%s' is left recursive: %s", rule.Line, rule.Name, strings.Join(path, " -> "))
+		}
+	}
+	return nil
+}
+
+// nullable returns the rules that can match no tokens at all.
+func (g *Grammar) nullable() map[string]bool {
+	nullable := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range g.Rules {
+			if !nullable[rule.Name] && matchesEmpty(rule.Expr, nullable) {
+				nullable[rule.Name] = true
+				changed = true
+			}
+		}
+	}
+	return nullable
+}
+
+func matchesEmpty(expr Expr, nullable map[string]bool) bool {
+	switch expr := expr.(type) {
+	case Alternation:
+		for _, alternative := range expr {
+			if matchesEmpty(alternative, nullable) {
+				return true
+			}
+		}
+		return false
+	case Sequence:
+		for _, item := range expr {
+			if !matchesEmpty(item, nullable) {
+				return false
+			}
+		}
+		return true
+	case *Repetition:
+		return expr.Optional || expr.Min == 0 || matchesEmpty(expr.Expr, nullable)
+	case Nonterminal:
+		return nullable[string(expr)]
+	case Not:
+		return true
+	}
+	return false
+}
+
+// leftRecursion returns a chain of rules leading from start back to itself
+// without consuming a token, or nil if there is none.
+func (g *Grammar) leftRecursion(start string, nullable map[string]bool) []string {
+	visited := make(map[string]bool)
+	var search func(name string, path []string) []string
+	search = func(name string, path []string) []string {
+		for _, next := range leftmost(g.rules[name].Expr, nullable) {
+			if next == start {
+				return append(path, next)
+			}
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			if found := search(next, append(path, next)); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return search(start, []string{start})
+}
+
+// leftmost returns the rules expr may start with.
+func leftmost(expr Expr, nullable map[string]bool) []string {
+	switch expr := expr.(type) {
+	case Alternation:
+		var names []string
+		for _, alternative := range expr {
+			names = append(names, leftmost(alternative, nullable)...)
+		}
+		return names
+	case Sequence:
+		var names []string
+		for _, item := range expr {
+			names = append(names, leftmost(item, nullable)...)
+			if !matchesEmpty(item, nullable) {
+				break
+			}
+		}
+		return names
+	case *Repetition:
+		return leftmost(expr.Expr, nullable)
+	case Nonterminal:
+		return []string{string(expr)}
+	}
+	return nil
+}
+
+const infinite = int(^uint(0) >> 1)
+
+// costs returns the least depth of nested rules each rule needs to match
+// some sentence; rules that can't match any have an infinite cost.
+func (g *Grammar) costs() map[string]int {
+	costs := make(map[string]int, len(g.Rules))
+	for _, rule := range g.Rules {
+		costs[rule.Name] = infinite
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range g.Rules {
+			if cost := exprCost(rule.Expr, costs); cost != infinite && cost+1 < costs[rule.Name] {
+				costs[rule.Name] = cost + 1
+				changed = true
+			}
+		}
+	}
+	return costs
+}
+
+func exprCost(expr Expr, costs map[string]int) int {
+	switch expr := expr.(type) {
+	case Alternation:
+		cost := infinite
+		for _, alternative := range expr {
+			cost = min(cost, exprCost(alternative, costs))
+		}
+		return cost
+	case Sequence:
+		cost := 0
+		for _, item := range expr {
+			cost = max(cost, exprCost(item, costs))
+		}
+		return cost
+	case *Repetition:
+		if expr.Optional || expr.Min == 0 {
+			return 0
+		}
+		return exprCost(expr.Expr, costs)
+	case Nonterminal:
+		return costs[string(expr)]
+	}
+	return 0
+}
@@ -0,0 +1,133 @@
+package ebnf
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+const list = `
+(* a list of numbers *)
+list    -> "[" ( item ( "," item )* )? "]" ;
+item    -> NUMBER | list | "-" item+ ;
+`
+
+func TestParse(t *testing.T) {
+	grammar, err := Parse(strings.NewReader(list), "list.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rule, ok := grammar.Rule("list")
+	if !ok {
+		t.Fatal("missing rule 'list'")
+	}
+	if got, want := rule.Expr.String(), `"[" ( item ( "," item )* )? "]"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, want := strings.Join(grammar.Terminals(), " "), ", - [ ]"; got != want {
+		t.Errorf("got terminals %s, want %s", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		err    string
+	}{
+		{`a -> "x"`, "Expect ';' after rule"},
+		{`a "x" ;`, "Expect '->' after rule name"},
+		{`a => "x" ;`, "Unexpected character '='"},
+		{`a -> ( "x" ;`, "Expect ')' after group"},
+		{`a -> "x ;`, "Unterminated terminal"},
+		{`a -> ;`, "Expect terminal, name or '('"},
+		{`(* a -> "x" ;`, "Unterminated comment"},
+		{`a -> "x" ; a -> "y" ;`, "Rule 'a' is already defined"},
+		{`a -> b ;`, "rule 'a' uses undefined rule 'b'"},
+		{`a -> "x" ; b -> "y" ;`, "rule 'b' is never used"},
+		{`a -> "(" a ")" ;`, "rule 'a' never finishes"},
+		{`e -> e "+" e | NUMBER ;`, "rule 'e' is left recursive: e -> e"},
+		{`a -> b? a "x" | "y" ; b -> "z" ;`, "rule 'a' is left recursive: a -> a"},
+		{`a -> "x" !"y" ;`, "Expect item after '!'"},
+		{`a -> !b "x" ;`, "Expect terminal after '!'"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(test.source), "test.ebnf")
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want one containing %q", err, test.err)
+			}
+		})
+	}
+}
+
+func TestGenerateAndRecognize(t *testing.T) {
+	grammar, err := Parse(strings.NewReader(list), "list.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recognizer := NewRecognizer(grammar)
+
+	generator := NewGenerator(grammar, rand.New(rand.NewSource(1)))
+	generator.Classes["NUMBER"] = func(r *rand.Rand) string { return "1" }
+	for i := 0; i < 200; i++ {
+		symbols, err := generator.Generate("list")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !recognizer.Recognize("list", symbols) {
+			t.Fatalf("generated sentence %q is not recognized", Sentence(symbols))
+		}
+	}
+
+	terminal := func(name string) Symbol { return Symbol{Name: name, Text: name} }
+	number := Symbol{Name: "NUMBER", Class: true, Text: "1"}
+	rejected := [][]Symbol{
+		{},
+		{terminal("[")},
+		{terminal("["), number, terminal(","), terminal("]")},
+		{terminal("["), terminal("-"), terminal("]")},
+		{terminal("["), number, number, terminal("]")},
+	}
+	for _, symbols := range rejected {
+		if recognizer.Recognize("list", symbols) {
+			t.Errorf("expected %q to be rejected", Sentence(symbols))
+		}
+	}
+	if !recognizer.Recognize("list", []Symbol{terminal("["), terminal("-"), terminal("-"), number, terminal("]")}) {
+		t.Error("expected \"[ - - 1 ]\" to be recognized")
+	}
+}
+
+func TestNot(t *testing.T) {
+	grammar, err := Parse(strings.NewReader(`stmt -> "{" "}" | !"{" item ";" ; item -> "{" "}" | NUMBER ;`), "not.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := grammar.Rules[0].Expr.String(), `"{" "}" | !"{" item ";"`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	recognizer := NewRecognizer(grammar)
+
+	generator := NewGenerator(grammar, rand.New(rand.NewSource(1)))
+	generator.Classes["NUMBER"] = func(r *rand.Rand) string { return "1" }
+	for i := 0; i < 50; i++ {
+		symbols, err := generator.Generate("stmt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !recognizer.Recognize("stmt", symbols) {
+			t.Fatalf("generated sentence %q is not recognized", Sentence(symbols))
+		}
+	}
+
+	terminal := func(name string) Symbol { return Symbol{Name: name, Text: name} }
+	number := Symbol{Name: "NUMBER", Class: true, Text: "1"}
+	if !recognizer.Recognize("stmt", []Symbol{number, terminal(";")}) {
+		t.Error("expected \"1 ;\" to be recognized")
+	}
+	if recognizer.Recognize("stmt", []Symbol{terminal("{"), terminal("}"), terminal(";")}) {
+		t.Error("expected \"{ } ;\" to be rejected")
+	}
+}
@@ -0,0 +1,163 @@
+package ebnf
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Symbol is one token of a generated sentence.
+type Symbol struct {
+	// Name is the text of a terminal, or the name of a token class
+	Name string
+	// Class is set for symbols standing for a token class
+	Class bool
+	// Text is how the symbol is written in source
+	Text string
+}
+
+// Sentence renders symbols as source, separated by spaces.
+func Sentence(symbols []Symbol) string {
+	texts := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		texts[i] = symbol.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// Generator produces random sentences of a grammar.
+type Generator struct {
+	grammar *Grammar
+	rand    *rand.Rand
+	costs   map[string]int
+
+	// Classes writes a random token of each token class
+	Classes map[string]func(r *rand.Rand) string
+	// RepeatChance is the probability of taking an optional item, and of
+	// each repetition of "*" and "+" beyond their minimum
+	RepeatChance float64
+	// MaxRepeat bounds how often "*" and "+" repeat beyond their minimum
+	MaxRepeat int
+	// MaxDepth and MaxSymbols bound the nesting of rules and the length of
+	// a sentence. The closer the generator gets to either, the more likely
+	// it is to take the shortest way to finish the sentence; past them it
+	// always does, so sentences can get somewhat longer.
+	MaxDepth   int
+	MaxSymbols int
+
+	symbols []Symbol
+}
+
+func NewGenerator(grammar *Grammar, r *rand.Rand) *Generator {
+	return &Generator{
+		grammar:      grammar,
+		rand:         r,
+		costs:        grammar.costs(),
+		Classes:      make(map[string]func(r *rand.Rand) string),
+		RepeatChance: 0.5,
+		MaxRepeat:    2,
+		MaxDepth:     64,
+		MaxSymbols:   40,
+	}
+}
+
+// Generate returns a random sentence of the rule start.
+func (g *Generator) Generate(start string) ([]Symbol, error) {
+	if _, ok := g.grammar.Rule(start); !ok {
+		return nil, fmt.Errorf("undefined rule '%s'", start)
+	}
+	g.symbols = nil
+	if err := g.generate(Nonterminal(start), 0); err != nil {
+		return nil, err
+	}
+	return g.symbols, nil
+}
+
+// expanding decides whether to grow the sentence rather than finish it as
+// soon as possible, which gets likelier the longer and deeper it is.
+func (g *Generator) expanding(depth int) bool {
+	pressure := max(float64(depth)/float64(g.MaxDepth), float64(len(g.symbols))/float64(g.MaxSymbols))
+	return g.rand.Float64() >= pressure
+}
+
+func (g *Generator) generate(expr Expr, depth int) error {
+	switch expr := expr.(type) {
+	case Alternation:
+		return g.generate(g.choose(expr, depth), depth)
+	case Sequence:
+		for i, item := range expr {
+			if not, ok := item.(Not); ok {
+				return g.avoid(not, expr[i+1:], depth)
+			}
+			if err := g.generate(item, depth); err != nil {
+				return err
+			}
+		}
+	case *Repetition:
+		n, most := expr.Min, expr.Min+g.MaxRepeat
+		if expr.Optional {
+			n, most = 0, 1
+		}
+		for n < most && g.rand.Float64() < g.RepeatChance && g.expanding(depth) {
+			n++
+		}
+		for i := 0; i < n; i++ {
+			if err := g.generate(expr.Expr, depth); err != nil {
+				return err
+			}
+		}
+	case Terminal:
+		g.symbols = append(g.symbols, Symbol{Name: string(expr), Text: string(expr)})
+	case Token:
+		class, ok := g.Classes[string(expr)]
+		if !ok {
+			return fmt.Errorf("no generator for token class %s", expr)
+		}
+		g.symbols = append(g.symbols, Symbol{Name: string(expr), Class: true, Text: class(g.rand)})
+	case Nonterminal:
+		rule, _ := g.grammar.Rule(string(expr))
+		return g.generate(rule.Expr, depth+1)
+	}
+	return nil
+}
+
+// maxAttempts bounds how often the generator tries again to produce items
+// not starting with the terminal of a Not.
+const maxAttempts = 100
+
+// avoid generates the items following not in a sequence, trying again until
+// they don't start with its terminal.
+func (g *Generator) avoid(not Not, items Sequence, depth int) error {
+	start := len(g.symbols)
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		g.symbols = g.symbols[:start]
+		if err := g.generate(items, depth); err != nil {
+			return err
+		}
+		if len(g.symbols) == start || g.symbols[start].Class || g.symbols[start].Name != string(not) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no sentence of %s that doesn't start with %s", items, Terminal(not))
+}
+
+// choose picks an alternative at random, or one of the cheapest when
+// finishing the sentence.
+func (g *Generator) choose(alternatives Alternation, depth int) Expr {
+	if g.expanding(depth) {
+		return alternatives[g.rand.Intn(len(alternatives))]
+	}
+
+	var cheapest []Expr
+	least := infinite
+	for _, alternative := range alternatives {
+		cost := exprCost(alternative, g.costs)
+		if cost < least {
+			cheapest, least = nil, cost
+		}
+		if cost == least {
+			cheapest = append(cheapest, alternative)
+		}
+	}
+	return cheapest[g.rand.Intn(len(cheapest))]
+}
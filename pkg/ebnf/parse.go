@@ -0,0 +1,256 @@
+package ebnf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenName
+	tokenTerminal
+	tokenArrow
+	tokenPunct
+)
+
+type token struct {
+	kind         tokenKind
+	text         string
+	line, column int
+}
+
+type lexer struct {
+	name         string
+	source       string
+	offset       int
+	line, column int
+}
+
+func (l *lexer) errorf(line, column int, format string, args ...any) error {
+	return fmt.Errorf("%s:%d:%d: %s", l.name, line, column, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) advance() byte {
+	c := l.source[l.offset]
+	l.offset++
+	if c == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return c
+}
+
+func (l *lexer) next() (token, error) {
+	for l.offset < len(l.source) {
+		if unicode.IsSpace(rune(l.source[l.offset])) {
+			l.advance()
+		} else if strings.HasPrefix(l.source[l.offset:], "(*") {
+			line, column := l.line, l.column
+			end := strings.Index(l.source[l.offset+2:], "*)")
+			if end == -1 {
+				return token{}, l.errorf(line, column, "Unterminated comment")
+			}
+			for n := end + 4; n > 0; n-- {
+				l.advance()
+			}
+		} else {
+			break
+		}
+	}
+
+	line, column := l.line, l.column
+	if l.offset >= len(l.source) {
+		return token{kind: tokenEOF, line: line, column: column}, nil
+	}
+
+	start := l.offset
+	c := l.advance()
+	switch {
+	case c == '"':
+		for l.offset < len(l.source) && l.source[l.offset] != '"' && l.source[l.offset] != '\n' {
+			l.advance()
+		}
+		if l.offset >= len(l.source) || l.source[l.offset] != '"' {
+			return token{}, l.errorf(line, column, "Unterminated terminal")
+		}
+		l.advance()
+		text := l.source[start+1 : l.offset-1]
+		if text == "" {
+			return token{}, l.errorf(line, column, "Empty terminal")
+		}
+		return token{tokenTerminal, text, line, column}, nil
+	case c == '_' || unicode.IsLetter(rune(c)):
+		for l.offset < len(l.source) && (l.source[l.offset] == '_' || unicode.IsLetter(rune(l.source[l.offset])) || unicode.IsDigit(rune(l.source[l.offset]))) {
+			l.advance()
+		}
+		return token{tokenName, l.source[start:l.offset], line, column}, nil
+	case c == '-' && l.offset < len(l.source) && l.source[l.offset] == '>':
+		l.advance()
+		return token{tokenArrow, "->", line, column}, nil
+	case strings.IndexByte("|;()*+?!", c) != -1:
+		return token{tokenPunct, string(c), line, column}, nil
+	}
+	return token{}, l.errorf(line, column, "Unexpected character '%c'", c)
+}
+
+type parser struct {
+	lexer   lexer
+	current token
+}
+
+func (p *parser) advance() error {
+	current, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.current = current
+	return nil
+}
+
+func (p *parser) expect(kind tokenKind, text, message string) (token, error) {
+	t := p.current
+	if t.kind != kind || (text != "" && t.text != text) {
+		return token{}, p.lexer.errorf(t.line, t.column, "%s", message)
+	}
+	return t, p.advance()
+}
+
+func (p *parser) isPunct(text string) bool {
+	return p.current.kind == tokenPunct && p.current.text == text
+}
+
+func (p *parser) grammar() (*Grammar, error) {
+	g := &Grammar{rules: make(map[string]*Rule)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	for p.current.kind != tokenEOF {
+		name, err := p.expect(tokenName, "", "Expect rule name")
+		if err != nil {
+			return nil, err
+		}
+		if isTokenClass(name.text) {
+			return nil, p.lexer.errorf(name.line, name.column, "Rule name '%s' is reserved for token classes", name.text)
+		}
+		if _, ok := g.rules[name.text]; ok {
+			return nil, p.lexer.errorf(name.line, name.column, "Rule '%s' is already defined", name.text)
+		}
+		if _, err := p.expect(tokenArrow, "", "Expect '->' after rule name"); err != nil {
+			return nil, err
+		}
+		expr, err := p.alternation()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenPunct, ";", "Expect ';' after rule"); err != nil {
+			return nil, err
+		}
+
+		rule := &Rule{Name: name.text, Expr: expr, Line: name.line}
+		g.Rules = append(g.Rules, rule)
+		g.rules[rule.Name] = rule
+	}
+	if len(g.Rules) == 0 {
+		return nil, fmt.Errorf("%s: no rules", p.lexer.name)
+	}
+	return g, nil
+}
+
+func (p *parser) alternation() (Expr, error) {
+	var alternatives Alternation
+	for {
+		sequence, err := p.sequence()
+		if err != nil {
+			return nil, err
+		}
+		alternatives = append(alternatives, sequence)
+		if !p.isPunct("|") {
+			break
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0], nil
+	}
+	return alternatives, nil
+}
+
+func (p *parser) sequence() (Expr, error) {
+	var items Sequence
+	for p.current.kind == tokenName || p.current.kind == tokenTerminal || p.isPunct("(") || p.isPunct("!") {
+		item, err := p.item()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil, p.lexer.errorf(p.current.line, p.current.column, "Expect terminal, name or '('")
+	}
+	if _, ok := items[len(items)-1].(Not); ok {
+		return nil, p.lexer.errorf(p.current.line, p.current.column, "Expect item after '!'")
+	}
+	if len(items) == 1 {
+		return items[0], nil
+	}
+	return items, nil
+}
+
+func (p *parser) item() (Expr, error) {
+	var expr Expr
+	t := p.current
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if t.kind == tokenPunct && t.text == "!" {
+		terminal, err := p.expect(tokenTerminal, "", "Expect terminal after '!'")
+		if err != nil {
+			return nil, err
+		}
+		return Not(terminal.text), nil
+	}
+	switch {
+	case t.kind == tokenTerminal:
+		expr = Terminal(t.text)
+	case t.kind == tokenName && isTokenClass(t.text):
+		expr = Token(t.text)
+	case t.kind == tokenName:
+		expr = Nonterminal(t.text)
+	default:
+		group, err := p.alternation()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenPunct, ")", "Expect ')' after group"); err != nil {
+			return nil, err
+		}
+		expr = group
+	}
+
+	for p.isPunct("*") || p.isPunct("+") || p.isPunct("?") {
+		switch p.current.text {
+		case "*":
+			expr = &Repetition{Expr: expr}
+		case "+":
+			expr = &Repetition{Expr: expr, Min: 1}
+		case "?":
+			expr = &Repetition{Expr: expr, Optional: true}
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return expr, nil
+}
+
+// isTokenClass reports whether name is written in capitals, like NUMBER.
+func isTokenClass(name string) bool {
+	return strings.ToUpper(name) == name && strings.ToLower(name) != name
+}
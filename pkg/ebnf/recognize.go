@@ -0,0 +1,192 @@
+package ebnf
+
+import (
+	"fmt"
+)
+
+// production is a plain BNF rule: lhs matches the symbols of rhs in order.
+type production struct {
+	lhs string
+	rhs []element
+}
+
+// element is a symbol of a production: a rule or a terminal. Terminals are
+// keyed by the Name of the Symbol they match. A lookahead is a terminal
+// that must not come next, and matches no symbols.
+type element struct {
+	name      string
+	terminal  bool
+	class     bool
+	lookahead bool
+}
+
+// Recognizer decides whether sentences belong to a grammar, using Earley's
+// algorithm on the grammar rewritten without repetitions and groups.
+type Recognizer struct {
+	productions map[string][]*production
+	nullable    map[string]bool
+	fresh       int
+}
+
+func NewRecognizer(grammar *Grammar) *Recognizer {
+	r := &Recognizer{productions: make(map[string][]*production)}
+	for _, rule := range grammar.Rules {
+		r.lower(rule.Name, rule.Expr)
+	}
+
+	r.nullable = make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for lhs, productions := range r.productions {
+			if r.nullable[lhs] {
+				continue
+			}
+			for _, production := range productions {
+				if r.allNullable(production.rhs) {
+					r.nullable[lhs] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return r
+}
+
+func (r *Recognizer) allNullable(elements []element) bool {
+	// a lookahead only matches nothing in some places, so it doesn't count
+	for _, e := range elements {
+		if e.terminal || e.lookahead || !r.nullable[e.name] {
+			return false
+		}
+	}
+	return true
+}
+
+// lower adds productions for lhs -> expr.
+func (r *Recognizer) lower(lhs string, expr Expr) {
+	alternatives, ok := expr.(Alternation)
+	if !ok {
+		alternatives = Alternation{expr}
+	}
+	for _, alternative := range alternatives {
+		items, ok := alternative.(Sequence)
+		if !ok {
+			items = Sequence{alternative}
+		}
+		rhs := make([]element, len(items))
+		for i, item := range items {
+			rhs[i] = r.element(lhs, item)
+		}
+		r.productions[lhs] = append(r.productions[lhs], &production{lhs, rhs})
+	}
+}
+
+// element returns the element matching expr, introducing a rule for groups
+// and repetitions.
+func (r *Recognizer) element(lhs string, expr Expr) element {
+	switch expr := expr.(type) {
+	case Terminal:
+		return element{name: string(expr), terminal: true}
+	case Token:
+		return element{name: string(expr), terminal: true, class: true}
+	case Nonterminal:
+		return element{name: string(expr)}
+	case Not:
+		return element{name: string(expr), lookahead: true}
+	}
+
+	r.fresh++
+	name := fmt.Sprintf("%s#%d", lhs, r.fresh)
+	self := element{name: name}
+	switch expr := expr.(type) {
+	case *Repetition:
+		item := r.element(name, expr.Expr)
+		switch {
+		case expr.Optional:
+			// name -> ε | item
+			r.productions[name] = append(r.productions[name], &production{name, nil}, &production{name, []element{item}})
+		case expr.Min == 0:
+			// name -> ε | item name
+			r.productions[name] = append(r.productions[name], &production{name, nil}, &production{name, []element{item, self}})
+		default:
+			// name -> item | item name
+			r.productions[name] = append(r.productions[name], &production{name, []element{item}}, &production{name, []element{item, self}})
+		}
+	default:
+		r.lower(name, expr)
+	}
+	return self
+}
+
+type item struct {
+	production *production
+	dot        int
+	origin     int
+}
+
+// Recognize reports whether symbols form a sentence of the rule start.
+func (r *Recognizer) Recognize(start string, symbols []Symbol) bool {
+	sets := make([][]item, len(symbols)+1)
+	seen := make([]map[item]bool, len(symbols)+1)
+	for i := range seen {
+		seen[i] = make(map[item]bool)
+	}
+	add := func(position int, it item) {
+		if !seen[position][it] {
+			seen[position][it] = true
+			sets[position] = append(sets[position], it)
+		}
+	}
+
+	for _, production := range r.productions[start] {
+		add(0, item{production, 0, 0})
+	}
+	for position := 0; position <= len(symbols); position++ {
+		for i := 0; i < len(sets[position]); i++ {
+			it := sets[position][i]
+			if it.dot == len(it.production.rhs) {
+				// complete
+				for _, parent := range sets[it.origin] {
+					if parent.dot < len(parent.production.rhs) {
+						next := parent.production.rhs[parent.dot]
+						if !next.terminal && next.name == it.production.lhs {
+							add(position, item{parent.production, parent.dot + 1, parent.origin})
+						}
+					}
+				}
+				continue
+			}
+
+			next := it.production.rhs[it.dot]
+			if next.lookahead {
+				if position == len(symbols) || symbols[position].Class || symbols[position].Name != next.name {
+					add(position, item{it.production, it.dot + 1, it.origin})
+				}
+				continue
+			}
+			if next.terminal {
+				// scan
+				if position < len(symbols) && symbols[position].Name == next.name && symbols[position].Class == next.class {
+					add(position+1, item{it.production, it.dot + 1, it.origin})
+				}
+				continue
+			}
+
+			// predict, stepping over rules that may match nothing
+			for _, production := range r.productions[next.name] {
+				add(position, item{production, 0, position})
+			}
+			if r.nullable[next.name] {
+				add(position, item{it.production, it.dot + 1, it.origin})
+			}
+		}
+	}
+
+	for _, it := range sets[len(symbols)] {
+		if it.origin == 0 && it.production.lhs == start && it.dot == len(it.production.rhs) {
+			return true
+		}
+	}
+	return false
+}
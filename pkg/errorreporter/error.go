@@ -2,20 +2,133 @@ package errorreporter
 
 import (
 	"fmt"
+	"io"
 	"os"
 )
 
 type ErrorReporter interface {
 	ReportScannerError(line, column int, lexeme, message string)
 	ReportParserError(line, column int, where, message string)
+	ReportRuntimeError(line, column int, message string)
+}
+
+// WarningReporter is implemented by error reporters that also show
+// warnings, about code that is valid but probably not what was meant.
+type WarningReporter interface {
+	ReportWarning(line, column int, where, message string)
 }
 
 type StderrErrorReporter struct{}
 
 func (e *StderrErrorReporter) ReportScannerError(line, column int, where, message string) {
-	fmt.Fprintf(os.Stderr, "[line=%d col=%d] Error: %s. Snippet: '%s'", line, column, message, where)
+	NewWriterErrorReporter(os.Stderr).ReportScannerError(line, column, where, message)
 }
 
 func (e *StderrErrorReporter) ReportParserError(line, column int, where, message string) {
-	fmt.Fprintf(os.Stderr, "[line=%d col=%d] Error: %s. Snippet: '%s'", line, column, message, where)
+	NewWriterErrorReporter(os.Stderr).ReportParserError(line, column, where, message)
+}
+
+func (e *StderrErrorReporter) ReportRuntimeError(line, column int, message string) {
+	NewWriterErrorReporter(os.Stderr).ReportRuntimeError(line, column, message)
+}
+
+func (e *StderrErrorReporter) ReportWarning(line, column int, where, message string) {
+	NewWriterErrorReporter(os.Stderr).ReportWarning(line, column, where, message)
+}
+
+// WriterErrorReporter writes errors to w as StderrErrorReporter writes them
+// to stderr, for callers capturing them, e.g. into a buffer.
+type WriterErrorReporter struct {
+	w io.Writer
+}
+
+func NewWriterErrorReporter(w io.Writer) *WriterErrorReporter {
+	return &WriterErrorReporter{w: w}
+}
+
+func (e *WriterErrorReporter) ReportScannerError(line, column int, where, message string) {
+	fmt.Fprintf(e.w, "[line=%d col=%d] Error: %s. Snippet: '%s'\n", line, column, message, where)
+}
+
+func (e *WriterErrorReporter) ReportParserError(line, column int, where, message string) {
+	fmt.Fprintf(e.w, "[line=%d col=%d] Error: %s. Snippet: '%s'\n", line, column, message, where)
+}
+
+func (e *WriterErrorReporter) ReportRuntimeError(line, column int, message string) {
+	fmt.Fprintf(e.w, "[line=%d col=%d] Runtime error: %s\n", line, column, message)
+}
+
+func (e *WriterErrorReporter) ReportWarning(line, column int, where, message string) {
+	fmt.Fprintf(e.w, "[line=%d col=%d] Warning: %s. Snippet: '%s'\n", line, column, message, where)
+}
+
+// NopErrorReporter discards every error, for callers that inspect the
+// scanner and parser errors themselves.
+type NopErrorReporter struct{}
+
+func (e *NopErrorReporter) ReportScannerError(line, column int, where, message string) {}
+
+func (e *NopErrorReporter) ReportParserError(line, column int, where, message string) {}
+
+func (e *NopErrorReporter) ReportRuntimeError(line, column int, message string) {}
+
+// Kinds of Diagnostic, named after the ErrorReporter method reporting them.
+const (
+	KindScanner = "scanner"
+	KindParser  = "parser"
+	KindRuntime = "runtime"
+	KindWarning = "warning"
+)
+
+// Diagnostic is one reported error or warning.
+type Diagnostic struct {
+	Kind         string
+	Line, Column int
+	// Where is the snippet or location description given by the reporter
+	Where   string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Kind == KindWarning {
+		return fmt.Sprintf("[line=%d col=%d] warning: %s", d.Line, d.Column, d.Message)
+	}
+	return fmt.Sprintf("[line=%d col=%d] %s error: %s", d.Line, d.Column, d.Kind, d.Message)
+}
+
+// CollectingErrorReporter keeps every reported error as a Diagnostic for the
+// caller to inspect. Warnings are kept apart from the errors.
+type CollectingErrorReporter struct {
+	diagnostics []Diagnostic
+	warnings    []Diagnostic
+}
+
+func (e *CollectingErrorReporter) ReportScannerError(line, column int, where, message string) {
+	e.diagnostics = append(e.diagnostics, Diagnostic{Kind: KindScanner, Line: line, Column: column, Where: where, Message: message})
+}
+
+func (e *CollectingErrorReporter) ReportParserError(line, column int, where, message string) {
+	e.diagnostics = append(e.diagnostics, Diagnostic{Kind: KindParser, Line: line, Column: column, Where: where, Message: message})
+}
+
+func (e *CollectingErrorReporter) ReportRuntimeError(line, column int, message string) {
+	e.diagnostics = append(e.diagnostics, Diagnostic{Kind: KindRuntime, Line: line, Column: column, Message: message})
+}
+
+func (e *CollectingErrorReporter) ReportWarning(line, column int, where, message string) {
+	e.warnings = append(e.warnings, Diagnostic{Kind: KindWarning, Line: line, Column: column, Where: where, Message: message})
+}
+
+func (e *CollectingErrorReporter) Diagnostics() []Diagnostic {
+	return e.diagnostics
+}
+
+func (e *CollectingErrorReporter) Warnings() []Diagnostic {
+	return e.warnings
+}
+
+// Reset forgets the diagnostics and warnings collected so far.
+func (e *CollectingErrorReporter) Reset() {
+	e.diagnostics = nil
+	e.warnings = nil
 }
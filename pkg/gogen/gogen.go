@@ -0,0 +1,949 @@
+// Package gogen translates programs into Go packages, which run them
+// without an interpreter.
+//
+// A generated package holds the program and a copy of the runtime support
+// library in package rt, and only imports the standard library. Run runs
+// the program's top level code; Call then calls its global functions. The
+// generated code carries //line directives, so the positions Go reports in
+// panics, stack traces and profiles are those of the rottenlang source.
+//
+// Variables are resolved when the program is translated, like the compiler
+// does: locals become Go variables, which closures capture, and globals
+// stay late bound. Imports, tasks, select statements and generators need
+// the scheduler and the modules of the engines, and aren't supported.
+package gogen
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+//go:embed rt/rt.go
+var runtimeSource string
+
+var ErrGenerate = errors.New("generate error")
+
+// RuntimeFile is the name of the file holding the runtime support library
+// in a generated package.
+const RuntimeFile = "rottenlang_runtime.go"
+
+// local is a variable of the program declared in a block or function.
+type local struct {
+	// ident is the Go identifier of the variable
+	ident    string
+	constant bool
+}
+
+// scope maps the names of a block's variables to their locals.
+type scope struct {
+	enclosing *scope
+	locals    map[string]*local
+}
+
+// functionScope is the translation state of a function body, or of the
+// top level code.
+type functionScope struct {
+	enclosing *functionScope
+	// tries counts the clauses of try statements enclosing the code being
+	// translated, which become closures returning whether they returned
+	tries int
+	// script is set for the top level code
+	script bool
+	// this is the Go identifier of "this" in the init method of a class,
+	// which returns it
+	this string
+}
+
+// Generator translates programs into Go.
+type Generator struct {
+	errorReporter errorreporter.ErrorReporter
+	hadError      bool
+	// file is the path of the source, named by the //line directives
+	file  string
+	b     strings.Builder
+	scope *scope
+	fn    *functionScope
+	// globals are the global variables the program uses
+	globals map[string]bool
+	// names counts the Go identifiers made up so far, keeping them unique
+	names int
+}
+
+var (
+	_ ast.Visitor[string]  = (*Generator)(nil)
+	_ ast.StmtVisitor[any] = (*Generator)(nil)
+)
+
+func NewGenerator(errorReporter errorreporter.ErrorReporter) *Generator {
+	return &Generator{
+		errorReporter: errorReporter,
+	}
+}
+
+// Generate translates a program, read from file, into the files of the Go
+// package pkg, keyed by file name. The //line directives name file, which
+// Go resolves relative to the directory the package is written to. A main
+// package also gets a main function running the program. Errors are
+// reported to the error reporter; ErrGenerate is returned if there were
+// any.
+func (g *Generator) Generate(statements []ast.Stmt, file, pkg string) (map[string][]byte, error) {
+	g.hadError = false
+	g.file = file
+	g.b.Reset()
+	g.scope = nil
+	g.fn = &functionScope{script: true}
+	g.globals = map[string]bool{}
+	g.names = 0
+
+	g.line("func script() {")
+	g.statements(statements)
+	g.line("}")
+	if g.hadError {
+		return nil, ErrGenerate
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by rottenlang build from %s. DO NOT EDIT.\n\n", file)
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	b.WriteString("import \"io\"\n\n")
+	b.WriteString("// Run runs the program, writing what it prints to out. It returns the\n")
+	b.WriteString("// *RuntimeError stopping the program, if any.\n")
+	b.WriteString("func Run(out io.Writer) error {\n\treturn rtRun(out, script)\n}\n\n")
+	if pkg == "main" {
+		b.WriteString("func main() {\n\trtMain(script)\n}\n\n")
+	}
+	if len(g.globals) > 0 {
+		names := make([]string, 0, len(g.globals))
+		for name := range g.globals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b.WriteString("var (\n")
+		for _, name := range names {
+			fmt.Fprintf(&b, "%s = rtGlobalVar(%s)\n", globalIdent(name), strconv.Quote(name))
+		}
+		b.WriteString(")\n\n")
+	}
+	b.WriteString(g.b.String())
+
+	program, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return map[string][]byte{
+		goFileName(file): program,
+		RuntimeFile:      []byte(runtime(pkg)),
+	}, nil
+}
+
+// runtime returns the runtime support library as a file of package pkg.
+func runtime(pkg string) string {
+	_, body, _ := strings.Cut(runtimeSource, "\npackage rt\n")
+	return "// Code generated by rottenlang build. DO NOT EDIT.\n\npackage " + pkg + "\n" + body
+}
+
+// goFileName returns the name of the Go file generated for the source file.
+func goFileName(file string) string {
+	base := file[strings.LastIndexAny(file, `/\`)+1:]
+	if base == "" {
+		base = "script"
+	}
+	return strings.TrimSuffix(base, ".rot") + ".rot.go"
+}
+
+func (g *Generator) error(token *ast.Token, message string) {
+	g.hadError = true
+	g.errorReporter.ReportParserError(token.Line, token.Column, fmt.Sprintf("at '%s'", *token.Lexeme), message)
+}
+
+// unsupported reports a construct the generated code can't run.
+func (g *Generator) unsupported(token *ast.Token, what string) {
+	g.error(token, what+" aren't supported by the go target")
+}
+
+func (g *Generator) line(format string, args ...any) {
+	fmt.Fprintf(&g.b, format+"\n", args...)
+}
+
+// directive positions the following line at token in the source.
+func (g *Generator) directive(token *ast.Token) {
+	if token != nil {
+		g.line("//line %s:%d:%d", g.file, token.Line, token.Column)
+	}
+}
+
+// ident makes up a unique Go identifier starting with prefix.
+func (g *Generator) ident(prefix string) string {
+	g.names++
+	return fmt.Sprintf("%s_%d", prefix, g.names)
+}
+
+func globalIdent(name string) string {
+	return "g_" + name
+}
+
+func (g *Generator) beginScope() {
+	g.scope = &scope{enclosing: g.scope, locals: map[string]*local{}}
+}
+
+func (g *Generator) endScope() {
+	g.scope = g.scope.enclosing
+}
+
+// declare declares name in the innermost scope, returning its Go
+// identifier.
+func (g *Generator) declare(name *ast.Token, constant bool) string {
+	l := &local{ident: g.ident("l_" + *name.Lexeme), constant: constant}
+	g.scope.locals[*name.Lexeme] = l
+	return l.ident
+}
+
+// resolve returns the local name refers to, or nil for a global.
+func (g *Generator) resolve(name *ast.Token) *local {
+	for s := g.scope; s != nil; s = s.enclosing {
+		if l, ok := s.locals[*name.Lexeme]; ok {
+			return l
+		}
+	}
+	g.globals[*name.Lexeme] = true
+	return nil
+}
+
+// Statements
+
+func (g *Generator) statements(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		g.statement(stmt)
+	}
+}
+
+func (g *Generator) statement(stmt ast.Stmt) {
+	g.directive(ast.LeadingToken(stmt))
+	ast.AcceptStmt[any](stmt, g)
+}
+
+// block translates statements in a scope of their own, between braces.
+func (g *Generator) block(statements []ast.Stmt) {
+	g.line("{")
+	g.scoped(statements)
+	g.line("}")
+}
+
+// scoped translates statements in a scope of their own.
+func (g *Generator) scoped(statements []ast.Stmt) {
+	g.beginScope()
+	g.statements(statements)
+	g.endScope()
+}
+
+// body translates the body of an if statement or a loop, which Go wants
+// between braces, without them.
+func (g *Generator) body(stmt ast.Stmt) {
+	if block, ok := stmt.(*ast.BlockStmt); ok {
+		g.scoped(block.Statements())
+		return
+	}
+	g.statement(stmt)
+}
+
+// returns translates a return from the function being translated.
+func (g *Generator) returns(value string) {
+	if g.fn.tries > 0 {
+		g.line("return true, %s", value)
+	} else {
+		g.line("return %s", value)
+	}
+}
+
+// endsWithReturn reports whether statements end with a return statement,
+// after which Go doesn't want another.
+func endsWithReturn(statements []ast.Stmt) bool {
+	if len(statements) == 0 {
+		return false
+	}
+	_, ok := statements[len(statements)-1].(*ast.ReturnStmt)
+	return ok
+}
+
+func (g *Generator) VisitExpressionStmt(stmt *ast.ExpressionStmt) any {
+	switch stmt.Expression().(type) {
+	case *ast.CallExpr, *ast.AssignExpr, *ast.CompoundAssignExpr, *ast.UpdateExpr, *ast.SetExpr, *ast.SetIndexExpr,
+		*ast.CompoundSetExpr, *ast.CompoundSetIndexExpr, *ast.UpdatePropertyExpr, *ast.UpdateIndexExpr:
+		g.line("%s", g.expression(stmt.Expression()))
+	default:
+		g.line("_ = %s", g.expression(stmt.Expression()))
+	}
+	return nil
+}
+
+func (g *Generator) VisitVarStmt(stmt *ast.VarStmt) any {
+	initializer := "nil"
+	if stmt.Initializer() != nil {
+		initializer = g.expression(stmt.Initializer())
+	}
+	if g.scope == nil {
+		name := *stmt.Name().Lexeme
+		g.globals[name] = true
+		g.line("%s.define(%s, %t)", globalIdent(name), initializer, stmt.Constant())
+		return nil
+	}
+	// the initializer sees the variables outside the declaration
+	ident := g.declare(stmt.Name(), stmt.Constant())
+	if stmt.Initializer() == nil {
+		g.line("var %s any", ident)
+	} else {
+		g.line("var %s any = %s", ident, initializer)
+	}
+	g.line("_ = %s", ident)
+	return nil
+}
+
+func (g *Generator) VisitImportStmt(stmt *ast.ImportStmt) any {
+	g.unsupported(stmt.Keyword(), "Imports")
+	return nil
+}
+
+func (g *Generator) VisitBlockStmt(stmt *ast.BlockStmt) any {
+	g.block(stmt.Statements())
+	return nil
+}
+
+func (g *Generator) VisitIfStmt(stmt *ast.IfStmt) any {
+	g.line("if rtTruthy(%s) {", g.expression(stmt.Condition()))
+	g.body(stmt.ThenBranch())
+	if stmt.ElseBranch() != nil {
+		g.line("} else {")
+		g.body(stmt.ElseBranch())
+	}
+	g.line("}")
+	return nil
+}
+
+func (g *Generator) VisitWhileStmt(stmt *ast.WhileStmt) any {
+	g.line("for rtTruthy(%s) {", g.expression(stmt.Condition()))
+	g.body(stmt.Body())
+	g.line("}")
+	return nil
+}
+
+// VisitForInStmt declares the loop variable inside the Go loop, so that
+// each run of the body has a variable of its own.
+func (g *Generator) VisitForInStmt(stmt *ast.ForInStmt) any {
+	keyword := stmt.Keyword()
+	next := g.ident("next")
+	g.line("for %s := rtIterate(%s, %d, %d); ; {", next, g.expression(stmt.Iterable()), keyword.Line, keyword.Column)
+	g.beginScope()
+	ident := g.declare(stmt.Name(), false)
+	g.line("%s, ok := %s()", ident, next)
+	g.line("if !ok {\nbreak\n}")
+	g.line("_ = %s", ident)
+	g.body(stmt.Body())
+	g.endScope()
+	g.line("}")
+	return nil
+}
+
+func (g *Generator) VisitFunctionStmt(stmt *ast.FunctionStmt) any {
+	if stmt.Generator() {
+		g.unsupported(stmt.Name(), "Generators")
+		return nil
+	}
+	name := *stmt.Name().Lexeme
+	if g.scope == nil {
+		g.globals[name] = true
+		g.line("%s.define(%s, false)", globalIdent(name), g.function(stmt, ""))
+		return nil
+	}
+	// declared first, so the function can call itself
+	ident := g.declare(stmt.Name(), false)
+	g.line("var %s any", ident)
+	g.line("%s = %s", ident, g.function(stmt, ""))
+	g.line("_ = %s", ident)
+	return nil
+}
+
+// function translates the declaration of a function into the expression
+// creating it. this is the Go identifier of "this" in an initializer, and
+// empty otherwise.
+func (g *Generator) function(stmt *ast.FunctionStmt, this string) string {
+	code := g.b.String()
+	g.b.Reset()
+	g.fn = &functionScope{enclosing: g.fn, this: this}
+	g.beginScope()
+
+	for i, param := range stmt.Params() {
+		ident := g.declare(param, false)
+		g.line("%s := args[%d]", ident, i)
+		g.line("_ = %s", ident)
+	}
+	g.statements(stmt.Body())
+	if !endsWithReturn(stmt.Body()) {
+		g.line("return %s", g.returnNil())
+	}
+
+	g.endScope()
+	g.fn = g.fn.enclosing
+	body := g.b.String()
+	g.b.Reset()
+	g.b.WriteString(code)
+	return fmt.Sprintf("rtFunc(%s, %d, func(args []any) any {\n%s})", strconv.Quote(*stmt.Name().Lexeme), len(stmt.Params()), body)
+}
+
+// VisitClassStmt declares the class like a function, so that its methods
+// can refer to it.
+func (g *Generator) VisitClassStmt(stmt *ast.ClassStmt) any {
+	name := *stmt.Name().Lexeme
+	if g.scope == nil {
+		g.globals[name] = true
+		g.line("%s.define(%s, false)", globalIdent(name), g.class(stmt))
+		return nil
+	}
+	ident := g.declare(stmt.Name(), false)
+	g.line("var %s any", ident)
+	g.line("%s = %s", ident, g.class(stmt))
+	g.line("_ = %s", ident)
+	return nil
+}
+
+// class translates the declaration of a class into the expression creating
+// it. Each method becomes a Go function taking the instance it is called
+// on, which "this" refers to.
+func (g *Generator) class(stmt *ast.ClassStmt) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "rtNewClass(%s, map[string]func(this any) *rtFunction{\n", strconv.Quote(*stmt.Name().Lexeme))
+	for _, method := range stmt.Methods() {
+		method := method.(*ast.FunctionStmt)
+		if method.Generator() {
+			g.unsupported(method.Name(), "Generators")
+			continue
+		}
+		g.beginScope()
+		this := g.declare(thisToken(method.Name()), true)
+		initializer := ""
+		if *method.Name().Lexeme == value.InitMethod {
+			initializer = this
+		}
+		fmt.Fprintf(&b, "%s: func(%s any) *rtFunction {\nreturn %s\n},\n", strconv.Quote(*method.Name().Lexeme), this, g.function(method, initializer))
+		g.endScope()
+	}
+	b.WriteString("})")
+	return b.String()
+}
+
+// thisToken returns the token of "this" at token, which the methods
+// declare like a variable.
+func thisToken(token *ast.Token) *ast.Token {
+	this := *token
+	lexeme := "this"
+	this.Lexeme = &lexeme
+	return &this
+}
+
+func (g *Generator) VisitReturnStmt(stmt *ast.ReturnStmt) any {
+	value := g.returnNil()
+	if stmt.Value() != nil {
+		value = g.expression(stmt.Value())
+	}
+	g.returns(value)
+	return nil
+}
+
+// returnNil returns the value of a return without one: nil, or the
+// instance in an initializer.
+func (g *Generator) returnNil() string {
+	if g.fn.this != "" {
+		return g.fn.this
+	}
+	return "nil"
+}
+
+func (g *Generator) VisitYieldStmt(stmt *ast.YieldStmt) any {
+	// the function is reported as a generator
+	return nil
+}
+
+func (g *Generator) VisitThrowStmt(stmt *ast.ThrowStmt) any {
+	keyword := stmt.Keyword()
+	g.line("rtThrow(%s, %d, %d)", g.expression(stmt.Value()), keyword.Line, keyword.Column)
+	return nil
+}
+
+// VisitTryStmt turns the clauses into closures returning whether they
+// returned from the enclosing function, and with which value.
+func (g *Generator) VisitTryStmt(stmt *ast.TryStmt) any {
+	if g.fn.script {
+		g.line("rtTry(")
+	} else {
+		g.line("if returned, result := rtTry(")
+	}
+	g.fn.tries++
+
+	g.line("func() (bool, any) {")
+	g.clause(nil, stmt.Body())
+	if stmt.CatchName() != nil {
+		g.line("func(e any) (bool, any) {")
+		g.clause(stmt.CatchName(), stmt.CatchBody())
+	} else {
+		g.line("nil,")
+	}
+	if stmt.Finally() != nil {
+		g.line("func() (bool, any) {")
+		g.clause(nil, stmt.FinallyBody())
+	} else {
+		g.line("nil,")
+	}
+
+	g.fn.tries--
+	if g.fn.script {
+		g.line(")")
+		return nil
+	}
+	g.line("); returned {")
+	g.returns("result")
+	g.line("}")
+	return nil
+}
+
+// clause translates the body of a clause of a try statement, with the
+// error caught bound to name if it isn't nil.
+func (g *Generator) clause(name *ast.Token, statements []ast.Stmt) {
+	g.beginScope()
+	if name != nil {
+		ident := g.declare(name, false)
+		g.line("var %s any = e", ident)
+		g.line("_ = %s", ident)
+	}
+	g.statements(statements)
+	g.endScope()
+	if !endsWithReturn(statements) {
+		g.line("return false, nil")
+	}
+	g.line("},")
+}
+
+func (g *Generator) VisitSelectStmt(stmt *ast.SelectStmt) any {
+	g.unsupported(stmt.Keyword(), "Select statements")
+	return nil
+}
+
+// Expressions
+
+func (g *Generator) expression(expr ast.Expr) string {
+	return ast.Accept[string](expr, g)
+}
+
+// operands translates expressions evaluated one after the other. Go
+// doesn't order reading a variable against calls, so a local read before
+// an operand that could assign it is read with a call.
+func (g *Generator) operands(exprs ...ast.Expr) []string {
+	translated := make([]string, len(exprs))
+	for i, expr := range exprs {
+		translated[i] = g.expression(expr)
+		variable, ok := ungroup(expr).(*ast.VariableExpr)
+		if !ok {
+			continue
+		}
+		if l := g.resolve(variable.Name()); l != nil && hasEffects(exprs[i+1:]) {
+			translated[i] = fmt.Sprintf("rtLoad(&%s)", l.ident)
+		}
+	}
+	return translated
+}
+
+// hasEffects reports whether evaluating exprs could assign a variable.
+func hasEffects(exprs []ast.Expr) bool {
+	effects := false
+	for _, expr := range exprs {
+		ast.Inspect(expr, func(node ast.Node) bool {
+			switch node.(type) {
+			case *ast.CallExpr, *ast.AssignExpr, *ast.CompoundAssignExpr, *ast.UpdateExpr:
+				effects = true
+			}
+			return !effects
+		})
+	}
+	return effects
+}
+
+func ungroup(expr ast.Expr) ast.Expr {
+	for {
+		grouping, ok := expr.(*ast.GroupingExpr)
+		if !ok {
+			return expr
+		}
+		expr = grouping.Expr()
+	}
+}
+
+// operators spells the operators the way the runtime names them, whatever
+// keyword the program wrote them with.
+var operators = map[ast.TokenType]string{
+	ast.TokenPlus:           "+",
+	ast.TokenMinus:          "-",
+	ast.TokenStar:           "*",
+	ast.TokenSlash:          "/",
+	ast.TokenPercent:        "%",
+	ast.TokenStarStar:       "**",
+	ast.TokenAmpersand:      "&",
+	ast.TokenPipe:           "|",
+	ast.TokenCaret:          "^",
+	ast.TokenLessLess:       "<<",
+	ast.TokenGreaterGreater: ">>",
+	ast.TokenTilde:          "~",
+	ast.TokenBang:           "!",
+	ast.TokenEqualEqual:     "==",
+	ast.TokenBangEqual:      "!=",
+	ast.TokenGreater:        ">",
+	ast.TokenGreaterEqual:   ">=",
+	ast.TokenLess:           "<",
+	ast.TokenLessEqual:      "<=",
+}
+
+// operator returns the runtime's name for the operator of token.
+func (g *Generator) operator(token *ast.Token, tokenType ast.TokenType) string {
+	op, ok := operators[tokenType]
+	if !ok {
+		g.error(token, "Unknown operator")
+	}
+	return strconv.Quote(op)
+}
+
+func (g *Generator) VisitBinaryExpr(expr *ast.BinaryExpr) string {
+	operands := g.operands(expr.Left(), expr.Right())
+	op := expr.Operator()
+	return fmt.Sprintf("rtBinary(%s, %s, %s, %d, %d)", g.operator(op, op.Type), operands[0], operands[1], op.Line, op.Column)
+}
+
+func (g *Generator) VisitUnaryExpr(expr *ast.UnaryExpr) string {
+	op := expr.Operator()
+	return fmt.Sprintf("rtUnary(%s, %s, %d, %d)", g.operator(op, op.Type), g.expression(expr.Right()), op.Line, op.Column)
+}
+
+func (g *Generator) VisitLiteralExpr(expr *ast.LiteralExpr) string {
+	return literal(expr.Value())
+}
+
+// literal returns the Go expression of a literal value.
+func literal(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return fmt.Sprintf("rtFloat(%q)", strconv.FormatFloat(v, 'g', -1, 64))
+		}
+		return "float64(" + strconv.FormatFloat(v, 'g', -1, 64) + ")"
+	case string:
+		return strconv.Quote(v)
+	}
+	panic(fmt.Sprintf("gogen: unexpected literal %T", v))
+}
+
+func (g *Generator) VisitGroupingExpr(expr *ast.GroupingExpr) string {
+	return g.expression(expr.Expr())
+}
+
+func (g *Generator) VisitVariableExpr(expr *ast.VariableExpr) string {
+	name := expr.Name()
+	if l := g.resolve(name); l != nil {
+		return l.ident
+	}
+	return fmt.Sprintf("%s.get(%d, %d)", globalIdent(*name.Lexeme), name.Line, name.Column)
+}
+
+func (g *Generator) VisitAssignExpr(expr *ast.AssignExpr) string {
+	return g.assign(expr.Name(), g.expression(expr.Value()))
+}
+
+// assign returns the Go expression assigning value to the variable name.
+func (g *Generator) assign(name *ast.Token, value string) string {
+	l := g.resolve(name)
+	if l == nil {
+		return fmt.Sprintf("%s.set(%s, %d, %d)", globalIdent(*name.Lexeme), value, name.Line, name.Column)
+	}
+	if l.constant {
+		g.error(name, fmt.Sprintf("Cannot assign to constant '%s'", *name.Lexeme))
+	}
+	return fmt.Sprintf("rtSet(&%s, %s)", l.ident, value)
+}
+
+func (g *Generator) VisitCompoundAssignExpr(expr *ast.CompoundAssignExpr) string {
+	name, token := expr.Name(), expr.Operator()
+	operator, _ := ast.CompoundOperator(token.Type)
+	current := fmt.Sprintf("%s.get(%d, %d)", globalIdent(*name.Lexeme), name.Line, name.Column)
+	if l := g.resolve(name); l != nil {
+		current = fmt.Sprintf("rtLoad(&%s)", l.ident)
+	}
+	result := fmt.Sprintf("rtBinary(%s, %s, %s, %d, %d)", g.operator(token, operator), current, g.expression(expr.Value()), token.Line, token.Column)
+	return g.assign(name, result)
+}
+
+func (g *Generator) VisitUpdateExpr(expr *ast.UpdateExpr) string {
+	name, token := expr.Name(), expr.Operator()
+	l := g.resolve(name)
+	if l == nil {
+		return fmt.Sprintf("%s.update(%s, %t, %d, %d, %d, %d)", globalIdent(*name.Lexeme), delta(token), expr.Prefix(), token.Line, token.Column, name.Line, name.Column)
+	}
+	if l.constant {
+		g.error(name, fmt.Sprintf("Cannot assign to constant '%s'", *name.Lexeme))
+	}
+	return fmt.Sprintf("rtUpdate(&%s, %s, %t, %d, %d)", l.ident, delta(token), expr.Prefix(), token.Line, token.Column)
+}
+
+// delta returns the number the "++" or "--" operator adds.
+func delta(operator *ast.Token) string {
+	if operator.Type == ast.TokenMinusMinus {
+		return "-1"
+	}
+	return "1"
+}
+
+// lazy returns a closure evaluating expr, for the operands evaluated only
+// when needed.
+func (g *Generator) lazy(expr ast.Expr) string {
+	return fmt.Sprintf("func() any { return %s }", g.expression(expr))
+}
+
+func (g *Generator) VisitLogicalExpr(expr *ast.LogicalExpr) string {
+	helper := "rtAnd"
+	if expr.Operator().Type == ast.TokenOr {
+		helper = "rtOr"
+	}
+	return fmt.Sprintf("%s(%s, %s)", helper, g.expression(expr.Left()), g.lazy(expr.Right()))
+}
+
+func (g *Generator) VisitConditionalExpr(expr *ast.ConditionalExpr) string {
+	return fmt.Sprintf("rtConditional(%s, %s, %s)", g.expression(expr.Condition()), g.lazy(expr.ThenBranch()), g.lazy(expr.ElseBranch()))
+}
+
+func (g *Generator) VisitCoalesceExpr(expr *ast.CoalesceExpr) string {
+	return fmt.Sprintf("rtCoalesce(%s, %s)", g.expression(expr.Left()), g.lazy(expr.Right()))
+}
+
+func (g *Generator) VisitGetExpr(expr *ast.GetExpr) string {
+	return g.chain(expr)
+}
+
+func (g *Generator) VisitOptionalGetExpr(expr *ast.OptionalGetExpr) string {
+	return g.chain(expr)
+}
+
+func (g *Generator) VisitCallExpr(expr *ast.CallExpr) string {
+	return g.chain(expr)
+}
+
+func (g *Generator) VisitIndexExpr(expr *ast.IndexExpr) string {
+	return g.chain(expr)
+}
+
+func (g *Generator) VisitSetExpr(expr *ast.SetExpr) string {
+	operands := g.operands(expr.Object(), expr.Value())
+	name := expr.Name()
+	return fmt.Sprintf("rtSetProperty(%s, %s, %s, %d, %d)", operands[0], strconv.Quote(*name.Lexeme), operands[1], name.Line, name.Column)
+}
+
+func (g *Generator) VisitSetIndexExpr(expr *ast.SetIndexExpr) string {
+	operands := g.operands(expr.Object(), expr.Index(), expr.Value())
+	bracket := expr.Bracket()
+	return fmt.Sprintf("rtSetIndex(%s, %s, %s, %d, %d)", operands[0], operands[1], operands[2], bracket.Line, bracket.Column)
+}
+
+func (g *Generator) VisitCompoundSetExpr(expr *ast.CompoundSetExpr) string {
+	name, token := expr.Name(), expr.Operator()
+	operator, _ := ast.CompoundOperator(token.Type)
+	return fmt.Sprintf("rtCompoundSetProperty(%s, %s, %s, %s, %d, %d, %d, %d)", g.expression(expr.Object()), strconv.Quote(*name.Lexeme),
+		g.operator(token, operator), g.lazy(expr.Value()), token.Line, token.Column, name.Line, name.Column)
+}
+
+func (g *Generator) VisitCompoundSetIndexExpr(expr *ast.CompoundSetIndexExpr) string {
+	operands := g.operands(expr.Object(), expr.Index())
+	bracket, token := expr.Bracket(), expr.Operator()
+	operator, _ := ast.CompoundOperator(token.Type)
+	return fmt.Sprintf("rtCompoundSetIndex(%s, %s, %s, %s, %d, %d, %d, %d)", operands[0], operands[1],
+		g.operator(token, operator), g.lazy(expr.Value()), token.Line, token.Column, bracket.Line, bracket.Column)
+}
+
+func (g *Generator) VisitUpdatePropertyExpr(expr *ast.UpdatePropertyExpr) string {
+	name, token := expr.Name(), expr.Operator()
+	return fmt.Sprintf("rtUpdateProperty(%s, %s, %s, %t, %d, %d, %d, %d)", g.expression(expr.Object()), strconv.Quote(*name.Lexeme),
+		delta(token), expr.Prefix(), token.Line, token.Column, name.Line, name.Column)
+}
+
+func (g *Generator) VisitUpdateIndexExpr(expr *ast.UpdateIndexExpr) string {
+	operands := g.operands(expr.Object(), expr.Index())
+	bracket, token := expr.Bracket(), expr.Operator()
+	return fmt.Sprintf("rtUpdateIndex(%s, %s, %s, %t, %d, %d, %d, %d)", operands[0], operands[1],
+		delta(token), expr.Prefix(), token.Line, token.Column, bracket.Line, bracket.Column)
+}
+
+func (g *Generator) VisitThisExpr(expr *ast.ThisExpr) string {
+	return g.resolve(thisToken(expr.Keyword())).ident
+}
+
+func (g *Generator) VisitListExpr(expr *ast.ListExpr) string {
+	return "rtNewList(" + strings.Join(g.operands(expr.Elements()...), ", ") + ")"
+}
+
+func (g *Generator) VisitMapExpr(expr *ast.MapExpr) string {
+	entries := make([]ast.Expr, 0, 2*len(expr.Keys()))
+	for i, key := range expr.Keys() {
+		entries = append(entries, key, expr.Values()[i])
+	}
+	brace := expr.Brace()
+	operands := append([]string{strconv.Itoa(brace.Line), strconv.Itoa(brace.Column)}, g.operands(entries...)...)
+	return "rtNewMap(" + strings.Join(operands, ", ") + ")"
+}
+
+// chain translates expr, a property access, index or call ending a chain of them,
+// such as a?.b.c. An optional access finding nil skips the rest of the
+// chain, which evaluates to nil.
+func (g *Generator) chain(expr ast.Expr) string {
+	return g.link(expr, func(value string) string { return value })
+}
+
+// link translates expr, a link of a chain, passing the translation of its
+// value to rest, which translates the rest of the chain.
+func (g *Generator) link(expr ast.Expr, rest func(value string) string) string {
+	switch expr := expr.(type) {
+	case *ast.GetExpr:
+		return g.link(expr.Object(), func(object string) string {
+			return rest(get(object, expr.Name()))
+		})
+	case *ast.OptionalGetExpr:
+		return g.link(expr.Object(), func(object string) string {
+			o := g.ident("object")
+			return fmt.Sprintf("func(%s any) any {\nif %s == nil {\nreturn nil\n}\nreturn %s\n}(%s)", o, o, rest(get(o, expr.Name())), object)
+		})
+	case *ast.IndexExpr:
+		bracket := expr.Bracket()
+		index := func(object string, index string) string {
+			return rest(fmt.Sprintf("rtIndex(%s, %s, %d, %d)", object, index, bracket.Line, bracket.Column))
+		}
+		if !isLink(expr.Object()) {
+			operands := g.operands(expr.Object(), expr.Index())
+			return index(operands[0], operands[1])
+		}
+		return g.link(expr.Object(), func(object string) string {
+			return index(object, g.expression(expr.Index()))
+		})
+	case *ast.CallExpr:
+		if !isLink(expr.Callee()) {
+			// the callee is loaded first, even when the arguments assign it
+			operands := g.operands(append([]ast.Expr{expr.Callee()}, expr.Arguments()...)...)
+			return rest(call(operands[0], operands[1:], expr.Paren()))
+		}
+		return g.link(expr.Callee(), func(callee string) string {
+			return rest(call(callee, g.operands(expr.Arguments()...), expr.Paren()))
+		})
+	}
+	return rest(g.expression(expr))
+}
+
+func isLink(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.GetExpr, *ast.OptionalGetExpr, *ast.IndexExpr, *ast.CallExpr:
+		return true
+	}
+	return false
+}
+
+func get(object string, name *ast.Token) string {
+	return fmt.Sprintf("rtGet(%s, %s, %d, %d)", object, strconv.Quote(*name.Lexeme), name.Line, name.Column)
+}
+
+func call(callee string, arguments []string, paren *ast.Token) string {
+	list := "nil"
+	if len(arguments) > 0 {
+		list = "[]any{" + strings.Join(arguments, ", ") + "}"
+	}
+	return fmt.Sprintf("rtCall(%s, %s, %d, %d)", callee, list, paren.Line, paren.Column)
+}
+
+// VisitMatchExpr translates the arms into a closure trying each in turn,
+// with the variables its pattern binds declared in a block of their own.
+func (g *Generator) VisitMatchExpr(expr *ast.MatchExpr) string {
+	var b strings.Builder
+	subject := g.ident("subject")
+	fmt.Fprintf(&b, "func() any {\n%s := %s\n", subject, g.expression(expr.Subject()))
+	for arm, pattern := range expr.Patterns() {
+		b.WriteString("{\n")
+		g.beginScope()
+		for _, name := range ast.PatternBindings(pattern) {
+			ident := g.declare(name, false)
+			fmt.Fprintf(&b, "var %s any\n_ = %s\n", ident, ident)
+		}
+		condition := g.pattern(pattern, subject)
+		if guard := expr.Guards()[arm]; guard != nil {
+			condition += " && rtTruthy(" + g.expression(guard) + ")"
+		}
+		fmt.Fprintf(&b, "if %s {\nreturn %s\n}\n", condition, g.expression(expr.Values()[arm]))
+		g.endScope()
+		b.WriteString("}\n")
+	}
+	keyword := expr.Keyword()
+	fmt.Fprintf(&b, "rtNoMatch(%s, %d, %d)\nreturn nil\n}()", subject, keyword.Line, keyword.Column)
+	return b.String()
+}
+
+// pattern returns the Go condition matching the value of the Go expression
+// v with pattern, binding the locals of the innermost scope.
+func (g *Generator) pattern(pattern ast.Pattern, v string) string {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		return fmt.Sprintf("rtEqual(%s, %s)", v, literal(pattern.Value()))
+	case *ast.WildcardPattern:
+		return "true"
+	case *ast.BindingPattern:
+		return fmt.Sprintf("rtBind(&%s, %s)", g.scope.locals[*pattern.Name().Lexeme].ident, v)
+	case *ast.ObjectPattern:
+		if len(pattern.Names()) == 0 {
+			return fmt.Sprintf("rtIsObject(%s)", v)
+		}
+		return strings.TrimPrefix(g.properties(pattern.Names(), pattern.Patterns(), v), " && ")
+	case *ast.ClassPattern:
+		return fmt.Sprintf("rtIsInstance(%s, %s)", v, strconv.Quote(*pattern.Name().Lexeme)) + g.properties(pattern.Names(), pattern.Patterns(), v)
+	case *ast.ListPattern:
+		elements, rest := g.ident("elements"), g.ident("rest")
+		var conditions []string
+		for i, element := range pattern.Patterns() {
+			conditions = append(conditions, g.pattern(element, fmt.Sprintf("%s[%d]", elements, i)))
+		}
+		if name := pattern.Rest(); name != nil && *name.Lexeme != "_" {
+			conditions = append(conditions, fmt.Sprintf("rtBind(&%s, %s)", g.scope.locals[*name.Lexeme].ident, rest))
+		}
+		if len(conditions) == 0 {
+			conditions = append(conditions, "true")
+		}
+		return fmt.Sprintf("rtElements(%s, %d, %t, func(%s []any, %s *rtList) bool { return %s })", v, len(pattern.Patterns()), pattern.Rest() != nil, elements, rest, strings.Join(conditions, " && "))
+	}
+	panic(fmt.Sprintf("gogen: unexpected pattern %T", pattern))
+}
+
+// properties returns the conditions matching the property of v called each
+// of names with the pattern at the same index of patterns, each following
+// " && ".
+func (g *Generator) properties(names []*ast.Token, patterns []ast.Pattern, v string) string {
+	var b strings.Builder
+	for i, name := range names {
+		property := g.ident("property")
+		fmt.Fprintf(&b, " && rtProperty(%s, %s, func(%s any) bool { return %s })", v, strconv.Quote(*name.Lexeme), property, g.pattern(patterns[i], property))
+	}
+	return b.String()
+}
+
+func (g *Generator) VisitSpawnExpr(expr *ast.SpawnExpr) string {
+	g.unsupported(expr.Keyword(), "Tasks")
+	return "nil"
+}
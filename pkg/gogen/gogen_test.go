@@ -0,0 +1,159 @@
+package gogen
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/golden"
+	"github.com/bagaswh/rottenlang/pkg/interpreter"
+	"github.com/bagaswh/rottenlang/pkg/parser"
+	"github.com/bagaswh/rottenlang/pkg/scanner"
+)
+
+func parse(t *testing.T, source string) []ast.Stmt {
+	t.Helper()
+	tokens, err := scanner.NewScanner(strings.NewReader(source), 0).ScanTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parser.NewParser(&errorreporter.NopErrorReporter{})
+	p.SetTokens(tokens)
+	statements := p.Parse()
+	if p.HadError() {
+		t.Fatal("source doesn't parse")
+	}
+	return statements
+}
+
+// build writes files into a Go module in a temporary directory and builds
+// it, returning the path of the binary.
+func build(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	dir := t.TempDir()
+	files["go.mod"] = []byte("module generated\n\ngo 1.24\n")
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	binary := filepath.Join(dir, "program")
+	cmd := exec.Command(gobin, "build", "-o", binary, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building generated code: %v\n%s", err, out)
+	}
+	return binary
+}
+
+// TestGolden translates the programs in testdata into Go and runs them,
+// comparing what they print with the .out files and what they write to
+// stderr, or the errors translating them, with the .err files. They must
+// print what the tree interpreter prints.
+func TestGolden(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	for _, program := range golden.Programs(t, "testdata") {
+		t.Run(filepath.Base(program), func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			statements := parse(t, string(source))
+
+			reporter := &errorreporter.CollectingErrorReporter{}
+			files, err := NewGenerator(reporter).Generate(statements, filepath.Base(program), "main")
+			if err != nil {
+				var messages strings.Builder
+				for _, diagnostic := range reporter.Diagnostics() {
+					messages.WriteString(diagnostic.String() + "\n")
+				}
+				golden.Check(t, program, ".out", "")
+				golden.Check(t, program, ".err", messages.String())
+				return
+			}
+
+			var stdout, stderr bytes.Buffer
+			cmd := exec.Command(build(t, files))
+			cmd.Stdout = &stdout
+			cmd.Stderr = &stderr
+			cmd.Run()
+			golden.Check(t, program, ".out", stdout.String())
+			golden.Check(t, program, ".err", stderr.String())
+
+			var want strings.Builder
+			interpreter.NewInterpreter(&errorreporter.NopErrorReporter{}, &want).Interpret(parse(t, string(source)))
+			if stdout.String() != want.String() {
+				t.Errorf("output differs from tree interpreter\ngot:  %q\nwant: %q", stdout.String(), want.String())
+			}
+		})
+	}
+}
+
+// TestPackage imports a generated package other than main, running the
+// program and then calling its functions.
+func TestPackage(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds Go programs")
+	}
+	statements := parse(t, `
+vibes factor = 2;
+func scale(n) { purrr n * factor; }
+print("loaded");
+`)
+	files, err := NewGenerator(&errorreporter.NopErrorReporter{}).Generate(statements, "scale.rot", "scale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if program := string(files["scale.rot.go"]); !strings.Contains(program, "//line scale.rot:3:") {
+		t.Errorf("generated code has no //line directive for line 3:\n%s", program)
+	}
+	module := map[string][]byte{}
+	for name, content := range files {
+		module["scale/"+name] = content
+	}
+	module["main.go"] = []byte(`package main
+
+import (
+	"fmt"
+	"os"
+
+	"generated/scale"
+)
+
+func main() {
+	fmt.Println(scale.Run(os.Stdout))
+	fmt.Println(scale.Call("scale", 21.0))
+	_, err := scale.Call("scale", "x")
+	fmt.Println(err)
+}
+`)
+
+	out, err := exec.Command(build(t, module)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("running generated code: %v\n%s", err, out)
+	}
+	want := `loaded
+<nil>
+42 <nil>
+runtime error at line 3, column 25: operands must be numbers
+`
+	if string(out) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out, want)
+	}
+}
@@ -0,0 +1,1095 @@
+// Package rt is the runtime support library of the Go code generated by
+// package gogen. The generator copies this file into every package it
+// generates, renaming the package, so it only imports the standard library
+// and its unexported names start with "rt", which the names generated for
+// the program never do.
+//
+// It follows the semantics of package value: the values of a program are
+// nil, bool, float64, string, functions, ranges, errors, lists, maps, and
+// classes and their instances.
+package rt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// rtMaxCallDepth bounds the number of nested calls a program may make
+// before failing with a stack overflow.
+const rtMaxCallDepth = 256
+
+// RuntimeError is an error raised while running the program, positioned at
+// the source location that caused it.
+type RuntimeError struct {
+	Line, Column int
+	Message      string
+	// Stack is the call stack where the error was raised, innermost call
+	// first, ending with the top level code
+	Stack []StackFrame
+	// thrown is the error value raised by a throw statement, if any
+	thrown *rtError
+}
+
+// StackFrame is a call in progress: Line and Column locate what the
+// function was executing.
+type StackFrame struct {
+	Function     string
+	Line, Column int
+}
+
+func (frame StackFrame) String() string {
+	return fmt.Sprintf("at %s (line %d, column %d)", frame.Function, frame.Line, frame.Column)
+}
+
+func (err *RuntimeError) Error() string {
+	return fmt.Sprintf("runtime error at line %d, column %d: %s", err.Line, err.Column, err.Message)
+}
+
+// Trace formats the stack of an error raised inside a function, one
+// indented line per call starting with a newline. Errors raised by top
+// level code have no trace.
+func (err *RuntimeError) Trace() string {
+	if len(err.Stack) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	for _, frame := range err.Stack {
+		b.WriteString("\n  " + frame.String())
+	}
+	return b.String()
+}
+
+// rtMu makes the program run once at a time: its globals are shared by
+// every call of Run and Call.
+var rtMu sync.Mutex
+
+var (
+	rtOut     io.Writer = io.Discard
+	rtGlobals           = map[string]*rtGlobal{}
+	// rtFrames are the calls in progress, rtFrames[0] being the top level
+	// code; each records where it called the next one
+	rtFrames []rtFrame
+)
+
+type rtFrame struct {
+	function     string
+	line, column int
+}
+
+// rtRun runs script, the top level code of the program, writing what it
+// prints to out. The globals start over each time.
+func rtRun(out io.Writer, script func()) (err error) {
+	rtMu.Lock()
+	defer rtMu.Unlock()
+	rtOut = out
+	for _, global := range rtGlobals {
+		global.value, global.defined, global.constant = nil, false, false
+	}
+	for _, native := range rtBuiltins {
+		rtGlobalVar(native.name).define(native, false)
+	}
+	return rtProtectScript(func() {
+		script()
+	})
+}
+
+// rtMain runs script as the program of a command, which exits with status
+// 1 when it stops with a runtime error.
+func rtMain(script func()) {
+	if err := rtRun(os.Stdout, script); err != nil {
+		runtimeErr := err.(*RuntimeError)
+		fmt.Fprintf(os.Stderr, "[line=%d col=%d] Runtime error: %s\n", runtimeErr.Line, runtimeErr.Column, runtimeErr.Message+runtimeErr.Trace())
+		os.Exit(1)
+	}
+}
+
+// Call calls the global function name with args, which must be values of
+// the program: nil, bool, float64 or string. Run must have run first to
+// define it.
+func Call(name string, args ...any) (result any, err error) {
+	rtMu.Lock()
+	defer rtMu.Unlock()
+	err = rtProtectScript(func() {
+		result = rtCall(rtGlobalVar(name).get(0, 0), args, 0, 0)
+	})
+	return result, err
+}
+
+// rtProtectScript runs f as top level code, returning the runtime error
+// stopping it.
+func rtProtectScript(f func()) (err error) {
+	rtFrames = append(rtFrames[:0], rtFrame{function: "<script>"})
+	defer func() {
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(*RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+	f()
+	return nil
+}
+
+// rtFail raises the runtime error message at line and column.
+func rtFail(line, column int, message string) {
+	panic(rtNewError(line, column, message))
+}
+
+func rtNewError(line, column int, message string) *RuntimeError {
+	return &RuntimeError{Line: line, Column: column, Message: message, Stack: rtStack(line, column)}
+}
+
+// rtStack returns the calls in progress, innermost first, with the
+// innermost one at line and column.
+func rtStack(line, column int) []StackFrame {
+	stack := make([]StackFrame, 0, len(rtFrames))
+	for i := len(rtFrames) - 1; i >= 0; i-- {
+		if i < len(rtFrames)-1 {
+			line, column = rtFrames[i].line, rtFrames[i].column
+		}
+		stack = append(stack, StackFrame{Function: rtFrames[i].function, Line: line, Column: column})
+	}
+	return stack
+}
+
+// Globals
+
+type rtGlobal struct {
+	name     string
+	value    any
+	defined  bool
+	constant bool
+}
+
+// rtGlobalVar returns the global variable name, which is the same for
+// every use of the name.
+func rtGlobalVar(name string) *rtGlobal {
+	global, ok := rtGlobals[name]
+	if !ok {
+		global = &rtGlobal{name: name}
+		rtGlobals[name] = global
+	}
+	return global
+}
+
+func (g *rtGlobal) define(v any, constant bool) {
+	g.value, g.defined, g.constant = v, true, constant
+}
+
+func (g *rtGlobal) get(line, column int) any {
+	if !g.defined {
+		rtFail(line, column, fmt.Sprintf("undefined variable '%s'", g.name))
+	}
+	return g.value
+}
+
+func (g *rtGlobal) set(v any, line, column int) any {
+	if !g.defined {
+		rtFail(line, column, fmt.Sprintf("undefined variable '%s'", g.name))
+	}
+	if g.constant {
+		rtFail(line, column, fmt.Sprintf("cannot assign to constant '%s'", g.name))
+	}
+	g.value = v
+	return v
+}
+
+// rtLoad reads a local variable. The generated code reads variables with
+// it where a call evaluated later could assign them, since Go doesn't order
+// reading a variable against calls.
+func rtLoad(p *any) any {
+	return *p
+}
+
+// rtSet assigns v to a local variable, returning v.
+func rtSet(p *any, v any) any {
+	*p = v
+	return v
+}
+
+// Functions
+
+// rtFunction is a function of the program.
+type rtFunction struct {
+	name  string
+	arity int
+	fn    func(args []any) any
+}
+
+func rtFunc(name string, arity int, fn func(args []any) any) *rtFunction {
+	return &rtFunction{name: name, arity: arity, fn: fn}
+}
+
+func (f *rtFunction) String() string {
+	return fmt.Sprintf("<fn %s>", f.name)
+}
+
+// rtNative is a builtin function.
+type rtNative struct {
+	name  string
+	arity int
+	fn    func(args []any) (any, error)
+}
+
+func (n *rtNative) String() string {
+	return fmt.Sprintf("<native fn %s>", n.name)
+}
+
+// rtCall calls callee, reporting errors at line and column, where the
+// calling function is.
+func rtCall(callee any, args []any, line, column int) any {
+	var name string
+	var arity int
+	switch callee := callee.(type) {
+	case *rtFunction:
+		name, arity = callee.name, callee.arity
+	case *rtNative:
+		name, arity = callee.name, callee.arity
+	case *rtClass:
+		instance := &rtInstance{class: callee, fields: map[string]any{}}
+		if init, ok := callee.methods[rtInitMethod]; ok {
+			rtCall(init(instance), args, line, column)
+		} else if len(args) != 0 {
+			rtFail(line, column, fmt.Sprintf("expected 0 arguments but got %d", len(args)))
+		}
+		return instance
+	default:
+		rtFail(line, column, fmt.Sprintf("can only call functions, got %s", rtTypeName(callee)))
+	}
+	if arity >= 0 && arity != len(args) {
+		rtFail(line, column, fmt.Sprintf("expected %d arguments but got %d", arity, len(args)))
+	}
+
+	if native, ok := callee.(*rtNative); ok {
+		result, err := native.fn(args)
+		if err != nil {
+			var runtimeErr *RuntimeError
+			if errors.As(err, &runtimeErr) {
+				panic(runtimeErr)
+			}
+			rtFail(line, column, err.Error())
+		}
+		return result
+	}
+
+	if len(rtFrames) > rtMaxCallDepth {
+		rtFail(line, column, "stack overflow")
+	}
+	caller := &rtFrames[len(rtFrames)-1]
+	caller.line, caller.column = line, column
+	rtFrames = append(rtFrames, rtFrame{function: name})
+	result := callee.(*rtFunction).fn(args)
+	rtFrames = rtFrames[:len(rtFrames)-1]
+	return result
+}
+
+// Operators
+
+// rtTruthy reports whether v counts as true in a condition: everything but
+// nil and cap (false) does.
+func rtTruthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	default:
+		return true
+	}
+}
+
+// rtEqual compares two values. Values of different types are never equal,
+// and functions are equal only to themselves.
+func rtEqual(a, b any) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	case float64:
+		b, ok := b.(float64)
+		return ok && a == b
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	default:
+		return a == b
+	}
+}
+
+// rtBinary applies the binary operator op, reporting errors at line and
+// column.
+func rtBinary(op string, a, b any, line, column int) any {
+	switch op {
+	case "==":
+		return rtEqual(a, b)
+	case "!=":
+		return !rtEqual(a, b)
+	case "+":
+		if a, ok := a.(string); ok {
+			if b, ok := b.(string); ok {
+				return a + b
+			}
+		}
+		x, y, ok := rtNumbers(a, b)
+		if !ok {
+			rtFail(line, column, "operands must be two numbers or two strings")
+		}
+		return x + y
+	}
+
+	x, y, ok := rtNumbers(a, b)
+	if !ok {
+		rtFail(line, column, "operands must be numbers")
+	}
+	switch op {
+	case "-":
+		return x - y
+	case "*":
+		return x * y
+	case "/":
+		return x / y
+	case "%":
+		return math.Mod(x, y)
+	case "**":
+		return math.Pow(x, y)
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	}
+
+	m, mok := rtInteger(x)
+	n, nok := rtInteger(y)
+	if !mok || !nok {
+		rtFail(line, column, "operands must be integers")
+	}
+	switch op {
+	case "&":
+		return float64(m & n)
+	case "|":
+		return float64(m | n)
+	case "^":
+		return float64(m ^ n)
+	}
+	if n < 0 {
+		rtFail(line, column, "shift count must not be negative")
+	}
+	if op == "<<" {
+		return float64(m << n)
+	}
+	return float64(m >> n)
+}
+
+// rtUnary applies the prefix operator op, reporting errors at line and
+// column.
+func rtUnary(op string, v any, line, column int) any {
+	if op == "!" {
+		return !rtTruthy(v)
+	}
+	x, ok := v.(float64)
+	if !ok {
+		rtFail(line, column, "operand must be a number")
+	}
+	switch op {
+	case "-":
+		return -x
+	case "+":
+		return x
+	}
+	n, ok := rtInteger(x)
+	if !ok {
+		rtFail(line, column, "operand must be an integer")
+	}
+	return float64(^n)
+}
+
+// rtInteger returns x as an int64 if it holds an integer in its range.
+func rtInteger(x float64) (int64, bool) {
+	if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(x), true
+}
+
+func rtNumbers(a, b any) (float64, float64, bool) {
+	x, ok := a.(float64)
+	if !ok {
+		return 0, 0, false
+	}
+	y, ok := b.(float64)
+	return x, y, ok
+}
+
+// rtUpdate adds delta to a local variable for "++" and "--", returning
+// the new value if prefix is set and the old one otherwise.
+func rtUpdate(p *any, delta float64, prefix bool, line, column int) any {
+	current := *p
+	*p = rtBinary("+", current, delta, line, column)
+	if prefix {
+		return *p
+	}
+	return current
+}
+
+// update is rtUpdate for a global variable, with the name at nameLine and
+// nameColumn.
+func (g *rtGlobal) update(delta float64, prefix bool, line, column, nameLine, nameColumn int) any {
+	current := g.get(nameLine, nameColumn)
+	updated := g.set(rtBinary("+", current, delta, line, column), nameLine, nameColumn)
+	if prefix {
+		return updated
+	}
+	return current
+}
+
+func rtAnd(left any, right func() any) any {
+	if !rtTruthy(left) {
+		return left
+	}
+	return right()
+}
+
+func rtOr(left any, right func() any) any {
+	if rtTruthy(left) {
+		return left
+	}
+	return right()
+}
+
+func rtCoalesce(left any, right func() any) any {
+	if left != nil {
+		return left
+	}
+	return right()
+}
+
+func rtConditional(condition any, thenBranch, elseBranch func() any) any {
+	if rtTruthy(condition) {
+		return thenBranch()
+	}
+	return elseBranch()
+}
+
+// Values
+
+// rtFloat parses the numbers Go has no literal for, such as "+Inf".
+func rtFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// rtStringify formats v the way print shows it.
+func rtStringify(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		if v {
+			return "nocap"
+		}
+		return "cap"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// rtTypeName returns the name of v's type as shown in error messages.
+func rtTypeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *rtClass:
+		return "class"
+	case *rtFunction, *rtNative:
+		return "function"
+	case *rtError:
+		return "error"
+	case *rtRange:
+		return "range"
+	case *rtList:
+		return "list"
+	case *rtMap:
+		return "map"
+	case *rtInstance:
+		return "instance"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// rtObject is a value with properties.
+type rtObject interface {
+	property(name string) (any, bool)
+}
+
+// rtGet reads the property name of object.
+func rtGet(object any, name string, line, column int) any {
+	o, ok := object.(rtObject)
+	if !ok {
+		rtFail(line, column, fmt.Sprintf("only objects have properties, got %s", rtTypeName(object)))
+	}
+	v, ok := o.property(name)
+	if !ok {
+		rtFail(line, column, fmt.Sprintf("undefined property '%s'", name))
+	}
+	return v
+}
+
+// rtSetProperty assigns v to the property name of object, which must be
+// an instance, returning v.
+func rtSetProperty(object any, name string, v any, line, column int) any {
+	instance, ok := object.(*rtInstance)
+	if !ok {
+		rtFail(line, column, fmt.Sprintf("only instances have fields, got %s", rtTypeName(object)))
+	}
+	instance.fields[name] = v
+	return v
+}
+
+// rtCompoundSetProperty applies op to the property name of object and
+// value, assigning the result, which it returns. Errors of op are reported
+// at line and column, those of the property at nameLine and nameColumn.
+func rtCompoundSetProperty(object any, name, op string, value func() any, line, column, nameLine, nameColumn int) any {
+	current := rtGet(object, name, nameLine, nameColumn)
+	return rtSetProperty(object, name, rtBinary(op, current, value(), line, column), nameLine, nameColumn)
+}
+
+// rtUpdateProperty is rtUpdate for the property name of object.
+func rtUpdateProperty(object any, name string, delta float64, prefix bool, line, column, nameLine, nameColumn int) any {
+	current := rtGet(object, name, nameLine, nameColumn)
+	updated := rtSetProperty(object, name, rtBinary("+", current, delta, line, column), nameLine, nameColumn)
+	if prefix {
+		return updated
+	}
+	return current
+}
+
+// rtError is an error value, made by the error builtin or by catching an
+// error.
+type rtError struct {
+	class   string
+	message string
+	// raised is the runtime error raising it
+	raised *RuntimeError
+}
+
+func (e *rtError) String() string {
+	return e.class + ": " + e.message
+}
+
+func (e *rtError) property(name string) (any, bool) {
+	switch name {
+	case "message":
+		return e.message, true
+	case "class":
+		return e.class, true
+	case "stack":
+		if e.raised == nil {
+			return nil, true
+		}
+		lines := make([]string, len(e.raised.Stack))
+		for i, frame := range e.raised.Stack {
+			lines[i] = frame.String()
+		}
+		return strings.Join(lines, "\n"), true
+	}
+	return nil, false
+}
+
+// rtList is a list, made by a list literal.
+type rtList struct {
+	elements []any
+}
+
+func rtNewList(elements ...any) *rtList {
+	return &rtList{elements: elements}
+}
+
+func (l *rtList) String() string {
+	return l.format(make(map[any]bool))
+}
+
+// format writes l, printing "[...]" for l itself if it contains itself,
+// and the lists and maps in printing the same way.
+func (l *rtList) format(printing map[any]bool) string {
+	if printing[l] {
+		return "[...]"
+	}
+	printing[l] = true
+	defer delete(printing, l)
+	parts := make([]string, len(l.elements))
+	for i, element := range l.elements {
+		parts[i] = rtStringifyIn(element, printing)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// rtStringifyIn is rtStringify for a value contained in the lists and maps
+// being printed.
+func rtStringifyIn(v any, printing map[any]bool) string {
+	switch v := v.(type) {
+	case *rtList:
+		return v.format(printing)
+	case *rtMap:
+		return v.format(printing)
+	}
+	return rtStringify(v)
+}
+
+// element returns the position of the element index refers to in l.
+func (l *rtList) element(index any, line, column int) int {
+	n, ok := index.(float64)
+	if !ok || n != math.Trunc(n) {
+		rtFail(line, column, fmt.Sprintf("list index must be an integer, got %s", rtStringify(index)))
+	}
+	if n < 0 || n >= float64(len(l.elements)) {
+		rtFail(line, column, fmt.Sprintf("list index %s out of range for length %d", rtStringify(n), len(l.elements)))
+	}
+	return int(n)
+}
+
+// rtMap is a map, made by a map literal, which keeps its keys in the order
+// they were added. Its entries with a string key are its properties.
+type rtMap struct {
+	keys    []any
+	entries map[any]any
+}
+
+// rtNewMap makes the map of a literal, whose keys and values alternate in
+// entries.
+func rtNewMap(line, column int, entries ...any) *rtMap {
+	m := &rtMap{entries: map[any]any{}}
+	for i := 0; i < len(entries); i += 2 {
+		m.set(entries[i], entries[i+1], line, column)
+	}
+	return m
+}
+
+func (m *rtMap) set(key, v any, line, column int) {
+	switch key := key.(type) {
+	case nil, bool, string:
+	case float64:
+		if math.IsNaN(key) {
+			rtFail(line, column, "map keys can't be NaN")
+		}
+	default:
+		rtFail(line, column, fmt.Sprintf("map keys must be nil, booleans, numbers or strings, got %s", rtTypeName(key)))
+	}
+	if _, ok := m.entries[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.entries[key] = v
+}
+
+func (m *rtMap) property(name string) (any, bool) {
+	v, ok := m.entries[name]
+	return v, ok
+}
+
+func (m *rtMap) String() string {
+	return m.format(make(map[any]bool))
+}
+
+// format writes m as rtList.format does, printing "{...}" for m itself.
+func (m *rtMap) format(printing map[any]bool) string {
+	if printing[m] {
+		return "{...}"
+	}
+	printing[m] = true
+	defer delete(printing, m)
+	parts := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		parts[i] = rtStringify(key) + ": " + rtStringifyIn(m.entries[key], printing)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// rtIndex reads object[index].
+func rtIndex(object, index any, line, column int) any {
+	switch object := object.(type) {
+	case *rtList:
+		return object.elements[object.element(index, line, column)]
+	case *rtMap:
+		return object.entries[index]
+	}
+	rtFail(line, column, fmt.Sprintf("can only index lists and maps, got %s", rtTypeName(object)))
+	return nil
+}
+
+// rtSetIndex assigns v to object[index], returning v.
+func rtSetIndex(object, index, v any, line, column int) any {
+	switch object := object.(type) {
+	case *rtList:
+		object.elements[object.element(index, line, column)] = v
+	case *rtMap:
+		object.set(index, v, line, column)
+	default:
+		rtFail(line, column, fmt.Sprintf("can only index lists and maps, got %s", rtTypeName(object)))
+	}
+	return v
+}
+
+// rtCompoundSetIndex applies op to object[index] and value, assigning the
+// result, which it returns. Errors of op are reported at line and column,
+// those of indexing at bracketLine and bracketColumn.
+func rtCompoundSetIndex(object, index any, op string, value func() any, line, column, bracketLine, bracketColumn int) any {
+	current := rtIndex(object, index, bracketLine, bracketColumn)
+	return rtSetIndex(object, index, rtBinary(op, current, value(), line, column), bracketLine, bracketColumn)
+}
+
+// rtUpdateIndex is rtUpdate for object[index].
+func rtUpdateIndex(object, index any, delta float64, prefix bool, line, column, bracketLine, bracketColumn int) any {
+	current := rtIndex(object, index, bracketLine, bracketColumn)
+	updated := rtSetIndex(object, index, rtBinary("+", current, delta, line, column), bracketLine, bracketColumn)
+	if prefix {
+		return updated
+	}
+	return current
+}
+
+// Classes
+
+// rtInitMethod is the name of the method initializing instances.
+const rtInitMethod = "init"
+
+// rtClass is a class of the program. Its methods return the function
+// calling them on an instance.
+type rtClass struct {
+	name    string
+	methods map[string]func(this any) *rtFunction
+}
+
+func rtNewClass(name string, methods map[string]func(this any) *rtFunction) *rtClass {
+	return &rtClass{name: name, methods: methods}
+}
+
+func (c *rtClass) String() string {
+	return fmt.Sprintf("<class %s>", c.name)
+}
+
+// rtInstance is an object made by calling a class.
+type rtInstance struct {
+	class  *rtClass
+	fields map[string]any
+}
+
+func (i *rtInstance) property(name string) (any, bool) {
+	if v, ok := i.fields[name]; ok {
+		return v, true
+	}
+	if method, ok := i.class.methods[name]; ok {
+		return method(i), true
+	}
+	return nil, false
+}
+
+func (i *rtInstance) String() string {
+	return fmt.Sprintf("<%s instance>", i.class.name)
+}
+
+// Errors
+
+// rtThrow raises v, or an error of class Error with v as its message if
+// it isn't an error.
+func rtThrow(v any, line, column int) {
+	e, ok := v.(*rtError)
+	if !ok {
+		e = &rtError{class: "Error", message: rtStringify(v)}
+	}
+	if e.raised == nil {
+		e.raised = &RuntimeError{Line: line, Column: column, Message: e.String(), Stack: rtStack(line, column), thrown: e}
+	}
+	panic(e.raised)
+}
+
+// rtCaught returns the error value handed to a catch clause for err.
+func rtCaught(err *RuntimeError) *rtError {
+	if err.thrown != nil {
+		return err.thrown
+	}
+	return &rtError{class: "RuntimeError", message: err.Message, raised: err}
+}
+
+// rtTry runs a try statement. Each clause returns whether it returned from
+// the enclosing function, with the value returned; catch and finally are
+// nil when the statement doesn't have them.
+func rtTry(body func() (bool, any), catch func(e any) (bool, any), finally func() (bool, any)) (bool, any) {
+	frames := len(rtFrames)
+	returned, result, r := rtProtect(body)
+	if err, ok := r.(*RuntimeError); ok && catch != nil {
+		rtFrames = rtFrames[:frames]
+		returned, result, r = rtProtect(func() (bool, any) {
+			return catch(rtCaught(err))
+		})
+	}
+	if finally != nil {
+		if _, ok := r.(*RuntimeError); r != nil && !ok {
+			panic(r)
+		}
+		rtFrames = rtFrames[:frames]
+		if finallyReturned, v := finally(); finallyReturned {
+			return true, v
+		}
+	}
+	if r != nil {
+		panic(r)
+	}
+	return returned, result
+}
+
+// rtProtect runs f, returning the panic stopping it.
+func rtProtect(f func() (bool, any)) (returned bool, result any, r any) {
+	defer func() {
+		r = recover()
+	}()
+	returned, result = f()
+	return returned, result, nil
+}
+
+// Match expressions
+
+// rtBind sets a variable bound by a pattern to v, matching.
+func rtBind(p *any, v any) bool {
+	*p = v
+	return true
+}
+
+// rtProperty matches the property name of v with match, the rest of an
+// object pattern.
+func rtProperty(v any, name string, match func(property any) bool) bool {
+	object, ok := v.(rtObject)
+	if !ok {
+		return false
+	}
+	property, ok := object.property(name)
+	return ok && match(property)
+}
+
+// rtIsInstance reports whether v is an instance of the class called name.
+func rtIsInstance(v any, name string) bool {
+	instance, ok := v.(*rtInstance)
+	return ok && instance.class.name == name
+}
+
+// rtElements matches the elements of v, which must be a list of count
+// elements, or at least count with rest set, with match, the rest of a
+// list pattern. The elements beyond count are passed in a list of their
+// own.
+func rtElements(v any, count int, rest bool, match func(elements []any, rest *rtList) bool) bool {
+	list, ok := v.(*rtList)
+	if !ok || len(list.elements) < count || !rest && len(list.elements) != count {
+		return false
+	}
+	var restList *rtList
+	if rest {
+		restList = rtNewList(append([]any(nil), list.elements[count:]...)...)
+	}
+	return match(list.elements, restList)
+}
+
+// rtIsObject reports whether v has properties, matching "{}".
+func rtIsObject(v any) bool {
+	_, ok := v.(rtObject)
+	return ok
+}
+
+// rtNoMatch raises the error of a match expression none of whose arms
+// matches v.
+func rtNoMatch(v any, line, column int) {
+	rtFail(line, column, fmt.Sprintf("no match arm matches %s", rtStringify(v)))
+}
+
+// Iteration
+
+// rtIterate returns the function producing the values a for-in loop over v
+// goes through, until it returns false.
+func rtIterate(v any, line, column int) func() (any, bool) {
+	switch v := v.(type) {
+	case string:
+		offset := 0
+		return func() (any, bool) {
+			if offset >= len(v) {
+				return nil, false
+			}
+			_, size := utf8.DecodeRuneInString(v[offset:])
+			c := v[offset : offset+size]
+			offset += size
+			return c, true
+		}
+	case *rtRange:
+		index, count := 0.0, v.count()
+		return func() (any, bool) {
+			if index >= count {
+				return nil, false
+			}
+			n := v.start + index*v.step
+			index++
+			return n, true
+		}
+	case *rtList:
+		index := 0
+		return func() (any, bool) {
+			if index >= len(v.elements) {
+				return nil, false
+			}
+			index++
+			return v.elements[index-1], true
+		}
+	case *rtMap:
+		index := 0
+		return func() (any, bool) {
+			if index >= len(v.keys) {
+				return nil, false
+			}
+			index++
+			return v.keys[index-1], true
+		}
+	case *rtFunction, *rtNative:
+		return rtCalls(v, line, column)
+	case rtObject:
+		if next, ok := v.property("next"); ok {
+			return rtCalls(next, line, column)
+		}
+		if method, ok := v.property("iterator"); ok {
+			it := rtCall(method, nil, line, column)
+			// a loop goes through the keys of a map
+			if object, ok := it.(rtObject); ok && !rtIsMap(it) {
+				if _, ok := object.property("next"); !ok {
+					rtFail(line, column, fmt.Sprintf("method iterator must return an iterator, got %s", rtTypeName(it)))
+				}
+			}
+			return rtIterate(it, line, column)
+		}
+	}
+	rtFail(line, column, fmt.Sprintf("can't iterate over %s", rtTypeName(v)))
+	return nil
+}
+
+// rtIsMap reports whether v is a map, which is an rtObject too.
+func rtIsMap(v any) bool {
+	_, ok := v.(*rtMap)
+	return ok
+}
+
+// rtCalls returns the function producing the values function returns
+// when called with no arguments, until it returns nil.
+func rtCalls(function any, line, column int) func() (any, bool) {
+	return func() (any, bool) {
+		next := rtCall(function, nil, line, column)
+		return next, next != nil
+	}
+}
+
+// rtRange is the sequence of numbers made by the range builtin.
+type rtRange struct {
+	start, end, step float64
+}
+
+func (r *rtRange) count() float64 {
+	n := math.Ceil((r.end - r.start) / r.step)
+	if n <= 0 || math.IsNaN(n) {
+		return 0
+	}
+	return n
+}
+
+func (r *rtRange) String() string {
+	return fmt.Sprintf("<range %s..%s step %s>", rtStringify(r.start), rtStringify(r.end), rtStringify(r.step))
+}
+
+// Builtins
+
+var rtBuiltins = []*rtNative{
+	{name: "print", arity: -1, fn: func(args []any) (any, error) {
+		parts := make([]string, len(args))
+		for i, arg := range args {
+			parts[i] = rtStringify(arg)
+		}
+		_, err := fmt.Fprintln(rtOut, strings.Join(parts, " "))
+		return nil, err
+	}},
+	{name: "clock", arity: 0, fn: func(args []any) (any, error) {
+		return float64(time.Now().UnixNano()) / float64(time.Second), nil
+	}},
+	{name: "error", arity: -1, fn: func(args []any) (any, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("error expects 1 or 2 arguments but got %d", len(args))
+		}
+		class := "Error"
+		if len(args) == 2 {
+			name, ok := args[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("error class must be a string, got %s", rtTypeName(args[1]))
+			}
+			class = name
+		}
+		return &rtError{class: class, message: rtStringify(args[0])}, nil
+	}},
+	{name: "range", arity: -1, fn: func(args []any) (any, error) {
+		if len(args) < 1 || len(args) > 3 {
+			return nil, fmt.Errorf("range expects 1 to 3 arguments but got %d", len(args))
+		}
+		bounds := []float64{0, 0, 1}
+		for i, arg := range args {
+			n, ok := arg.(float64)
+			if !ok {
+				return nil, fmt.Errorf("range bounds must be numbers, got %s", rtTypeName(arg))
+			}
+			bounds[i] = n
+		}
+		if len(args) == 1 {
+			bounds[0], bounds[1] = 0, bounds[0]
+		}
+		if bounds[2] == 0 {
+			return nil, errors.New("range step can't be 0")
+		}
+		return &rtRange{start: bounds[0], end: bounds[1], step: bounds[2]}, nil
+	}},
+	{name: "len", arity: 1, fn: func(args []any) (any, error) {
+		switch v := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(v)), nil
+		case *rtList:
+			return float64(len(v.elements)), nil
+		case *rtMap:
+			return float64(len(v.keys)), nil
+		}
+		return nil, fmt.Errorf("len expects a string, list or map, got %s", rtTypeName(args[0]))
+	}},
+	{name: "push", arity: 2, fn: func(args []any) (any, error) {
+		list, ok := args[0].(*rtList)
+		if !ok {
+			return nil, fmt.Errorf("push expects a list, got %s", rtTypeName(args[0]))
+		}
+		list.elements = append(list.elements, args[1])
+		return nil, nil
+	}},
+}
@@ -0,0 +1,89 @@
+// Package golden checks the output of test programs against expectation
+// files kept next to them: testdata/foo.rot is expected to produce
+// testdata/foo.tokens, foo.ast, foo.out or foo.err, depending on the test.
+//
+// After a deliberate change to the output, rewrite the files with
+//
+//	go test ./pkg/scanner ./pkg/parser ./pkg/rottenlang -update
+//
+// and review the diff.
+package golden
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the actual output")
+
+// Programs returns the rottenlang programs in dir, failing t if there are
+// none.
+func Programs(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.rot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no programs in %s", dir)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Check compares got with the file expecting the output of program with
+// the extension ext, such as ".out". A missing file expects no output; with
+// -update, empty output removes the file rather than writing it empty.
+func Check(t *testing.T, program, ext, got string) {
+	t.Helper()
+	path := strings.TrimSuffix(program, filepath.Ext(program)) + ext
+
+	if *update {
+		var err error
+		if got == "" {
+			err = os.Remove(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = os.WriteFile(path, []byte(got), 0o644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from the actual output (rerun with -update to accept it)\n%s", path, diff(string(want), got))
+	}
+}
+
+// diff describes where got first departs from want, line by line.
+func diff(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	for i := 0; ; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) || i >= len(gotLines) {
+			return fmt.Sprintf("line %d:\n  want: %s\n  got:  %s", i+1, w, g)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+// Environment maps variable names to values for one scope.
+type Environment struct {
+	values    map[string]any
+	constants map[string]bool
+	enclosing *Environment
+}
+
+func NewEnvironment(enclosing *Environment) *Environment {
+	return &Environment{
+		values:    make(map[string]any),
+		constants: make(map[string]bool),
+		enclosing: enclosing,
+	}
+}
+
+// Define binds name in this scope, shadowing any outer binding.
+func (e *Environment) Define(name string, value any, constant bool) {
+	e.values[name] = value
+	if constant {
+		e.constants[name] = true
+	} else {
+		delete(e.constants, name)
+	}
+}
+
+func (e *Environment) Get(name *ast.Token) (any, error) {
+	if value, ok := e.lookup(*name.Lexeme); ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("undefined variable '%s'", *name.Lexeme)
+}
+
+// lookup returns the value bound to name in this scope or an enclosing one.
+func (e *Environment) lookup(name string) (any, bool) {
+	for env := e; env != nil; env = env.enclosing {
+		if value, ok := env.values[name]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func (e *Environment) Assign(name *ast.Token, value any) error {
+	for env := e; env != nil; env = env.enclosing {
+		if _, ok := env.values[*name.Lexeme]; ok {
+			if env.constants[*name.Lexeme] {
+				return fmt.Errorf("cannot assign to constant '%s'", *name.Lexeme)
+			}
+			env.values[*name.Lexeme] = value
+			return nil
+		}
+	}
+	return fmt.Errorf("undefined variable '%s'", *name.Lexeme)
+}
@@ -0,0 +1,78 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// Function is a user defined function closed over the environment it was
+// declared in.
+type Function struct {
+	declaration *ast.FunctionStmt
+	closure     *Environment
+	// file holds the declaration
+	file string
+	// initializer is set for the init method of a class, whose calls
+	// return the instance
+	initializer bool
+}
+
+func NewFunction(declaration *ast.FunctionStmt, closure *Environment) *Function {
+	return &Function{
+		declaration: declaration,
+		closure:     closure,
+	}
+}
+
+func (f *Function) Arity() int {
+	return len(f.declaration.Params())
+}
+
+func (f *Function) Name() string {
+	return *f.declaration.Name().Lexeme
+}
+
+func (f *Function) String() string {
+	return fmt.Sprintf("<fn %s>", f.Name())
+}
+
+// thisName is the name "this" is bound to in the scope of a method, which
+// no variable can have since it is a keyword.
+const thisName = "this"
+
+// bind returns the method f called on instance, which it sees as "this".
+func (f *Function) bind(instance *value.Instance) *Function {
+	environment := NewEnvironment(f.closure)
+	environment.Define(thisName, instance, true)
+	return &Function{declaration: f.declaration, closure: environment, file: f.file, initializer: f.initializer}
+}
+
+// returnValue unwinds the Go stack from a "purrr" statement back to the call
+// that is returning.
+type returnValue struct {
+	value any
+}
+
+func (f *Function) call(interpreter *Interpreter, arguments []any) (result any) {
+	environment := NewEnvironment(f.closure)
+	for i, param := range f.declaration.Params() {
+		environment.Define(*param.Lexeme, arguments[i], false)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			ret, ok := r.(*returnValue)
+			if !ok {
+				panic(r)
+			}
+			result = ret.value
+		}
+		if f.initializer {
+			result, _ = f.closure.lookup(thisName)
+		}
+	}()
+	interpreter.executeBlock(f.declaration.Body(), environment)
+	return nil
+}
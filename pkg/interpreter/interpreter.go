@@ -0,0 +1,1288 @@
+// Package interpreter executes programs by walking their syntax tree.
+package interpreter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/debugger"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/profiler"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+type Interpreter struct {
+	// builtins are defined in the global scope of the program and of every
+	// module
+	builtins      []*value.Native
+	importer      value.Importer
+	globals       *Environment
+	environment   *Environment
+	errorReporter errorreporter.ErrorReporter
+	callDepth     int
+	// frames are the calls in progress, for the stack of runtime errors
+	frames []frame
+	// meter is shared with the interpreters of the program's tasks
+	meter     *value.Meter
+	scheduler *value.Scheduler
+	// location is the latest token reached, where execution is reported to
+	// stop when it runs out of steps
+	location *ast.Token
+	// running counts the active calls to Interpret, Evaluate and Call, which
+	// nest when a native function calls back into the interpreter
+	running int
+	// yield suspends the generator whose body runs on the interpreter
+	yield func(v any) error
+	// file is the file of the code running, as shown to the debugger
+	file     string
+	debugger *debugger.Debugger
+	// profile follows the calls on the interpreter for the profiler, when
+	// the program is profiled
+	profiler *profiler.Profiler
+	profile  *profiler.Stack
+}
+
+// frame is a call in progress, of a function or of the top level code of a
+// program or module.
+type frame struct {
+	function string
+	// call is the token calling the function, where the caller is, or nil
+	call *ast.Token
+	// file holds the code of the function, and caller is the scope the
+	// caller was in
+	file   string
+	caller *Environment
+}
+
+// script is the name of the top level code in stack traces.
+const script = "<script>"
+
+// NewInterpreter creates an interpreter whose builtins, such as print, write
+// to out.
+func NewInterpreter(errorReporter errorreporter.ErrorReporter, out io.Writer) *Interpreter {
+	i := &Interpreter{
+		errorReporter: errorReporter,
+		meter:         &value.Meter{},
+	}
+	i.scheduler = value.NewScheduler(i.position)
+	i.builtins = append(value.Builtins(out), i.scheduler.Builtins()...)
+	i.globals = i.newGlobals()
+	i.environment = i.globals
+	return i
+}
+
+// newGlobals creates a global scope holding the builtins.
+func (i *Interpreter) newGlobals() *Environment {
+	globals := NewEnvironment(nil)
+	for _, native := range i.builtins {
+		globals.Define(native.Name(), native, false)
+	}
+	return globals
+}
+
+// SetImporter sets the importer loading the modules of import statements.
+func (i *Interpreter) SetImporter(importer value.Importer) {
+	i.importer = importer
+}
+
+func (i *Interpreter) Globals() *Environment {
+	return i.globals
+}
+
+// DefineGlobal binds name to v in the global scope.
+func (i *Interpreter) DefineGlobal(name string, v any) {
+	i.globals.Define(name, v, false)
+}
+
+// Global returns the value of the global variable name.
+func (i *Interpreter) Global(name string) (any, bool) {
+	v, ok := i.globals.values[name]
+	return v, ok
+}
+
+// SetLimits bounds the resources used by the following programs.
+func (i *Interpreter) SetLimits(limits value.Limits) {
+	i.meter.SetLimits(limits)
+}
+
+// SetContext makes the following programs stop with a runtime error once
+// ctx is done.
+func (i *Interpreter) SetContext(ctx context.Context) {
+	i.meter.SetContext(ctx)
+}
+
+// SetFile names the file the following programs are read from.
+func (i *Interpreter) SetFile(file string) {
+	i.file = file
+}
+
+// SetDebugger makes the following programs stop wherever debugger wants
+// them to.
+func (i *Interpreter) SetDebugger(debugger *debugger.Debugger) {
+	i.debugger = debugger
+}
+
+// SetProfiler makes profiler record the following programs.
+func (i *Interpreter) SetProfiler(profiler *profiler.Profiler) {
+	i.profiler = profiler
+	i.profile = nil
+	if profiler != nil {
+		i.profile = profiler.NewStack()
+	}
+}
+
+// Steps returns the number of steps taken by the latest program.
+func (i *Interpreter) Steps() int64 {
+	return i.meter.Steps()
+}
+
+// Interpret executes statements in the global scope. A runtime error stops
+// execution; it is reported to the error reporter and returned.
+func (i *Interpreter) Interpret(statements []ast.Stmt) (err error) {
+	i.enter()
+	defer i.recoverRuntimeError(&err)
+	defer i.pushFrame(script, nil)()
+
+	for _, stmt := range statements {
+		i.execute(stmt)
+	}
+	i.wait()
+	return nil
+}
+
+// enter starts a call to an entry point. The outermost one starts a new
+// program, whose steps are counted from zero.
+func (i *Interpreter) enter() {
+	i.running++
+	if i.running == 1 {
+		i.meter.Reset()
+	}
+}
+
+// recoverRuntimeError turns a runtime error panic into *err, reporting it
+// and resetting the interpreter so the next program starts from the global
+// scope. It must be deferred by every entry point after calling enter.
+func (i *Interpreter) recoverRuntimeError(err *error) {
+	i.running--
+	r := recover()
+	if r == nil {
+		return
+	}
+	runtimeErr, ok := r.(*value.RuntimeError)
+	if !ok {
+		panic(r)
+	}
+	if i.running > 0 {
+		// the outermost entry point reports the error once the native
+		// function that called back into the interpreter returns it
+		*err = runtimeErr
+		return
+	}
+	i.environment = i.globals
+	i.callDepth = 0
+	i.frames = i.frames[:0]
+	i.scheduler.Stop()
+	i.errorReporter.ReportRuntimeError(runtimeErr.Line, runtimeErr.Column, runtimeErr.Message+runtimeErr.Trace())
+	*err = runtimeErr
+}
+
+// InterpretInteractive executes statements like Interpret, and returns the
+// value of the final statement when it is an expression statement. The REPL
+// uses it to show results.
+func (i *Interpreter) InterpretInteractive(statements []ast.Stmt) (result any, err error) {
+	if len(statements) == 0 {
+		return nil, nil
+	}
+	last, ok := statements[len(statements)-1].(*ast.ExpressionStmt)
+	if !ok {
+		return nil, i.Interpret(statements)
+	}
+
+	i.enter()
+	defer i.recoverRuntimeError(&err)
+	defer i.pushFrame(script, nil)()
+	for _, stmt := range statements[:len(statements)-1] {
+		i.execute(stmt)
+	}
+	result = i.evaluate(last.Expression())
+	i.wait()
+	return result, nil
+}
+
+// ExecuteModule executes the statements of a module, read from file, in a
+// global scope of its own, returning a function looking up the module's
+// globals. Importers use it while the import statement runs.
+func (i *Interpreter) ExecuteModule(file string, statements []ast.Stmt) (lookup func(name string) (any, bool), err error) {
+	i.enter()
+	defer i.recoverRuntimeError(&err)
+	previous, previousFile := i.environment, i.file
+	defer func() {
+		i.environment, i.file = previous, previousFile
+	}()
+	i.file = file
+	defer i.pushFrame(script, i.location)()
+
+	globals := i.newGlobals()
+	i.environment = globals
+	for _, stmt := range statements {
+		i.execute(stmt)
+	}
+	return func(name string) (any, bool) {
+		v, ok := globals.values[name]
+		return v, ok
+	}, nil
+}
+
+// Evaluate evaluates expr in the current scope.
+func (i *Interpreter) Evaluate(expr ast.Expr) (result any, err error) {
+	i.enter()
+	defer i.recoverRuntimeError(&err)
+	return i.evaluate(expr), nil
+}
+
+// Call calls callee, a function value, with arguments given from Go. Native
+// functions may use it to call back into the running program.
+func (i *Interpreter) Call(callee any, arguments []any) (result any, err error) {
+	i.enter()
+	defer i.recoverRuntimeError(&err)
+	// a call from outside the program has no place in it to report errors
+	// at, while a native function calling back is at its own call
+	call := i.location
+	if call == nil || i.running == 1 {
+		call = ast.EOF
+	}
+	result = i.call(call, callee, arguments)
+	i.wait()
+	return result, nil
+}
+
+// wait runs the tasks the program spawned until they all end, once the
+// outermost entry point is done with the program itself.
+func (i *Interpreter) wait() {
+	if i.running > 1 {
+		return
+	}
+	if err := i.scheduler.Wait(); err != nil {
+		panic(err)
+	}
+}
+
+// fork returns an interpreter for a task of the program, sharing its
+// globals and resources but with a call stack of its own.
+func (i *Interpreter) fork() *Interpreter {
+	f := &Interpreter{
+		builtins:      i.builtins,
+		importer:      i.importer,
+		globals:       i.globals,
+		environment:   i.globals,
+		errorReporter: i.errorReporter,
+		meter:         i.meter,
+		scheduler:     i.scheduler,
+		file:          i.file,
+		debugger:      i.debugger,
+	}
+	f.SetProfiler(i.profiler)
+	return f
+}
+
+// runTask calls callee as the body of a task, returning the runtime error
+// ending it instead of reporting it: the program reports it, if it stops
+// the program.
+func (i *Interpreter) runTask(paren *ast.Token, callee any, arguments []any) (result any, err error) {
+	// calls back into the interpreter nest in the task
+	i.running++
+	defer func() {
+		i.running--
+		if r := recover(); r != nil {
+			runtimeErr, ok := r.(*value.RuntimeError)
+			if !ok {
+				panic(r)
+			}
+			err = runtimeErr
+		}
+	}()
+	return i.call(paren, callee, arguments), nil
+}
+
+// generator returns the generator of a call of f, a generator function,
+// whose body runs on an interpreter of its own.
+func (i *Interpreter) generator(paren *ast.Token, f *Function, arguments []any) *value.Generator {
+	return value.NewGenerator(f.Name(), func(yield func(v any) error) (err error) {
+		body := i.fork()
+		body.yield = yield
+		body.file = f.file
+		// calls back into the interpreter nest in the body
+		body.running++
+		defer func() {
+			body.running--
+			if r := recover(); r != nil {
+				runtimeErr, ok := r.(*value.RuntimeError)
+				if !ok {
+					panic(r)
+				}
+				err = runtimeErr
+			}
+		}()
+		defer body.pushFrame(f.Name(), paren)()
+		f.call(body, arguments)
+		return nil
+	})
+}
+
+// position returns where execution is, reported when the task running on
+// i blocks.
+func (i *Interpreter) position() (line, column int) {
+	if i.location == nil {
+		return ast.EOF.Line, ast.EOF.Column
+	}
+	return i.location.Line, i.location.Column
+}
+
+// pushFrame records the start of a call, returning the function recording
+// its end.
+func (i *Interpreter) pushFrame(function string, call *ast.Token) func() {
+	if i.profile != nil {
+		i.profile.Call(len(i.frames), function, i.file)
+	}
+	i.frames = append(i.frames, frame{function: function, call: call, file: i.file, caller: i.environment})
+	return func() {
+		i.frames = i.frames[:len(i.frames)-1]
+	}
+}
+
+// stackTrace returns the calls in progress, innermost first, with the
+// innermost one at line and column.
+func (i *Interpreter) stackTrace(line, column int) []value.StackFrame {
+	stack := make([]value.StackFrame, 0, len(i.frames))
+	for j := len(i.frames) - 1; j >= 0; j-- {
+		stack = append(stack, value.StackFrame{Function: i.frames[j].function, Line: line, Column: column})
+		if call := i.frames[j].call; call != nil {
+			line, column = call.Line, call.Column
+		}
+	}
+	return stack
+}
+
+func (i *Interpreter) execute(stmt ast.Stmt) {
+	i.step(stmtToken(stmt))
+	if i.debugger != nil {
+		i.debug(stmt)
+	}
+	ast.AcceptStmt[any](stmt, i)
+}
+
+// debug hands the debugger the statement about to run, to stop before it.
+func (i *Interpreter) debug(stmt ast.Stmt) {
+	if _, ok := stmt.(*ast.BlockStmt); ok {
+		// the statements of the block stop it instead
+		return
+	}
+	token := ast.LeadingToken(stmt)
+	if token == nil {
+		return
+	}
+	position := debugger.Position{File: i.file, Line: token.Line, Column: startColumn(token)}
+	if err := i.debugger.Statement(position, &debugTarget{i: i, position: position}); err != nil {
+		panic(i.wrapError(token, err))
+	}
+}
+
+// debugTarget shows the debugger the program stopped on an interpreter.
+type debugTarget struct {
+	i        *Interpreter
+	position debugger.Position
+}
+
+func (t *debugTarget) Depth() int {
+	return len(t.i.frames)
+}
+
+func (t *debugTarget) Stack() []debugger.Frame {
+	i := t.i
+	stack := make([]debugger.Frame, 0, len(i.frames))
+	position, environment := t.position, i.environment
+	for j := len(i.frames) - 1; j >= 0; j-- {
+		position.File = i.frames[j].file
+		stack = append(stack, debugger.Frame{Function: i.frames[j].function, Position: position, Scopes: i.scopes(environment)})
+		if call := i.frames[j].call; call != nil {
+			position.Line, position.Column = call.Line, startColumn(call)
+		}
+		environment = i.frames[j].caller
+	}
+	return stack
+}
+
+// scopes returns the variables seen from environment: the locals, from
+// every scope but the global one, and the globals but the builtins.
+func (i *Interpreter) scopes(environment *Environment) []debugger.Scope {
+	locals := debugger.Scope{Name: "Locals", Variables: []debugger.Variable{}}
+	globals := debugger.Scope{Name: "Globals", Variables: []debugger.Variable{}}
+	seen := make(map[string]bool)
+	for env := environment; env != nil; env = env.enclosing {
+		scope := &locals
+		if env.enclosing == nil {
+			scope = &globals
+		}
+		for name, v := range env.values {
+			if seen[name] || env.enclosing == nil && i.isBuiltin(v) {
+				continue
+			}
+			seen[name] = true
+			scope.Variables = append(scope.Variables, debugger.Variable{Name: name, Value: v})
+		}
+	}
+	for _, scope := range []debugger.Scope{locals, globals} {
+		sort.Slice(scope.Variables, func(a, b int) bool {
+			return scope.Variables[a].Name < scope.Variables[b].Name
+		})
+	}
+	return []debugger.Scope{locals, globals}
+}
+
+func (i *Interpreter) isBuiltin(v any) bool {
+	for _, builtin := range i.builtins {
+		if v == any(builtin) {
+			return true
+		}
+	}
+	return false
+}
+
+// startColumn returns the column token starts at. Tokens record the column
+// of their last character.
+func startColumn(token *ast.Token) int {
+	return max(token.Column-len(*token.Lexeme)+1, 1)
+}
+
+func (i *Interpreter) evaluate(expr ast.Expr) any {
+	i.step(exprToken(expr))
+	return ast.Accept[any](expr, i)
+}
+
+// step charges a step to the meter, and to the profiler when there is one.
+// token, when known, becomes the location reported if execution stops.
+func (i *Interpreter) step(token *ast.Token) {
+	if token != nil {
+		i.location = token
+	}
+	if i.profile != nil {
+		line := 0
+		if token != nil {
+			line = token.Line
+		}
+		i.profile.Step(len(i.frames), line)
+	}
+	if err := i.meter.Step(); err != nil {
+		panic(i.wrapError(i.location, err))
+	}
+}
+
+// checkValue stops execution when v exceeds the memory limits. Values are
+// checked as operators and builtins make them, so the profiler counts the
+// allocations here.
+func (i *Interpreter) checkValue(token *ast.Token, v any) {
+	if i.profile != nil {
+		i.profile.Made(v)
+	}
+	if err := i.meter.CheckValue(v); err != nil {
+		panic(i.wrapError(token, err))
+	}
+}
+
+// checkGrown stops execution when one of values, lists and maps a builtin
+// or an assignment may have added to, grew past the memory limits since
+// their lengths were recorded. Unlike checkValue it doesn't count an
+// allocation.
+func (i *Interpreter) checkGrown(token *ast.Token, values []any, lengths []int) {
+	if err := i.meter.CheckGrown(values, lengths); err != nil {
+		panic(i.wrapError(token, err))
+	}
+}
+
+func (i *Interpreter) executeBlock(statements []ast.Stmt, environment *Environment) {
+	previous := i.environment
+	defer func() {
+		i.environment = previous
+	}()
+
+	i.environment = environment
+	for _, stmt := range statements {
+		i.execute(stmt)
+	}
+}
+
+func (i *Interpreter) runtimeError(token *ast.Token, message string) *value.RuntimeError {
+	err := value.NewRuntimeError(token.Line, token.Column, message)
+	err.Stack = i.stackTrace(token.Line, token.Column)
+	return err
+}
+
+// wrapError creates a runtime error at token caused by err. token may be nil
+// when no location is known.
+func (i *Interpreter) wrapError(token *ast.Token, err error) *value.RuntimeError {
+	if token == nil {
+		token = ast.EOF
+	}
+	runtimeErr := value.WrapRuntimeError(token.Line, token.Column, err)
+	runtimeErr.Stack = i.stackTrace(token.Line, token.Column)
+	return runtimeErr
+}
+
+// raise returns the runtime error for err, raised at token unless it is
+// one already: an error raised by a call back into the interpreter, or the
+// error of a task stopping the program. The error ending the body of a
+// generator gets the stack of the calls resuming it.
+func (i *Interpreter) raise(token *ast.Token, err error) *value.RuntimeError {
+	var resumeErr *value.ResumeError
+	if errors.As(err, &resumeErr) {
+		return resumeErr.Raise(i.stackTrace(token.Line, token.Column))
+	}
+	var runtimeErr *value.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr
+	}
+	return i.wrapError(token, err)
+}
+
+// Statements
+
+func (i *Interpreter) VisitExpressionStmt(stmt *ast.ExpressionStmt) any {
+	i.evaluate(stmt.Expression())
+	return nil
+}
+
+func (i *Interpreter) VisitVarStmt(stmt *ast.VarStmt) any {
+	var v any
+	if stmt.Initializer() != nil {
+		v = i.evaluate(stmt.Initializer())
+	}
+	i.environment.Define(*stmt.Name().Lexeme, v, stmt.Constant())
+	return nil
+}
+
+func (i *Interpreter) VisitImportStmt(stmt *ast.ImportStmt) any {
+	if i.importer == nil {
+		panic(i.runtimeError(stmt.Keyword(), "can't import modules here"))
+	}
+	module, err := i.importer.Import(stmt.Path().Literal.(string))
+	if err != nil {
+		panic(i.wrapError(stmt.Keyword(), err))
+	}
+	i.environment.Define(*stmt.Name().Lexeme, module, true)
+	return nil
+}
+
+func (i *Interpreter) VisitThrowStmt(stmt *ast.ThrowStmt) any {
+	thrown := i.evaluate(stmt.Value())
+	e, ok := thrown.(*value.Error)
+	if !ok {
+		e = value.NewError(value.ErrorClass, value.Stringify(thrown))
+	}
+	keyword := stmt.Keyword()
+	panic(e.Raise(keyword.Line, keyword.Column, i.stackTrace(keyword.Line, keyword.Column)))
+}
+
+func (i *Interpreter) VisitTryStmt(stmt *ast.TryStmt) any {
+	if stmt.Finally() != nil {
+		defer i.executeFinally(stmt.FinallyBody(), i.environment)
+	}
+	i.executeTry(stmt)
+	return nil
+}
+
+// executeTry runs the body of a try statement, and its catch clause if the
+// body raises an error that can be caught.
+func (i *Interpreter) executeTry(stmt *ast.TryStmt) {
+	environment, frames := i.environment, len(i.frames)
+	defer func() {
+		if stmt.CatchName() == nil {
+			return
+		}
+		r := recover()
+		if r == nil {
+			return
+		}
+		runtimeErr, ok := r.(*value.RuntimeError)
+		if !ok || !value.Catchable(runtimeErr) {
+			panic(r)
+		}
+		i.frames = i.frames[:frames]
+		catch := NewEnvironment(environment)
+		catch.Define(*stmt.CatchName().Lexeme, value.Caught(runtimeErr), false)
+		i.executeBlock(stmt.CatchBody(), catch)
+	}()
+	i.executeBlock(stmt.Body(), NewEnvironment(environment))
+}
+
+// executeFinally runs the finally clause of a try statement in environment,
+// then goes on with how the statement ended: it returns, or goes on raising
+// the error being raised or the value being returned. It must be deferred.
+func (i *Interpreter) executeFinally(body []ast.Stmt, environment *Environment) {
+	r := recover()
+	if err, ok := r.(*value.RuntimeError); ok && !value.Catchable(err) {
+		panic(r)
+	}
+	i.executeBlock(body, NewEnvironment(environment))
+	if r != nil {
+		panic(r)
+	}
+}
+
+// VisitSelectStmt runs the body of the first case to proceed, with the
+// value it received bound in a scope of its own, or the else branch when
+// no case can proceed at once.
+func (i *Interpreter) VisitSelectStmt(stmt *ast.SelectStmt) any {
+	channels := make([]any, len(stmt.Cases()))
+	cases := make([]value.SelectCase, len(stmt.Cases()))
+	for n, c := range stmt.Cases() {
+		channelExpr, send, valueExpr, _ := ast.SelectCase(c)
+		channels[n] = i.evaluate(channelExpr)
+		cases[n].Send = send
+		if send {
+			cases[n].Value = i.evaluate(valueExpr)
+		}
+	}
+	for n, channel := range channels {
+		ch, ok := channel.(*value.Channel)
+		if !ok {
+			panic(i.runtimeError(stmt.Keyword(), fmt.Sprintf("select case needs a channel, got %s", value.TypeName(channel))))
+		}
+		cases[n].Channel = ch
+	}
+
+	i.location = stmt.Keyword()
+	index, received, err := i.scheduler.Select(cases, stmt.ElseBranch() != nil)
+	if err != nil {
+		panic(i.raise(stmt.Keyword(), err))
+	}
+	if index < 0 {
+		i.execute(stmt.ElseBranch())
+		return nil
+	}
+	environment := NewEnvironment(i.environment)
+	if name := stmt.Names()[index]; name != nil {
+		environment.Define(*name.Lexeme, received, false)
+	}
+	i.executeBlock([]ast.Stmt{stmt.Bodies()[index]}, environment)
+	return nil
+}
+
+// VisitForInStmt runs the body for each value of the iterable, with the
+// value bound in a scope of its own.
+func (i *Interpreter) VisitForInStmt(stmt *ast.ForInStmt) any {
+	iterable := i.evaluate(stmt.Iterable())
+	i.location = stmt.Keyword()
+	iterator, err := value.Iterate(iterable, i.Call)
+	if err != nil {
+		panic(i.raise(stmt.Keyword(), err))
+	}
+	for {
+		i.location = stmt.Keyword()
+		v, ok, err := iterator.Next()
+		if err != nil {
+			panic(i.raise(stmt.Keyword(), err))
+		}
+		if !ok {
+			return nil
+		}
+		environment := NewEnvironment(i.environment)
+		environment.Define(*stmt.Name().Lexeme, v, false)
+		i.executeBlock([]ast.Stmt{stmt.Body()}, environment)
+		i.debugLoop(stmt)
+	}
+}
+
+func (i *Interpreter) VisitYieldStmt(stmt *ast.YieldStmt) any {
+	v := i.evaluate(stmt.Value())
+	i.location = stmt.Keyword()
+	if err := i.yield(v); err != nil {
+		panic(i.wrapError(stmt.Keyword(), err))
+	}
+	return nil
+}
+
+func (i *Interpreter) VisitBlockStmt(stmt *ast.BlockStmt) any {
+	i.executeBlock(stmt.Statements(), NewEnvironment(i.environment))
+	return nil
+}
+
+func (i *Interpreter) VisitIfStmt(stmt *ast.IfStmt) any {
+	if value.IsTruthy(i.evaluate(stmt.Condition())) {
+		i.execute(stmt.ThenBranch())
+	} else if stmt.ElseBranch() != nil {
+		i.execute(stmt.ElseBranch())
+	}
+	return nil
+}
+
+func (i *Interpreter) VisitWhileStmt(stmt *ast.WhileStmt) any {
+	for value.IsTruthy(i.evaluate(stmt.Condition())) {
+		i.execute(stmt.Body())
+		i.debugLoop(stmt)
+	}
+	return nil
+}
+
+// debugLoop stops the debugger again on the loop, after each run of its
+// body: a loop whose body has no statements is paused or terminated there.
+func (i *Interpreter) debugLoop(loop ast.Stmt) {
+	if i.debugger != nil {
+		i.debug(loop)
+	}
+}
+
+func (i *Interpreter) VisitFunctionStmt(stmt *ast.FunctionStmt) any {
+	function := NewFunction(stmt, i.environment)
+	function.file = i.file
+	if i.profile != nil {
+		i.profile.Allocate()
+	}
+	i.environment.Define(*stmt.Name().Lexeme, function, false)
+	return nil
+}
+
+// VisitClassStmt declares the class, whose methods close over the scope
+// it is declared in.
+func (i *Interpreter) VisitClassStmt(stmt *ast.ClassStmt) any {
+	methods := make(map[string]value.Callable, len(stmt.Methods()))
+	for _, method := range stmt.Methods() {
+		declaration := method.(*ast.FunctionStmt)
+		function := NewFunction(declaration, i.environment)
+		function.file = i.file
+		function.initializer = *declaration.Name().Lexeme == value.InitMethod
+		methods[*declaration.Name().Lexeme] = function
+	}
+	if i.profile != nil {
+		// the methods, then the class
+		for n := 0; n <= len(methods); n++ {
+			i.profile.Allocate()
+		}
+	}
+	i.environment.Define(*stmt.Name().Lexeme, value.NewClass(*stmt.Name().Lexeme, methods), false)
+	return nil
+}
+
+func (i *Interpreter) VisitReturnStmt(stmt *ast.ReturnStmt) any {
+	var v any
+	if stmt.Value() != nil {
+		v = i.evaluate(stmt.Value())
+	}
+	panic(&returnValue{value: v})
+}
+
+// Expressions
+
+func (i *Interpreter) VisitBinaryExpr(expr *ast.BinaryExpr) any {
+	left := i.evaluate(expr.Left())
+	right := i.evaluate(expr.Right())
+	result, err := value.Binary(expr.Operator().Type, left, right)
+	if err != nil {
+		panic(i.runtimeError(expr.Operator(), err.Error()))
+	}
+	i.checkValue(expr.Operator(), result)
+	return result
+}
+
+func (i *Interpreter) VisitUnaryExpr(expr *ast.UnaryExpr) any {
+	right := i.evaluate(expr.Right())
+	result, err := value.Unary(expr.Operator().Type, right)
+	if err != nil {
+		panic(i.runtimeError(expr.Operator(), err.Error()))
+	}
+	return result
+}
+
+func (i *Interpreter) VisitLiteralExpr(expr *ast.LiteralExpr) any {
+	return expr.Value()
+}
+
+func (i *Interpreter) VisitGroupingExpr(expr *ast.GroupingExpr) any {
+	return i.evaluate(expr.Expr())
+}
+
+func (i *Interpreter) VisitVariableExpr(expr *ast.VariableExpr) any {
+	return i.lookUpVariable(expr.Name())
+}
+
+func (i *Interpreter) lookUpVariable(name *ast.Token) any {
+	v, err := i.environment.Get(name)
+	if err != nil {
+		panic(i.runtimeError(name, err.Error()))
+	}
+	return v
+}
+
+func (i *Interpreter) VisitAssignExpr(expr *ast.AssignExpr) any {
+	v := i.evaluate(expr.Value())
+	if err := i.environment.Assign(expr.Name(), v); err != nil {
+		panic(i.runtimeError(expr.Name(), err.Error()))
+	}
+	return v
+}
+
+func (i *Interpreter) VisitCompoundAssignExpr(expr *ast.CompoundAssignExpr) any {
+	current := i.lookUpVariable(expr.Name())
+	right := i.evaluate(expr.Value())
+	operator, _ := ast.CompoundOperator(expr.Operator().Type)
+	return i.assignResult(expr.Name(), expr.Operator(), operator, current, right)
+}
+
+func (i *Interpreter) VisitUpdateExpr(expr *ast.UpdateExpr) any {
+	current := i.lookUpVariable(expr.Name())
+	updated := i.assignResult(expr.Name(), expr.Operator(), updateOperator(expr.Operator()), current, 1.0)
+	if expr.Prefix() {
+		return updated
+	}
+	return current
+}
+
+// updateOperator returns the binary operator applied by "++" or "--".
+func updateOperator(operator *ast.Token) ast.TokenType {
+	if operator.Type == ast.TokenMinusMinus {
+		return ast.TokenMinus
+	}
+	return ast.TokenPlus
+}
+
+// assignResult assigns "current operator right" to the variable name,
+// reporting errors at token.
+func (i *Interpreter) assignResult(name, token *ast.Token, operator ast.TokenType, current, right any) any {
+	result := i.operate(token, operator, current, right)
+	if err := i.environment.Assign(name, result); err != nil {
+		panic(i.runtimeError(name, err.Error()))
+	}
+	return result
+}
+
+// operate returns "current operator right", reporting errors at token.
+func (i *Interpreter) operate(token *ast.Token, operator ast.TokenType, current, right any) any {
+	result, err := value.Binary(operator, current, right)
+	if err != nil {
+		panic(i.runtimeError(token, err.Error()))
+	}
+	i.checkValue(token, result)
+	return result
+}
+
+func (i *Interpreter) VisitLogicalExpr(expr *ast.LogicalExpr) any {
+	left := i.evaluate(expr.Left())
+	if expr.Operator().Type == ast.TokenOr {
+		if value.IsTruthy(left) {
+			return left
+		}
+	} else if !value.IsTruthy(left) {
+		return left
+	}
+	return i.evaluate(expr.Right())
+}
+
+func (i *Interpreter) VisitConditionalExpr(expr *ast.ConditionalExpr) any {
+	if value.IsTruthy(i.evaluate(expr.Condition())) {
+		return i.evaluate(expr.ThenBranch())
+	}
+	return i.evaluate(expr.ElseBranch())
+}
+
+func (i *Interpreter) VisitCoalesceExpr(expr *ast.CoalesceExpr) any {
+	if left := i.evaluate(expr.Left()); left != nil {
+		return left
+	}
+	return i.evaluate(expr.Right())
+}
+
+func (i *Interpreter) VisitGetExpr(expr *ast.GetExpr) any {
+	v, _ := i.link(expr)
+	return v
+}
+
+func (i *Interpreter) VisitOptionalGetExpr(expr *ast.OptionalGetExpr) any {
+	v, _ := i.link(expr)
+	return v
+}
+
+func (i *Interpreter) VisitIndexExpr(expr *ast.IndexExpr) any {
+	v, _ := i.link(expr)
+	return v
+}
+
+func (i *Interpreter) VisitSetExpr(expr *ast.SetExpr) any {
+	object := i.evaluate(expr.Object())
+	v := i.evaluate(expr.Value())
+	i.setProperty(object, expr.Name(), v)
+	return v
+}
+
+func (i *Interpreter) VisitSetIndexExpr(expr *ast.SetIndexExpr) any {
+	object := i.evaluate(expr.Object())
+	index := i.evaluate(expr.Index())
+	v := i.evaluate(expr.Value())
+	i.setIndex(expr.Bracket(), object, index, v)
+	return v
+}
+
+func (i *Interpreter) VisitCompoundSetExpr(expr *ast.CompoundSetExpr) any {
+	object := i.evaluate(expr.Object())
+	current := i.getProperty(object, expr.Name())
+	right := i.evaluate(expr.Value())
+	operator, _ := ast.CompoundOperator(expr.Operator().Type)
+	result := i.operate(expr.Operator(), operator, current, right)
+	i.setProperty(object, expr.Name(), result)
+	return result
+}
+
+func (i *Interpreter) VisitCompoundSetIndexExpr(expr *ast.CompoundSetIndexExpr) any {
+	object := i.evaluate(expr.Object())
+	index := i.evaluate(expr.Index())
+	current := i.index(expr.Bracket(), object, index)
+	right := i.evaluate(expr.Value())
+	operator, _ := ast.CompoundOperator(expr.Operator().Type)
+	result := i.operate(expr.Operator(), operator, current, right)
+	i.setIndex(expr.Bracket(), object, index, result)
+	return result
+}
+
+func (i *Interpreter) VisitUpdatePropertyExpr(expr *ast.UpdatePropertyExpr) any {
+	object := i.evaluate(expr.Object())
+	current := i.getProperty(object, expr.Name())
+	updated := i.operate(expr.Operator(), updateOperator(expr.Operator()), current, 1.0)
+	i.setProperty(object, expr.Name(), updated)
+	if expr.Prefix() {
+		return updated
+	}
+	return current
+}
+
+func (i *Interpreter) VisitUpdateIndexExpr(expr *ast.UpdateIndexExpr) any {
+	object := i.evaluate(expr.Object())
+	index := i.evaluate(expr.Index())
+	current := i.index(expr.Bracket(), object, index)
+	updated := i.operate(expr.Operator(), updateOperator(expr.Operator()), current, 1.0)
+	i.setIndex(expr.Bracket(), object, index, updated)
+	if expr.Prefix() {
+		return updated
+	}
+	return current
+}
+
+func (i *Interpreter) setProperty(object any, name *ast.Token, v any) {
+	if err := value.SetProperty(object, *name.Lexeme, v); err != nil {
+		panic(i.runtimeError(name, err.Error()))
+	}
+}
+
+// index returns object[index], reporting errors at bracket.
+func (i *Interpreter) index(bracket *ast.Token, object, index any) any {
+	v, err := value.Index(object, index)
+	if err != nil {
+		panic(i.runtimeError(bracket, err.Error()))
+	}
+	return v
+}
+
+// setIndex assigns v to object[index], reporting errors at bracket.
+func (i *Interpreter) setIndex(bracket *ast.Token, object, index, v any) {
+	objects := []any{object}
+	lengths := i.meter.Lengths(objects)
+	if err := value.SetIndex(object, index, v); err != nil {
+		panic(i.runtimeError(bracket, err.Error()))
+	}
+	i.checkGrown(bracket, objects, lengths)
+}
+
+func (i *Interpreter) VisitThisExpr(expr *ast.ThisExpr) any {
+	v, _ := i.environment.lookup(thisName)
+	return v
+}
+
+func (i *Interpreter) VisitListExpr(expr *ast.ListExpr) any {
+	elements := make([]any, len(expr.Elements()))
+	for n, element := range expr.Elements() {
+		elements[n] = i.evaluate(element)
+	}
+	list := value.NewList(elements)
+	i.checkValue(expr.Bracket(), list)
+	return list
+}
+
+func (i *Interpreter) VisitMapExpr(expr *ast.MapExpr) any {
+	m := value.NewMap()
+	for n, key := range expr.Keys() {
+		k := i.evaluate(key)
+		v := i.evaluate(expr.Values()[n])
+		if err := m.Set(k, v); err != nil {
+			panic(i.runtimeError(expr.Brace(), err.Error()))
+		}
+	}
+	i.checkValue(expr.Brace(), m)
+	return m
+}
+
+// link evaluates expr, a property access, index or call, as a link of the
+// chain of them it ends. It reports false when an optional access in the chain found
+// nil: the rest of the chain is skipped, so a?.b.c evaluates to nil when a
+// is nil.
+func (i *Interpreter) link(expr ast.Expr) (any, bool) {
+	switch expr := expr.(type) {
+	case *ast.GetExpr:
+		object, ok := i.chain(expr.Object())
+		if !ok {
+			return nil, false
+		}
+		return i.getProperty(object, expr.Name()), true
+	case *ast.OptionalGetExpr:
+		object, ok := i.chain(expr.Object())
+		if !ok || object == nil {
+			return nil, false
+		}
+		return i.getProperty(object, expr.Name()), true
+	case *ast.IndexExpr:
+		object, ok := i.chain(expr.Object())
+		if !ok {
+			return nil, false
+		}
+		index := i.evaluate(expr.Index())
+		return i.index(expr.Bracket(), object, index), true
+	case *ast.CallExpr:
+		callee, ok := i.chain(expr.Callee())
+		if !ok {
+			return nil, false
+		}
+		arguments := make([]any, len(expr.Arguments()))
+		for n, argument := range expr.Arguments() {
+			arguments[n] = i.evaluate(argument)
+		}
+		return i.call(expr.Paren(), callee, arguments), true
+	}
+	return i.evaluate(expr), true
+}
+
+// chain evaluates expr, the object or callee of a link, continuing the
+// chain when expr is a link itself.
+func (i *Interpreter) chain(expr ast.Expr) (any, bool) {
+	switch expr.(type) {
+	case *ast.GetExpr, *ast.OptionalGetExpr, *ast.IndexExpr, *ast.CallExpr:
+		i.step(exprToken(expr))
+		return i.link(expr)
+	}
+	return i.evaluate(expr), true
+}
+
+func (i *Interpreter) getProperty(object any, name *ast.Token) any {
+	v, err := value.GetProperty(object, *name.Lexeme)
+	if err != nil {
+		panic(i.runtimeError(name, err.Error()))
+	}
+	return v
+}
+
+func (i *Interpreter) VisitCallExpr(expr *ast.CallExpr) any {
+	v, _ := i.link(expr)
+	return v
+}
+
+// call calls callee, reporting errors at paren.
+func (i *Interpreter) call(paren *ast.Token, callee any, arguments []any) any {
+	callable, ok := callee.(value.Callable)
+	if !ok {
+		panic(i.runtimeError(paren, fmt.Sprintf("can only call functions, got %s", value.TypeName(callee))))
+	}
+	if callable.Arity() >= 0 && callable.Arity() != len(arguments) {
+		panic(i.runtimeError(paren, fmt.Sprintf("expected %d arguments but got %d", callable.Arity(), len(arguments))))
+	}
+
+	switch callee := callee.(type) {
+	case *Function:
+		if callee.declaration.Generator() {
+			if i.profile != nil {
+				i.profile.Allocate()
+			}
+			return i.generator(paren, callee, arguments)
+		}
+		if err := i.meter.CheckCallDepth(i.callDepth); err != nil {
+			panic(i.wrapError(paren, err))
+		}
+		i.callDepth++
+		file := i.file
+		i.file = callee.file
+		defer func() {
+			i.callDepth--
+			i.file = file
+		}()
+		defer i.pushFrame(callee.Name(), paren)()
+		return callee.call(i, arguments)
+	case *value.Class:
+		instance := value.NewInstance(callee)
+		if i.profile != nil {
+			i.profile.Allocate()
+		}
+		if init, ok := callee.Method(value.InitMethod); ok {
+			i.call(paren, value.NewBoundMethod(instance, init), arguments)
+		}
+		return instance
+	case *value.BoundMethod:
+		if method, ok := callee.Method().(*Function); ok {
+			return i.call(paren, method.bind(callee.Receiver()), arguments)
+		}
+	case *value.Native:
+		// functions the native calls back are called from here
+		i.location = paren
+		if i.profile != nil {
+			i.profile.Builtin(callee.Name())
+		}
+		lengths := i.meter.Lengths(arguments)
+		result, err := callee.Call(arguments)
+		if err != nil {
+			panic(i.raise(paren, err))
+		}
+		i.checkValue(paren, result)
+		// push grows the list it is given
+		i.checkGrown(paren, arguments, lengths)
+		return result
+	}
+	panic(i.runtimeError(paren, fmt.Sprintf("can't call %s", value.TypeName(callee))))
+}
+
+// VisitSpawnExpr evaluates the callee and arguments of the call, then
+// starts a task calling it on an interpreter of its own.
+func (i *Interpreter) VisitSpawnExpr(expr *ast.SpawnExpr) any {
+	call := expr.Call().(*ast.CallExpr)
+	callee := i.evaluate(call.Callee())
+	arguments := make([]any, len(call.Arguments()))
+	for n, argument := range call.Arguments() {
+		arguments[n] = i.evaluate(argument)
+	}
+
+	callable, ok := callee.(value.Callable)
+	if !ok {
+		panic(i.runtimeError(call.Paren(), fmt.Sprintf("can only spawn functions, got %s", value.TypeName(callee))))
+	}
+	task := i.fork()
+	if i.profile != nil {
+		i.profile.Allocate()
+	}
+	return i.scheduler.Spawn(callable.Name(), task.position, func() (any, error) {
+		return task.runTask(call.Paren(), callee, arguments)
+	})
+}
+
+// VisitMatchExpr evaluates the arms in order, each in a scope of its own
+// holding the variables its pattern binds.
+func (i *Interpreter) VisitMatchExpr(expr *ast.MatchExpr) any {
+	subject := i.evaluate(expr.Subject())
+	for arm, pattern := range expr.Patterns() {
+		bound, ok := value.Match(pattern, subject)
+		if !ok {
+			continue
+		}
+		environment := NewEnvironment(i.environment)
+		for j, name := range ast.PatternBindings(pattern) {
+			environment.Define(*name.Lexeme, bound[j], false)
+		}
+		if guard := expr.Guards()[arm]; guard != nil && !value.IsTruthy(i.evaluateIn(guard, environment)) {
+			continue
+		}
+		return i.evaluateIn(expr.Values()[arm], environment)
+	}
+	panic(i.wrapError(expr.Keyword(), value.NoMatch(subject)))
+}
+
+// evaluateIn evaluates expr with environment as the innermost scope.
+func (i *Interpreter) evaluateIn(expr ast.Expr, environment *Environment) any {
+	previous := i.environment
+	defer func() {
+		i.environment = previous
+	}()
+
+	i.environment = environment
+	return i.evaluate(expr)
+}
+
+// exprToken returns the token locating expr, or nil if it has none.
+func exprToken(expr ast.Expr) *ast.Token {
+	switch expr := expr.(type) {
+	case *ast.BinaryExpr:
+		return expr.Operator()
+	case *ast.UnaryExpr:
+		return expr.Operator()
+	case *ast.VariableExpr:
+		return expr.Name()
+	case *ast.AssignExpr:
+		return expr.Name()
+	case *ast.CompoundAssignExpr:
+		return expr.Operator()
+	case *ast.UpdateExpr:
+		return expr.Operator()
+	case *ast.LogicalExpr:
+		return expr.Operator()
+	case *ast.ConditionalExpr:
+		return expr.Question()
+	case *ast.CoalesceExpr:
+		return expr.Operator()
+	case *ast.GetExpr:
+		return expr.Name()
+	case *ast.OptionalGetExpr:
+		return expr.Name()
+	case *ast.CallExpr:
+		return expr.Paren()
+	case *ast.IndexExpr:
+		return expr.Bracket()
+	case *ast.SetExpr:
+		return expr.Name()
+	case *ast.SetIndexExpr:
+		return expr.Bracket()
+	case *ast.CompoundSetExpr:
+		return expr.Operator()
+	case *ast.CompoundSetIndexExpr:
+		return expr.Operator()
+	case *ast.UpdatePropertyExpr:
+		return expr.Operator()
+	case *ast.UpdateIndexExpr:
+		return expr.Operator()
+	case *ast.ThisExpr:
+		return expr.Keyword()
+	case *ast.ListExpr:
+		return expr.Bracket()
+	case *ast.MapExpr:
+		return expr.Brace()
+	case *ast.MatchExpr:
+		return expr.Keyword()
+	case *ast.SpawnExpr:
+		return expr.Keyword()
+	}
+	return nil
+}
+
+// stmtToken returns the token locating stmt, or nil if it has none.
+func stmtToken(stmt ast.Stmt) *ast.Token {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStmt:
+		return exprToken(stmt.Expression())
+	case *ast.VarStmt:
+		return stmt.Name()
+	case *ast.WhileStmt:
+		return stmt.Keyword()
+	case *ast.FunctionStmt:
+		return stmt.Name()
+	case *ast.ClassStmt:
+		return stmt.Name()
+	case *ast.ReturnStmt:
+		return stmt.Keyword()
+	case *ast.ImportStmt:
+		return stmt.Keyword()
+	case *ast.ThrowStmt:
+		return stmt.Keyword()
+	case *ast.TryStmt:
+		return stmt.Keyword()
+	case *ast.SelectStmt:
+		return stmt.Keyword()
+	case *ast.ForInStmt:
+		return stmt.Keyword()
+	case *ast.YieldStmt:
+		return stmt.Keyword()
+	}
+	return nil
+}
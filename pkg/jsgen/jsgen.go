@@ -0,0 +1,848 @@
+// Package jsgen translates programs into JavaScript modules, which run
+// them in browsers and Node.js without an interpreter.
+//
+// A translated program is an ES2020 module exporting run, call and main,
+// with a Source Map v3 file mapping the generated code back to the tokens
+// of the rottenlang source. It imports the runtime support library, which
+// the translation includes, for the semantics JavaScript doesn't share,
+// such as truthiness, equality and the errors of operators.
+//
+// Variables are resolved when the program is translated, like the compiler
+// does: locals become JavaScript variables, which closures capture, and
+// globals stay late bound. Imports, tasks, select statements and generators
+// need the scheduler and the modules of the engines, and aren't supported.
+package jsgen
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+//go:embed runtime.js
+var runtimeSource string
+
+var ErrGenerate = errors.New("generate error")
+
+// RuntimeFile is the name of the runtime support library the translated
+// programs import.
+const RuntimeFile = "rottenlang_runtime.js"
+
+// local is a variable of the program declared in a block or function.
+type local struct {
+	// ident is the JavaScript identifier of the variable
+	ident    string
+	constant bool
+}
+
+// scope maps the names of a block's variables to their locals.
+type scope struct {
+	enclosing *scope
+	locals    map[string]*local
+}
+
+// Generator translates programs into JavaScript.
+type Generator struct {
+	errorReporter errorreporter.ErrorReporter
+	hadError      bool
+	b             strings.Builder
+	scope         *scope
+	// globals are the global variables the program uses
+	globals map[string]bool
+	// names counts the identifiers made up so far, keeping them unique
+	names int
+	// this is the identifier of "this" in the init method of a class being
+	// translated, which returns it
+	this string
+}
+
+var (
+	_ ast.Visitor[string]  = (*Generator)(nil)
+	_ ast.StmtVisitor[any] = (*Generator)(nil)
+)
+
+func NewGenerator(errorReporter errorreporter.ErrorReporter) *Generator {
+	return &Generator{
+		errorReporter: errorReporter,
+	}
+}
+
+// Generate translates a program, read from file, into a module and its
+// source map, keyed by file name along with the runtime support library.
+// The source map names file, which browsers resolve relative to the
+// directory the files are written to. Errors are reported to the error
+// reporter; ErrGenerate is returned if there were any.
+func (g *Generator) Generate(statements []ast.Stmt, file string) (map[string][]byte, error) {
+	g.hadError = false
+	g.b.Reset()
+	g.scope = nil
+	g.globals = map[string]bool{}
+	g.names = 0
+	g.this = ""
+
+	g.line("function script() {")
+	g.statements(statements)
+	g.line("}")
+	if g.hadError {
+		return nil, ErrGenerate
+	}
+	script := g.b.String()
+
+	name := moduleName(file)
+	var b strings.Builder
+	fmt.Fprintf(&b, "// Code generated by rottenlang build from %s. DO NOT EDIT.\n\n", file)
+	fmt.Fprintf(&b, "import * as rt from \"./%s\";\n\n", RuntimeFile)
+	if len(g.globals) > 0 {
+		names := make([]string, 0, len(g.globals))
+		for name := range g.globals {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "const %s = rt.globalVar(%s);\n", globalIdent(name), quote(name))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(script)
+	b.WriteString(`
+// run runs the program, handing what it prints to write, which defaults to
+// the standard output. It throws the RuntimeError stopping the program, if
+// any.
+export function run(write) {
+rt.run(write, script);
+}
+
+// call calls the global function name with args, once run has defined it.
+export function call(name, ...args) {
+return rt.callGlobal(name, args);
+}
+
+// main runs the program, reporting the runtime error stopping it, if any.
+export function main() {
+rt.main(script);
+}
+
+export { RuntimeError } from "./` + RuntimeFile + `";
+`)
+
+	code, mappings := layout(b.String())
+	code += "//# sourceMappingURL=" + name + ".map\n"
+	sourceMap, err := json.Marshal(sourceMapV3{
+		Version:  3,
+		File:     name,
+		Sources:  []string{file},
+		Names:    []string{},
+		Mappings: mappings,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		name:          []byte(code),
+		name + ".map": sourceMap,
+		RuntimeFile:   []byte(runtimeSource),
+	}, nil
+}
+
+// moduleName returns the name of the module generated for the source file.
+func moduleName(file string) string {
+	base := file[strings.LastIndexAny(file, `/\`)+1:]
+	if base == "" {
+		base = "script"
+	}
+	return strings.TrimSuffix(base, ".rot") + ".rot.js"
+}
+
+func (g *Generator) error(token *ast.Token, message string) {
+	g.hadError = true
+	g.errorReporter.ReportParserError(token.Line, token.Column, fmt.Sprintf("at '%s'", *token.Lexeme), message)
+}
+
+// unsupported reports a construct the generated code can't run.
+func (g *Generator) unsupported(token *ast.Token, what string) {
+	g.error(token, what+" aren't supported by the js target")
+}
+
+// line writes a line of code. The code is indented once translated, from
+// the brackets opening and closing it.
+func (g *Generator) line(format string, args ...any) {
+	fmt.Fprintf(&g.b, format+"\n", args...)
+}
+
+// ident makes up a unique identifier starting with prefix.
+func (g *Generator) ident(prefix string) string {
+	g.names++
+	return fmt.Sprintf("%s_%d", prefix, g.names)
+}
+
+func globalIdent(name string) string {
+	return "g_" + name
+}
+
+func (g *Generator) beginScope() {
+	g.scope = &scope{enclosing: g.scope, locals: map[string]*local{}}
+}
+
+func (g *Generator) endScope() {
+	g.scope = g.scope.enclosing
+}
+
+// declare declares name in the innermost scope, returning its identifier.
+func (g *Generator) declare(name *ast.Token, constant bool) string {
+	l := &local{ident: g.ident("l_" + *name.Lexeme), constant: constant}
+	g.scope.locals[*name.Lexeme] = l
+	return l.ident
+}
+
+// resolve returns the local name refers to, or nil for a global.
+func (g *Generator) resolve(name *ast.Token) *local {
+	for s := g.scope; s != nil; s = s.enclosing {
+		if l, ok := s.locals[*name.Lexeme]; ok {
+			return l
+		}
+	}
+	g.globals[*name.Lexeme] = true
+	return nil
+}
+
+// quote returns s as a JavaScript string literal.
+func quote(s string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// Statements
+
+// endsWithReturn reports whether statements end with a return statement,
+// after which another would never run.
+func endsWithReturn(statements []ast.Stmt) bool {
+	if len(statements) == 0 {
+		return false
+	}
+	_, ok := statements[len(statements)-1].(*ast.ReturnStmt)
+	return ok
+}
+
+func (g *Generator) statements(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		g.statement(stmt)
+	}
+}
+
+func (g *Generator) statement(stmt ast.Stmt) {
+	g.b.WriteString(mark(ast.LeadingToken(stmt)))
+	ast.AcceptStmt[any](stmt, g)
+}
+
+// scoped translates statements in a scope of their own.
+func (g *Generator) scoped(statements []ast.Stmt) {
+	g.beginScope()
+	g.statements(statements)
+	g.endScope()
+}
+
+// body translates the body of an if statement or a loop, which is written
+// between braces, without them.
+func (g *Generator) body(stmt ast.Stmt) {
+	if block, ok := stmt.(*ast.BlockStmt); ok {
+		g.scoped(block.Statements())
+		return
+	}
+	g.statement(stmt)
+}
+
+func (g *Generator) VisitExpressionStmt(stmt *ast.ExpressionStmt) any {
+	g.line("%s;", g.expression(stmt.Expression()))
+	return nil
+}
+
+func (g *Generator) VisitVarStmt(stmt *ast.VarStmt) any {
+	initializer := "null"
+	if stmt.Initializer() != nil {
+		initializer = g.expression(stmt.Initializer())
+	}
+	if g.scope == nil {
+		name := *stmt.Name().Lexeme
+		g.globals[name] = true
+		g.line("%s.define(%s, %t);", globalIdent(name), initializer, stmt.Constant())
+		return nil
+	}
+	// the initializer sees the variables outside the declaration
+	ident := g.declare(stmt.Name(), stmt.Constant())
+	keyword := "let"
+	if stmt.Constant() {
+		keyword = "const"
+	}
+	g.line("%s %s = %s;", keyword, ident, initializer)
+	return nil
+}
+
+func (g *Generator) VisitImportStmt(stmt *ast.ImportStmt) any {
+	g.unsupported(stmt.Keyword(), "Imports")
+	return nil
+}
+
+func (g *Generator) VisitBlockStmt(stmt *ast.BlockStmt) any {
+	g.line("{")
+	g.scoped(stmt.Statements())
+	g.line("}")
+	return nil
+}
+
+func (g *Generator) VisitIfStmt(stmt *ast.IfStmt) any {
+	g.line("if (rt.truthy(%s)) {", g.expression(stmt.Condition()))
+	g.body(stmt.ThenBranch())
+	if stmt.ElseBranch() != nil {
+		g.line("} else {")
+		g.body(stmt.ElseBranch())
+	}
+	g.line("}")
+	return nil
+}
+
+func (g *Generator) VisitWhileStmt(stmt *ast.WhileStmt) any {
+	g.line("while (rt.truthy(%s)) {", g.expression(stmt.Condition()))
+	g.body(stmt.Body())
+	g.line("}")
+	return nil
+}
+
+// VisitForInStmt declares the loop variable with let, so that each run of
+// the body has a variable of its own.
+func (g *Generator) VisitForInStmt(stmt *ast.ForInStmt) any {
+	keyword := stmt.Keyword()
+	iterable := g.expression(stmt.Iterable())
+	g.beginScope()
+	ident := g.declare(stmt.Name(), false)
+	g.line("for (let %s of rt.iterate(%s, %d, %d)) {", ident, iterable, keyword.Line, keyword.Column)
+	g.body(stmt.Body())
+	g.endScope()
+	g.line("}")
+	return nil
+}
+
+func (g *Generator) VisitFunctionStmt(stmt *ast.FunctionStmt) any {
+	if stmt.Generator() {
+		g.unsupported(stmt.Name(), "Generators")
+		return nil
+	}
+	name := *stmt.Name().Lexeme
+	if g.scope == nil {
+		g.globals[name] = true
+		g.line("%s.define(%s, false);", globalIdent(name), g.function(stmt, ""))
+		return nil
+	}
+	// declared first, so the function can call itself
+	ident := g.declare(stmt.Name(), false)
+	g.line("let %s = %s;", ident, g.function(stmt, ""))
+	return nil
+}
+
+// function translates the declaration of a function into the expression
+// creating it. this is the identifier of "this" in an initializer, and
+// empty otherwise.
+func (g *Generator) function(stmt *ast.FunctionStmt, this string) string {
+	code := g.b.String()
+	g.b.Reset()
+	enclosing := g.this
+	g.this = this
+	g.beginScope()
+
+	params := make([]string, len(stmt.Params()))
+	for i, param := range stmt.Params() {
+		params[i] = g.declare(param, false)
+	}
+	g.statements(stmt.Body())
+	if !endsWithReturn(stmt.Body()) {
+		g.line("return %s;", g.returnNil())
+	}
+
+	g.endScope()
+	g.this = enclosing
+	body := g.b.String()
+	g.b.Reset()
+	g.b.WriteString(code)
+	return fmt.Sprintf("rt.func(%s, %d, (%s) => {\n%s})", quote(*stmt.Name().Lexeme), len(params), strings.Join(params, ", "), body)
+}
+
+// VisitClassStmt declares the class like a function, so that its methods
+// can refer to it.
+func (g *Generator) VisitClassStmt(stmt *ast.ClassStmt) any {
+	name := *stmt.Name().Lexeme
+	if g.scope == nil {
+		g.globals[name] = true
+		g.line("%s.define(%s, false);", globalIdent(name), g.class(stmt))
+		return nil
+	}
+	ident := g.declare(stmt.Name(), false)
+	g.line("let %s = %s;", ident, g.class(stmt))
+	return nil
+}
+
+// class translates the declaration of a class into the expression creating
+// it. Each method becomes a function taking the instance it is called on,
+// which "this" refers to.
+func (g *Generator) class(stmt *ast.ClassStmt) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "rt.newClass(%s, new Map([\n", quote(*stmt.Name().Lexeme))
+	for _, method := range stmt.Methods() {
+		method := method.(*ast.FunctionStmt)
+		if method.Generator() {
+			g.unsupported(method.Name(), "Generators")
+			continue
+		}
+		g.beginScope()
+		this := g.declare(thisToken(method.Name()), true)
+		initializer := ""
+		if *method.Name().Lexeme == value.InitMethod {
+			initializer = this
+		}
+		fmt.Fprintf(&b, "[%s, (%s) => %s],\n", quote(*method.Name().Lexeme), this, g.function(method, initializer))
+		g.endScope()
+	}
+	b.WriteString("]))")
+	return b.String()
+}
+
+// thisToken returns the token of "this" at token, which the methods
+// declare like a variable.
+func thisToken(token *ast.Token) *ast.Token {
+	this := *token
+	lexeme := "this"
+	this.Lexeme = &lexeme
+	return &this
+}
+
+func (g *Generator) VisitReturnStmt(stmt *ast.ReturnStmt) any {
+	value := g.returnNil()
+	if stmt.Value() != nil {
+		value = g.expression(stmt.Value())
+	}
+	g.line("return %s;", value)
+	return nil
+}
+
+// returnNil returns the value of a return without one: nil, or the
+// instance in an initializer.
+func (g *Generator) returnNil() string {
+	if g.this != "" {
+		return g.this
+	}
+	return "null"
+}
+
+func (g *Generator) VisitYieldStmt(stmt *ast.YieldStmt) any {
+	// the function is reported as a generator
+	return nil
+}
+
+func (g *Generator) VisitThrowStmt(stmt *ast.ThrowStmt) any {
+	keyword := stmt.Keyword()
+	g.line("rt.raise(%s, %d, %d);", g.expression(stmt.Value()), keyword.Line, keyword.Column)
+	return nil
+}
+
+// VisitTryStmt translates a try statement into one of JavaScript, whose
+// clauses behave the same, down to a return from a finally clause
+// overriding the one before.
+func (g *Generator) VisitTryStmt(stmt *ast.TryStmt) any {
+	g.line("try {")
+	g.scoped(stmt.Body())
+	if stmt.CatchName() != nil {
+		g.line("} catch (e) {")
+		g.beginScope()
+		g.line("let %s = rt.caught(e);", g.declare(stmt.CatchName(), false))
+		g.statements(stmt.CatchBody())
+		g.endScope()
+	}
+	if stmt.Finally() != nil {
+		g.line("} finally {")
+		g.scoped(stmt.FinallyBody())
+	} else if stmt.CatchName() == nil {
+		g.line("} finally {")
+	}
+	g.line("}")
+	return nil
+}
+
+func (g *Generator) VisitSelectStmt(stmt *ast.SelectStmt) any {
+	g.unsupported(stmt.Keyword(), "Select statements")
+	return nil
+}
+
+// Expressions
+
+func (g *Generator) expression(expr ast.Expr) string {
+	return ast.Accept[string](expr, g)
+}
+
+// operators spells the operators the way the runtime names them, whatever
+// keyword the program wrote them with.
+var operators = map[ast.TokenType]string{
+	ast.TokenPlus:           "+",
+	ast.TokenMinus:          "-",
+	ast.TokenStar:           "*",
+	ast.TokenSlash:          "/",
+	ast.TokenPercent:        "%",
+	ast.TokenStarStar:       "**",
+	ast.TokenAmpersand:      "&",
+	ast.TokenPipe:           "|",
+	ast.TokenCaret:          "^",
+	ast.TokenLessLess:       "<<",
+	ast.TokenGreaterGreater: ">>",
+	ast.TokenTilde:          "~",
+	ast.TokenBang:           "!",
+	ast.TokenEqualEqual:     "==",
+	ast.TokenBangEqual:      "!=",
+	ast.TokenGreater:        ">",
+	ast.TokenGreaterEqual:   ">=",
+	ast.TokenLess:           "<",
+	ast.TokenLessEqual:      "<=",
+}
+
+// operator returns the runtime's name for the operator of token.
+func (g *Generator) operator(token *ast.Token, tokenType ast.TokenType) string {
+	op, ok := operators[tokenType]
+	if !ok {
+		g.error(token, "Unknown operator")
+	}
+	return quote(op)
+}
+
+func (g *Generator) VisitBinaryExpr(expr *ast.BinaryExpr) string {
+	left, right := g.expression(expr.Left()), g.expression(expr.Right())
+	op := expr.Operator()
+	return fmt.Sprintf("%srt.binary(%s, %s, %s, %d, %d)", mark(op), g.operator(op, op.Type), left, right, op.Line, op.Column)
+}
+
+func (g *Generator) VisitUnaryExpr(expr *ast.UnaryExpr) string {
+	op := expr.Operator()
+	return fmt.Sprintf("%srt.unary(%s, %s, %d, %d)", mark(op), g.operator(op, op.Type), g.expression(expr.Right()), op.Line, op.Column)
+}
+
+func (g *Generator) VisitLiteralExpr(expr *ast.LiteralExpr) string {
+	return literal(expr.Value())
+}
+
+// literal returns the JavaScript expression of a literal value.
+func literal(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		var s string
+		switch {
+		case math.IsNaN(v):
+			s = "NaN"
+		case math.IsInf(v, 1):
+			s = "Infinity"
+		case math.IsInf(v, -1):
+			s = "-Infinity"
+		default:
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		if math.Signbit(v) {
+			return "(" + s + ")"
+		}
+		return s
+	case string:
+		return quote(v)
+	}
+	panic(fmt.Sprintf("jsgen: unexpected literal %T", v))
+}
+
+func (g *Generator) VisitGroupingExpr(expr *ast.GroupingExpr) string {
+	return g.expression(expr.Expr())
+}
+
+func (g *Generator) VisitVariableExpr(expr *ast.VariableExpr) string {
+	name := expr.Name()
+	if l := g.resolve(name); l != nil {
+		return mark(name) + l.ident
+	}
+	return fmt.Sprintf("%s%s.get(%d, %d)", mark(name), globalIdent(*name.Lexeme), name.Line, name.Column)
+}
+
+// local returns the local name refers to for an assignment, reporting an
+// assignment to a constant, or nil for a global.
+func (g *Generator) local(name *ast.Token) *local {
+	l := g.resolve(name)
+	if l != nil && l.constant {
+		g.error(name, fmt.Sprintf("Cannot assign to constant '%s'", *name.Lexeme))
+	}
+	return l
+}
+
+func (g *Generator) VisitAssignExpr(expr *ast.AssignExpr) string {
+	name := expr.Name()
+	value := g.expression(expr.Value())
+	if l := g.local(name); l != nil {
+		return fmt.Sprintf("%s(%s = %s)", mark(name), l.ident, value)
+	}
+	return fmt.Sprintf("%s%s.set(%s, %d, %d)", mark(name), globalIdent(*name.Lexeme), value, name.Line, name.Column)
+}
+
+func (g *Generator) VisitCompoundAssignExpr(expr *ast.CompoundAssignExpr) string {
+	name, token := expr.Name(), expr.Operator()
+	operator, _ := ast.CompoundOperator(token.Type)
+	l := g.local(name)
+	current := fmt.Sprintf("%s.get(%d, %d)", globalIdent(*name.Lexeme), name.Line, name.Column)
+	if l != nil {
+		current = l.ident
+	}
+	result := fmt.Sprintf("rt.binary(%s, %s, %s, %d, %d)", g.operator(token, operator), current, g.expression(expr.Value()), token.Line, token.Column)
+	if l != nil {
+		return fmt.Sprintf("%s(%s = %s)", mark(name), l.ident, result)
+	}
+	return fmt.Sprintf("%s%s.set(%s, %d, %d)", mark(name), globalIdent(*name.Lexeme), result, name.Line, name.Column)
+}
+
+func (g *Generator) VisitUpdateExpr(expr *ast.UpdateExpr) string {
+	name, token := expr.Name(), expr.Operator()
+	l := g.local(name)
+	if l == nil {
+		return fmt.Sprintf("%s%s.update(%s, %t, %d, %d, %d, %d)", mark(token), globalIdent(*name.Lexeme), delta(token), expr.Prefix(), token.Line, token.Column, name.Line, name.Column)
+	}
+	update := fmt.Sprintf("%s = rt.binary(\"+\", %s, %s, %d, %d)", l.ident, l.ident, delta(token), token.Line, token.Column)
+	if expr.Prefix() {
+		return fmt.Sprintf("%s(%s)", mark(token), update)
+	}
+	return fmt.Sprintf("%srt.postfix(%s, %s)", mark(token), l.ident, update)
+}
+
+// delta returns the number the "++" or "--" operator adds.
+func delta(operator *ast.Token) string {
+	if operator.Type == ast.TokenMinusMinus {
+		return "-1"
+	}
+	return "1"
+}
+
+// lazy returns an arrow function evaluating expr, for the operands
+// evaluated only when needed.
+func (g *Generator) lazy(expr ast.Expr) string {
+	return "() => " + g.expression(expr)
+}
+
+func (g *Generator) VisitLogicalExpr(expr *ast.LogicalExpr) string {
+	helper := "rt.and"
+	if expr.Operator().Type == ast.TokenOr {
+		helper = "rt.or"
+	}
+	return fmt.Sprintf("%s%s(%s, %s)", mark(expr.Operator()), helper, g.expression(expr.Left()), g.lazy(expr.Right()))
+}
+
+func (g *Generator) VisitConditionalExpr(expr *ast.ConditionalExpr) string {
+	return fmt.Sprintf("(rt.truthy(%s) ? %s : %s)", g.expression(expr.Condition()), g.expression(expr.ThenBranch()), g.expression(expr.ElseBranch()))
+}
+
+// VisitCoalesceExpr uses JavaScript's operator, as nil is null.
+func (g *Generator) VisitCoalesceExpr(expr *ast.CoalesceExpr) string {
+	return fmt.Sprintf("(%s ?? %s)", g.expression(expr.Left()), g.expression(expr.Right()))
+}
+
+func (g *Generator) VisitGetExpr(expr *ast.GetExpr) string {
+	return g.chain(expr)
+}
+
+func (g *Generator) VisitOptionalGetExpr(expr *ast.OptionalGetExpr) string {
+	return g.chain(expr)
+}
+
+func (g *Generator) VisitCallExpr(expr *ast.CallExpr) string {
+	return g.chain(expr)
+}
+
+func (g *Generator) VisitIndexExpr(expr *ast.IndexExpr) string {
+	return g.chain(expr)
+}
+
+func (g *Generator) VisitSetExpr(expr *ast.SetExpr) string {
+	name := expr.Name()
+	return fmt.Sprintf("%srt.setProperty(%s, %s, %s, %d, %d)", mark(name), g.expression(expr.Object()), quote(*name.Lexeme), g.expression(expr.Value()), name.Line, name.Column)
+}
+
+func (g *Generator) VisitSetIndexExpr(expr *ast.SetIndexExpr) string {
+	bracket := expr.Bracket()
+	return fmt.Sprintf("%srt.setIndex(%s, %s, %s, %d, %d)", mark(bracket), g.expression(expr.Object()), g.expression(expr.Index()), g.expression(expr.Value()), bracket.Line, bracket.Column)
+}
+
+func (g *Generator) VisitCompoundSetExpr(expr *ast.CompoundSetExpr) string {
+	name, token := expr.Name(), expr.Operator()
+	operator, _ := ast.CompoundOperator(token.Type)
+	return fmt.Sprintf("%srt.compoundSetProperty(%s, %s, %s, %s, %d, %d, %d, %d)", mark(token), g.expression(expr.Object()), quote(*name.Lexeme),
+		g.operator(token, operator), g.lazy(expr.Value()), token.Line, token.Column, name.Line, name.Column)
+}
+
+func (g *Generator) VisitCompoundSetIndexExpr(expr *ast.CompoundSetIndexExpr) string {
+	bracket, token := expr.Bracket(), expr.Operator()
+	operator, _ := ast.CompoundOperator(token.Type)
+	return fmt.Sprintf("%srt.compoundSetIndex(%s, %s, %s, %s, %d, %d, %d, %d)", mark(token), g.expression(expr.Object()), g.expression(expr.Index()),
+		g.operator(token, operator), g.lazy(expr.Value()), token.Line, token.Column, bracket.Line, bracket.Column)
+}
+
+func (g *Generator) VisitUpdatePropertyExpr(expr *ast.UpdatePropertyExpr) string {
+	name, token := expr.Name(), expr.Operator()
+	return fmt.Sprintf("%srt.updateProperty(%s, %s, %s, %t, %d, %d, %d, %d)", mark(token), g.expression(expr.Object()), quote(*name.Lexeme),
+		delta(token), expr.Prefix(), token.Line, token.Column, name.Line, name.Column)
+}
+
+func (g *Generator) VisitUpdateIndexExpr(expr *ast.UpdateIndexExpr) string {
+	bracket, token := expr.Bracket(), expr.Operator()
+	return fmt.Sprintf("%srt.updateIndex(%s, %s, %s, %t, %d, %d, %d, %d)", mark(token), g.expression(expr.Object()), g.expression(expr.Index()),
+		delta(token), expr.Prefix(), token.Line, token.Column, bracket.Line, bracket.Column)
+}
+
+func (g *Generator) VisitThisExpr(expr *ast.ThisExpr) string {
+	return mark(expr.Keyword()) + g.resolve(thisToken(expr.Keyword())).ident
+}
+
+func (g *Generator) VisitListExpr(expr *ast.ListExpr) string {
+	elements := make([]string, len(expr.Elements()))
+	for i, element := range expr.Elements() {
+		elements[i] = g.expression(element)
+	}
+	return fmt.Sprintf("%srt.list([%s])", mark(expr.Bracket()), strings.Join(elements, ", "))
+}
+
+func (g *Generator) VisitMapExpr(expr *ast.MapExpr) string {
+	entries := make([]string, 0, 2*len(expr.Keys()))
+	for i, key := range expr.Keys() {
+		entries = append(entries, g.expression(key), g.expression(expr.Values()[i]))
+	}
+	brace := expr.Brace()
+	return fmt.Sprintf("%srt.map(%d, %d, [%s])", mark(brace), brace.Line, brace.Column, strings.Join(entries, ", "))
+}
+
+// chain translates expr, a property access, index or call ending a chain of them,
+// such as a?.b.c. An optional access finding nil skips the rest of the
+// chain, which evaluates to nil.
+func (g *Generator) chain(expr ast.Expr) string {
+	return g.link(expr, func(value string) string { return value })
+}
+
+// link translates expr, a link of a chain, passing the translation of its
+// value to rest, which translates the rest of the chain.
+func (g *Generator) link(expr ast.Expr, rest func(value string) string) string {
+	switch expr := expr.(type) {
+	case *ast.GetExpr:
+		return g.link(expr.Object(), func(object string) string {
+			name := expr.Name()
+			return rest(fmt.Sprintf("%srt.get(%s, %s, %d, %d)", mark(name), object, quote(*name.Lexeme), name.Line, name.Column))
+		})
+	case *ast.OptionalGetExpr:
+		return g.link(expr.Object(), func(object string) string {
+			name := expr.Name()
+			o := g.ident("object")
+			get := fmt.Sprintf("%srt.get(%s, %s, %d, %d)", mark(name), o, quote(*name.Lexeme), name.Line, name.Column)
+			return fmt.Sprintf("((%s) => %s == null ? null : %s)(%s)", o, o, rest(get), object)
+		})
+	case *ast.IndexExpr:
+		return g.link(expr.Object(), func(object string) string {
+			bracket := expr.Bracket()
+			return rest(fmt.Sprintf("%srt.index(%s, %s, %d, %d)", mark(bracket), object, g.expression(expr.Index()), bracket.Line, bracket.Column))
+		})
+	case *ast.CallExpr:
+		return g.link(expr.Callee(), func(callee string) string {
+			arguments := make([]string, len(expr.Arguments()))
+			for i, argument := range expr.Arguments() {
+				arguments[i] = g.expression(argument)
+			}
+			paren := expr.Paren()
+			return rest(fmt.Sprintf("%srt.call(%s, [%s], %d, %d)", mark(paren), callee, strings.Join(arguments, ", "), paren.Line, paren.Column))
+		})
+	}
+	return rest(g.expression(expr))
+}
+
+// VisitMatchExpr translates the arms into an arrow function trying each in
+// turn, with the variables its pattern binds declared in a block of their
+// own.
+func (g *Generator) VisitMatchExpr(expr *ast.MatchExpr) string {
+	var b strings.Builder
+	keyword := expr.Keyword()
+	subject := g.ident("subject")
+	fmt.Fprintf(&b, "%s(() => {\nconst %s = %s;\n", mark(keyword), subject, g.expression(expr.Subject()))
+	for arm, pattern := range expr.Patterns() {
+		b.WriteString("{\n")
+		g.beginScope()
+		for _, name := range ast.PatternBindings(pattern) {
+			fmt.Fprintf(&b, "let %s = null;\n", g.declare(name, false))
+		}
+		condition := g.pattern(pattern, subject)
+		if guard := expr.Guards()[arm]; guard != nil {
+			condition += " && rt.truthy(" + g.expression(guard) + ")"
+		}
+		fmt.Fprintf(&b, "if (%s) {\nreturn %s;\n}\n", condition, g.expression(expr.Values()[arm]))
+		g.endScope()
+		b.WriteString("}\n")
+	}
+	fmt.Fprintf(&b, "return rt.noMatch(%s, %d, %d);\n})()", subject, keyword.Line, keyword.Column)
+	return b.String()
+}
+
+// pattern returns the condition matching the value of the expression v
+// with pattern, binding the locals of the innermost scope.
+func (g *Generator) pattern(pattern ast.Pattern, v string) string {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		return fmt.Sprintf("rt.equal(%s, %s)", v, literal(pattern.Value()))
+	case *ast.WildcardPattern:
+		return "true"
+	case *ast.BindingPattern:
+		return fmt.Sprintf("((%s = %s), true)", g.scope.locals[*pattern.Name().Lexeme].ident, v)
+	case *ast.ObjectPattern:
+		if len(pattern.Names()) == 0 {
+			return fmt.Sprintf("rt.isObject(%s)", v)
+		}
+		return strings.TrimPrefix(g.properties(pattern.Names(), pattern.Patterns(), v), " && ")
+	case *ast.ClassPattern:
+		return fmt.Sprintf("rt.isInstance(%s, %s)", v, quote(*pattern.Name().Lexeme)) + g.properties(pattern.Names(), pattern.Patterns(), v)
+	case *ast.ListPattern:
+		elements, rest := g.ident("elements"), g.ident("rest")
+		var conditions []string
+		for i, element := range pattern.Patterns() {
+			conditions = append(conditions, g.pattern(element, fmt.Sprintf("%s[%d]", elements, i)))
+		}
+		if name := pattern.Rest(); name != nil && *name.Lexeme != "_" {
+			conditions = append(conditions, fmt.Sprintf("((%s = %s), true)", g.scope.locals[*name.Lexeme].ident, rest))
+		}
+		if len(conditions) == 0 {
+			conditions = append(conditions, "true")
+		}
+		return fmt.Sprintf("rt.elements(%s, %d, %t, (%s, %s) => %s)", v, len(pattern.Patterns()), pattern.Rest() != nil, elements, rest, strings.Join(conditions, " && "))
+	}
+	panic(fmt.Sprintf("jsgen: unexpected pattern %T", pattern))
+}
+
+// properties returns the conditions matching the property of v called each
+// of names with the pattern at the same index of patterns, each following
+// " && ".
+func (g *Generator) properties(names []*ast.Token, patterns []ast.Pattern, v string) string {
+	var b strings.Builder
+	for i, name := range names {
+		property := g.ident("property")
+		fmt.Fprintf(&b, " && rt.property(%s, %s, (%s) => %s)", v, quote(*name.Lexeme), property, g.pattern(patterns[i], property))
+	}
+	return b.String()
+}
+
+func (g *Generator) VisitSpawnExpr(expr *ast.SpawnExpr) string {
+	g.unsupported(expr.Keyword(), "Tasks")
+	return "null"
+}
@@ -0,0 +1,213 @@
+package jsgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/golden"
+	"github.com/bagaswh/rottenlang/pkg/interpreter"
+	"github.com/bagaswh/rottenlang/pkg/parser"
+	"github.com/bagaswh/rottenlang/pkg/scanner"
+)
+
+func parse(t *testing.T, source string) []ast.Stmt {
+	t.Helper()
+	tokens, err := scanner.NewScanner(strings.NewReader(source), 0).ScanTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parser.NewParser(&errorreporter.NopErrorReporter{})
+	p.SetTokens(tokens)
+	statements := p.Parse()
+	if p.HadError() {
+		t.Fatal("source doesn't parse")
+	}
+	return statements
+}
+
+// node writes files into a temporary directory and runs the module script
+// there with Node.js, returning what it wrote to stdout and stderr.
+func node(t *testing.T, files map[string][]byte, script string) (string, string) {
+	t.Helper()
+	nodebin, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(nodebin, "--input-type=module", "-e", script)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Run()
+	return stdout.String(), stderr.String()
+}
+
+// TestGolden translates the programs in testdata into JavaScript and runs
+// them with Node.js, comparing what they print with the .out files and
+// what they write to stderr, or the errors translating them, with the .err
+// files. They must print what the tree interpreter prints.
+func TestGolden(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs Node.js")
+	}
+	for _, program := range golden.Programs(t, "testdata") {
+		t.Run(filepath.Base(program), func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			statements := parse(t, string(source))
+
+			reporter := &errorreporter.CollectingErrorReporter{}
+			files, err := NewGenerator(reporter).Generate(statements, filepath.Base(program))
+			if err != nil {
+				var messages strings.Builder
+				for _, diagnostic := range reporter.Diagnostics() {
+					messages.WriteString(diagnostic.String() + "\n")
+				}
+				golden.Check(t, program, ".out", "")
+				golden.Check(t, program, ".err", messages.String())
+				return
+			}
+
+			module := moduleName(filepath.Base(program))
+			stdout, stderr := node(t, files, `import { main } from "./`+module+`"; main();`)
+			golden.Check(t, program, ".out", stdout)
+			golden.Check(t, program, ".err", stderr)
+
+			var want strings.Builder
+			interpreter.NewInterpreter(&errorreporter.NopErrorReporter{}, &want).Interpret(parse(t, string(source)))
+			if stdout != want.String() {
+				t.Errorf("output differs from tree interpreter\ngot:  %q\nwant: %q", stdout, want.String())
+			}
+		})
+	}
+}
+
+// TestModule imports a translated program, running it and then calling
+// its functions.
+func TestModule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs Node.js")
+	}
+	statements := parse(t, `
+vibes factor = 2;
+func scale(n) { purrr n * factor; }
+print("loaded");
+`)
+	files, err := NewGenerator(&errorreporter.NopErrorReporter{}).Generate(statements, "scale.rot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr := node(t, files, `
+import { run, call, RuntimeError } from "./scale.rot.js";
+let printed = "";
+run((s) => { printed += s; });
+console.log(JSON.stringify(printed), call("scale", 21));
+try {
+  call("scale", "x");
+} catch (e) {
+  console.log(e instanceof RuntimeError, e.line, e.column, e.message);
+}
+`)
+	want := `"loaded\n" 42
+true 3 25 operands must be numbers
+`
+	if stdout != want || stderr != "" {
+		t.Errorf("got:\n%s%s\nwant:\n%s", stdout, stderr, want)
+	}
+}
+
+// TestSourceMap checks that the code translating a token maps back to it.
+func TestSourceMap(t *testing.T) {
+	statements := parse(t, "vibes x = 1;\nfunc f() {\n  purrr x - \"a\";\n}\n")
+	files, err := NewGenerator(&errorreporter.NopErrorReporter{}).Generate(statements, "f.rot")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sourceMap sourceMapV3
+	if err := json.Unmarshal(files["f.rot.js.map"], &sourceMap); err != nil {
+		t.Fatal(err)
+	}
+	if sourceMap.Version != 3 || sourceMap.File != "f.rot.js" || len(sourceMap.Sources) != 1 || sourceMap.Sources[0] != "f.rot" {
+		t.Fatalf("unexpected source map %+v", sourceMap)
+	}
+
+	lines := strings.Split(string(files["f.rot.js"]), "\n")
+	for _, tc := range []struct {
+		code                  string
+		sourceLine, sourceCol int
+	}{
+		{`g_x.define(`, 0, 6},
+		{`rt.binary("-"`, 2, 10},
+		{`g_x.get(`, 2, 8},
+		{`return`, 2, 2},
+	} {
+		line, column := -1, -1
+		for i, l := range lines {
+			if c := strings.Index(l, tc.code); c >= 0 {
+				line, column = i, c
+				break
+			}
+		}
+		if line < 0 {
+			t.Fatalf("generated code has no %q", tc.code)
+		}
+		sourceLine, sourceCol, ok := lookup(t, sourceMap.Mappings, line, column)
+		if !ok || sourceLine != tc.sourceLine || sourceCol != tc.sourceCol {
+			t.Errorf("%q maps to %d:%d (%t), want %d:%d", tc.code, sourceLine, sourceCol, ok, tc.sourceLine, tc.sourceCol)
+		}
+	}
+}
+
+// lookup decodes mappings, returning the source position of the segment
+// starting at line and column of the generated code.
+func lookup(t *testing.T, mappings string, line, column int) (int, int, bool) {
+	t.Helper()
+	var fields [4]int
+	for i, segments := range strings.Split(mappings, ";") {
+		fields[0] = 0
+		if segments == "" {
+			continue
+		}
+		for _, segment := range strings.Split(segments, ",") {
+			for f := 0; segment != ""; f++ {
+				var n, shift int
+				for {
+					digit := strings.IndexByte(base64Digits, segment[0])
+					segment = segment[1:]
+					n |= (digit & 31) << shift
+					shift += 5
+					if digit&32 == 0 {
+						break
+					}
+				}
+				if n&1 == 1 {
+					n = -(n >> 1)
+				} else {
+					n >>= 1
+				}
+				fields[f] += n
+			}
+			if i == line && fields[0] == column {
+				return fields[2], fields[3], true
+			}
+		}
+	}
+	return 0, 0, false
+}
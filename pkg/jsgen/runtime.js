@@ -0,0 +1,930 @@
+// Runtime support library of the JavaScript generated by rottenlang build.
+// The generator writes this module next to every program it translates,
+// which imports it as rt. It follows the semantics of package value: the
+// values of a program are null (nil), booleans, numbers, strings,
+// functions, ranges, errors, lists, maps, and classes and their instances.
+
+// maxCallDepth bounds the number of nested calls a program may make before
+// failing with a stack overflow.
+const maxCallDepth = 256;
+
+// RuntimeError is an error raised while running the program, positioned at
+// the source location that caused it.
+export class RuntimeError extends Error {
+  constructor(line, column, message, frames, thrown = null) {
+    super(message);
+    this.name = "RuntimeError";
+    this.line = line;
+    this.column = column;
+    // frames is the call stack where the error was raised, innermost call
+    // first, ending with the top level code
+    this.frames = frames;
+    // thrown is the error value raised by a throw statement, if any
+    this.thrown = thrown;
+  }
+
+  // trace formats the stack of an error raised inside a function, one
+  // indented line per call starting with a newline. Errors raised by top
+  // level code have no trace.
+  trace() {
+    if (this.frames.length < 2) {
+      return "";
+    }
+    return this.frames.map((frame) => "\n  " + formatFrame(frame)).join("");
+  }
+}
+
+function formatFrame(frame) {
+  return `at ${frame.function} (line ${frame.line}, column ${frame.column})`;
+}
+
+// NativeError is thrown by builtins, and raised as a runtime error where
+// they were called.
+class NativeError extends Error {}
+
+const node = globalThis.process?.stdout ? globalThis.process : null;
+
+function defaultOut(s) {
+  if (node) {
+    node.stdout.write(s);
+  } else {
+    console.log(s.replace(/\n$/, ""));
+  }
+}
+
+let out = defaultOut;
+const globals = new Map();
+// frames are the calls in progress, frames[0] being the top level code;
+// each records where it called the next one
+let frames = [];
+
+// run runs script, the top level code of the program, handing what it
+// prints to write. The globals start over each time. It throws the
+// RuntimeError stopping the program, if any.
+export function run(write, script) {
+  out = write ?? defaultOut;
+  for (const global of globals.values()) {
+    global.value = null;
+    global.defined = false;
+    global.constant = false;
+  }
+  for (const native of builtins) {
+    globalVar(native.name).define(native, false);
+  }
+  frames = [{ function: "<script>", line: 0, column: 0 }];
+  script();
+}
+
+// main runs script as the program of a command, which exits with status 1
+// when it stops with a runtime error.
+export function main(script) {
+  try {
+    run(defaultOut, script);
+  } catch (e) {
+    if (!(e instanceof RuntimeError)) {
+      throw e;
+    }
+    console.error(`[line=${e.line} col=${e.column}] Runtime error: ${e.message}${e.trace()}`);
+    if (node) {
+      node.exitCode = 1;
+    }
+  }
+}
+
+// callGlobal calls the global function name with args. run must have run
+// first to define it.
+export function callGlobal(name, args) {
+  frames = [{ function: "<script>", line: 0, column: 0 }];
+  return call(globalVar(name).get(0, 0), args, 0, 0);
+}
+
+// fail raises the runtime error message at line and column.
+function fail(line, column, message) {
+  throw new RuntimeError(line, column, message, stackAt(line, column));
+}
+
+// stackAt returns the calls in progress, innermost first, with the
+// innermost one at line and column.
+function stackAt(line, column) {
+  const stack = [];
+  for (let i = frames.length - 1; i >= 0; i--) {
+    if (i < frames.length - 1) {
+      ({ line, column } = frames[i]);
+    }
+    stack.push({ function: frames[i].function, line, column });
+  }
+  return stack;
+}
+
+// Globals
+
+class Global {
+  constructor(name) {
+    this.name = name;
+    this.value = null;
+    this.defined = false;
+    this.constant = false;
+  }
+
+  define(v, constant) {
+    this.value = v;
+    this.defined = true;
+    this.constant = constant;
+  }
+
+  get(line, column) {
+    if (!this.defined) {
+      fail(line, column, `undefined variable '${this.name}'`);
+    }
+    return this.value;
+  }
+
+  set(v, line, column) {
+    if (!this.defined) {
+      fail(line, column, `undefined variable '${this.name}'`);
+    }
+    if (this.constant) {
+      fail(line, column, `cannot assign to constant '${this.name}'`);
+    }
+    this.value = v;
+    return v;
+  }
+
+  // update adds delta for "++" and "--", with the operator at line and
+  // column and the name at nameLine and nameColumn. It returns the new
+  // value if prefix is set and the old one otherwise.
+  update(delta, prefix, line, column, nameLine, nameColumn) {
+    const current = this.get(nameLine, nameColumn);
+    const updated = this.set(binary("+", current, delta, line, column), nameLine, nameColumn);
+    return prefix ? updated : current;
+  }
+}
+
+// globalVar returns the global variable name, which is the same for every
+// use of the name.
+export function globalVar(name) {
+  let global = globals.get(name);
+  if (!global) {
+    global = new Global(name);
+    globals.set(name, global);
+  }
+  return global;
+}
+
+// postfix returns the value a local variable had before "++" or "--"
+// assigned it the second argument.
+export function postfix(current, updated) {
+  return current;
+}
+
+// Functions
+
+// Closure is a function of the program.
+class Closure {
+  constructor(name, arity, fn) {
+    this.name = name;
+    this.arity = arity;
+    this.fn = fn;
+  }
+
+  toString() {
+    return `<fn ${this.name}>`;
+  }
+}
+
+export function func(name, arity, fn) {
+  return new Closure(name, arity, fn);
+}
+
+// Native is a builtin function.
+class Native {
+  constructor(name, arity, fn) {
+    this.name = name;
+    this.arity = arity;
+    this.fn = fn;
+  }
+
+  toString() {
+    return `<native fn ${this.name}>`;
+  }
+}
+
+// call calls callee, reporting errors at line and column, where the calling
+// function is.
+export function call(callee, args, line, column) {
+  if (callee instanceof ClassValue) {
+    const instance = new Instance(callee);
+    const init = callee.methods.get(initMethod);
+    if (init) {
+      call(init(instance), args, line, column);
+    } else if (args.length !== 0) {
+      fail(line, column, `expected 0 arguments but got ${args.length}`);
+    }
+    return instance;
+  }
+  if (!(callee instanceof Closure) && !(callee instanceof Native)) {
+    fail(line, column, `can only call functions, got ${typeName(callee)}`);
+  }
+  if (callee.arity >= 0 && callee.arity !== args.length) {
+    fail(line, column, `expected ${callee.arity} arguments but got ${args.length}`);
+  }
+
+  if (callee instanceof Native) {
+    try {
+      return callee.fn(args);
+    } catch (e) {
+      if (e instanceof NativeError) {
+        fail(line, column, e.message);
+      }
+      throw e;
+    }
+  }
+
+  if (frames.length > maxCallDepth) {
+    fail(line, column, "stack overflow");
+  }
+  const caller = frames[frames.length - 1];
+  caller.line = line;
+  caller.column = column;
+  frames.push({ function: callee.name, line: 0, column: 0 });
+  try {
+    return callee.fn(...args);
+  } finally {
+    frames.pop();
+  }
+}
+
+// Operators
+
+// truthy reports whether v counts as true in a condition: everything but
+// nil and cap (false) does.
+export function truthy(v) {
+  if (v === null || v === undefined) {
+    return false;
+  }
+  return typeof v === "boolean" ? v : true;
+}
+
+// equal compares two values. Values of different types are never equal,
+// and functions are equal only to themselves.
+export function equal(a, b) {
+  return a === b || (a === undefined && b === null) || (a === null && b === undefined);
+}
+
+// binary applies the binary operator op, reporting errors at line and
+// column.
+export function binary(op, a, b, line, column) {
+  switch (op) {
+    case "==":
+      return equal(a, b);
+    case "!=":
+      return !equal(a, b);
+    case "+":
+      if (typeof a === "string" && typeof b === "string") {
+        return a + b;
+      }
+      if (typeof a !== "number" || typeof b !== "number") {
+        fail(line, column, "operands must be two numbers or two strings");
+      }
+      return a + b;
+  }
+
+  if (typeof a !== "number" || typeof b !== "number") {
+    fail(line, column, "operands must be numbers");
+  }
+  switch (op) {
+    case "-":
+      return a - b;
+    case "*":
+      return a * b;
+    case "/":
+      return a / b;
+    case "%":
+      return a % b;
+    case "**":
+      return pow(a, b);
+    case ">":
+      return a > b;
+    case ">=":
+      return a >= b;
+    case "<":
+      return a < b;
+    case "<=":
+      return a <= b;
+  }
+
+  const m = integer(a);
+  const n = integer(b);
+  if (m === null || n === null) {
+    fail(line, column, "operands must be integers");
+  }
+  switch (op) {
+    case "&":
+      return Number(m & n);
+    case "|":
+      return Number(m | n);
+    case "^":
+      return Number(m ^ n);
+  }
+  if (n < 0n) {
+    fail(line, column, "shift count must not be negative");
+  }
+  if (op === "<<") {
+    return n >= 64n ? 0 : Number(BigInt.asIntN(64, m << n));
+  }
+  if (n >= 64n) {
+    return m < 0n ? -1 : 0;
+  }
+  return Number(m >> n);
+}
+
+// unary applies the prefix operator op, reporting errors at line and
+// column.
+export function unary(op, v, line, column) {
+  if (op === "!") {
+    return !truthy(v);
+  }
+  if (typeof v !== "number") {
+    fail(line, column, "operand must be a number");
+  }
+  switch (op) {
+    case "-":
+      return -v;
+    case "+":
+      return v;
+  }
+  const n = integer(v);
+  if (n === null) {
+    fail(line, column, "operand must be an integer");
+  }
+  return Number(~n);
+}
+
+// pow raises x to the power y like Go's math.Pow, which has a result where
+// JavaScript has NaN.
+function pow(x, y) {
+  if (x === 1 || (x === -1 && (y === Infinity || y === -Infinity))) {
+    return 1;
+  }
+  return x ** y;
+}
+
+// integer returns x as a 64-bit BigInt if it holds an integer in its range,
+// or null.
+function integer(x) {
+  if (!Number.isInteger(x) || x < -(2 ** 63) || x >= 2 ** 63) {
+    return null;
+  }
+  return BigInt(x);
+}
+
+export function and(left, right) {
+  return truthy(left) ? right() : left;
+}
+
+export function or(left, right) {
+  return truthy(left) ? left : right();
+}
+
+// Values
+
+// stringify formats v the way print shows it.
+export function stringify(v) {
+  if (v === null || v === undefined) {
+    return "nil";
+  }
+  switch (typeof v) {
+    case "boolean":
+      return v ? "nocap" : "cap";
+    case "number":
+      return formatNumber(v);
+    case "string":
+      return v;
+  }
+  return String(v);
+}
+
+// formatNumber formats n in decimal, without an exponent, with the fewest
+// digits that read back as n.
+function formatNumber(n) {
+  if (Number.isNaN(n)) {
+    return "NaN";
+  }
+  if (n === Infinity || n === -Infinity) {
+    return n > 0 ? "+Inf" : "-Inf";
+  }
+  if (Object.is(n, -0)) {
+    return "-0";
+  }
+  const s = String(n);
+  const e = s.indexOf("e");
+  if (e < 0) {
+    return s;
+  }
+  let mantissa = s.slice(0, e);
+  let sign = "";
+  if (mantissa.startsWith("-")) {
+    sign = "-";
+    mantissa = mantissa.slice(1);
+  }
+  const [whole, fraction = ""] = mantissa.split(".");
+  const digits = whole + fraction;
+  const point = whole.length + Number(s.slice(e + 1));
+  if (point <= 0) {
+    return sign + "0." + "0".repeat(-point) + digits;
+  }
+  if (point >= digits.length) {
+    return sign + digits + "0".repeat(point - digits.length);
+  }
+  return sign + digits.slice(0, point) + "." + digits.slice(point);
+}
+
+// typeName returns the name of v's type as shown in error messages.
+function typeName(v) {
+  if (v === null || v === undefined) {
+    return "nil";
+  }
+  switch (typeof v) {
+    case "boolean":
+      return "bool";
+    case "number":
+      return "number";
+    case "string":
+      return "string";
+  }
+  if (v instanceof ClassValue) {
+    return "class";
+  }
+  if (v instanceof Closure || v instanceof Native) {
+    return "function";
+  }
+  if (v instanceof ErrorValue) {
+    return "error";
+  }
+  if (v instanceof Range) {
+    return "range";
+  }
+  if (v instanceof List) {
+    return "list";
+  }
+  if (v instanceof MapValue) {
+    return "map";
+  }
+  if (v instanceof Instance) {
+    return "instance";
+  }
+  return typeof v;
+}
+
+// isObject reports whether v has properties, matching "{}".
+export function isObject(v) {
+  return v instanceof ErrorValue || v instanceof MapValue || v instanceof Instance;
+}
+
+// get reads the property name of object.
+export function get(object, name, line, column) {
+  if (!isObject(object)) {
+    fail(line, column, `only objects have properties, got ${typeName(object)}`);
+  }
+  const [v, ok] = object.property(name);
+  if (!ok) {
+    fail(line, column, `undefined property '${name}'`);
+  }
+  return v;
+}
+
+// setProperty assigns v to the property name of object, which must be an
+// instance, returning v.
+export function setProperty(object, name, v, line, column) {
+  if (!(object instanceof Instance)) {
+    fail(line, column, `only instances have fields, got ${typeName(object)}`);
+  }
+  object.fields.set(name, v);
+  return v;
+}
+
+// compoundSetProperty applies op to the property name of object and the
+// value of valueFn, assigning the result, which it returns. Errors of op are
+// reported at line and column, those of the property at nameLine and
+// nameColumn.
+export function compoundSetProperty(object, name, op, valueFn, line, column, nameLine, nameColumn) {
+  const current = get(object, name, nameLine, nameColumn);
+  return setProperty(object, name, binary(op, current, valueFn(), line, column), nameLine, nameColumn);
+}
+
+// updateProperty is Global.update for the property name of object.
+export function updateProperty(object, name, delta, prefix, line, column, nameLine, nameColumn) {
+  const current = get(object, name, nameLine, nameColumn);
+  const updated = setProperty(object, name, binary("+", current, delta, line, column), nameLine, nameColumn);
+  return prefix ? updated : current;
+}
+
+// List is a list, made by a list literal.
+class List {
+  constructor(elements) {
+    this.elements = elements;
+  }
+
+  // element returns the position of the element index refers to.
+  element(index, line, column) {
+    if (!Number.isInteger(index)) {
+      fail(line, column, `list index must be an integer, got ${stringify(index)}`);
+    }
+    if (index < 0 || index >= this.elements.length) {
+      fail(line, column, `list index ${stringify(index)} out of range for length ${this.elements.length}`);
+    }
+    return index;
+  }
+
+  toString() {
+    return this.format(new Set());
+  }
+
+  // format writes the list, printing "[...]" for the list itself if it
+  // contains itself, and the lists and maps in printing the same way.
+  format(printing) {
+    if (printing.has(this)) {
+      return "[...]";
+    }
+    printing.add(this);
+    const parts = this.elements.map((element) => stringifyIn(element, printing));
+    printing.delete(this);
+    return "[" + parts.join(", ") + "]";
+  }
+}
+
+// stringifyIn is stringify for a value contained in the lists and maps
+// being printed.
+function stringifyIn(v, printing) {
+  if (v instanceof List || v instanceof MapValue) {
+    return v.format(printing);
+  }
+  return stringify(v);
+}
+
+export function list(elements) {
+  return new List(elements);
+}
+
+// MapValue is a map, made by a map literal, which keeps its keys in the
+// order they were added. Its entries with a string key are its
+// properties.
+class MapValue {
+  constructor() {
+    this.entries = new Map();
+  }
+
+  set(key, v, line, column) {
+    if (key === undefined) {
+      key = null;
+    }
+    if (key !== null && !["boolean", "number", "string"].includes(typeof key)) {
+      fail(line, column, `map keys must be nil, booleans, numbers or strings, got ${typeName(key)}`);
+    }
+    if (Number.isNaN(key)) {
+      fail(line, column, "map keys can't be NaN");
+    }
+    this.entries.set(key, v);
+  }
+
+  property(name) {
+    return [this.entries.get(name) ?? null, this.entries.has(name)];
+  }
+
+  toString() {
+    return this.format(new Set());
+  }
+
+  // format writes the map as List.format does, printing "{...}" for the
+  // map itself.
+  format(printing) {
+    if (printing.has(this)) {
+      return "{...}";
+    }
+    printing.add(this);
+    const parts = [];
+    for (const [key, v] of this.entries) {
+      parts.push(stringify(key) + ": " + stringifyIn(v, printing));
+    }
+    printing.delete(this);
+    return "{" + parts.join(", ") + "}";
+  }
+}
+
+// map makes the map of a literal, whose keys and values alternate in
+// entries.
+export function map(line, column, entries) {
+  const m = new MapValue();
+  for (let i = 0; i < entries.length; i += 2) {
+    m.set(entries[i], entries[i + 1], line, column);
+  }
+  return m;
+}
+
+// index reads object[index].
+export function index(object, index, line, column) {
+  if (object instanceof List) {
+    return object.elements[object.element(index, line, column)];
+  }
+  if (object instanceof MapValue) {
+    return object.entries.get(index ?? null) ?? null;
+  }
+  fail(line, column, `can only index lists and maps, got ${typeName(object)}`);
+}
+
+// setIndex assigns v to object[index], returning v.
+export function setIndex(object, index, v, line, column) {
+  if (object instanceof List) {
+    object.elements[object.element(index, line, column)] = v;
+  } else if (object instanceof MapValue) {
+    object.set(index, v, line, column);
+  } else {
+    fail(line, column, `can only index lists and maps, got ${typeName(object)}`);
+  }
+  return v;
+}
+
+// compoundSetIndex applies op to object[i] and the value of valueFn,
+// assigning the result, which it returns. Errors of op are reported at line
+// and column, those of indexing at bracketLine and bracketColumn.
+export function compoundSetIndex(object, i, op, valueFn, line, column, bracketLine, bracketColumn) {
+  const current = index(object, i, bracketLine, bracketColumn);
+  return setIndex(object, i, binary(op, current, valueFn(), line, column), bracketLine, bracketColumn);
+}
+
+// updateIndex is Global.update for object[i].
+export function updateIndex(object, i, delta, prefix, line, column, bracketLine, bracketColumn) {
+  const current = index(object, i, bracketLine, bracketColumn);
+  const updated = setIndex(object, i, binary("+", current, delta, line, column), bracketLine, bracketColumn);
+  return prefix ? updated : current;
+}
+
+// Classes
+
+// initMethod is the name of the method initializing instances.
+const initMethod = "init";
+
+// ClassValue is a class of the program. Its methods return the function
+// calling them on an instance.
+class ClassValue {
+  constructor(name, methods) {
+    this.name = name;
+    this.methods = methods;
+  }
+
+  toString() {
+    return `<class ${this.name}>`;
+  }
+}
+
+export function newClass(name, methods) {
+  return new ClassValue(name, methods);
+}
+
+// Instance is an object made by calling a class.
+class Instance {
+  constructor(cls) {
+    this.cls = cls;
+    this.fields = new Map();
+  }
+
+  property(name) {
+    if (this.fields.has(name)) {
+      return [this.fields.get(name), true];
+    }
+    const method = this.cls.methods.get(name);
+    if (method) {
+      return [method(this), true];
+    }
+    return [null, false];
+  }
+
+  toString() {
+    return `<${this.cls.name} instance>`;
+  }
+}
+
+// ErrorValue is an error value, made by the error builtin or by catching
+// an error.
+class ErrorValue {
+  constructor(errorClass, message, raised = null) {
+    this.errorClass = errorClass;
+    this.message = message;
+    // raised is the runtime error raising it
+    this.raised = raised;
+  }
+
+  toString() {
+    return `${this.errorClass}: ${this.message}`;
+  }
+
+  property(name) {
+    switch (name) {
+      case "message":
+        return [this.message, true];
+      case "class":
+        return [this.errorClass, true];
+      case "stack":
+        if (this.raised === null) {
+          return [null, true];
+        }
+        return [this.raised.frames.map(formatFrame).join("\n"), true];
+    }
+    return [null, false];
+  }
+}
+
+// Errors
+
+// raise throws v, or an error of class Error with v as its message if it
+// isn't an error.
+export function raise(v, line, column) {
+  const e = v instanceof ErrorValue ? v : new ErrorValue("Error", stringify(v));
+  if (e.raised === null) {
+    e.raised = new RuntimeError(line, column, e.toString(), stackAt(line, column), e);
+  }
+  throw e.raised;
+}
+
+// caught returns the error value handed to a catch clause for e, which is
+// rethrown if it isn't an error of the program.
+export function caught(e) {
+  if (!(e instanceof RuntimeError)) {
+    throw e;
+  }
+  return e.thrown ?? new ErrorValue("RuntimeError", e.message, e);
+}
+
+// Match expressions
+
+// property matches the property name of v with match, the rest of an
+// object pattern.
+export function property(v, name, match) {
+  if (!isObject(v)) {
+    return false;
+  }
+  const [value, ok] = v.property(name);
+  return ok && match(value);
+}
+
+// isInstance reports whether v is an instance of the class called name.
+export function isInstance(v, name) {
+  return v instanceof Instance && v.cls.name === name;
+}
+
+// elements matches the elements of v, which must be a list of count
+// elements, or at least count with rest set, with match, the rest of a
+// list pattern. The elements beyond count are passed in a list of their
+// own.
+export function elements(v, count, rest, match) {
+  if (!(v instanceof List) || v.elements.length < count || (!rest && v.elements.length !== count)) {
+    return false;
+  }
+  return match(v.elements, rest ? new List(v.elements.slice(count)) : null);
+}
+
+// noMatch raises the error of a match expression none of whose arms
+// matches v.
+export function noMatch(v, line, column) {
+  fail(line, column, `no match arm matches ${stringify(v)}`);
+}
+
+// Iteration
+
+// iterate returns the values a for-in loop over v goes through.
+export function iterate(v, line, column) {
+  if (typeof v === "string") {
+    return v;
+  }
+  if (v instanceof Range) {
+    return v.values();
+  }
+  if (v instanceof List) {
+    return (function* () {
+      for (let i = 0; i < v.elements.length; i++) {
+        yield v.elements[i];
+      }
+    })();
+  }
+  if (v instanceof MapValue) {
+    return v.entries.keys();
+  }
+  if (v instanceof Closure || v instanceof Native) {
+    return calls(v, line, column);
+  }
+  if (isObject(v)) {
+    const [next, hasNext] = v.property("next");
+    if (hasNext) {
+      return calls(next, line, column);
+    }
+    const [method, hasIterator] = v.property("iterator");
+    if (hasIterator) {
+      const it = call(method, [], line, column);
+      // a loop goes through the keys of a map
+      if (isObject(it) && !(it instanceof MapValue) && !it.property("next")[1]) {
+        fail(line, column, `method iterator must return an iterator, got ${typeName(it)}`);
+      }
+      return iterate(it, line, column);
+    }
+  }
+  fail(line, column, `can't iterate over ${typeName(v)}`);
+}
+
+// calls returns the values function returns when called with no
+// arguments, until it returns nil.
+function* calls(fn, line, column) {
+  for (;;) {
+    const next = call(fn, [], line, column);
+    if (next === null || next === undefined) {
+      return;
+    }
+    yield next;
+  }
+}
+
+// Range is the sequence of numbers made by the range builtin.
+class Range {
+  constructor(start, end, step) {
+    this.start = start;
+    this.end = end;
+    this.step = step;
+  }
+
+  count() {
+    const n = Math.ceil((this.end - this.start) / this.step);
+    return n > 0 ? n : 0;
+  }
+
+  *values() {
+    const count = this.count();
+    for (let index = 0; index < count; index++) {
+      yield this.start + index * this.step;
+    }
+  }
+
+  toString() {
+    return `<range ${stringify(this.start)}..${stringify(this.end)} step ${stringify(this.step)}>`;
+  }
+}
+
+// Builtins
+
+const builtins = [
+  new Native("print", -1, (args) => {
+    out(args.map(stringify).join(" ") + "\n");
+    return null;
+  }),
+  new Native("clock", 0, () => Date.now() / 1000),
+  new Native("error", -1, (args) => {
+    if (args.length < 1 || args.length > 2) {
+      throw new NativeError(`error expects 1 or 2 arguments but got ${args.length}`);
+    }
+    let errorClass = "Error";
+    if (args.length === 2) {
+      if (typeof args[1] !== "string") {
+        throw new NativeError(`error class must be a string, got ${typeName(args[1])}`);
+      }
+      errorClass = args[1];
+    }
+    return new ErrorValue(errorClass, stringify(args[0]));
+  }),
+  new Native("range", -1, (args) => {
+    if (args.length < 1 || args.length > 3) {
+      throw new NativeError(`range expects 1 to 3 arguments but got ${args.length}`);
+    }
+    const bounds = [0, 0, 1];
+    args.forEach((arg, i) => {
+      if (typeof arg !== "number") {
+        throw new NativeError(`range bounds must be numbers, got ${typeName(arg)}`);
+      }
+      bounds[i] = arg;
+    });
+    if (args.length === 1) {
+      bounds[1] = bounds[0];
+      bounds[0] = 0;
+    }
+    if (bounds[2] === 0) {
+      throw new NativeError("range step can't be 0");
+    }
+    return new Range(...bounds);
+  }),
+  new Native("len", 1, ([v]) => {
+    if (typeof v === "string") {
+      return [...v].length;
+    }
+    if (v instanceof List) {
+      return v.elements.length;
+    }
+    if (v instanceof MapValue) {
+      return v.entries.size;
+    }
+    throw new NativeError(`len expects a string, list or map, got ${typeName(v)}`);
+  }),
+  new Native("push", 2, ([list, v]) => {
+    if (!(list instanceof List)) {
+      throw new NativeError(`push expects a list, got ${typeName(list)}`);
+    }
+    list.elements.push(v);
+    return null;
+  }),
+];
@@ -0,0 +1,191 @@
+package jsgen
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+// sourceMapV3 is a source map, as specified by revision 3 of the format.
+type sourceMapV3 struct {
+	Version  int      `json:"version"`
+	File     string   `json:"file"`
+	Sources  []string `json:"sources"`
+	Names    []string `json:"names"`
+	Mappings string   `json:"mappings"`
+}
+
+// The code is generated with marks in it, each standing before the code
+// translating the token it names. Laying out the code removes them,
+// recording where they were in the mappings of the source map. JSON string
+// literals escape control characters, so the code never holds the marks'
+// delimiters otherwise.
+const (
+	markStart = '\x01'
+	markEnd   = '\x02'
+)
+
+// mark returns the mark mapping the code following it to token, or nothing
+// if token is nil.
+func mark(token *ast.Token) string {
+	if token == nil {
+		return ""
+	}
+	// columns count from the token's last character
+	column := token.Column - len(*token.Lexeme)
+	if column < 0 {
+		column = 0
+	}
+	return fmt.Sprintf("%c%d:%d%c", markStart, token.Line-1, column, markEnd)
+}
+
+// layout indents the generated code by the brackets it opens and closes,
+// and takes the marks out of it, returning the code and the mappings they
+// make up.
+func layout(code string) (string, string) {
+	var out, mappings strings.Builder
+	var encoder vlqEncoder
+	var indenter indenter
+	for i, line := range strings.Split(strings.TrimSuffix(code, "\n"), "\n") {
+		if i > 0 {
+			mappings.WriteByte(';')
+		}
+		if line == "" {
+			out.WriteByte('\n')
+			continue
+		}
+
+		indent := strings.Repeat("  ", indenter.indent(stripMarks(line)))
+		out.WriteString(indent)
+
+		// columns count UTF-16 code units
+		column := len(indent)
+		segments := 0
+		for len(line) > 0 {
+			start := strings.IndexByte(line, markStart)
+			if start < 0 {
+				out.WriteString(line)
+				break
+			}
+			out.WriteString(line[:start])
+			column += len(utf16.Encode([]rune(line[:start])))
+			end := strings.IndexByte(line[start:], markEnd) + start
+			var sourceLine, sourceColumn int
+			fmt.Sscanf(line[start+1:end], "%d:%d", &sourceLine, &sourceColumn)
+			line = line[end+1:]
+
+			if segments > 0 {
+				mappings.WriteByte(',')
+			}
+			segments++
+			encoder.segment(&mappings, column, sourceLine, sourceColumn)
+		}
+		out.WriteByte('\n')
+		encoder.column = 0
+	}
+	return out.String(), mappings.String()
+}
+
+// stripMarks returns line without its marks.
+func stripMarks(line string) string {
+	for {
+		start := strings.IndexByte(line, markStart)
+		if start < 0 {
+			return line
+		}
+		end := strings.IndexByte(line[start:], markEnd) + start
+		line = line[:start] + line[end+1:]
+	}
+}
+
+// indenter indents code one level for every line leaving brackets open,
+// however many it opens.
+type indenter struct {
+	// open counts the brackets left open by each line indenting the code
+	open []int
+}
+
+// indent returns the indentation level of line, which is outdented by the
+// closing brackets starting it, and takes in the brackets it opens and
+// closes, outside of string literals and comments.
+func (in *indenter) indent(line string) int {
+	if strings.HasPrefix(line, "//") {
+		return len(in.open)
+	}
+	level := len(in.open)
+	leading := true
+	opened := 0
+	inString := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '(' || c == '[' || c == '{':
+			opened++
+		case c == ')' || c == ']' || c == '}':
+			if opened > 0 {
+				opened--
+				break
+			}
+			if len(in.open) == 0 {
+				break
+			}
+			top := len(in.open) - 1
+			if leading && len(in.open) == level {
+				// a line starting by closing a level is written at it
+				level--
+			}
+			if in.open[top]--; in.open[top] == 0 {
+				in.open = in.open[:top]
+			}
+		}
+		leading = leading && strings.IndexByte(")]}", c) >= 0
+	}
+	if opened > 0 {
+		in.open = append(in.open, opened)
+	}
+	return level
+}
+
+// vlqEncoder writes the segments of mappings, each field relative to the
+// one of the segment before, as the format wants.
+type vlqEncoder struct {
+	column, line, sourceColumn int
+}
+
+func (e *vlqEncoder) segment(b *strings.Builder, column, line, sourceColumn int) {
+	writeVLQ(b, column-e.column)
+	// every segment maps to the first and only source
+	writeVLQ(b, 0)
+	writeVLQ(b, line-e.line)
+	writeVLQ(b, sourceColumn-e.sourceColumn)
+	e.column, e.line, e.sourceColumn = column, line, sourceColumn
+}
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes n as a base 64 variable-length quantity: five bits a
+// digit, least significant first, with the sign in the lowest bit.
+func writeVLQ(b *strings.Builder, n int) {
+	v := n << 1
+	if n < 0 {
+		v = -n<<1 | 1
+	}
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		b.WriteByte(base64Digits[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
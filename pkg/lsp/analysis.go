@@ -0,0 +1,534 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/parser"
+	"github.com/bagaswh/rottenlang/pkg/scanner"
+)
+
+// declaration is a name introduced by a function, class, variable or
+// constant declaration, a method or a function parameter.
+type declaration struct {
+	name     *ast.Token
+	kind     int
+	function *ast.FunctionStmt
+	// parameter is set for function parameters
+	parameter bool
+}
+
+type analysis struct {
+	tokens      []*ast.Token
+	statements  []ast.Stmt
+	diagnostics []Diagnostic
+	// declarations maps the name token of every declaration
+	declarations map[*ast.Token]*declaration
+	// references maps identifiers to the declaration they resolve to
+	references map[*ast.Token]*declaration
+	symbols    []DocumentSymbol
+}
+
+func analyze(doc *document) *analysis {
+	a := &analysis{
+		diagnostics:  make([]Diagnostic, 0),
+		declarations: make(map[*ast.Token]*declaration),
+		references:   make(map[*ast.Token]*declaration),
+		symbols:      make([]DocumentSymbol, 0),
+	}
+
+	s := scanner.NewScanner(strings.NewReader(doc.text), 0)
+	s.ScanTokens()
+	a.tokens = s.Tokens()
+	for _, lineErrs := range s.ScannerErrors() {
+		for _, scanErr := range lineErrs {
+			a.diagnostics = append(a.diagnostics, scanDiagnostic(doc, scanErr))
+		}
+	}
+
+	p := parser.NewParser(&errorreporter.NopErrorReporter{})
+	p.SetTokens(a.tokens)
+	a.statements = p.Parse()
+	for _, parseErr := range p.Errors() {
+		a.diagnostics = append(a.diagnostics, Diagnostic{
+			Range:    doc.tokenRange(parseErr.Token()),
+			Severity: SeverityError,
+			Source:   "rottenlang",
+			Message:  parseErr.Message(),
+		})
+	}
+	for _, warning := range p.Warnings() {
+		a.diagnostics = append(a.diagnostics, Diagnostic{
+			Range:    doc.tokenRange(warning.Token()),
+			Severity: SeverityWarning,
+			Source:   "rottenlang",
+			Message:  warning.Message(),
+		})
+	}
+
+	r := newResolver(doc, a)
+	r.resolveProgram(a.statements)
+	return a
+}
+
+func scanDiagnostic(doc *document, scanErr *scanner.GenericScanError) Diagnostic {
+	line := scanErr.Line() - 1
+	if line >= len(doc.lineStarts) {
+		line = len(doc.lineStarts) - 1
+	}
+	// the scanner reports the column where it stopped, after the lexeme
+	end := doc.lineStarts[line] + scanErr.Column()
+	if end > len(doc.text) {
+		end = len(doc.text)
+	}
+	start := end - len(scanErr.Lexeme())
+	if start < doc.lineStarts[line] {
+		start = doc.lineStarts[line]
+	}
+	return Diagnostic{
+		Range:    doc.span(start, end),
+		Severity: SeverityError,
+		Source:   "rottenlang",
+		Message:  scanErr.Message(),
+	}
+}
+
+// resolver binds identifiers to their declarations and collects document
+// symbols while walking the program.
+type resolver struct {
+	doc      *document
+	analysis *analysis
+	scopes   []map[string]*declaration
+	// symbols is where the symbols of the function being walked go
+	symbols *[]DocumentSymbol
+}
+
+func newResolver(doc *document, a *analysis) *resolver {
+	return &resolver{
+		doc:      doc,
+		analysis: a,
+		symbols:  &a.symbols,
+	}
+}
+
+func (r *resolver) resolveProgram(statements []ast.Stmt) {
+	r.beginScope()
+	// top level functions and variables may be used before their
+	// declaration, from inside functions
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.FunctionStmt:
+			r.declare(stmt.Name(), SymbolKindFunction, stmt)
+		case *ast.ClassStmt:
+			r.declare(stmt.Name(), SymbolKindClass, nil)
+		case *ast.VarStmt:
+			r.declare(stmt.Name(), varKind(stmt), nil)
+		case *ast.ImportStmt:
+			r.declare(stmt.Name(), SymbolKindModule, nil)
+		}
+	}
+	r.resolveStmts(statements)
+	r.endScope()
+}
+
+func (r *resolver) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]*declaration))
+}
+
+func (r *resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *resolver) declare(name *ast.Token, kind int, function *ast.FunctionStmt) *declaration {
+	decl, ok := r.analysis.declarations[name]
+	if !ok {
+		decl = &declaration{name: name, kind: kind, function: function}
+		r.analysis.declarations[name] = decl
+	}
+	r.scopes[len(r.scopes)-1][*name.Lexeme] = decl
+	return decl
+}
+
+func (r *resolver) reference(name *ast.Token) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if decl, ok := r.scopes[i][*name.Lexeme]; ok {
+			r.analysis.references[name] = decl
+			return
+		}
+	}
+}
+
+func (r *resolver) addSymbol(symbol DocumentSymbol) {
+	*r.symbols = append(*r.symbols, symbol)
+}
+
+func (r *resolver) resolveStmts(statements []ast.Stmt) {
+	for _, stmt := range statements {
+		if stmt != nil {
+			ast.AcceptStmt[any](stmt, r)
+		}
+	}
+}
+
+func (r *resolver) resolveExpr(expr ast.Expr) {
+	if expr != nil {
+		ast.Accept[any](expr, r)
+	}
+}
+
+func varKind(stmt *ast.VarStmt) int {
+	if stmt.Constant() {
+		return SymbolKindConstant
+	}
+	return SymbolKindVariable
+}
+
+func functionSignature(stmt *ast.FunctionStmt) string {
+	params := make([]string, len(stmt.Params()))
+	for i, param := range stmt.Params() {
+		params[i] = *param.Lexeme
+	}
+	return "func " + *stmt.Name().Lexeme + "(" + strings.Join(params, ", ") + ")"
+}
+
+// Statements
+
+func (r *resolver) VisitExpressionStmt(stmt *ast.ExpressionStmt) any {
+	r.resolveExpr(stmt.Expression())
+	return nil
+}
+
+func (r *resolver) VisitVarStmt(stmt *ast.VarStmt) any {
+	r.resolveExpr(stmt.Initializer())
+	r.declare(stmt.Name(), varKind(stmt), nil)
+
+	nameRange := r.doc.tokenRange(stmt.Name())
+	r.addSymbol(DocumentSymbol{
+		Name:           *stmt.Name().Lexeme,
+		Kind:           varKind(stmt),
+		Range:          nameRange,
+		SelectionRange: nameRange,
+	})
+	return nil
+}
+
+func (r *resolver) VisitImportStmt(stmt *ast.ImportStmt) any {
+	r.declare(stmt.Name(), SymbolKindModule, nil)
+
+	nameRange := r.doc.tokenRange(stmt.Name())
+	r.addSymbol(DocumentSymbol{
+		Name:           *stmt.Name().Lexeme,
+		Detail:         *stmt.Path().Lexeme,
+		Kind:           SymbolKindModule,
+		Range:          nameRange,
+		SelectionRange: nameRange,
+	})
+	return nil
+}
+
+func (r *resolver) VisitBlockStmt(stmt *ast.BlockStmt) any {
+	r.beginScope()
+	r.resolveStmts(stmt.Statements())
+	r.endScope()
+	return nil
+}
+
+func (r *resolver) VisitIfStmt(stmt *ast.IfStmt) any {
+	r.resolveExpr(stmt.Condition())
+	r.resolveStmts([]ast.Stmt{stmt.ThenBranch(), stmt.ElseBranch()})
+	return nil
+}
+
+func (r *resolver) VisitWhileStmt(stmt *ast.WhileStmt) any {
+	r.resolveExpr(stmt.Condition())
+	r.resolveStmts([]ast.Stmt{stmt.Body()})
+	return nil
+}
+
+func (r *resolver) VisitForInStmt(stmt *ast.ForInStmt) any {
+	r.resolveExpr(stmt.Iterable())
+	r.beginScope()
+	r.declare(stmt.Name(), SymbolKindVariable, nil)
+	r.resolveStmts([]ast.Stmt{stmt.Body()})
+	r.endScope()
+	return nil
+}
+
+func (r *resolver) VisitFunctionStmt(stmt *ast.FunctionStmt) any {
+	r.declare(stmt.Name(), SymbolKindFunction, stmt)
+	r.function(stmt, SymbolKindFunction)
+	return nil
+}
+
+// VisitClassStmt resolves the methods, which aren't variables, so they are
+// declared without a scope.
+func (r *resolver) VisitClassStmt(stmt *ast.ClassStmt) any {
+	r.declare(stmt.Name(), SymbolKindClass, nil)
+
+	children := make([]DocumentSymbol, 0)
+	enclosing := r.symbols
+	r.symbols = &children
+	for _, method := range stmt.Methods() {
+		method := method.(*ast.FunctionStmt)
+		r.analysis.declarations[method.Name()] = &declaration{name: method.Name(), kind: SymbolKindMethod, function: method}
+		r.function(method, SymbolKindMethod)
+	}
+	r.symbols = enclosing
+
+	nameRange := r.doc.tokenRange(stmt.Name())
+	r.addSymbol(DocumentSymbol{
+		Name:           *stmt.Name().Lexeme,
+		Detail:         "class " + *stmt.Name().Lexeme,
+		Kind:           SymbolKindClass,
+		Range:          nameRange,
+		SelectionRange: nameRange,
+		Children:       children,
+	})
+	return nil
+}
+
+// function resolves the parameters and body of stmt, adding a symbol of
+// kind for it.
+func (r *resolver) function(stmt *ast.FunctionStmt, kind int) {
+	children := make([]DocumentSymbol, 0)
+	enclosing := r.symbols
+	r.symbols = &children
+
+	r.beginScope()
+	for _, param := range stmt.Params() {
+		r.declare(param, SymbolKindVariable, nil).parameter = true
+	}
+	r.resolveStmts(stmt.Body())
+	r.endScope()
+
+	r.symbols = enclosing
+	nameRange := r.doc.tokenRange(stmt.Name())
+	r.addSymbol(DocumentSymbol{
+		Name:           *stmt.Name().Lexeme,
+		Detail:         functionSignature(stmt),
+		Kind:           kind,
+		Range:          nameRange,
+		SelectionRange: nameRange,
+		Children:       children,
+	})
+}
+
+func (r *resolver) VisitReturnStmt(stmt *ast.ReturnStmt) any {
+	r.resolveExpr(stmt.Value())
+	return nil
+}
+
+func (r *resolver) VisitYieldStmt(stmt *ast.YieldStmt) any {
+	r.resolveExpr(stmt.Value())
+	return nil
+}
+
+func (r *resolver) VisitThrowStmt(stmt *ast.ThrowStmt) any {
+	r.resolveExpr(stmt.Value())
+	return nil
+}
+
+func (r *resolver) VisitTryStmt(stmt *ast.TryStmt) any {
+	r.beginScope()
+	r.resolveStmts(stmt.Body())
+	r.endScope()
+	if stmt.CatchName() != nil {
+		r.beginScope()
+		r.declare(stmt.CatchName(), SymbolKindVariable, nil)
+		r.resolveStmts(stmt.CatchBody())
+		r.endScope()
+	}
+	if stmt.Finally() != nil {
+		r.beginScope()
+		r.resolveStmts(stmt.FinallyBody())
+		r.endScope()
+	}
+	return nil
+}
+
+func (r *resolver) VisitSelectStmt(stmt *ast.SelectStmt) any {
+	for i, c := range stmt.Cases() {
+		r.resolveExpr(c)
+		r.beginScope()
+		if name := stmt.Names()[i]; name != nil {
+			r.declare(name, SymbolKindVariable, nil)
+		}
+		r.resolveStmts([]ast.Stmt{stmt.Bodies()[i]})
+		r.endScope()
+	}
+	r.resolveStmts([]ast.Stmt{stmt.ElseBranch()})
+	return nil
+}
+
+// Expressions
+
+func (r *resolver) VisitBinaryExpr(expr *ast.BinaryExpr) any {
+	r.resolveExpr(expr.Left())
+	r.resolveExpr(expr.Right())
+	return nil
+}
+
+func (r *resolver) VisitUnaryExpr(expr *ast.UnaryExpr) any {
+	r.resolveExpr(expr.Right())
+	return nil
+}
+
+func (r *resolver) VisitLiteralExpr(expr *ast.LiteralExpr) any {
+	return nil
+}
+
+func (r *resolver) VisitGroupingExpr(expr *ast.GroupingExpr) any {
+	r.resolveExpr(expr.Expr())
+	return nil
+}
+
+func (r *resolver) VisitVariableExpr(expr *ast.VariableExpr) any {
+	r.reference(expr.Name())
+	return nil
+}
+
+func (r *resolver) VisitAssignExpr(expr *ast.AssignExpr) any {
+	r.resolveExpr(expr.Value())
+	r.reference(expr.Name())
+	return nil
+}
+
+func (r *resolver) VisitCompoundAssignExpr(expr *ast.CompoundAssignExpr) any {
+	r.resolveExpr(expr.Value())
+	r.reference(expr.Name())
+	return nil
+}
+
+func (r *resolver) VisitUpdateExpr(expr *ast.UpdateExpr) any {
+	r.reference(expr.Name())
+	return nil
+}
+
+func (r *resolver) VisitLogicalExpr(expr *ast.LogicalExpr) any {
+	r.resolveExpr(expr.Left())
+	r.resolveExpr(expr.Right())
+	return nil
+}
+
+func (r *resolver) VisitConditionalExpr(expr *ast.ConditionalExpr) any {
+	r.resolveExpr(expr.Condition())
+	r.resolveExpr(expr.ThenBranch())
+	r.resolveExpr(expr.ElseBranch())
+	return nil
+}
+
+func (r *resolver) VisitCoalesceExpr(expr *ast.CoalesceExpr) any {
+	r.resolveExpr(expr.Left())
+	r.resolveExpr(expr.Right())
+	return nil
+}
+
+// property names aren't variables, only the object is resolved
+
+func (r *resolver) VisitGetExpr(expr *ast.GetExpr) any {
+	r.resolveExpr(expr.Object())
+	return nil
+}
+
+func (r *resolver) VisitOptionalGetExpr(expr *ast.OptionalGetExpr) any {
+	r.resolveExpr(expr.Object())
+	return nil
+}
+
+func (r *resolver) VisitIndexExpr(expr *ast.IndexExpr) any {
+	r.resolveExpr(expr.Object())
+	r.resolveExpr(expr.Index())
+	return nil
+}
+
+func (r *resolver) VisitSetExpr(expr *ast.SetExpr) any {
+	r.resolveExpr(expr.Object())
+	r.resolveExpr(expr.Value())
+	return nil
+}
+
+func (r *resolver) VisitSetIndexExpr(expr *ast.SetIndexExpr) any {
+	r.resolveExpr(expr.Object())
+	r.resolveExpr(expr.Index())
+	r.resolveExpr(expr.Value())
+	return nil
+}
+
+func (r *resolver) VisitCompoundSetExpr(expr *ast.CompoundSetExpr) any {
+	r.resolveExpr(expr.Object())
+	r.resolveExpr(expr.Value())
+	return nil
+}
+
+func (r *resolver) VisitCompoundSetIndexExpr(expr *ast.CompoundSetIndexExpr) any {
+	r.resolveExpr(expr.Object())
+	r.resolveExpr(expr.Index())
+	r.resolveExpr(expr.Value())
+	return nil
+}
+
+func (r *resolver) VisitUpdatePropertyExpr(expr *ast.UpdatePropertyExpr) any {
+	r.resolveExpr(expr.Object())
+	return nil
+}
+
+func (r *resolver) VisitUpdateIndexExpr(expr *ast.UpdateIndexExpr) any {
+	r.resolveExpr(expr.Object())
+	r.resolveExpr(expr.Index())
+	return nil
+}
+
+func (r *resolver) VisitThisExpr(expr *ast.ThisExpr) any {
+	return nil
+}
+
+func (r *resolver) VisitListExpr(expr *ast.ListExpr) any {
+	for _, element := range expr.Elements() {
+		r.resolveExpr(element)
+	}
+	return nil
+}
+
+func (r *resolver) VisitMapExpr(expr *ast.MapExpr) any {
+	for i, key := range expr.Keys() {
+		r.resolveExpr(key)
+		r.resolveExpr(expr.Values()[i])
+	}
+	return nil
+}
+
+func (r *resolver) VisitCallExpr(expr *ast.CallExpr) any {
+	r.resolveExpr(expr.Callee())
+	for _, argument := range expr.Arguments() {
+		r.resolveExpr(argument)
+	}
+	return nil
+}
+
+// VisitMatchExpr resolves each arm in a scope holding the variables its
+// pattern binds. The classes named by class patterns are references.
+func (r *resolver) VisitMatchExpr(expr *ast.MatchExpr) any {
+	r.resolveExpr(expr.Subject())
+	for i, pattern := range expr.Patterns() {
+		ast.Inspect(pattern, func(node ast.Node) bool {
+			if class, ok := node.(*ast.ClassPattern); ok {
+				r.reference(class.Name())
+			}
+			return true
+		})
+		r.beginScope()
+		for _, name := range ast.PatternBindings(pattern) {
+			r.declare(name, SymbolKindVariable, nil)
+		}
+		r.resolveExpr(expr.Guards()[i])
+		r.resolveExpr(expr.Values()[i])
+		r.endScope()
+	}
+	return nil
+}
+
+func (r *resolver) VisitSpawnExpr(expr *ast.SpawnExpr) any {
+	r.resolveExpr(expr.Call())
+	return nil
+}
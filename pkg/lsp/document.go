@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+// document is an open text document together with what analysis found in
+// its latest version.
+type document struct {
+	uri  string
+	text string
+	// lineStarts holds the byte offset of every line's first character
+	lineStarts []int
+	analysis   *analysis
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{
+		uri:        uri,
+		text:       text,
+		lineStarts: []int{0},
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+	d.analysis = analyze(d)
+	return d
+}
+
+// position converts a byte offset to an LSP position, whose character is
+// counted in UTF-16 code units.
+func (d *document) position(offset int) Position {
+	if offset > len(d.text) {
+		offset = len(d.text)
+	}
+	line := sort.Search(len(d.lineStarts), func(i int) bool {
+		return d.lineStarts[i] > offset
+	}) - 1
+
+	character := 0
+	for _, r := range d.text[d.lineStarts[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// offset converts an LSP position back to a byte offset.
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+
+	offset := d.lineStarts[pos.Line]
+	character := 0
+	for offset < len(d.text) && character < pos.Character {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		if r == '\n' {
+			break
+		}
+		character += utf16Len(r)
+		offset += size
+	}
+	return offset
+}
+
+func (d *document) span(start, end int) Range {
+	return Range{Start: d.position(start), End: d.position(end)}
+}
+
+func (d *document) tokenRange(token *ast.Token) Range {
+	return d.span(token.Offset, token.Offset+len(*token.Lexeme))
+}
+
+// tokenAt returns the token covering offset, if any. A position right after
+// a token, where the cursor usually sits, counts as on it.
+func (d *document) tokenAt(offset int) *ast.Token {
+	tokens := d.analysis.tokens
+	i := sort.Search(len(tokens), func(i int) bool {
+		return tokens[i].Offset > offset
+	}) - 1
+
+	for ; i >= 0 && i < len(tokens); i-- {
+		token := tokens[i]
+		if token.Type == ast.TokenEOF {
+			continue
+		}
+		if offset <= token.Offset+len(*token.Lexeme) {
+			return token
+		}
+		break
+	}
+	return nil
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
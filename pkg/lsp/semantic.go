@@ -0,0 +1,151 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+// Semantic token types, indexes into semanticTokenTypes.
+const (
+	semanticKeyword = iota
+	semanticOperator
+	semanticString
+	semanticNumber
+	semanticComment
+	semanticVariable
+	semanticFunction
+	semanticParameter
+	semanticClass
+	semanticMethod
+)
+
+var semanticTokenTypes = []string{
+	semanticKeyword:   "keyword",
+	semanticOperator:  "operator",
+	semanticString:    "string",
+	semanticNumber:    "number",
+	semanticComment:   "comment",
+	semanticVariable:  "variable",
+	semanticFunction:  "function",
+	semanticParameter: "parameter",
+	semanticClass:     "class",
+	semanticMethod:    "method",
+}
+
+var operatorTokens = map[ast.TokenType]bool{
+	ast.TokenMinus:            true,
+	ast.TokenPlus:             true,
+	ast.TokenSlash:            true,
+	ast.TokenStar:             true,
+	ast.TokenEqual:            true,
+	ast.TokenEqualEqual:       true,
+	ast.TokenBang:             true,
+	ast.TokenBangEqual:        true,
+	ast.TokenGreater:          true,
+	ast.TokenGreaterEqual:     true,
+	ast.TokenLess:             true,
+	ast.TokenLessEqual:        true,
+	ast.TokenAnd:              true,
+	ast.TokenOr:               true,
+	ast.TokenQuestion:         true,
+	ast.TokenColon:            true,
+	ast.TokenQuestionQuestion: true,
+	ast.TokenQuestionDot:      true,
+	ast.TokenPercent:          true,
+	ast.TokenAmpersand:        true,
+	ast.TokenPipe:             true,
+	ast.TokenCaret:            true,
+	ast.TokenTilde:            true,
+	ast.TokenPlusEqual:        true,
+	ast.TokenMinusEqual:       true,
+	ast.TokenStarEqual:        true,
+	ast.TokenSlashEqual:       true,
+	ast.TokenPercentEqual:     true,
+	ast.TokenPlusPlus:         true,
+	ast.TokenMinusMinus:       true,
+	ast.TokenStarStar:         true,
+	ast.TokenLessLess:         true,
+	ast.TokenGreaterGreater:   true,
+}
+
+// semanticType classifies token, returning false for tokens left to the
+// editor's own highlighting, such as punctuation.
+func (a *analysis) semanticType(token *ast.Token) (int, bool) {
+	switch token.Type {
+	case ast.TokenString:
+		return semanticString, true
+	case ast.TokenNumber:
+		return semanticNumber, true
+	case ast.TokenComment, ast.TokenCStyleComment:
+		return semanticComment, true
+	case ast.TokenIdentifier:
+		decl, ok := a.references[token]
+		if !ok {
+			decl, ok = a.declarations[token]
+		}
+		switch {
+		case ok && decl.kind == SymbolKindFunction:
+			return semanticFunction, true
+		case ok && decl.kind == SymbolKindClass:
+			return semanticClass, true
+		case ok && decl.kind == SymbolKindMethod:
+			return semanticMethod, true
+		case ok && decl.parameter:
+			return semanticParameter, true
+		}
+		return semanticVariable, true
+	}
+
+	if operatorTokens[token.Type] {
+		// slang spellings like "ong" are operators too
+		return semanticOperator, true
+	}
+	if _, ok := lookupKeyword(*token.Lexeme); ok {
+		return semanticKeyword, true
+	}
+	return 0, false
+}
+
+// semanticTokens encodes the document's tokens in the relative format of
+// textDocument/semanticTokens. Tokens spanning lines are split since not
+// every client supports multi-line tokens.
+func semanticTokens(doc *document) []int {
+	data := make([]int, 0)
+	prevLine, prevChar := 0, 0
+
+	emit := func(start, end, tokenType int) {
+		if start >= end {
+			return
+		}
+		pos := doc.position(start)
+		length := doc.position(end).Character - pos.Character
+
+		deltaChar := pos.Character
+		if pos.Line == prevLine {
+			deltaChar -= prevChar
+		}
+		data = append(data, pos.Line-prevLine, deltaChar, length, tokenType, 0)
+		prevLine, prevChar = pos.Line, pos.Character
+	}
+
+	for _, token := range doc.analysis.tokens {
+		tokenType, ok := doc.analysis.semanticType(token)
+		if !ok {
+			continue
+		}
+
+		start := token.Offset
+		end := token.Offset + len(*token.Lexeme)
+		for start < end {
+			lineEnd := strings.IndexByte(doc.text[start:end], '\n')
+			if lineEnd == -1 {
+				emit(start, end, tokenType)
+				break
+			}
+			emit(start, start+lineEnd, tokenType)
+			start += lineEnd + 1
+		}
+	}
+	return data
+}
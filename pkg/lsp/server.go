@@ -0,0 +1,333 @@
+// Package lsp implements a Language Server Protocol server for rottenlang
+// over stdio.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+var ErrExitWithoutShutdown = errors.New("exit notification received before shutdown")
+
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+var handlers = map[string]handler{
+	"initialize":                       (*Server).initialize,
+	"initialized":                      nil,
+	"shutdown":                         (*Server).shutdown,
+	"textDocument/didOpen":             (*Server).didOpen,
+	"textDocument/didChange":           (*Server).didChange,
+	"textDocument/didClose":            (*Server).didClose,
+	"textDocument/hover":               (*Server).hover,
+	"textDocument/definition":          (*Server).definition,
+	"textDocument/documentSymbol":      (*Server).documentSymbol,
+	"textDocument/completion":          (*Server).completion,
+	"textDocument/semanticTokens/full": (*Server).semanticTokensFull,
+}
+
+type Server struct {
+	in  *bufio.Reader
+	out io.Writer
+	// writeMu serializes writes to out
+	writeMu sync.Mutex
+
+	documents    map[string]*document
+	shutdownSeen bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: make(map[string]*document),
+	}
+}
+
+// Run serves requests until the client sends "exit" or closes the input.
+func (s *Server) Run() error {
+	for {
+		body, err := readMessage(s.in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdownSeen {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		s.handle(&req)
+	}
+}
+
+func (s *Server) handle(req *request) {
+	h, ok := handlers[req.Method]
+	if !ok {
+		// unknown notifications are ignored, unknown requests are errors
+		if req.ID != nil {
+			s.reply(req.ID, nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)})
+		}
+		return
+	}
+	if h == nil {
+		return
+	}
+
+	result, err := h(s, req.Params)
+	if req.ID == nil {
+		return
+	}
+	if err != nil {
+		var rpcErr *responseError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &responseError{Code: codeInvalidRequest, Message: err.Error()}
+		}
+		s.reply(req.ID, nil, rpcErr)
+		return
+	}
+	s.reply(req.ID, result, nil)
+}
+
+func (s *Server) reply(id *json.RawMessage, result any, rpcErr *responseError) {
+	s.send(&response{JSONRPC: "2.0", ID: id, Result: result, Error: rpcErr})
+}
+
+func (s *Server) notify(method string, params any) {
+	s.send(&notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *Server) send(message any) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	// nothing sensible to do when the client went away
+	_ = writeMessage(s.out, message)
+}
+
+func decode(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	doc, ok := s.documents[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown document %s", uri)}
+	}
+	return doc, nil
+}
+
+// Lifecycle
+
+func (s *Server) initialize(params json.RawMessage) (any, error) {
+	return map[string]any{
+		"capabilities": map[string]any{
+			// full document sync
+			"textDocumentSync":       1,
+			"hoverProvider":          true,
+			"definitionProvider":     true,
+			"documentSymbolProvider": true,
+			"completionProvider":     map[string]any{},
+			"semanticTokensProvider": map[string]any{
+				"legend": SemanticTokensLegend{
+					TokenTypes:     semanticTokenTypes,
+					TokenModifiers: []string{},
+				},
+				"full": true,
+			},
+		},
+		"serverInfo": map[string]any{
+			"name": "rottenlang",
+		},
+	}, nil
+}
+
+func (s *Server) shutdown(params json.RawMessage) (any, error) {
+	s.shutdownSeen = true
+	return nil, nil
+}
+
+// Document synchronization
+
+func (s *Server) didOpen(params json.RawMessage) (any, error) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	s.update(p.TextDocument.URI, p.TextDocument.Text)
+	return nil, nil
+}
+
+func (s *Server) didChange(params json.RawMessage) (any, error) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.ContentChanges) == 0 {
+		return nil, nil
+	}
+	// with full sync the last change holds the whole document
+	s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+	return nil, nil
+}
+
+func (s *Server) didClose(params json.RawMessage) (any, error) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.documents, p.TextDocument.URI)
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+	return nil, nil
+}
+
+func (s *Server) update(uri, text string) {
+	doc := newDocument(uri, text)
+	s.documents[uri] = doc
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: doc.analysis.diagnostics})
+}
+
+// Language features
+
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	token := doc.tokenAt(doc.offset(p.Position))
+	if token == nil {
+		return nil, nil
+	}
+
+	contents := fmt.Sprintf("`%s`", token.Name())
+	if keyword, ok := lookupKeyword(*token.Lexeme); ok {
+		contents += fmt.Sprintf(" — %s", keyword.Meaning)
+	}
+	if token.Type == ast.TokenIdentifier {
+		if decl := doc.analysis.lookup(token); decl != nil && decl.function != nil {
+			contents = fmt.Sprintf("```rottenlang\n%s\n```\n%s", functionSignature(decl.function), contents)
+		}
+	}
+
+	tokenRange := doc.tokenRange(token)
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: contents},
+		Range:    &tokenRange,
+	}, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	token := doc.tokenAt(doc.offset(p.Position))
+	if token == nil || token.Type != ast.TokenIdentifier {
+		return nil, nil
+	}
+	decl := doc.analysis.lookup(token)
+	if decl == nil {
+		return nil, nil
+	}
+	return &Location{URI: doc.uri, Range: doc.tokenRange(decl.name)}, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (any, error) {
+	var p DocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return doc.analysis.symbols, nil
+}
+
+func (s *Server) completion(params json.RawMessage) (any, error) {
+	items := make([]CompletionItem, 0)
+	for _, keyword := range ast.Keywords() {
+		token := ast.NewToken(keyword.Type, &keyword.Spelling, nil, 0, 0)
+		items = append(items, CompletionItem{
+			Label:         keyword.Spelling,
+			Kind:          CompletionKindKeyword,
+			Detail:        token.Name(),
+			Documentation: keyword.Meaning,
+		})
+	}
+
+	builtins := append(value.Builtins(io.Discard), value.NewScheduler(nil).Builtins()...)
+	sort.Slice(builtins, func(i, j int) bool {
+		return builtins[i].Name() < builtins[j].Name()
+	})
+	for _, builtin := range builtins {
+		items = append(items, CompletionItem{
+			Label:  builtin.Name(),
+			Kind:   CompletionKindFunction,
+			Detail: builtin.String(),
+		})
+	}
+	return items, nil
+}
+
+func (s *Server) semanticTokensFull(params json.RawMessage) (any, error) {
+	var p DocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	doc, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	return &SemanticTokens{Data: semanticTokens(doc)}, nil
+}
+
+// lookup returns the declaration token refers to, or declares.
+func (a *analysis) lookup(token *ast.Token) *declaration {
+	if decl, ok := a.references[token]; ok {
+		return decl
+	}
+	return a.declarations[token]
+}
+
+// lookupKeyword finds the keywords table entry for lexeme. Multi-word
+// keywords may have been written with any spacing between their words.
+func lookupKeyword(lexeme string) (ast.Keyword, bool) {
+	spelling := strings.Join(strings.Fields(lexeme), " ")
+	for _, keyword := range ast.Keywords() {
+		if keyword.Spelling == spelling {
+			return keyword, true
+		}
+	}
+	return ast.Keyword{}, false
+}
@@ -0,0 +1,172 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+const uri = "file:///test.rot"
+
+const source = `func double(n) {
+  purrr n ong 2;
+}
+vibes x = double(21);
+print(x + y;
+`
+
+// session runs the server over the given requests and returns its replies
+// by id, plus the notifications it sent.
+func session(t *testing.T, requests ...map[string]any) (map[int]json.RawMessage, []request) {
+	t.Helper()
+
+	var in bytes.Buffer
+	for _, req := range requests {
+		req["jsonrpc"] = "2.0"
+		if err := writeMessage(&in, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeMessage(&in, map[string]any{"jsonrpc": "2.0", "id": 999, "method": "shutdown"})
+	writeMessage(&in, map[string]any{"jsonrpc": "2.0", "method": "exit"})
+
+	var out bytes.Buffer
+	if err := NewServer(&in, &out).Run(); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+
+	results := make(map[int]json.RawMessage)
+	notifications := make([]request, 0)
+	r := bufio.NewReader(&out)
+	for {
+		body, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		var msg struct {
+			ID     *int            `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+			Result json.RawMessage `json:"result"`
+			Error  *responseError  `json:"error"`
+		}
+		if err := json.Unmarshal(body, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Error != nil {
+			t.Fatalf("request %d failed: %s", *msg.ID, msg.Error.Message)
+		}
+		if msg.ID != nil {
+			results[*msg.ID] = msg.Result
+		} else {
+			notifications = append(notifications, request{Method: msg.Method, Params: msg.Params})
+		}
+	}
+	return results, notifications
+}
+
+func open() map[string]any {
+	return map[string]any{
+		"method": "textDocument/didOpen",
+		"params": map[string]any{"textDocument": map[string]any{"uri": uri, "version": 1, "text": source}},
+	}
+}
+
+func at(id int, method string, line, character int) map[string]any {
+	return map[string]any{
+		"id":     id,
+		"method": method,
+		"params": map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": line, "character": character},
+		},
+	}
+}
+
+func TestServer(t *testing.T) {
+	results, notifications := session(t,
+		map[string]any{"id": 1, "method": "initialize", "params": map[string]any{}},
+		open(),
+		// the "double" in "double(21)"
+		at(2, "textDocument/definition", 3, 12),
+		// the "ong" operator
+		at(3, "textDocument/hover", 1, 11),
+		map[string]any{"id": 4, "method": "textDocument/documentSymbol", "params": map[string]any{"textDocument": map[string]any{"uri": uri}}},
+		map[string]any{"id": 5, "method": "textDocument/completion", "params": map[string]any{"textDocument": map[string]any{"uri": uri}}},
+		map[string]any{"id": 6, "method": "textDocument/semanticTokens/full", "params": map[string]any{"textDocument": map[string]any{"uri": uri}}},
+	)
+
+	t.Run("diagnostics", func(t *testing.T) {
+		if len(notifications) != 1 || notifications[0].Method != "textDocument/publishDiagnostics" {
+			t.Fatalf("got notifications %v", notifications)
+		}
+		var p PublishDiagnosticsParams
+		json.Unmarshal(notifications[0].Params, &p)
+		if len(p.Diagnostics) != 1 || p.Diagnostics[0].Range.Start.Line != 4 {
+			t.Errorf("got diagnostics %+v, want one on line 4", p.Diagnostics)
+		}
+	})
+
+	t.Run("definition", func(t *testing.T) {
+		var loc Location
+		json.Unmarshal(results[2], &loc)
+		want := Range{Start: Position{0, 5}, End: Position{0, 11}}
+		if loc.Range != want {
+			t.Errorf("got %+v, want %+v", loc.Range, want)
+		}
+	})
+
+	t.Run("hover", func(t *testing.T) {
+		var hover Hover
+		json.Unmarshal(results[3], &hover)
+		if want := "`STAR` — Multiplication"; hover.Contents.Value != want {
+			t.Errorf("got %q, want %q", hover.Contents.Value, want)
+		}
+	})
+
+	t.Run("symbols", func(t *testing.T) {
+		var symbols []DocumentSymbol
+		json.Unmarshal(results[4], &symbols)
+		if len(symbols) != 2 || symbols[0].Name != "double" || symbols[1].Name != "x" {
+			t.Errorf("got %+v", symbols)
+		}
+	})
+
+	t.Run("completion", func(t *testing.T) {
+		var items []CompletionItem
+		json.Unmarshal(results[5], &items)
+		keywords := 0
+		for _, item := range items {
+			if item.Kind == CompletionKindKeyword {
+				keywords++
+			}
+		}
+		if keywords != len(ast.Keywords()) {
+			t.Errorf("got %d keyword completions, want %d", keywords, len(ast.Keywords()))
+		}
+	})
+
+	t.Run("semantic tokens", func(t *testing.T) {
+		var tokens SemanticTokens
+		json.Unmarshal(results[6], &tokens)
+		// walk to the token starting at line 1, character 10: "ong"
+		line, char := 0, 0
+		for i := 0; i < len(tokens.Data); i += 5 {
+			if tokens.Data[i] > 0 {
+				char = 0
+			}
+			line += tokens.Data[i]
+			char += tokens.Data[i+1]
+			if line == 1 && char == 10 {
+				if tokens.Data[i+3] != semanticOperator {
+					t.Errorf("ong classified as %s", semanticTokenTypes[tokens.Data[i+3]])
+				}
+				return
+			}
+		}
+		t.Error("no semantic token for ong")
+	})
+}
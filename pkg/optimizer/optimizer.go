@@ -0,0 +1,255 @@
+// Package optimizer rewrites programs into simpler ones that run the same
+// way, computing at compile time what doesn't depend on the program's
+// input.
+package optimizer
+
+import (
+	"math"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// Pass is one rewrite of a program. The tree is rewritten bottom-up: Expr
+// and Stmt are handed each node once its children have been rewritten, and
+// return the node taking its place, which is the node itself when it
+// stays. Stmt returns nil to remove a statement. Either may be nil.
+type Pass struct {
+	Name string
+	Expr func(expr ast.Expr) ast.Expr
+	Stmt func(stmt ast.Stmt) ast.Stmt
+}
+
+// Passes lists the passes Optimize runs, in order.
+var Passes = []Pass{FoldConstants, EliminateDeadBranches, RemoveGroupings}
+
+// Optimize runs every pass of Passes over statements.
+func Optimize(statements []ast.Stmt) []ast.Stmt {
+	return Run(statements, Passes...)
+}
+
+// Run runs passes over statements, one after the other, returning the
+// rewritten program. The statements given aren't modified.
+func Run(statements []ast.Stmt, passes ...Pass) []ast.Stmt {
+	for _, pass := range passes {
+		statements = (&rewriter{pass: pass}).statements(statements)
+	}
+	return statements
+}
+
+// FoldConstants replaces the operations on literals with their result, as
+// in "1 + 2 * 3" becoming "7". An operation that fails, such as "1 + nocap",
+// is left to fail when the program runs. So is one giving a string, which
+// the program's limits may reject, or a number with no literal, such as
+// "1 / 0".
+var FoldConstants = Pass{
+	Name: "fold-constants",
+	Expr: func(expr ast.Expr) ast.Expr {
+		switch expr := expr.(type) {
+		case *ast.BinaryExpr:
+			left, ok := constant(expr.Left())
+			if !ok {
+				return expr
+			}
+			right, ok := constant(expr.Right())
+			if !ok {
+				return expr
+			}
+			if result, err := value.Binary(expr.Operator().Type, left, right); err == nil && foldable(result) {
+				return ast.NewLiteralExpr(result)
+			}
+		case *ast.UnaryExpr:
+			right, ok := constant(expr.Right())
+			if !ok {
+				return expr
+			}
+			if result, err := value.Unary(expr.Operator().Type, right); err == nil && foldable(result) {
+				return ast.NewLiteralExpr(result)
+			}
+		}
+		return expr
+	},
+}
+
+// EliminateDeadBranches removes the code a constant condition never runs:
+// the branch not taken by an if statement or a conditional expression, a
+// while loop whose condition is false, and the right operand of a logical
+// or coalescing expression whose left operand decides its value.
+var EliminateDeadBranches = Pass{
+	Name: "eliminate-dead-branches",
+	Expr: func(expr ast.Expr) ast.Expr {
+		switch expr := expr.(type) {
+		case *ast.ConditionalExpr:
+			if condition, ok := constant(expr.Condition()); ok {
+				if value.IsTruthy(condition) {
+					return expr.ThenBranch()
+				}
+				return expr.ElseBranch()
+			}
+		case *ast.LogicalExpr:
+			if left, ok := constant(expr.Left()); ok {
+				// "or" stops at a true left operand, "and" at a false one
+				if value.IsTruthy(left) == (expr.Operator().Type == ast.TokenOr) {
+					return expr.Left()
+				}
+				return expr.Right()
+			}
+		case *ast.CoalesceExpr:
+			if left, ok := constant(expr.Left()); ok {
+				if left != nil {
+					return expr.Left()
+				}
+				return expr.Right()
+			}
+		}
+		return expr
+	},
+	Stmt: func(stmt ast.Stmt) ast.Stmt {
+		switch stmt := stmt.(type) {
+		case *ast.IfStmt:
+			if condition, ok := constant(stmt.Condition()); ok {
+				if value.IsTruthy(condition) {
+					return stmt.ThenBranch()
+				}
+				// nil when there is no else branch, removing the statement
+				return stmt.ElseBranch()
+			}
+		case *ast.WhileStmt:
+			if condition, ok := constant(stmt.Condition()); ok && !value.IsTruthy(condition) {
+				return nil
+			}
+		}
+		return stmt
+	},
+}
+
+// RemoveGroupings removes the parentheses that don't change how a program
+// parses: those around a literal, a variable, a list, a map, a call, a
+// property or an index outside of an optional chain, or other parentheses,
+// and those around a whole expression, such as the condition of an if
+// statement, the argument of a call or an index.
+var RemoveGroupings = Pass{
+	Name: "remove-groupings",
+	Expr: func(expr ast.Expr) ast.Expr {
+		switch expr := expr.(type) {
+		case *ast.GroupingExpr:
+			if atomic(expr.Expr()) {
+				return expr.Expr()
+			}
+		case *ast.CallExpr:
+			arguments := make([]ast.Expr, len(expr.Arguments()))
+			for i, argument := range expr.Arguments() {
+				arguments[i] = ungroup(argument)
+			}
+			return ast.NewCallExpr(expr.Callee(), expr.Paren(), arguments)
+		case *ast.AssignExpr:
+			return ast.NewAssignExpr(expr.Name(), ungroup(expr.Value()))
+		case *ast.SetExpr:
+			return ast.NewSetExpr(expr.Object(), expr.Name(), ungroup(expr.Value()))
+		case *ast.SetIndexExpr:
+			return ast.NewSetIndexExpr(expr.Object(), expr.Bracket(), ungroup(expr.Index()), ungroup(expr.Value()))
+		case *ast.IndexExpr:
+			return ast.NewIndexExpr(expr.Object(), expr.Bracket(), ungroup(expr.Index()))
+		case *ast.CompoundAssignExpr:
+			return ast.NewCompoundAssignExpr(expr.Name(), expr.Operator(), ungroup(expr.Value()))
+		case *ast.CompoundSetExpr:
+			return ast.NewCompoundSetExpr(expr.Object(), expr.Name(), expr.Operator(), ungroup(expr.Value()))
+		case *ast.CompoundSetIndexExpr:
+			return ast.NewCompoundSetIndexExpr(expr.Object(), expr.Bracket(), ungroup(expr.Index()), expr.Operator(), ungroup(expr.Value()))
+		case *ast.UpdateIndexExpr:
+			return ast.NewUpdateIndexExpr(expr.Object(), expr.Bracket(), ungroup(expr.Index()), expr.Operator(), expr.Prefix())
+		case *ast.MatchExpr:
+			return ast.NewMatchExpr(expr.Keyword(), ungroup(expr.Subject()), expr.Patterns(), expr.Guards(), expr.Values())
+		}
+		return expr
+	},
+	Stmt: func(stmt ast.Stmt) ast.Stmt {
+		switch stmt := stmt.(type) {
+		case *ast.ExpressionStmt:
+			return ast.NewExpressionStmt(ungroup(stmt.Expression()))
+		case *ast.VarStmt:
+			return ast.NewVarStmt(stmt.Name(), ungroup(stmt.Initializer()), stmt.Constant())
+		case *ast.IfStmt:
+			return ast.NewIfStmt(ungroup(stmt.Condition()), stmt.ThenBranch(), stmt.ElseBranch())
+		case *ast.WhileStmt:
+			return ast.NewWhileStmt(stmt.Keyword(), ungroup(stmt.Condition()), stmt.Body())
+		case *ast.ForInStmt:
+			return ast.NewForInStmt(stmt.Keyword(), stmt.Name(), ungroup(stmt.Iterable()), stmt.Body())
+		case *ast.ReturnStmt:
+			return ast.NewReturnStmt(stmt.Keyword(), ungroup(stmt.Value()))
+		case *ast.YieldStmt:
+			return ast.NewYieldStmt(stmt.Keyword(), ungroup(stmt.Value()))
+		case *ast.ThrowStmt:
+			return ast.NewThrowStmt(stmt.Keyword(), ungroup(stmt.Value()))
+		}
+		return stmt
+	},
+}
+
+// foldable reports whether a folded operation can be replaced by result.
+func foldable(result any) bool {
+	switch result := result.(type) {
+	case string:
+		return false
+	case float64:
+		return !math.IsInf(result, 0) && !math.IsNaN(result)
+	}
+	return true
+}
+
+// constant returns the value of expr when it is a literal, possibly in
+// parentheses.
+func constant(expr ast.Expr) (any, bool) {
+	if literal, ok := ungroup(expr).(*ast.LiteralExpr); ok {
+		return literal.Value(), true
+	}
+	return nil, false
+}
+
+// ungroup returns expr without the parentheses around it.
+func ungroup(expr ast.Expr) ast.Expr {
+	for {
+		grouping, ok := expr.(*ast.GroupingExpr)
+		if !ok {
+			return expr
+		}
+		expr = grouping.Expr()
+	}
+}
+
+// atomic reports whether expr parses the same with or without parentheses
+// wherever it appears.
+func atomic(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.LiteralExpr:
+		// a negative number is written with a minus operator
+		n, ok := expr.Value().(float64)
+		return !ok || !math.Signbit(n)
+	case *ast.VariableExpr, *ast.GroupingExpr, *ast.ThisExpr, *ast.ListExpr, *ast.MapExpr:
+		return true
+	case *ast.CallExpr, *ast.GetExpr, *ast.OptionalGetExpr, *ast.IndexExpr:
+		// parentheses end a chain, so that nil?.a in (nil?.a).b doesn't
+		// skip .b
+		return !optionalChain(expr)
+	}
+	return false
+}
+
+// optionalChain reports whether expr ends a chain of property accesses and
+// calls holding an optional access.
+func optionalChain(expr ast.Expr) bool {
+	for {
+		switch link := expr.(type) {
+		case *ast.OptionalGetExpr:
+			return true
+		case *ast.GetExpr:
+			expr = link.Object()
+		case *ast.IndexExpr:
+			expr = link.Object()
+		case *ast.CallExpr:
+			expr = link.Callee()
+		default:
+			return false
+		}
+	}
+}
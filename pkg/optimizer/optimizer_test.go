@@ -0,0 +1,60 @@
+package optimizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/golden"
+	"github.com/bagaswh/rottenlang/pkg/parser"
+	"github.com/bagaswh/rottenlang/pkg/printer"
+	"github.com/bagaswh/rottenlang/pkg/scanner"
+)
+
+func parse(t *testing.T, source string) []ast.Stmt {
+	t.Helper()
+	tokens, err := scanner.NewScanner(strings.NewReader(source), 0).ScanTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parser.NewParser(&errorreporter.NopErrorReporter{})
+	p.SetTokens(tokens)
+	statements := p.Parse()
+	if p.HadError() {
+		t.Fatal("source doesn't parse")
+	}
+	return statements
+}
+
+// TestGolden optimizes the programs in testdata, comparing them, printed
+// as source code, with the .opt files. The printed programs must parse
+// back to the same trees.
+func TestGolden(t *testing.T) {
+	for _, program := range golden.Programs(t, "testdata") {
+		t.Run(filepath.Base(program), func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			printed := printer.NewASTPrinter().PrintProgram(Optimize(parse(t, string(source)))) + "\n"
+			golden.Check(t, program, ".opt", printed)
+
+			reprinted := printer.NewASTPrinter().PrintProgram(parse(t, printed)) + "\n"
+			if reprinted != printed {
+				t.Errorf("optimized program doesn't parse back to itself\ngot:  %q\nwant: %q", reprinted, printed)
+			}
+		})
+	}
+}
+
+func TestRunLeavesProgram(t *testing.T) {
+	statements := parse(t, "print(1 + 2);")
+	before := printer.NewASTPrinter().PrintProgram(statements)
+	Run(statements, FoldConstants)
+	if after := printer.NewASTPrinter().PrintProgram(statements); after != before {
+		t.Errorf("Run modified the program: %q, was %q", after, before)
+	}
+}
@@ -0,0 +1,243 @@
+package optimizer
+
+import "github.com/bagaswh/rottenlang/pkg/ast"
+
+// rewriter runs a pass over a tree, rebuilding each node from its rewritten
+// children before handing it to the pass.
+type rewriter struct {
+	pass Pass
+}
+
+var (
+	_ ast.Visitor[ast.Expr]     = (*rewriter)(nil)
+	_ ast.StmtVisitor[ast.Stmt] = (*rewriter)(nil)
+)
+
+func (r *rewriter) expr(expr ast.Expr) ast.Expr {
+	if expr == nil {
+		return nil
+	}
+	expr = ast.Accept[ast.Expr](expr, r)
+	if r.pass.Expr != nil {
+		expr = r.pass.Expr(expr)
+	}
+	return expr
+}
+
+func (r *rewriter) exprs(exprs []ast.Expr) []ast.Expr {
+	rewritten := make([]ast.Expr, len(exprs))
+	for i, expr := range exprs {
+		rewritten[i] = r.expr(expr)
+	}
+	return rewritten
+}
+
+// stmt rewrites stmt, returning nil when the pass removes it.
+func (r *rewriter) stmt(stmt ast.Stmt) ast.Stmt {
+	if stmt == nil {
+		return nil
+	}
+	stmt = ast.AcceptStmt[ast.Stmt](stmt, r)
+	if r.pass.Stmt != nil {
+		stmt = r.pass.Stmt(stmt)
+	}
+	return stmt
+}
+
+// body rewrites a statement that can't be left out, such as the body of a
+// loop, replacing it with an empty block when the pass removes it.
+func (r *rewriter) body(stmt ast.Stmt) ast.Stmt {
+	if rewritten := r.stmt(stmt); rewritten != nil {
+		return rewritten
+	}
+	return ast.NewBlockStmt(nil)
+}
+
+func (r *rewriter) statements(statements []ast.Stmt) []ast.Stmt {
+	rewritten := make([]ast.Stmt, 0, len(statements))
+	for _, stmt := range statements {
+		if stmt = r.stmt(stmt); stmt != nil {
+			rewritten = append(rewritten, stmt)
+		}
+	}
+	return rewritten
+}
+
+// Expressions
+
+func (r *rewriter) VisitBinaryExpr(expr *ast.BinaryExpr) ast.Expr {
+	return ast.NewBinaryExpr(r.expr(expr.Left()), expr.Operator(), r.expr(expr.Right()))
+}
+
+func (r *rewriter) VisitUnaryExpr(expr *ast.UnaryExpr) ast.Expr {
+	return ast.NewUnaryExpr(expr.Operator(), r.expr(expr.Right()))
+}
+
+func (r *rewriter) VisitLiteralExpr(expr *ast.LiteralExpr) ast.Expr {
+	return expr
+}
+
+func (r *rewriter) VisitGroupingExpr(expr *ast.GroupingExpr) ast.Expr {
+	return ast.NewGroupingExpr(r.expr(expr.Expr()))
+}
+
+func (r *rewriter) VisitVariableExpr(expr *ast.VariableExpr) ast.Expr {
+	return expr
+}
+
+func (r *rewriter) VisitAssignExpr(expr *ast.AssignExpr) ast.Expr {
+	return ast.NewAssignExpr(expr.Name(), r.expr(expr.Value()))
+}
+
+func (r *rewriter) VisitCompoundAssignExpr(expr *ast.CompoundAssignExpr) ast.Expr {
+	return ast.NewCompoundAssignExpr(expr.Name(), expr.Operator(), r.expr(expr.Value()))
+}
+
+func (r *rewriter) VisitUpdateExpr(expr *ast.UpdateExpr) ast.Expr {
+	return expr
+}
+
+func (r *rewriter) VisitLogicalExpr(expr *ast.LogicalExpr) ast.Expr {
+	return ast.NewLogicalExpr(r.expr(expr.Left()), expr.Operator(), r.expr(expr.Right()))
+}
+
+func (r *rewriter) VisitConditionalExpr(expr *ast.ConditionalExpr) ast.Expr {
+	return ast.NewConditionalExpr(r.expr(expr.Condition()), expr.Question(), r.expr(expr.ThenBranch()), r.expr(expr.ElseBranch()))
+}
+
+func (r *rewriter) VisitCoalesceExpr(expr *ast.CoalesceExpr) ast.Expr {
+	return ast.NewCoalesceExpr(r.expr(expr.Left()), expr.Operator(), r.expr(expr.Right()))
+}
+
+func (r *rewriter) VisitGetExpr(expr *ast.GetExpr) ast.Expr {
+	return ast.NewGetExpr(r.expr(expr.Object()), expr.Name())
+}
+
+func (r *rewriter) VisitOptionalGetExpr(expr *ast.OptionalGetExpr) ast.Expr {
+	return ast.NewOptionalGetExpr(r.expr(expr.Object()), expr.Name())
+}
+
+func (r *rewriter) VisitCallExpr(expr *ast.CallExpr) ast.Expr {
+	return ast.NewCallExpr(r.expr(expr.Callee()), expr.Paren(), r.exprs(expr.Arguments()))
+}
+
+func (r *rewriter) VisitIndexExpr(expr *ast.IndexExpr) ast.Expr {
+	return ast.NewIndexExpr(r.expr(expr.Object()), expr.Bracket(), r.expr(expr.Index()))
+}
+
+func (r *rewriter) VisitSetExpr(expr *ast.SetExpr) ast.Expr {
+	return ast.NewSetExpr(r.expr(expr.Object()), expr.Name(), r.expr(expr.Value()))
+}
+
+func (r *rewriter) VisitSetIndexExpr(expr *ast.SetIndexExpr) ast.Expr {
+	return ast.NewSetIndexExpr(r.expr(expr.Object()), expr.Bracket(), r.expr(expr.Index()), r.expr(expr.Value()))
+}
+
+func (r *rewriter) VisitCompoundSetExpr(expr *ast.CompoundSetExpr) ast.Expr {
+	return ast.NewCompoundSetExpr(r.expr(expr.Object()), expr.Name(), expr.Operator(), r.expr(expr.Value()))
+}
+
+func (r *rewriter) VisitCompoundSetIndexExpr(expr *ast.CompoundSetIndexExpr) ast.Expr {
+	return ast.NewCompoundSetIndexExpr(r.expr(expr.Object()), expr.Bracket(), r.expr(expr.Index()), expr.Operator(), r.expr(expr.Value()))
+}
+
+func (r *rewriter) VisitUpdatePropertyExpr(expr *ast.UpdatePropertyExpr) ast.Expr {
+	return ast.NewUpdatePropertyExpr(r.expr(expr.Object()), expr.Name(), expr.Operator(), expr.Prefix())
+}
+
+func (r *rewriter) VisitUpdateIndexExpr(expr *ast.UpdateIndexExpr) ast.Expr {
+	return ast.NewUpdateIndexExpr(r.expr(expr.Object()), expr.Bracket(), r.expr(expr.Index()), expr.Operator(), expr.Prefix())
+}
+
+func (r *rewriter) VisitThisExpr(expr *ast.ThisExpr) ast.Expr {
+	return expr
+}
+
+func (r *rewriter) VisitListExpr(expr *ast.ListExpr) ast.Expr {
+	return ast.NewListExpr(expr.Bracket(), r.exprs(expr.Elements()))
+}
+
+func (r *rewriter) VisitMapExpr(expr *ast.MapExpr) ast.Expr {
+	return ast.NewMapExpr(expr.Brace(), r.exprs(expr.Keys()), r.exprs(expr.Values()))
+}
+
+func (r *rewriter) VisitMatchExpr(expr *ast.MatchExpr) ast.Expr {
+	return ast.NewMatchExpr(expr.Keyword(), r.expr(expr.Subject()), expr.Patterns(), r.exprs(expr.Guards()), r.exprs(expr.Values()))
+}
+
+func (r *rewriter) VisitSpawnExpr(expr *ast.SpawnExpr) ast.Expr {
+	return ast.NewSpawnExpr(expr.Keyword(), r.expr(expr.Call()))
+}
+
+// Statements
+
+func (r *rewriter) VisitExpressionStmt(stmt *ast.ExpressionStmt) ast.Stmt {
+	return ast.NewExpressionStmt(r.expr(stmt.Expression()))
+}
+
+func (r *rewriter) VisitVarStmt(stmt *ast.VarStmt) ast.Stmt {
+	return ast.NewVarStmt(stmt.Name(), r.expr(stmt.Initializer()), stmt.Constant())
+}
+
+func (r *rewriter) VisitBlockStmt(stmt *ast.BlockStmt) ast.Stmt {
+	return ast.NewBlockStmt(r.statements(stmt.Statements()))
+}
+
+func (r *rewriter) VisitIfStmt(stmt *ast.IfStmt) ast.Stmt {
+	return ast.NewIfStmt(r.expr(stmt.Condition()), r.body(stmt.ThenBranch()), r.stmt(stmt.ElseBranch()))
+}
+
+func (r *rewriter) VisitWhileStmt(stmt *ast.WhileStmt) ast.Stmt {
+	return ast.NewWhileStmt(stmt.Keyword(), r.expr(stmt.Condition()), r.body(stmt.Body()))
+}
+
+func (r *rewriter) VisitFunctionStmt(stmt *ast.FunctionStmt) ast.Stmt {
+	return ast.NewFunctionStmt(stmt.Name(), stmt.Params(), r.statements(stmt.Body()), stmt.Generator())
+}
+
+func (r *rewriter) VisitClassStmt(stmt *ast.ClassStmt) ast.Stmt {
+	// a method can't be left out, so the pass doesn't get to remove it
+	methods := make([]ast.Stmt, len(stmt.Methods()))
+	for i, method := range stmt.Methods() {
+		methods[i] = ast.AcceptStmt[ast.Stmt](method, r)
+	}
+	return ast.NewClassStmt(stmt.Name(), methods)
+}
+
+func (r *rewriter) VisitForInStmt(stmt *ast.ForInStmt) ast.Stmt {
+	return ast.NewForInStmt(stmt.Keyword(), stmt.Name(), r.expr(stmt.Iterable()), r.body(stmt.Body()))
+}
+
+func (r *rewriter) VisitReturnStmt(stmt *ast.ReturnStmt) ast.Stmt {
+	return ast.NewReturnStmt(stmt.Keyword(), r.expr(stmt.Value()))
+}
+
+func (r *rewriter) VisitYieldStmt(stmt *ast.YieldStmt) ast.Stmt {
+	return ast.NewYieldStmt(stmt.Keyword(), r.expr(stmt.Value()))
+}
+
+func (r *rewriter) VisitImportStmt(stmt *ast.ImportStmt) ast.Stmt {
+	return stmt
+}
+
+func (r *rewriter) VisitThrowStmt(stmt *ast.ThrowStmt) ast.Stmt {
+	return ast.NewThrowStmt(stmt.Keyword(), r.expr(stmt.Value()))
+}
+
+func (r *rewriter) VisitTryStmt(stmt *ast.TryStmt) ast.Stmt {
+	return ast.NewTryStmt(stmt.Keyword(), r.statements(stmt.Body()), stmt.CatchName(), r.statements(stmt.CatchBody()), stmt.Finally(), r.statements(stmt.FinallyBody()))
+}
+
+func (r *rewriter) VisitSelectStmt(stmt *ast.SelectStmt) ast.Stmt {
+	bodies := make([]ast.Stmt, len(stmt.Bodies()))
+	for i, body := range stmt.Bodies() {
+		bodies[i] = r.body(body)
+	}
+	// an else branch makes the statement not wait, so it stays even when
+	// empty
+	var elseBranch ast.Stmt
+	if stmt.ElseBranch() != nil {
+		elseBranch = r.body(stmt.ElseBranch())
+	}
+	return ast.NewSelectStmt(stmt.Keyword(), r.exprs(stmt.Cases()), stmt.Names(), bodies, elseBranch)
+}
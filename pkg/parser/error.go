@@ -22,3 +22,11 @@ func NewGenericParserError(token *ast.Token, where, message string) *GenricParse
 		message: message,
 	}
 }
+
+func (err *GenricParserError) Token() *ast.Token {
+	return err.token
+}
+
+func (err *GenricParserError) Message() string {
+	return err.message
+}
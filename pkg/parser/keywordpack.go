@@ -0,0 +1,186 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+// KeywordPack is a user supplied extension of the language's spellings. Its
+// keywords are extra spellings of existing tokens, like the built-in slang,
+// and its operators are new word operators that call a function with their
+// operands: with {"spelling": "vs", "kind": "infix", "function": "compare"},
+// "a vs b" is "compare(a, b)".
+//
+// Packs are usually loaded from JSON with LoadKeywordPack.
+type KeywordPack struct {
+	Name      string         `json:"name"`
+	Keywords  []PackKeyword  `json:"keywords"`
+	Operators []PackOperator `json:"operators"`
+}
+
+type PackKeyword struct {
+	// Spelling is one or more words, e.g. "plus" or "is bigger than"
+	Spelling string `json:"spelling"`
+	// Token names the token the spelling scans to, as returned by
+	// Token.Name, e.g. "PLUS"
+	Token   string `json:"token"`
+	Meaning string `json:"meaning"`
+}
+
+type PackOperator struct {
+	// Spelling is a single word that isn't a keyword
+	Spelling string `json:"spelling"`
+	// Kind is "infix", "prefix" or "postfix"
+	Kind string `json:"kind"`
+	// Precedence names the level of an infix or postfix operator, e.g.
+	// "term" or "comparison"; see LookupPrecedence
+	Precedence string `json:"precedence"`
+	// Associativity is "left", the default, or "right"
+	Associativity string `json:"associativity"`
+	// Function is the name of the function called with the operands
+	Function string `json:"function"`
+}
+
+// LoadKeywordPack reads a keyword pack from JSON and validates it.
+func LoadKeywordPack(r io.Reader) (*KeywordPack, error) {
+	var pack KeywordPack
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&pack); err != nil {
+		return nil, fmt.Errorf("invalid keyword pack: %w", err)
+	}
+	if _, err := pack.ScanKeywords(); err != nil {
+		return nil, err
+	}
+	if err := pack.Register(NewOperators()); err != nil {
+		return nil, err
+	}
+	return &pack, nil
+}
+
+// ScanKeywords returns the pack's keywords in the form the scanner takes.
+func (k *KeywordPack) ScanKeywords() ([]ast.Keyword, error) {
+	keywords := make([]ast.Keyword, 0, len(k.Keywords))
+	for _, keyword := range k.Keywords {
+		words := strings.Fields(keyword.Spelling)
+		if len(words) == 0 {
+			return nil, k.errorf("keyword with an empty spelling")
+		}
+		for _, word := range words {
+			if !isWord(word) {
+				return nil, k.errorf("keyword %q: '%s' is not a word", keyword.Spelling, word)
+			}
+		}
+		tokenType, ok := ast.LookupTokenName(keyword.Token)
+		if !ok || !isKeywordToken(tokenType) {
+			return nil, k.errorf("keyword %q: unknown token %q", keyword.Spelling, keyword.Token)
+		}
+		keywords = append(keywords, ast.Keyword{Spelling: strings.Join(words, " "), Type: tokenType, Meaning: keyword.Meaning})
+	}
+	return keywords, nil
+}
+
+// Register adds the pack's operators to operators.
+func (k *KeywordPack) Register(operators *Operators) error {
+	for _, operator := range k.Operators {
+		if !isWord(operator.Spelling) {
+			return k.errorf("operator %q: spelling must be a single word", operator.Spelling)
+		}
+		if k.isKeyword(operator.Spelling) {
+			return k.errorf("operator %q: spelling is a keyword", operator.Spelling)
+		}
+		if !isWord(operator.Function) {
+			return k.errorf("operator %q: invalid function name %q", operator.Spelling, operator.Function)
+		}
+
+		var associativity Associativity
+		switch operator.Associativity {
+		case "", "left":
+			associativity = AssocLeft
+		case "right":
+			associativity = AssocRight
+		default:
+			return k.errorf("operator %q: unknown associativity %q", operator.Spelling, operator.Associativity)
+		}
+
+		function := operator.Function
+		if operator.Kind == "prefix" {
+			operators.WordPrefix(operator.Spelling, func(p *Parser, token *ast.Token) ast.Expr {
+				operand := p.parsePrecedence(PrecUnary)
+				return callOperator(function, token, operand)
+			})
+			continue
+		}
+
+		precedence, ok := LookupPrecedence(operator.Precedence)
+		if !ok {
+			return k.errorf("operator %q: unknown precedence %q", operator.Spelling, operator.Precedence)
+		}
+		switch operator.Kind {
+		case "infix":
+			operators.WordInfix(operator.Spelling, precedence, associativity, func(p *Parser, left ast.Expr, token *ast.Token) ast.Expr {
+				return callOperator(function, token, left, p.operand(token))
+			})
+		case "postfix":
+			operators.WordInfix(operator.Spelling, precedence, associativity, func(p *Parser, left ast.Expr, token *ast.Token) ast.Expr {
+				return callOperator(function, token, left)
+			})
+		default:
+			return k.errorf("operator %q: unknown kind %q", operator.Spelling, operator.Kind)
+		}
+	}
+	return nil
+}
+
+func (k *KeywordPack) isKeyword(word string) bool {
+	if _, ok := ast.LookupKeyword(word); ok {
+		return true
+	}
+	for _, keyword := range k.Keywords {
+		if strings.Join(strings.Fields(keyword.Spelling), " ") == word {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *KeywordPack) errorf(format string, args ...any) error {
+	return fmt.Errorf("keyword pack '%s': %s", k.Name, fmt.Sprintf(format, args...))
+}
+
+// callOperator builds the call a word operator stands for. Errors of the
+// call are reported at the operator.
+func callOperator(function string, operator *ast.Token, operands ...ast.Expr) ast.Expr {
+	name := ast.NewToken(ast.TokenIdentifier, &function, nil, operator.Line, operator.Column)
+	name.Offset = operator.Offset
+	return ast.NewCallExpr(ast.NewVariableExpr(name), operator, operands)
+}
+
+// isWord reports whether s could be scanned as an identifier.
+func isWord(s string) bool {
+	if s == "" || (s[0] >= '0' && s[0] <= '9') {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// isKeywordToken reports whether a keyword may scan to tokenType. Tokens
+// carrying a value or standing for no source text may not.
+func isKeywordToken(tokenType ast.TokenType) bool {
+	switch tokenType {
+	case ast.TokenIdentifier, ast.TokenNumber, ast.TokenString,
+		ast.TokenComment, ast.TokenCStyleComment, ast.TokenEOF:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,226 @@
+package parser
+
+import (
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+// Precedence is how tightly an operator binds its operands; operators with
+// a higher precedence bind tighter.
+type Precedence int
+
+const (
+	PrecNone        Precedence = iota
+	PrecAssignment             // = += -= *= /= %=
+	PrecConditional            // ?:
+	PrecCoalesce               // ??
+	PrecOr                     // or
+	PrecAnd                    // and
+	PrecEquality               // == !=
+	PrecComparison             // < > <= >=
+	PrecBitwiseOr              // |
+	PrecBitwiseXor             // ^
+	PrecBitwiseAnd             // &
+	PrecShift                  // << >>
+	PrecTerm                   // + -
+	PrecFactor                 // * / %
+	PrecUnary                  // ! - + ~ and prefix ++ --
+	PrecPower                  // **
+	PrecPostfix                // postfix ++ --
+	PrecCall                   // () [] . ?.
+	PrecPrimary
+)
+
+var precedenceNames = map[string]Precedence{
+	"assignment":  PrecAssignment,
+	"conditional": PrecConditional,
+	"coalesce":    PrecCoalesce,
+	"or":          PrecOr,
+	"and":         PrecAnd,
+	"equality":    PrecEquality,
+	"comparison":  PrecComparison,
+	"bitwise_or":  PrecBitwiseOr,
+	"bitwise_xor": PrecBitwiseXor,
+	"bitwise_and": PrecBitwiseAnd,
+	"shift":       PrecShift,
+	"term":        PrecTerm,
+	"factor":      PrecFactor,
+	"unary":       PrecUnary,
+	"power":       PrecPower,
+	"postfix":     PrecPostfix,
+	"call":        PrecCall,
+}
+
+// LookupPrecedence returns the precedence level called name, such as "term"
+// or "bitwise_and".
+func LookupPrecedence(name string) (Precedence, bool) {
+	precedence, ok := precedenceNames[name]
+	return precedence, ok
+}
+
+type Associativity int
+
+const (
+	// AssocLeft groups "a op b op c" as "(a op b) op c".
+	AssocLeft Associativity = iota
+	// AssocRight groups "a op b op c" as "a op (b op c)".
+	AssocRight
+)
+
+// PrefixParselet parses an expression that starts with token, which has
+// already been consumed.
+type PrefixParselet func(p *Parser, token *ast.Token) ast.Expr
+
+// InfixParselet parses the rest of an expression that continues left with
+// operator, which has already been consumed. Postfix operators are infix
+// operators whose parselet doesn't parse a right operand.
+type InfixParselet func(p *Parser, left ast.Expr, operator *ast.Token) ast.Expr
+
+type infixRule struct {
+	precedence    Precedence
+	associativity Associativity
+	parse         InfixParselet
+}
+
+// Operators is the table driving the expression parser. It maps the token
+// starting an expression to its prefix parselet, and the token continuing
+// one to its infix parselet, precedence and associativity.
+//
+// Operators spelled as a single word, such as those of a keyword pack, scan
+// to identifier tokens and are registered by spelling instead. A word prefix
+// operator shadows the variable of the same name.
+type Operators struct {
+	prefix     map[ast.TokenType]PrefixParselet
+	infix      map[ast.TokenType]infixRule
+	wordPrefix map[string]PrefixParselet
+	wordInfix  map[string]infixRule
+}
+
+// NewOperators returns the table of the built-in grammar.
+func NewOperators() *Operators {
+	o := &Operators{
+		prefix:     make(map[ast.TokenType]PrefixParselet),
+		infix:      make(map[ast.TokenType]infixRule),
+		wordPrefix: make(map[string]PrefixParselet),
+		wordInfix:  make(map[string]infixRule),
+	}
+
+	o.Prefix(ast.TokenFalse, (*Parser).literal)
+	o.Prefix(ast.TokenTrue, (*Parser).literal)
+	o.Prefix(ast.TokenNil, (*Parser).literal)
+	o.Prefix(ast.TokenNumber, (*Parser).literal)
+	o.Prefix(ast.TokenString, (*Parser).literal)
+	o.Prefix(ast.TokenIdentifier, (*Parser).variable)
+	o.Prefix(ast.TokenLeftParen, (*Parser).grouping)
+	o.Prefix(ast.TokenLeftBracket, (*Parser).list)
+	o.Prefix(ast.TokenLeftBrace, (*Parser).mapLiteral)
+	o.Prefix(ast.TokenThis, (*Parser).this)
+	o.Prefix(ast.TokenBang, (*Parser).unary)
+	o.Prefix(ast.TokenMinus, (*Parser).unary)
+	o.Prefix(ast.TokenPlus, (*Parser).unary)
+	o.Prefix(ast.TokenTilde, (*Parser).unary)
+	o.Prefix(ast.TokenPlusPlus, (*Parser).prefixUpdate)
+	o.Prefix(ast.TokenMinusMinus, (*Parser).prefixUpdate)
+	o.Prefix(ast.TokenMatch, (*Parser).matchExpression)
+	o.Prefix(ast.TokenSpawn, (*Parser).spawn)
+
+	o.Infix(ast.TokenEqual, PrecAssignment, AssocRight, (*Parser).assignment)
+	for _, compound := range []ast.TokenType{ast.TokenPlusEqual, ast.TokenMinusEqual, ast.TokenStarEqual, ast.TokenSlashEqual, ast.TokenPercentEqual} {
+		o.Infix(compound, PrecAssignment, AssocRight, (*Parser).compoundAssignment)
+	}
+	o.Infix(ast.TokenQuestion, PrecConditional, AssocRight, (*Parser).conditional)
+	o.Infix(ast.TokenQuestionQuestion, PrecCoalesce, AssocLeft, (*Parser).coalesce)
+	o.Infix(ast.TokenOr, PrecOr, AssocLeft, (*Parser).logical)
+	o.Infix(ast.TokenAnd, PrecAnd, AssocLeft, (*Parser).logical)
+	o.binary(PrecEquality, AssocLeft, ast.TokenBangEqual, ast.TokenEqualEqual)
+	o.binary(PrecComparison, AssocLeft, ast.TokenGreater, ast.TokenGreaterEqual, ast.TokenLess, ast.TokenLessEqual)
+	o.binary(PrecBitwiseOr, AssocLeft, ast.TokenPipe)
+	o.binary(PrecBitwiseXor, AssocLeft, ast.TokenCaret)
+	o.binary(PrecBitwiseAnd, AssocLeft, ast.TokenAmpersand)
+	o.binary(PrecShift, AssocLeft, ast.TokenLessLess, ast.TokenGreaterGreater)
+	o.binary(PrecTerm, AssocLeft, ast.TokenMinus, ast.TokenPlus)
+	o.binary(PrecFactor, AssocLeft, ast.TokenSlash, ast.TokenStar, ast.TokenPercent)
+	o.binary(PrecPower, AssocRight, ast.TokenStarStar)
+	o.Infix(ast.TokenPlusPlus, PrecPostfix, AssocLeft, (*Parser).postfixUpdate)
+	o.Infix(ast.TokenMinusMinus, PrecPostfix, AssocLeft, (*Parser).postfixUpdate)
+	o.Infix(ast.TokenLeftParen, PrecCall, AssocLeft, (*Parser).call)
+	o.Infix(ast.TokenLeftBracket, PrecCall, AssocLeft, (*Parser).index)
+	o.Infix(ast.TokenDot, PrecCall, AssocLeft, (*Parser).get)
+	o.Infix(ast.TokenQuestionDot, PrecCall, AssocLeft, (*Parser).optionalGet)
+	return o
+}
+
+// defaultOperators is the table parsers use until given another one.
+var defaultOperators = NewOperators()
+
+func (o *Operators) binary(precedence Precedence, associativity Associativity, tokenTypes ...ast.TokenType) {
+	for _, tokenType := range tokenTypes {
+		o.Infix(tokenType, precedence, associativity, (*Parser).binary)
+	}
+}
+
+// Prefix registers parse for expressions starting with tokenType, replacing
+// any existing entry.
+func (o *Operators) Prefix(tokenType ast.TokenType, parse PrefixParselet) {
+	o.prefix[tokenType] = parse
+}
+
+// Infix registers tokenType as an infix or postfix operator, replacing any
+// existing entry.
+func (o *Operators) Infix(tokenType ast.TokenType, precedence Precedence, associativity Associativity, parse InfixParselet) {
+	o.infix[tokenType] = infixRule{precedence, associativity, parse}
+}
+
+// WordPrefix registers parse for expressions starting with the identifier
+// spelling.
+func (o *Operators) WordPrefix(spelling string, parse PrefixParselet) {
+	o.wordPrefix[spelling] = parse
+}
+
+// WordInfix registers the identifier spelling as an infix or postfix
+// operator. An identifier can't otherwise follow an expression, so this
+// never changes the meaning of an existing program.
+func (o *Operators) WordInfix(spelling string, precedence Precedence, associativity Associativity, parse InfixParselet) {
+	o.wordInfix[spelling] = infixRule{precedence, associativity, parse}
+}
+
+// Clone returns a copy of the table that can be changed independently.
+func (o *Operators) Clone() *Operators {
+	clone := &Operators{
+		prefix:     make(map[ast.TokenType]PrefixParselet, len(o.prefix)),
+		infix:      make(map[ast.TokenType]infixRule, len(o.infix)),
+		wordPrefix: make(map[string]PrefixParselet, len(o.wordPrefix)),
+		wordInfix:  make(map[string]infixRule, len(o.wordInfix)),
+	}
+	for tokenType, parse := range o.prefix {
+		clone.prefix[tokenType] = parse
+	}
+	for tokenType, rule := range o.infix {
+		clone.infix[tokenType] = rule
+	}
+	for spelling, parse := range o.wordPrefix {
+		clone.wordPrefix[spelling] = parse
+	}
+	for spelling, rule := range o.wordInfix {
+		clone.wordInfix[spelling] = rule
+	}
+	return clone
+}
+
+func (o *Operators) prefixFor(token *ast.Token) (PrefixParselet, bool) {
+	if token.Type == ast.TokenIdentifier {
+		if parse, ok := o.wordPrefix[*token.Lexeme]; ok {
+			return parse, true
+		}
+	}
+	parse, ok := o.prefix[token.Type]
+	return parse, ok
+}
+
+func (o *Operators) infixFor(token *ast.Token) (infixRule, bool) {
+	if token.Type == ast.TokenIdentifier {
+		rule, ok := o.wordInfix[*token.Lexeme]
+		return rule, ok
+	}
+	rule, ok := o.infix[token.Type]
+	return rule, ok
+}
@@ -3,161 +3,972 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/bagaswh/rottenlang/pkg/ast"
 	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/value"
 )
 
+var (
+	ErrParser = errors.New("parse error")
+)
+
+// maxArguments bounds parameter and argument lists so that a call's argument
+// count fits in a single bytecode operand.
+const maxArguments = 255
+
 type Parser struct {
 	tokens        []*ast.Token
 	current       int
 	errorReporter errorreporter.ErrorReporter
+	errors        []*GenricParserError
+	warnings      []*GenricParserError
+	// functionDepth counts the function bodies enclosing the current token
+	functionDepth int
+	// blockDepth counts the blocks, function bodies included, enclosing the
+	// current token
+	blockDepth int
+	// classDepth counts the class bodies enclosing the current token
+	classDepth int
+	// yields is set once the function body being parsed yields, and
+	// valueReturns holds its "purrr" statements returning a value, which a
+	// generator can't have
+	yields       bool
+	valueReturns []*ast.Token
+	// operators drives the expression parser
+	operators *Operators
 }
 
 func NewParser(errorReporter errorreporter.ErrorReporter) *Parser {
 	return &Parser{
 		errorReporter: errorReporter,
+		operators:     defaultOperators,
 	}
 }
 
+// SetOperators replaces the table of operators expressions are parsed with,
+// e.g. by one extended with a keyword pack's operators.
+func (p *Parser) SetOperators(operators *Operators) {
+	p.operators = operators
+}
+
+// SetTokens sets the tokens to parse. Comment tokens are dropped since they
+// carry no meaning for the parser.
 func (p *Parser) SetTokens(tokens []*ast.Token) {
-	p.tokens = tokens
+	p.tokens = make([]*ast.Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Type == ast.TokenComment || token.Type == ast.TokenCStyleComment {
+			continue
+		}
+		p.tokens = append(p.tokens, token)
+	}
 	p.Reset()
 }
 
 func (p *Parser) Reset() {
 	p.current = 0
+	p.errors = nil
+	p.warnings = nil
+	p.functionDepth = 0
+	p.blockDepth = 0
+	p.classDepth = 0
+	p.yields = false
+	p.valueReturns = nil
+}
+
+// Parse parses a whole program. Statements that fail to parse are reported
+// to the error reporter and left out of the result; use HadError to find out
+// whether that happened.
+func (p *Parser) Parse() []ast.Stmt {
+	if p.tokens == nil {
+		panic(errors.New("tokens is nil"))
+	}
+
+	statements := make([]ast.Stmt, 0)
+	for !p.isAtEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
 }
 
-func (p *Parser) Parse() ast.Expr {
+// ParseExpression parses a single expression spanning all the tokens.
+func (p *Parser) ParseExpression() (expr ast.Expr) {
 	if p.tokens == nil {
 		panic(errors.New("tokens is nil"))
 	}
 
-	// defer func() {
-	// 	r := recover()
-	// 	log.Fatalln(r)
-	// 	switch r.(type) {
-	// 	case GenricParserError:
-	// 		p.synchronize()
-	// 		return
-	// 		// default:
-	// 		// 	panic(r)
-	// 	}
-	// }()
-	return p.expression()
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*GenricParserError); !ok {
+				panic(r)
+			}
+			expr = nil
+		}
+	}()
+	expr = p.expression()
+	if !p.isAtEnd() {
+		p.error(p.peek(), "Expect end of expression")
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) HadError() bool {
+	return len(p.errors) > 0
+}
+
+func (p *Parser) Errors() []*GenricParserError {
+	return p.errors
 }
 
-func (p *Parser) synchronize() {}
+// Warnings returns the warnings about code that parsed but is probably not
+// what was meant, such as a match missing a case.
+func (p *Parser) Warnings() []*GenricParserError {
+	return p.warnings
+}
+
+// synchronize discards tokens until the start of the next statement so that
+// one syntax error doesn't cascade into many.
+func (p *Parser) synchronize() {
+	p.advance()
+
+	for !p.isAtEnd() {
+		if p.previous().Type == ast.TokenSemicolon {
+			return
+		}
+
+		switch p.peek().Type {
+		case ast.TokenFunc, ast.TokenClass, ast.TokenVar, ast.TokenConst, ast.TokenFor,
+			ast.TokenIf, ast.TokenWhile, ast.TokenReturn, ast.TokenImport,
+			ast.TokenThrow, ast.TokenTry, ast.TokenSelect, ast.TokenYield:
+			return
+		}
+
+		p.advance()
+	}
+}
+
+func (p *Parser) declaration() (stmt ast.Stmt) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(*GenricParserError); !ok {
+				panic(r)
+			}
+			p.synchronize()
+			stmt = nil
+		}
+	}()
+
+	if p.match(ast.TokenImport) {
+		return p.importDeclaration()
+	}
+	if p.match(ast.TokenFunc) {
+		return p.function(false)
+	}
+	if p.match(ast.TokenClass) {
+		return p.classDeclaration()
+	}
+	if p.match(ast.TokenVar, ast.TokenConst) {
+		return p.varDeclaration()
+	}
+	return p.statement()
+}
+
+// function parses a function declaration after "func", or a method. The
+// body of an initializer, the init method of a class, can't yield or
+// return a value, since calling the class returns the instance.
+func (p *Parser) function(initializer bool) ast.Stmt {
+	name := p.consume(ast.TokenIdentifier, "Expect function name")
+	p.consume(ast.TokenLeftParen, "Expect '(' after function name")
+
+	params := make([]*ast.Token, 0)
+	if !p.check(ast.TokenRightParen) {
+		for {
+			if len(params) >= maxArguments {
+				p.error(p.peek(), fmt.Sprintf("Can't have more than %d parameters", maxArguments))
+			}
+			params = append(params, p.consume(ast.TokenIdentifier, "Expect parameter name"))
+			if !p.match(ast.TokenComma) {
+				break
+			}
+		}
+	}
+	p.consume(ast.TokenRightParen, "Expect ')' after parameters")
+
+	p.consume(ast.TokenLeftBrace, "Expect '{' before function body")
+	p.functionDepth++
+	yields, valueReturns := p.yields, p.valueReturns
+	p.yields, p.valueReturns = false, nil
+	defer func() {
+		p.functionDepth--
+		p.yields, p.valueReturns = yields, valueReturns
+	}()
+	body := p.block()
+	if initializer {
+		if p.yields {
+			p.error(name, "Can't yield from an initializer")
+		}
+		for _, keyword := range p.valueReturns {
+			p.error(keyword, "Can't return a value from an initializer")
+		}
+	} else if p.yields {
+		for _, keyword := range p.valueReturns {
+			p.error(keyword, "Can't return a value from a generator")
+		}
+	}
+	return ast.NewFunctionStmt(name, params, body, p.yields)
+}
+
+// classDeclaration parses "class Name { method(params) { ... } ... }",
+// whose methods are declared like functions without "func".
+func (p *Parser) classDeclaration() ast.Stmt {
+	name := p.consume(ast.TokenIdentifier, "Expect class name")
+	p.consume(ast.TokenLeftBrace, "Expect '{' before class body")
+	p.classDepth++
+	defer func() {
+		p.classDepth--
+	}()
+
+	methods := make([]ast.Stmt, 0)
+	declared := make(map[string]bool)
+	for !p.check(ast.TokenRightBrace) && !p.isAtEnd() {
+		initializer := p.check(ast.TokenIdentifier) && *p.peek().Lexeme == value.InitMethod
+		method := p.function(initializer).(*ast.FunctionStmt)
+		if declared[*method.Name().Lexeme] {
+			p.error(method.Name(), "Already a method with this name in this class")
+		}
+		declared[*method.Name().Lexeme] = true
+		methods = append(methods, method)
+	}
+	p.consume(ast.TokenRightBrace, "Expect '}' after class body")
+	return ast.NewClassStmt(name, methods)
+}
+
+func (p *Parser) importDeclaration() ast.Stmt {
+	keyword := p.previous()
+	if p.blockDepth > 0 {
+		p.error(keyword, "Can only import at the top level")
+	}
+	path := p.consume(ast.TokenString, "Expect module path after 'import'")
+	p.consume(ast.TokenAs, "Expect 'as' after module path")
+	name := p.consume(ast.TokenIdentifier, "Expect module name after 'as'")
+	p.consume(ast.TokenSemicolon, "Expect ';' after import")
+	return ast.NewImportStmt(keyword, path, name)
+}
+
+func (p *Parser) varDeclaration() ast.Stmt {
+	constant := p.previous().Type == ast.TokenConst
+	name := p.consume(ast.TokenIdentifier, "Expect variable name")
+
+	var initializer ast.Expr
+	if p.match(ast.TokenEqual) {
+		initializer = p.expression()
+	} else if constant {
+		panic(p.error(p.peek(), "Expect '=' after constant name"))
+	}
+
+	p.consume(ast.TokenSemicolon, "Expect ';' after variable declaration")
+	return ast.NewVarStmt(name, initializer, constant)
+}
+
+func (p *Parser) statement() ast.Stmt {
+	if p.match(ast.TokenFor) {
+		return p.forStatement()
+	}
+	if p.match(ast.TokenIf) {
+		return p.ifStatement()
+	}
+	if p.match(ast.TokenReturn) {
+		return p.returnStatement()
+	}
+	if p.match(ast.TokenWhile) {
+		return p.whileStatement()
+	}
+	if p.match(ast.TokenThrow) {
+		return p.throwStatement()
+	}
+	if p.match(ast.TokenTry) {
+		return p.tryStatement()
+	}
+	if p.match(ast.TokenSelect) {
+		return p.selectStatement()
+	}
+	if p.match(ast.TokenYield) {
+		return p.yieldStatement()
+	}
+	if p.match(ast.TokenLeftBrace) {
+		return ast.NewBlockStmt(p.block())
+	}
+	return p.expressionStatement()
+}
+
+// forStatement desugars a C-style for loop into a while loop wrapped in
+// blocks, so later stages never see "for". A loop over the values of an
+// iterable is a ForInStmt instead.
+func (p *Parser) forStatement() ast.Stmt {
+	keyword := p.previous()
+	p.consume(ast.TokenLeftParen, "Expect '(' after 'for'")
+	if p.check(ast.TokenIdentifier) && p.checkNext(ast.TokenIn) {
+		return p.forInStatement(keyword)
+	}
+
+	var initializer ast.Stmt
+	if p.match(ast.TokenSemicolon) {
+		initializer = nil
+	} else if p.match(ast.TokenVar) {
+		initializer = p.varDeclaration()
+	} else {
+		initializer = p.expressionStatement()
+	}
+
+	var condition ast.Expr
+	if !p.check(ast.TokenSemicolon) {
+		condition = p.expression()
+	}
+	p.consume(ast.TokenSemicolon, "Expect ';' after loop condition")
+
+	var increment ast.Expr
+	if !p.check(ast.TokenRightParen) {
+		increment = p.expression()
+	}
+	p.consume(ast.TokenRightParen, "Expect ')' after for clauses")
+
+	body := p.statement()
+
+	if increment != nil {
+		body = ast.NewBlockStmt([]ast.Stmt{body, ast.NewExpressionStmt(increment)})
+	}
+	if condition == nil {
+		condition = ast.NewLiteralExpr(true)
+	}
+	body = ast.NewWhileStmt(keyword, condition, body)
+	if initializer != nil {
+		body = ast.NewBlockStmt([]ast.Stmt{initializer, body})
+	}
+	return body
+}
+
+// forInStatement parses the rest of a loop over the values of an iterable,
+// after "for (".
+func (p *Parser) forInStatement(keyword *ast.Token) ast.Stmt {
+	name := p.advance()
+	p.advance()
+	iterable := p.expression()
+	p.consume(ast.TokenRightParen, "Expect ')' after loop iterable")
+	body := p.statement()
+	return ast.NewForInStmt(keyword, name, iterable, body)
+}
+
+func (p *Parser) ifStatement() ast.Stmt {
+	p.consume(ast.TokenLeftParen, "Expect '(' after 'chat is this real'")
+	condition := p.expression()
+	p.consume(ast.TokenRightParen, "Expect ')' after if condition")
+
+	thenBranch := p.statement()
+	var elseBranch ast.Stmt
+	if p.match(ast.TokenElse) {
+		elseBranch = p.statement()
+	}
+	return ast.NewIfStmt(condition, thenBranch, elseBranch)
+}
+
+func (p *Parser) returnStatement() ast.Stmt {
+	keyword := p.previous()
+	if p.functionDepth == 0 {
+		p.error(keyword, "Can't return from top-level code")
+	}
+	var value ast.Expr
+	if !p.check(ast.TokenSemicolon) {
+		value = p.expression()
+		p.valueReturns = append(p.valueReturns, keyword)
+	}
+	p.consume(ast.TokenSemicolon, "Expect ';' after return value")
+	return ast.NewReturnStmt(keyword, value)
+}
+
+func (p *Parser) yieldStatement() ast.Stmt {
+	keyword := p.previous()
+	if p.functionDepth == 0 {
+		p.error(keyword, "Can't yield from top-level code")
+	}
+	p.yields = true
+	value := p.expression()
+	p.consume(ast.TokenSemicolon, "Expect ';' after yielded value")
+	return ast.NewYieldStmt(keyword, value)
+}
+
+func (p *Parser) whileStatement() ast.Stmt {
+	keyword := p.previous()
+	p.consume(ast.TokenLeftParen, "Expect '(' after 'skibidi'")
+	condition := p.expression()
+	p.consume(ast.TokenRightParen, "Expect ')' after condition")
+	body := p.statement()
+	return ast.NewWhileStmt(keyword, condition, body)
+}
+
+func (p *Parser) throwStatement() ast.Stmt {
+	keyword := p.previous()
+	value := p.expression()
+	p.consume(ast.TokenSemicolon, "Expect ';' after thrown value")
+	return ast.NewThrowStmt(keyword, value)
+}
+
+func (p *Parser) tryStatement() ast.Stmt {
+	keyword := p.previous()
+	p.consume(ast.TokenLeftBrace, "Expect '{' after 'try'")
+	body := p.block()
+
+	var catchName, finally *ast.Token
+	var catchBody, finallyBody []ast.Stmt
+	if p.match(ast.TokenCatch) {
+		p.consume(ast.TokenLeftParen, "Expect '(' after 'catch'")
+		catchName = p.consume(ast.TokenIdentifier, "Expect error name")
+		p.consume(ast.TokenRightParen, "Expect ')' after error name")
+		p.consume(ast.TokenLeftBrace, "Expect '{' before catch body")
+		catchBody = p.block()
+	}
+	if p.match(ast.TokenFinally) {
+		finally = p.previous()
+		p.consume(ast.TokenLeftBrace, "Expect '{' after 'finally'")
+		finallyBody = p.block()
+	}
+	if catchName == nil && finally == nil {
+		panic(p.error(p.peek(), "Expect 'catch' or 'finally' after try block"))
+	}
+	return ast.NewTryStmt(keyword, body, catchName, catchBody, finally, finallyBody)
+}
+
+// selectStatement parses "select { case { ... } ... else { ... } }", where
+// each case is a call of a channel's send or receive method, and a receive
+// may name the value it receives: "ch.receive() as msg { ... }".
+func (p *Parser) selectStatement() ast.Stmt {
+	keyword := p.previous()
+	p.consume(ast.TokenLeftBrace, "Expect '{' after 'select'")
+
+	cases := make([]ast.Expr, 0)
+	names := make([]*ast.Token, 0)
+	bodies := make([]ast.Stmt, 0)
+	var elseBranch ast.Stmt
+	for !p.check(ast.TokenRightBrace) && !p.isAtEnd() {
+		if p.match(ast.TokenElse) {
+			if elseBranch != nil {
+				p.error(p.previous(), "Already an else branch in this select")
+			}
+			p.consume(ast.TokenLeftBrace, "Expect '{' after 'else'")
+			elseBranch = ast.NewBlockStmt(p.block())
+			continue
+		}
+
+		c := p.expression()
+		_, send, _, ok := ast.SelectCase(c)
+		if !ok {
+			p.error(p.previous(), "Expect channel send or receive in select case")
+		}
+		var name *ast.Token
+		if p.match(ast.TokenAs) {
+			name = p.consume(ast.TokenIdentifier, "Expect name after 'as'")
+			if send {
+				p.error(name, "Can only name the value of a receive")
+			}
+		}
+		p.consume(ast.TokenLeftBrace, "Expect '{' before select case body")
+		cases = append(cases, c)
+		names = append(names, name)
+		bodies = append(bodies, ast.NewBlockStmt(p.block()))
+	}
+	p.consume(ast.TokenRightBrace, "Expect '}' after select cases")
+	if len(cases) == 0 {
+		p.error(keyword, "Expect at least one select case")
+	}
+	return ast.NewSelectStmt(keyword, cases, names, bodies, elseBranch)
+}
+
+func (p *Parser) block() []ast.Stmt {
+	p.blockDepth++
+	defer func() {
+		p.blockDepth--
+	}()
+	statements := make([]ast.Stmt, 0)
+	for !p.check(ast.TokenRightBrace) && !p.isAtEnd() {
+		if stmt := p.declaration(); stmt != nil {
+			statements = append(statements, stmt)
+		}
+	}
+	p.consume(ast.TokenRightBrace, "Expect '}' after block")
+	return statements
+}
+
+func (p *Parser) expressionStatement() ast.Stmt {
+	expr := p.expression()
+	p.consume(ast.TokenSemicolon, "Expect ';' after expression")
+	return ast.NewExpressionStmt(expr)
+}
 
 func (p *Parser) expression() ast.Expr {
-	return p.equality()
+	return p.parsePrecedence(PrecAssignment)
 }
 
-func (p *Parser) equality() ast.Expr {
-	expr := p.comparison()
-	for p.match(ast.TokenBangEqual, ast.TokenEqualEqual) {
-		operator := p.previous()
-		right := p.comparison()
-		expr = ast.NewBinaryExpr(expr, operator, right)
+// parsePrecedence parses an expression made of operators that bind at least
+// as tightly as precedence. Operators binding looser are left for a caller.
+func (p *Parser) parsePrecedence(precedence Precedence) ast.Expr {
+	prefix, ok := p.operators.prefixFor(p.peek())
+	if !ok {
+		panic(p.error(p.peek(), "Expect expression"))
+	}
+	expr := prefix(p, p.advance())
+
+	// An operator binding tighter than the one just applied would have been
+	// taken by its right operand, unless it had none: "a++(1)" is not a call,
+	// and neither is "-a++(1)".
+	limit := PrecPrimary
+	if _, ok := expr.(*ast.UnaryExpr); ok {
+		limit = PrecUnary
 	}
+	for !p.isAtEnd() {
+		rule, ok := p.operators.infixFor(p.peek())
+		if !ok || rule.precedence < precedence || rule.precedence > limit {
+			break
+		}
+		expr = rule.parse(p, expr, p.advance())
+		limit = rule.precedence
+	}
+
 	return expr
 }
 
-func (p *Parser) comparison() ast.Expr {
-	expr := p.term()
+// operand parses the right operand of the infix operator just consumed.
+// Operators of the same precedence only nest in it if operator is right
+// associative.
+func (p *Parser) operand(operator *ast.Token) ast.Expr {
+	rule, _ := p.operators.infixFor(operator)
+	if rule.associativity == AssocRight {
+		return p.parsePrecedence(rule.precedence)
+	}
+	return p.parsePrecedence(rule.precedence + 1)
+}
+
+func (p *Parser) assignment(left ast.Expr, equals *ast.Token) ast.Expr {
+	value := p.operand(equals)
 
-	for p.match(ast.TokenGreater, ast.TokenGreaterEqual, ast.TokenLess, ast.TokenLessEqual) {
-		operator := p.previous()
-		right := p.term()
-		expr = ast.NewBinaryExpr(expr, operator, right)
+	switch target := left.(type) {
+	case *ast.VariableExpr:
+		return ast.NewAssignExpr(target.Name(), value)
+	case *ast.GetExpr:
+		return ast.NewSetExpr(target.Object(), target.Name(), value)
+	case *ast.IndexExpr:
+		return ast.NewSetIndexExpr(target.Object(), target.Bracket(), target.Index(), value)
 	}
+	// report without panicking, the parser isn't confused
+	p.error(equals, "Invalid assignment target")
+	return left
+}
 
-	return expr
+func (p *Parser) compoundAssignment(left ast.Expr, operator *ast.Token) ast.Expr {
+	value := p.operand(operator)
+
+	switch target := left.(type) {
+	case *ast.VariableExpr:
+		return ast.NewCompoundAssignExpr(target.Name(), operator, value)
+	case *ast.GetExpr:
+		return ast.NewCompoundSetExpr(target.Object(), target.Name(), operator, value)
+	case *ast.IndexExpr:
+		return ast.NewCompoundSetIndexExpr(target.Object(), target.Bracket(), target.Index(), operator, value)
+	}
+	p.error(operator, "Invalid assignment target")
+	return left
+}
+
+// conditional parses "condition ? thenBranch : elseBranch", which is right
+// associative: "a ? b : c ? d : e" is "a ? b : (c ? d : e)".
+func (p *Parser) conditional(condition ast.Expr, question *ast.Token) ast.Expr {
+	thenBranch := p.expression()
+	p.consume(ast.TokenColon, "Expect ':' after then branch of conditional expression")
+	elseBranch := p.operand(question)
+	return ast.NewConditionalExpr(condition, question, thenBranch, elseBranch)
+}
+
+func (p *Parser) coalesce(left ast.Expr, operator *ast.Token) ast.Expr {
+	return ast.NewCoalesceExpr(left, operator, p.operand(operator))
+}
+
+func (p *Parser) logical(left ast.Expr, operator *ast.Token) ast.Expr {
+	return ast.NewLogicalExpr(left, operator, p.operand(operator))
 }
 
-func (p *Parser) term() ast.Expr {
-	expr := p.factor()
+// The bitwise operators bind tighter than comparisons, so "x & 1 == 0"
+// tests the lowest bit of x. "**" binds tighter than a unary operator on its
+// left: "-2 ** 2" is -4.
+
+func (p *Parser) binary(left ast.Expr, operator *ast.Token) ast.Expr {
+	return ast.NewBinaryExpr(left, operator, p.operand(operator))
+}
+
+func (p *Parser) unary(operator *ast.Token) ast.Expr {
+	right := p.parsePrecedence(PrecUnary)
+	return ast.NewUnaryExpr(operator, right)
+}
 
-	for p.match(ast.TokenMinus, ast.TokenPlus) {
-		operator := p.previous()
-		right := p.factor()
-		expr = ast.NewBinaryExpr(expr, operator, right)
+func (p *Parser) prefixUpdate(operator *ast.Token) ast.Expr {
+	operand := p.parsePrecedence(PrecUnary)
+	return p.update(operator, operand, true)
+}
+
+func (p *Parser) postfixUpdate(operand ast.Expr, operator *ast.Token) ast.Expr {
+	return p.update(operator, operand, false)
+}
+
+func (p *Parser) update(operator *ast.Token, operand ast.Expr, prefix bool) ast.Expr {
+	switch target := operand.(type) {
+	case *ast.VariableExpr:
+		return ast.NewUpdateExpr(target.Name(), operator, prefix)
+	case *ast.GetExpr:
+		return ast.NewUpdatePropertyExpr(target.Object(), target.Name(), operator, prefix)
+	case *ast.IndexExpr:
+		return ast.NewUpdateIndexExpr(target.Object(), target.Bracket(), target.Index(), operator, prefix)
 	}
+	p.error(operator, "Invalid increment or decrement target")
+	return operand
+}
 
-	return expr
+func (p *Parser) call(callee ast.Expr, _ *ast.Token) ast.Expr {
+	arguments := make([]ast.Expr, 0)
+	if !p.check(ast.TokenRightParen) {
+		for {
+			if len(arguments) >= maxArguments {
+				p.error(p.peek(), fmt.Sprintf("Can't have more than %d arguments", maxArguments))
+			}
+			arguments = append(arguments, p.expression())
+			if !p.match(ast.TokenComma) {
+				break
+			}
+		}
+	}
+	paren := p.consume(ast.TokenRightParen, "Expect ')' after arguments")
+	return ast.NewCallExpr(callee, paren, arguments)
+}
+
+func (p *Parser) get(object ast.Expr, _ *ast.Token) ast.Expr {
+	name := p.propertyName("Expect property name after '.'")
+	return ast.NewGetExpr(object, name)
 }
 
-func (p *Parser) factor() ast.Expr {
-	expr := p.unary()
+func (p *Parser) optionalGet(object ast.Expr, _ *ast.Token) ast.Expr {
+	name := p.propertyName("Expect property name after '?.'")
+	return ast.NewOptionalGetExpr(object, name)
+}
 
-	for p.match(ast.TokenSlash, ast.TokenStar) {
-		operator := p.previous()
-		right := p.unary()
-		expr = ast.NewBinaryExpr(expr, operator, right)
+// propertyName consumes the name of a property, which may be a keyword of
+// one word, such as the property "class" of errors.
+func (p *Parser) propertyName(message string) *ast.Token {
+	if token := p.peek(); token.Type != ast.TokenIdentifier && token.Lexeme != nil {
+		if _, ok := ast.LookupKeyword(*token.Lexeme); ok && !strings.Contains(*token.Lexeme, " ") {
+			return p.advance()
+		}
 	}
+	return p.consume(ast.TokenIdentifier, message)
+}
 
-	return expr
+// index parses "object[index]".
+func (p *Parser) index(object ast.Expr, bracket *ast.Token) ast.Expr {
+	index := p.expression()
+	p.consume(ast.TokenRightBracket, "Expect ']' after index")
+	return ast.NewIndexExpr(object, bracket, index)
 }
 
-func (p *Parser) unary() ast.Expr {
-	fmt.Println("unary(): token type:", p.peek().Name(), "current", p.current)
-	for p.match(ast.TokenBang, ast.TokenMinus, ast.TokenPlus) {
-		operator := p.previous()
-		fmt.Println("unary():  operator:", operator)
-		right := p.unary()
-		return ast.NewUnaryExpr(operator, right)
+func (p *Parser) this(keyword *ast.Token) ast.Expr {
+	if p.classDepth == 0 {
+		p.error(keyword, "Can't use 'this' outside of a class")
 	}
+	return ast.NewThisExpr(keyword)
+}
 
-	return p.primary()
+// list parses "[a, b, ...]", which may end with a comma.
+func (p *Parser) list(bracket *ast.Token) ast.Expr {
+	elements := make([]ast.Expr, 0)
+	for !p.check(ast.TokenRightBracket) && !p.isAtEnd() {
+		elements = append(elements, p.expression())
+		if !p.match(ast.TokenComma) {
+			break
+		}
+	}
+	p.consume(ast.TokenRightBracket, "Expect ']' after list elements")
+	return ast.NewListExpr(bracket, elements)
 }
 
-func (p *Parser) primary() ast.Expr {
-	if p.match(ast.TokenFalse) {
-		return ast.NewLiteralExpr(false)
+// mapLiteral parses "{key: value, ...}", which may end with a comma. A key
+// is a name, standing for its string, a string or a number.
+func (p *Parser) mapLiteral(brace *ast.Token) ast.Expr {
+	keys := make([]ast.Expr, 0)
+	values := make([]ast.Expr, 0)
+	for !p.check(ast.TokenRightBrace) && !p.isAtEnd() {
+		var key ast.Expr
+		if p.match(ast.TokenString, ast.TokenNumber) {
+			key = ast.NewLiteralExpr(p.previous().Literal)
+		} else {
+			key = ast.NewLiteralExpr(*p.propertyName("Expect map key").Lexeme)
+		}
+		p.consume(ast.TokenColon, "Expect ':' after map key")
+		keys = append(keys, key)
+		values = append(values, p.expression())
+		if !p.match(ast.TokenComma) {
+			break
+		}
 	}
-	if p.match(ast.TokenTrue) {
+	p.consume(ast.TokenRightBrace, "Expect '}' after map entries")
+	return ast.NewMapExpr(brace, keys, values)
+}
+
+func (p *Parser) literal(token *ast.Token) ast.Expr {
+	switch token.Type {
+	case ast.TokenFalse:
+		return ast.NewLiteralExpr(false)
+	case ast.TokenTrue:
 		return ast.NewLiteralExpr(true)
-	}
-	if p.match(ast.TokenNil) {
+	case ast.TokenNil:
 		return ast.NewLiteralExpr(nil)
 	}
+	return ast.NewLiteralExpr(token.Literal)
+}
+
+// matchExpression parses "match (subject) { pattern => value, ... }", where
+// an arm may have a guard after its pattern: "n chat is this real n > 0".
+func (p *Parser) matchExpression(keyword *ast.Token) ast.Expr {
+	p.consume(ast.TokenLeftParen, "Expect '(' after 'match'")
+	subject := p.expression()
+	p.consume(ast.TokenRightParen, "Expect ')' after match subject")
+	p.consume(ast.TokenLeftBrace, "Expect '{' before match arms")
+
+	patterns := make([]ast.Pattern, 0)
+	guards := make([]ast.Expr, 0)
+	values := make([]ast.Expr, 0)
+	for !p.check(ast.TokenRightBrace) && !p.isAtEnd() {
+		pattern := p.pattern()
+		bound := make(map[string]bool)
+		for _, name := range ast.PatternBindings(pattern) {
+			if bound[*name.Lexeme] {
+				p.error(name, "Already a binding with this name in this pattern")
+			}
+			bound[*name.Lexeme] = true
+		}
+
+		var guard ast.Expr
+		if p.match(ast.TokenIf) {
+			guard = p.expression()
+		}
+		p.consume(ast.TokenEqualGreater, "Expect '=>' after match pattern")
+		patterns = append(patterns, pattern)
+		guards = append(guards, guard)
+		values = append(values, p.expression())
+		if !p.match(ast.TokenComma) {
+			break
+		}
+	}
+	p.consume(ast.TokenRightBrace, "Expect '}' after match arms")
+	if len(patterns) == 0 {
+		p.error(keyword, "Expect at least one match arm")
+	}
+
+	p.checkExhaustive(keyword, patterns, guards)
+	return ast.NewMatchExpr(keyword, subject, patterns, guards, values)
+}
+
+// spawn parses "spawn callee(arguments)".
+func (p *Parser) spawn(keyword *ast.Token) ast.Expr {
+	call := p.parsePrecedence(PrecCall)
+	if _, ok := call.(*ast.CallExpr); !ok {
+		panic(p.error(p.previous(), "Expect function call after 'spawn'"))
+	}
+	return ast.NewSpawnExpr(keyword, call)
+}
+
+// pattern parses the pattern of a match arm. "_" is the wildcard; any other
+// identifier binds the value it matches.
+func (p *Parser) pattern() ast.Pattern {
+	switch {
+	case p.match(ast.TokenNumber, ast.TokenString):
+		return ast.NewLiteralPattern(p.previous(), p.previous().Literal)
+	case p.match(ast.TokenMinus):
+		number := p.consume(ast.TokenNumber, "Expect number after '-' in pattern")
+		return ast.NewLiteralPattern(number, -number.Literal.(float64))
+	case p.match(ast.TokenTrue):
+		return ast.NewLiteralPattern(p.previous(), true)
+	case p.match(ast.TokenFalse):
+		return ast.NewLiteralPattern(p.previous(), false)
+	case p.match(ast.TokenNil):
+		return ast.NewLiteralPattern(p.previous(), nil)
+	case p.match(ast.TokenIdentifier):
+		name := p.previous()
+		if p.match(ast.TokenLeftBrace) {
+			brace := p.previous()
+			names, patterns := p.propertyPatterns()
+			return ast.NewClassPattern(name, brace, names, patterns)
+		}
+		if *name.Lexeme == "_" {
+			return ast.NewWildcardPattern(name)
+		}
+		return ast.NewBindingPattern(name)
+	case p.match(ast.TokenLeftBrace):
+		brace := p.previous()
+		names, patterns := p.propertyPatterns()
+		return ast.NewObjectPattern(brace, names, patterns)
+	case p.match(ast.TokenLeftBracket):
+		return p.listPattern(p.previous())
+	}
+	panic(p.error(p.peek(), "Expect pattern"))
+}
+
+// listPattern parses "[pattern, ...]", which may end with "...name" binding
+// the remaining elements.
+func (p *Parser) listPattern(bracket *ast.Token) ast.Pattern {
+	patterns := make([]ast.Pattern, 0)
+	var rest *ast.Token
+	for !p.check(ast.TokenRightBracket) && !p.isAtEnd() {
+		if p.match(ast.TokenDotDotDot) {
+			rest = p.consume(ast.TokenIdentifier, "Expect name after '...'")
+			break
+		}
+		patterns = append(patterns, p.pattern())
+		if !p.match(ast.TokenComma) {
+			break
+		}
+	}
+	p.consume(ast.TokenRightBracket, "Expect ']' after list pattern")
+	return ast.NewListPattern(bracket, patterns, rest)
+}
 
-	if p.match(ast.TokenNumber) {
-		fmt.Println("match token number at line", p.peek().Line)
-		return ast.NewLiteralExpr(p.previous().Literal)
+// propertyPatterns parses the rest of "{name: pattern, ...}", after the
+// brace. A name without a pattern binds the property to a variable of the
+// same name.
+func (p *Parser) propertyPatterns() ([]*ast.Token, []ast.Pattern) {
+	names := make([]*ast.Token, 0)
+	patterns := make([]ast.Pattern, 0)
+	if !p.check(ast.TokenRightBrace) {
+		for {
+			name := p.propertyName("Expect property name in pattern")
+			var pattern ast.Pattern = ast.NewBindingPattern(name)
+			if p.match(ast.TokenColon) {
+				pattern = p.pattern()
+			} else if name.Type != ast.TokenIdentifier {
+				// a keyword can't name the variable bound
+				p.error(p.peek(), "Expect ':' after keyword property name")
+			}
+			names = append(names, name)
+			patterns = append(patterns, pattern)
+			if !p.match(ast.TokenComma) {
+				break
+			}
+		}
 	}
+	p.consume(ast.TokenRightBrace, "Expect '}' after object pattern")
+	return names, patterns
+}
 
-	if p.match(ast.TokenLeftParen) {
-		expr := p.expression()
-		p.consume(ast.TokenRightParen, "Expect ')' after expression")
-		return ast.NewGroupingExpr(expr)
+// checkExhaustive warns about arms following one that matches everything,
+// and about matches on booleans or nil missing a case: arms for nocap or
+// cap must cover both, and arms only for nil must be followed by one for
+// the other values. Matches with other patterns can't be checked.
+func (p *Parser) checkExhaustive(keyword *ast.Token, patterns []ast.Pattern, guards []ast.Expr) {
+	covered := make(map[any]bool)
+	bools, nils := false, false
+	for i, pattern := range patterns {
+		switch pattern := pattern.(type) {
+		case *ast.WildcardPattern, *ast.BindingPattern:
+			if guards[i] == nil {
+				if i < len(patterns)-1 {
+					p.warn(patternToken(patterns[i+1]), "Unreachable match arm")
+				}
+				return
+			}
+		case *ast.LiteralPattern:
+			switch pattern.Value().(type) {
+			case bool:
+				bools = true
+			case nil:
+				nils = true
+			default:
+				return
+			}
+			if guards[i] == nil {
+				covered[pattern.Value()] = true
+			}
+		default:
+			return
+		}
 	}
 
-	return ast.NewLiteralExpr(p.peek().Literal)
+	switch {
+	case bools:
+		if !covered[true] {
+			p.warn(keyword, "Match doesn't cover nocap")
+		}
+		if !covered[false] {
+			p.warn(keyword, "Match doesn't cover cap")
+		}
+	case nils:
+		p.warn(keyword, "Match doesn't cover values other than nil")
+	}
+}
+
+// patternToken returns the first token of pattern.
+func patternToken(pattern ast.Pattern) *ast.Token {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		return pattern.Token()
+	case *ast.WildcardPattern:
+		return pattern.Underscore()
+	case *ast.BindingPattern:
+		return pattern.Name()
+	case *ast.ObjectPattern:
+		return pattern.Brace()
+	case *ast.ListPattern:
+		return pattern.Bracket()
+	case *ast.ClassPattern:
+		return pattern.Name()
+	}
+	return nil
+}
+
+func (p *Parser) variable(name *ast.Token) ast.Expr {
+	return ast.NewVariableExpr(name)
+}
+
+func (p *Parser) grouping(_ *ast.Token) ast.Expr {
+	expr := p.expression()
+	p.consume(ast.TokenRightParen, "Expect ')' after expression")
+	return ast.NewGroupingExpr(expr)
 }
 
 func (p *Parser) consume(tokenType ast.TokenType, errorMessageWhenNotMatched string) *ast.Token {
 	if p.check(tokenType) {
 		return p.advance()
 	}
-	err := p.error(p.peek(), errorMessageWhenNotMatched)
-	fmt.Println(err)
-	panic(err)
+	panic(p.error(p.peek(), errorMessageWhenNotMatched))
 }
 
 func (p *Parser) error(token *ast.Token, message string) *GenricParserError {
 	var err *GenricParserError
 	if token.Type == ast.TokenEOF {
-		err = NewGenericParserError(token, " at end", message)
+		err = NewGenericParserError(token, "at end", message)
 	} else {
 		err = NewGenericParserError(token, fmt.Sprintf("at '%s'", *token.Lexeme), message)
 	}
+	p.errors = append(p.errors, err)
 	p.errorReporter.ReportParserError(err.token.Line, err.token.Column, err.where, err.message)
 	return err
 }
 
-// func (p *Parser) report(tokenType ast.TokenType, )
+// warn reports message about token to the error reporter, if it shows
+// warnings, without failing the parse.
+func (p *Parser) warn(token *ast.Token, message string) {
+	warning := NewGenericParserError(token, fmt.Sprintf("at '%s'", *token.Lexeme), message)
+	p.warnings = append(p.warnings, warning)
+	if reporter, ok := p.errorReporter.(errorreporter.WarningReporter); ok {
+		reporter.ReportWarning(token.Line, token.Column, warning.where, message)
+	}
+}
 
 func (p *Parser) peek() *ast.Token {
 	if p.current < len(p.tokens) {
@@ -184,6 +995,15 @@ func (p *Parser) check(tokenType ast.TokenType) bool {
 	return curr.Type == tokenType
 }
 
+// checkNext reports whether the token after the current one is of
+// tokenType.
+func (p *Parser) checkNext(tokenType ast.TokenType) bool {
+	if p.isAtEnd() || p.current+1 >= len(p.tokens) {
+		return false
+	}
+	return p.tokens[p.current+1].Type == tokenType
+}
+
 func (p *Parser) previous() *ast.Token {
 	if p.current <= 0 {
 		return p.tokens[0]
@@ -0,0 +1,661 @@
+package parser
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/ebnf"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/golden"
+	"github.com/bagaswh/rottenlang/pkg/printer"
+	"github.com/bagaswh/rottenlang/pkg/scanner"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// sexpr formats expr fully parenthesized, making its shape explicit.
+// Groupings are dropped since they only exist to override precedence.
+func sexpr(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		return fmt.Sprintf("(%s %s %s)", e.Operator().Name(), sexpr(e.Left()), sexpr(e.Right()))
+	case *ast.LogicalExpr:
+		return fmt.Sprintf("(%s %s %s)", e.Operator().Name(), sexpr(e.Left()), sexpr(e.Right()))
+	case *ast.CoalesceExpr:
+		return fmt.Sprintf("(?? %s %s)", sexpr(e.Left()), sexpr(e.Right()))
+	case *ast.UnaryExpr:
+		return fmt.Sprintf("(%s %s)", e.Operator().Name(), sexpr(e.Right()))
+	case *ast.GroupingExpr:
+		return sexpr(e.Expr())
+	case *ast.LiteralExpr:
+		if s, ok := e.Value().(string); ok {
+			return strconv.Quote(s)
+		}
+		return value.Stringify(e.Value())
+	case *ast.VariableExpr:
+		return *e.Name().Lexeme
+	case *ast.AssignExpr:
+		return fmt.Sprintf("(= %s %s)", *e.Name().Lexeme, sexpr(e.Value()))
+	case *ast.CompoundAssignExpr:
+		return fmt.Sprintf("(%s %s %s)", e.Operator().Name(), *e.Name().Lexeme, sexpr(e.Value()))
+	case *ast.UpdateExpr:
+		if e.Prefix() {
+			return fmt.Sprintf("(%s %s)", e.Operator().Name(), *e.Name().Lexeme)
+		}
+		return fmt.Sprintf("(%s %s)", *e.Name().Lexeme, e.Operator().Name())
+	case *ast.CompoundSetExpr:
+		return fmt.Sprintf("(%s (. %s %s) %s)", e.Operator().Name(), sexpr(e.Object()), *e.Name().Lexeme, sexpr(e.Value()))
+	case *ast.CompoundSetIndexExpr:
+		return fmt.Sprintf("(%s ([] %s %s) %s)", e.Operator().Name(), sexpr(e.Object()), sexpr(e.Index()), sexpr(e.Value()))
+	case *ast.UpdatePropertyExpr:
+		target := fmt.Sprintf("(. %s %s)", sexpr(e.Object()), *e.Name().Lexeme)
+		if e.Prefix() {
+			return fmt.Sprintf("(%s %s)", e.Operator().Name(), target)
+		}
+		return fmt.Sprintf("(%s %s)", target, e.Operator().Name())
+	case *ast.UpdateIndexExpr:
+		target := fmt.Sprintf("([] %s %s)", sexpr(e.Object()), sexpr(e.Index()))
+		if e.Prefix() {
+			return fmt.Sprintf("(%s %s)", e.Operator().Name(), target)
+		}
+		return fmt.Sprintf("(%s %s)", target, e.Operator().Name())
+	case *ast.ConditionalExpr:
+		return fmt.Sprintf("(?: %s %s %s)", sexpr(e.Condition()), sexpr(e.ThenBranch()), sexpr(e.ElseBranch()))
+	case *ast.GetExpr:
+		return fmt.Sprintf("(. %s %s)", sexpr(e.Object()), *e.Name().Lexeme)
+	case *ast.OptionalGetExpr:
+		return fmt.Sprintf("(?. %s %s)", sexpr(e.Object()), *e.Name().Lexeme)
+	case *ast.CallExpr:
+		args := make([]string, 0, len(e.Arguments())+1)
+		args = append(args, sexpr(e.Callee()))
+		for _, arg := range e.Arguments() {
+			args = append(args, sexpr(arg))
+		}
+		return "(call " + strings.Join(args, " ") + ")"
+	case *ast.MatchExpr:
+		arms := []string{sexpr(e.Subject())}
+		for i, pattern := range e.Patterns() {
+			arm := "(" + patternSexpr(pattern)
+			if guard := e.Guards()[i]; guard != nil {
+				arm += " if " + sexpr(guard)
+			}
+			arms = append(arms, arm+" => "+sexpr(e.Values()[i])+")")
+		}
+		return "(match " + strings.Join(arms, " ") + ")"
+	case *ast.SpawnExpr:
+		return "(spawn " + sexpr(e.Call()) + ")"
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+func patternSexpr(pattern ast.Pattern) string {
+	switch p := pattern.(type) {
+	case *ast.LiteralPattern:
+		return sexpr(ast.NewLiteralExpr(p.Value()))
+	case *ast.WildcardPattern:
+		return "_"
+	case *ast.BindingPattern:
+		return "$" + *p.Name().Lexeme
+	case *ast.ObjectPattern:
+		properties := []string{"object"}
+		for i, name := range p.Names() {
+			properties = append(properties, fmt.Sprintf("(%s %s)", *name.Lexeme, patternSexpr(p.Patterns()[i])))
+		}
+		return "(" + strings.Join(properties, " ") + ")"
+	}
+	return fmt.Sprintf("%T", pattern)
+}
+
+// dump writes stmt in the style of sexpr, one statement per line indented
+// by its nesting.
+func dump(b *strings.Builder, stmt ast.Stmt, depth int) {
+	indent := strings.Repeat("  ", depth)
+	nested := func(head string, body ...ast.Stmt) {
+		b.WriteString(indent + "(" + head + "\n")
+		for _, stmt := range body {
+			dump(b, stmt, depth+1)
+		}
+		b.WriteString(indent + ")\n")
+	}
+
+	switch s := stmt.(type) {
+	case *ast.ExpressionStmt:
+		b.WriteString(indent + sexpr(s.Expression()) + "\n")
+	case *ast.VarStmt:
+		keyword := "var"
+		if s.Constant() {
+			keyword = "const"
+		}
+		if s.Initializer() == nil {
+			fmt.Fprintf(b, "%s(%s %s)\n", indent, keyword, *s.Name().Lexeme)
+		} else {
+			fmt.Fprintf(b, "%s(%s %s %s)\n", indent, keyword, *s.Name().Lexeme, sexpr(s.Initializer()))
+		}
+	case *ast.BlockStmt:
+		nested("block", s.Statements()...)
+	case *ast.IfStmt:
+		if s.ElseBranch() == nil {
+			nested("if "+sexpr(s.Condition()), s.ThenBranch())
+		} else {
+			nested("if "+sexpr(s.Condition()), s.ThenBranch(), s.ElseBranch())
+		}
+	case *ast.WhileStmt:
+		nested("while "+sexpr(s.Condition()), s.Body())
+	case *ast.FunctionStmt:
+		params := make([]string, len(s.Params()))
+		for i, param := range s.Params() {
+			params[i] = *param.Lexeme
+		}
+		kind := "func"
+		if s.Generator() {
+			kind = "generator"
+		}
+		nested(fmt.Sprintf("%s %s (%s)", kind, *s.Name().Lexeme, strings.Join(params, " ")), s.Body()...)
+	case *ast.ForInStmt:
+		nested("for "+*s.Name().Lexeme+" in "+sexpr(s.Iterable()), s.Body())
+	case *ast.ImportStmt:
+		b.WriteString(fmt.Sprintf("%s(import %s %s)\n", indent, *s.Path().Lexeme, *s.Name().Lexeme))
+	case *ast.ThrowStmt:
+		b.WriteString(indent + "(throw " + sexpr(s.Value()) + ")\n")
+	case *ast.YieldStmt:
+		b.WriteString(indent + "(yield " + sexpr(s.Value()) + ")\n")
+	case *ast.TryStmt:
+		// the clauses follow the body, nested in the statement
+		b.WriteString(indent + "(try\n")
+		for _, bodyStmt := range s.Body() {
+			dump(b, bodyStmt, depth+1)
+		}
+		clause := func(head string, body []ast.Stmt) {
+			b.WriteString(indent + "  (" + head + "\n")
+			for _, clauseStmt := range body {
+				dump(b, clauseStmt, depth+2)
+			}
+			b.WriteString(indent + "  )\n")
+		}
+		if s.CatchName() != nil {
+			clause("catch "+*s.CatchName().Lexeme, s.CatchBody())
+		}
+		if s.Finally() != nil {
+			clause("finally", s.FinallyBody())
+		}
+		b.WriteString(indent + ")\n")
+	case *ast.SelectStmt:
+		// each case heads the block of its body
+		b.WriteString(indent + "(select\n")
+		for i, c := range s.Cases() {
+			head := "case " + sexpr(c)
+			if name := s.Names()[i]; name != nil {
+				head += " as " + *name.Lexeme
+			}
+			b.WriteString(indent + "  (" + head + "\n")
+			dump(b, s.Bodies()[i], depth+2)
+			b.WriteString(indent + "  )\n")
+		}
+		if s.ElseBranch() != nil {
+			b.WriteString(indent + "  (else\n")
+			dump(b, s.ElseBranch(), depth+2)
+			b.WriteString(indent + "  )\n")
+		}
+		b.WriteString(indent + ")\n")
+	case *ast.ReturnStmt:
+		if s.Value() == nil {
+			b.WriteString(indent + "(return)\n")
+		} else {
+			b.WriteString(indent + "(return " + sexpr(s.Value()) + ")\n")
+		}
+	default:
+		fmt.Fprintf(b, "%s%T\n", indent, stmt)
+	}
+}
+
+func parseExpression(t *testing.T, source string) (ast.Expr, *Parser) {
+	t.Helper()
+	tokens, err := scanner.NewScanner(strings.NewReader(source), 0).ScanTokens()
+	if err != nil {
+		t.Fatalf("scanning %q: %v", source, err)
+	}
+	p := NewParser(&errorreporter.NopErrorReporter{})
+	p.SetTokens(tokens)
+	return p.ParseExpression(), p
+}
+
+// TestPrecedence pins down how every operator of the grammar binds, relative
+// to its neighbours and to itself.
+func TestPrecedence(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"1 + 2 * 3", "(PLUS 1 (STAR 2 3))"},
+		{"1 * 2 + 3", "(PLUS (STAR 1 2) 3)"},
+		{"1 - 2 - 3", "(MINUS (MINUS 1 2) 3)"},
+		{"8 / 4 / 2", "(SLASH (SLASH 8 4) 2)"},
+		{"7 % 3 * 2", "(STAR (PERCENT 7 3) 2)"},
+		{"(1 + 2) * 3", "(STAR (PLUS 1 2) 3)"},
+		{"2 ** 3 ** 2", "(STAR_STAR 2 (STAR_STAR 3 2))"},
+		{"-2 ** 2", "(MINUS (STAR_STAR 2 2))"},
+		{"2 ** -1", "(STAR_STAR 2 (MINUS 1))"},
+		{"2 * 3 ** 2", "(STAR 2 (STAR_STAR 3 2))"},
+		{"2 ** 3 * 2", "(STAR (STAR_STAR 2 3) 2)"},
+		{"!!a", "(BANG (BANG a))"},
+		{"-a * b", "(STAR (MINUS a) b)"},
+		{"~a & b", "(AMPERSAND (TILDE a) b)"},
+		{"1 << 2 + 3", "(LESS_LESS 1 (PLUS 2 3))"},
+		{"1 << 2 >> 3", "(GREATER_GREATER (LESS_LESS 1 2) 3)"},
+		{"a & b << 1", "(AMPERSAND a (LESS_LESS b 1))"},
+		{"a | b ^ c & d", "(PIPE a (CARET b (AMPERSAND c d)))"},
+		{"a ^ b ^ c", "(CARET (CARET a b) c)"},
+		{"x & 1 == 0", "(EQUAL_EQUAL (AMPERSAND x 1) 0)"},
+		{"a < b | c", "(LESS a (PIPE b c))"},
+		{"a < b == c > d", "(EQUAL_EQUAL (LESS a b) (GREATER c d))"},
+		{"a == b != c", "(BANG_EQUAL (EQUAL_EQUAL a b) c)"},
+		{"a and b == c", "(AND a (EQUAL_EQUAL b c))"},
+		{"a or b and c", "(OR a (AND b c))"},
+		{"a or b or c", "(OR (OR a b) c)"},
+		{"a ?? b or c", "(?? a (OR b c))"},
+		{"a ?? b ?? c", "(?? (?? a b) c)"},
+		{"a ? b : c ? d : e", "(?: a b (?: c d e))"},
+		{"a ? b = 1 : c", "(?: a (= b 1) c)"},
+		{"a ?? b ? c : d", "(?: (?? a b) c d)"},
+		{"a = b = c", "(= a (= b c))"},
+		{"a = b ? c : d", "(= a (?: b c d))"},
+		{"a += b -= 1", "(PLUS_EQUAL a (MINUS_EQUAL b 1))"},
+		{"a *= 2 + 3", "(STAR_EQUAL a (PLUS 2 3))"},
+		{"-a++", "(MINUS (a PLUS_PLUS))"},
+		{"++a * 2", "(STAR (PLUS_PLUS a) 2)"},
+		{"a-- ** 2", "(STAR_STAR (a MINUS_MINUS) 2)"},
+		{"2 ** b++", "(STAR_STAR 2 (b PLUS_PLUS))"},
+		{"a.b += c[0] -= 1", "(PLUS_EQUAL (. a b) (MINUS_EQUAL ([] c 0) 1))"},
+		{"-a.b++", "(MINUS ((. a b) PLUS_PLUS))"},
+		{"++a[i] * 2", "(STAR (PLUS_PLUS ([] a i)) 2)"},
+		{"-f(1)(2)", "(MINUS (call (call f 1) 2))"},
+		{"a.b.c", "(. (. a b) c)"},
+		{"a?.b.c(1)", "(call (. (?. a b) c) 1)"},
+		{"f(a, b + 1, c = 2)", "(call f a (PLUS b 1) (= c 2))"},
+		{"-a.b ** 2", "(MINUS (STAR_STAR (. a b) 2))"},
+		{"1 based 2 ong 3", "(PLUS 1 (STAR 2 3))"},
+		{"a glowup b collab c", "(AMPERSAND (STAR_STAR a b) c)"},
+		{"deadass a rizz b", "(AND (BANG a) b)"},
+		{"a stacks b ratio 2", "(PLUS_EQUAL a (PERCENT b 2))"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			expr, p := parseExpression(t, test.source)
+			if p.HadError() {
+				t.Fatalf("unexpected parse errors: %v", p.Errors())
+			}
+			if got := sexpr(expr); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		message string
+	}{
+		{"a + b = c", "Invalid assignment target"},
+		{"1 += 2", "Invalid assignment target"},
+		{"1++", "Invalid increment or decrement target"},
+		{"++(a)", "Invalid increment or decrement target"},
+		{"a ? b", "Expect ':' after then branch of conditional expression"},
+		{"1 +", "Expect expression"},
+		{"(1", "Expect ')' after expression"},
+		{"f(1", "Expect ')' after arguments"},
+		{"a.", "Expect property name after '.'"},
+		{"1 2", "Expect end of expression"},
+		{"a++(1)", "Expect end of expression"},
+		{"-a++(1)", "Expect end of expression"},
+		{"a.b() += 1", "Invalid assignment target"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			_, p := parseExpression(t, test.source)
+			if !p.HadError() {
+				t.Fatal("expected a parse error")
+			}
+			if got := p.Errors()[0].message; got != test.message {
+				t.Errorf("got error %q, want %q", got, test.message)
+			}
+		})
+	}
+}
+
+func TestKeywordPack(t *testing.T) {
+	pack, err := LoadKeywordPack(strings.NewReader(`{
+		"name": "test",
+		"keywords": [
+			{"spelling": "plus", "token": "PLUS"},
+			{"spelling": "is   bigger than", "token": "GREATER"}
+		],
+		"operators": [
+			{"spelling": "vs", "kind": "infix", "precedence": "comparison", "function": "compare"},
+			{"spelling": "pow", "kind": "infix", "precedence": "power", "associativity": "right", "function": "pow"},
+			{"spelling": "hype", "kind": "prefix", "function": "hype"},
+			{"spelling": "bruh", "kind": "postfix", "precedence": "postfix", "function": "bruh"}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keywords, err := pack.ScanKeywords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	operators := NewOperators()
+	if err := pack.Register(operators); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"1 plus 2 * 3", "(PLUS 1 (STAR 2 3))"},
+		{"a is bigger than b == c", "(EQUAL_EQUAL (GREATER a b) c)"},
+		{"a + 1 vs b == c", "(EQUAL_EQUAL (call compare (PLUS a 1) b) c)"},
+		{"a vs b vs c", "(call compare (call compare a b) c)"},
+		{"a pow b pow c", "(call pow a (call pow b c))"},
+		{"hype a * b", "(STAR (call hype a) b)"},
+		{"a bruh + 1", "(PLUS (call bruh a) 1)"},
+		{"vs + pow", "(PLUS vs pow)"},
+	}
+	for _, test := range tests {
+		t.Run(test.source, func(t *testing.T) {
+			s := scanner.NewScanner(strings.NewReader(test.source), 0)
+			s.SetKeywords(keywords)
+			tokens, err := s.ScanTokens()
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := NewParser(&errorreporter.NopErrorReporter{})
+			p.SetOperators(operators)
+			p.SetTokens(tokens)
+			expr := p.ParseExpression()
+			if p.HadError() {
+				t.Fatalf("unexpected parse errors: %v", p.Errors())
+			}
+			if got := sexpr(expr); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+
+	// the pack leaves the default table alone
+	if _, p := parseExpression(t, "a vs b"); !p.HadError() {
+		t.Error("expected the default operators not to know 'vs'")
+	}
+
+	invalid := []string{
+		`{"keywords": [{"spelling": "plus", "token": "NOPE"}]}`,
+		`{"keywords": [{"spelling": "x!", "token": "PLUS"}]}`,
+		`{"operators": [{"spelling": "vibes", "kind": "infix", "precedence": "term", "function": "f"}]}`,
+		`{"operators": [{"spelling": "vs", "kind": "infix", "precedence": "loud", "function": "f"}]}`,
+		`{"operators": [{"spelling": "vs", "kind": "sideways", "precedence": "term", "function": "f"}]}`,
+		`{"operators": [{"spelling": "a b", "kind": "infix", "precedence": "term", "function": "f"}]}`,
+		`{"unknown": 1}`,
+	}
+	for _, source := range invalid {
+		if _, err := LoadKeywordPack(strings.NewReader(source)); err == nil {
+			t.Errorf("expected an error loading %s", source)
+		}
+	}
+}
+
+// TestGrammar checks that the parser accepts exactly the language described
+// by grammar/a.ebnf: random sentences of the grammar must parse, and random
+// mutations of them must parse if and only if they still are sentences.
+func TestGrammar(t *testing.T) {
+	f, err := os.Open("../../grammar/a.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	grammar, err := ebnf.Parse(f, "a.ebnf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	generator := ebnf.NewGenerator(grammar, r)
+	pick := func(choices ...string) func(*rand.Rand) string {
+		return func(r *rand.Rand) string { return choices[r.Intn(len(choices))] }
+	}
+	generator.Classes["NUMBER"] = pick("0", "1", "2.5", "42")
+	generator.Classes["STRING"] = pick(`""`, `"rotten"`)
+	generator.Classes["IDENTIFIER"] = pick("a", "b", "x", "_tmp")
+	recognizer := ebnf.NewRecognizer(grammar)
+
+	// every symbol a mutation may insert
+	alphabet := []ebnf.Symbol{
+		{Name: "NUMBER", Class: true, Text: "1"},
+		{Name: "STRING", Class: true, Text: `"s"`},
+		{Name: "IDENTIFIER", Class: true, Text: "a"},
+	}
+	for _, terminal := range grammar.Terminals() {
+		alphabet = append(alphabet, ebnf.Symbol{Name: terminal, Text: terminal})
+	}
+
+	// Programs are wrapped in a function, so that "purrr" and "yield" may
+	// appear anywhere in them. "import" may then appear nowhere, which the
+	// parser reports without failing to parse, as it does names bound twice
+	// by a pattern, values returned by generators, the rules of select and
+	// those of classes.
+	wrap := func(symbols []ebnf.Symbol) []ebnf.Symbol {
+		wrapped := []ebnf.Symbol{
+			{Name: "func", Text: "func"},
+			{Name: "IDENTIFIER", Class: true, Text: "test"},
+			{Name: "(", Text: "("},
+			{Name: ")", Text: ")"},
+			{Name: "{", Text: "{"},
+		}
+		wrapped = append(wrapped, symbols...)
+		return append(wrapped, ebnf.Symbol{Name: "}", Text: "}"})
+	}
+	parses := func(source string) bool {
+		tokens, err := scanner.NewScanner(strings.NewReader(source), 0).ScanTokens()
+		if err != nil {
+			t.Fatalf("scanning %q: %v", source, err)
+		}
+		p := NewParser(&errorreporter.NopErrorReporter{})
+		p.SetTokens(tokens)
+		p.Parse()
+		for _, err := range p.Errors() {
+			// a mutation may close the function early
+			switch err.message {
+			case "Can't return from top-level code", "Can only import at the top level",
+				"Can't yield from top-level code", "Can't return a value from a generator",
+				"Already a binding with this name in this pattern",
+				"Expect channel send or receive in select case",
+				"Can only name the value of a receive", "Already an else branch in this select",
+				"Expect at least one select case", "Can't use 'this' outside of a class",
+				"Already a method with this name in this class", "Can't yield from an initializer",
+				"Can't return a value from an initializer":
+			default:
+				return false
+			}
+		}
+		return true
+	}
+
+	// Some terminals, such as "else", only appear deep in sentences, so
+	// generate more sentences until every one was used.
+	used := make(map[string]bool)
+	allUsed := func() bool {
+		for _, terminal := range grammar.Terminals() {
+			if !used[terminal] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i < 3000 || !allUsed() && i < 30000; i++ {
+		// Each level of precedence may repeat, so long sentences are mostly
+		// made of expressions. Vary how long they get.
+		generator.RepeatChance = r.Float64() * 0.6
+		generator.MaxSymbols = 10 + r.Intn(100)
+		symbols, err := generator.Generate("program")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, symbol := range symbols {
+			used[symbol.Name] = true
+		}
+		if source := ebnf.Sentence(wrap(symbols)); !parses(source) {
+			t.Fatalf("sentence of the grammar fails to parse: %s", source)
+		}
+
+		mutated := mutate(r, symbols, alphabet)
+		source := ebnf.Sentence(wrap(mutated))
+		if want, got := recognizer.Recognize("program", wrap(mutated)), parses(source); got != want {
+			t.Fatalf("grammar accepts: %v, parser accepts: %v\n%s", want, got, source)
+		}
+	}
+
+	for _, terminal := range grammar.Terminals() {
+		if !used[terminal] {
+			t.Errorf("no sentence used %q", terminal)
+		}
+	}
+
+	// the grammar documents every operator the parser knows
+	documented := make(map[ast.TokenType]bool)
+	for _, symbol := range alphabet {
+		tokens, err := scanner.NewScanner(strings.NewReader(symbol.Text), 0).ScanTokens()
+		if err != nil {
+			t.Fatal(err)
+		}
+		documented[tokens[0].Type] = true
+	}
+	for tokenType := range defaultOperators.prefix {
+		if !documented[tokenType] {
+			t.Errorf("prefix operator %s is missing from the grammar", (&ast.Token{Type: tokenType}).Name())
+		}
+	}
+	for tokenType := range defaultOperators.infix {
+		if !documented[tokenType] {
+			t.Errorf("infix operator %s is missing from the grammar", (&ast.Token{Type: tokenType}).Name())
+		}
+	}
+}
+
+// mutate returns a copy of symbols with a random symbol deleted, inserted,
+// replaced or swapped with its neighbour.
+func mutate(r *rand.Rand, symbols []ebnf.Symbol, alphabet []ebnf.Symbol) []ebnf.Symbol {
+	mutated := append([]ebnf.Symbol(nil), symbols...)
+	random := alphabet[r.Intn(len(alphabet))]
+	if len(mutated) == 0 {
+		return append(mutated, random)
+	}
+
+	i := r.Intn(len(mutated))
+	switch r.Intn(4) {
+	case 0:
+		return append(mutated[:i], mutated[i+1:]...)
+	case 1:
+		return append(mutated[:i], append([]ebnf.Symbol{random}, mutated[i:]...)...)
+	case 2:
+		mutated[i] = random
+	default:
+		if i+1 < len(mutated) {
+			mutated[i], mutated[i+1] = mutated[i+1], mutated[i]
+		}
+	}
+	return mutated
+}
+
+// parseProgram scans and parses source, reporting errors to reporter. It
+// tells whether both succeeded; the parser gets to see the tokens even
+// when scanning fails.
+func parseProgram(source string, reporter errorreporter.ErrorReporter) ([]ast.Stmt, bool) {
+	s := scanner.NewScanner(strings.NewReader(source), 0)
+	_, err := s.ScanTokens()
+	p := NewParser(reporter)
+	p.SetTokens(s.Tokens())
+	statements := p.Parse()
+	return statements, err == nil && !p.HadError()
+}
+
+// FuzzParse checks that the parser survives any input, and that printing a
+// program it accepts gives back source parsing to the same program.
+func FuzzParse(f *testing.F) {
+	paths, err := filepath.Glob("../*/testdata/*.rot")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(source))
+	}
+	for _, seed := range []string{
+		"",
+		"vibes a = 1 + 2 * -3;",
+		"func f(a, b) { purrr a ?? b?.c; }",
+		"chat is this real (a) { b++; } else c -= 2 ** 3;",
+		"for (vibes i = 0; i < 10; i += 1) skibidi (nocap) {}",
+		`a = b ? "x" : f(1)(2).c;`,
+		"- -1; !!a; ~~b; ---a;",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, source string) {
+		statements, ok := parseProgram(source, &errorreporter.NopErrorReporter{})
+		if !ok {
+			return
+		}
+
+		printed := printer.NewASTPrinter().PrintProgram(statements)
+		reparsed, ok := parseProgram(printed, &errorreporter.NopErrorReporter{})
+		if !ok {
+			t.Fatalf("printed program does not parse:\n%s", printed)
+		}
+		if len(reparsed) != len(statements) {
+			t.Fatalf("printed program has %d statements, want %d:\n%s", len(reparsed), len(statements), printed)
+		}
+		for i := range statements {
+			if !ast.Equal(statements[i], reparsed[i]) {
+				t.Fatalf("statement %d changed when printed as:\n%s", i, printer.NewASTPrinter().PrintStmt(statements[i]))
+			}
+		}
+	})
+}
+
+// TestGolden parses the programs in testdata, comparing their trees with
+// the .ast files and their warnings and errors with the .err files.
+func TestGolden(t *testing.T) {
+	for _, program := range golden.Programs(t, "testdata") {
+		t.Run(filepath.Base(program), func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reporter := &errorreporter.CollectingErrorReporter{}
+			statements, _ := parseProgram(string(source), reporter)
+
+			var b strings.Builder
+			for _, stmt := range statements {
+				dump(&b, stmt, 0)
+			}
+			golden.Check(t, program, ".ast", b.String())
+
+			var messages strings.Builder
+			for _, diagnostic := range append(reporter.Warnings(), reporter.Diagnostics()...) {
+				messages.WriteString(diagnostic.String() + "\n")
+			}
+			golden.Check(t, program, ".err", messages.String())
+		})
+	}
+}
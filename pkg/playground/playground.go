@@ -0,0 +1,284 @@
+// Package playground runs the stages of the language over a source, for
+// the WebAssembly build of rottenlang, reporting what each produces as
+// JSON. Nothing is written to stdout or stderr: both are captured into
+// the Result.
+package playground
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/printer"
+	"github.com/bagaswh/rottenlang/pkg/rottenlang"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// DefaultMaxSteps bounds the steps of programs given to Run without a
+// limit. A WebAssembly program can't be preempted, so a program that never
+// ends would hang the page running it.
+const DefaultMaxSteps = 10_000_000
+
+// Result is what a stage produced from a source. Only the fields of the
+// stage are set, besides the captured output and the diagnostics.
+type Result struct {
+	Tokens []Token `json:"tokens,omitempty"`
+	// AST lists the statements of the program, each an object with a
+	// "type" naming the node and a member for each of its fields
+	AST       []Node `json:"ast,omitempty"`
+	Formatted string `json:"formatted,omitempty"`
+	// Stdout receives what the program printed
+	Stdout string `json:"stdout"`
+	// Stderr receives the errors and warnings, as the command line writes
+	// them
+	Stderr      string       `json:"stderr"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Warnings    []Diagnostic `json:"warnings"`
+	// Error is set when the stage failed
+	Error string `json:"error,omitempty"`
+}
+
+// JSON returns the result encoded as JSON.
+func (r *Result) JSON() string {
+	b, err := json.Marshal(r)
+	if err != nil {
+		b, _ = json.Marshal(&Result{Error: err.Error()})
+	}
+	return string(b)
+}
+
+type Token struct {
+	// Name is the type of the token, as returned by ast.Token.Name
+	Name    string `json:"name"`
+	Lexeme  string `json:"lexeme"`
+	Literal any    `json:"literal,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Offset  int    `json:"offset"`
+}
+
+func newToken(token *ast.Token) Token {
+	return Token{
+		Name:    token.Name(),
+		Lexeme:  *token.Lexeme,
+		Literal: literal(token.Literal),
+		Line:    token.Line,
+		Column:  token.Column,
+		Offset:  token.Offset,
+	}
+}
+
+type Diagnostic struct {
+	// Kind is one of the errorreporter kinds, e.g. "parser"
+	Kind    string `json:"kind"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Where   string `json:"where,omitempty"`
+	Message string `json:"message"`
+}
+
+func diagnostics(list []errorreporter.Diagnostic) []Diagnostic {
+	converted := make([]Diagnostic, len(list))
+	for i, d := range list {
+		converted[i] = Diagnostic{Kind: d.Kind, Line: d.Line, Column: d.Column, Where: d.Where, Message: d.Message}
+	}
+	return converted
+}
+
+// Node encodes a syntax tree node as a JSON object, keeping its fields in
+// the order of the spec.
+type Node struct {
+	node ast.Node
+}
+
+func (n Node) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(`{"type":`)
+	writeJSON(&b, nodeType(n.node))
+	for _, field := range ast.Fields(n.node) {
+		b.WriteByte(',')
+		writeJSON(&b, field.Name)
+		b.WriteByte(':')
+		writeJSON(&b, fieldValue(field.Value))
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func writeJSON(b *bytes.Buffer, v any) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		encoded, _ = json.Marshal(err.Error())
+	}
+	b.Write(encoded)
+}
+
+// nodeType names node by its type, e.g. "BinaryExpr".
+func nodeType(node ast.Node) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+}
+
+// fieldValue returns the value of a field of a node, made encodable.
+func fieldValue(v any) any {
+	switch v := v.(type) {
+	case *ast.Token:
+		if v == nil {
+			return nil
+		}
+		return newToken(v)
+	case []*ast.Token:
+		tokens := make([]any, len(v))
+		for i, token := range v {
+			tokens[i] = fieldValue(token)
+		}
+		return tokens
+	case ast.Node:
+		return Node{v}
+	case []ast.Expr:
+		return nodes(v)
+	case []ast.Stmt:
+		return nodes(v)
+	case []ast.Pattern:
+		return nodes(v)
+	}
+	return literal(v)
+}
+
+func nodes[N ast.Node](list []N) []any {
+	converted := make([]any, len(list))
+	for i, node := range list {
+		converted[i] = fieldValue(node)
+	}
+	return converted
+}
+
+// literal returns a literal value, spelling the numbers JSON can't hold.
+func literal(v any) any {
+	if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+		return value.Stringify(f)
+	}
+	return v
+}
+
+// reporter collects the diagnostics of a stage and writes them into its
+// stderr, capturing what the program prints into its stdout.
+type reporter struct {
+	errorreporter.CollectingErrorReporter
+	stdout, stderr bytes.Buffer
+	writer         *errorreporter.WriterErrorReporter
+}
+
+func newReporter() *reporter {
+	r := &reporter{}
+	r.writer = errorreporter.NewWriterErrorReporter(&r.stderr)
+	return r
+}
+
+func (r *reporter) ReportScannerError(line, column int, where, message string) {
+	r.CollectingErrorReporter.ReportScannerError(line, column, where, message)
+	r.writer.ReportScannerError(line, column, where, message)
+}
+
+func (r *reporter) ReportParserError(line, column int, where, message string) {
+	r.CollectingErrorReporter.ReportParserError(line, column, where, message)
+	r.writer.ReportParserError(line, column, where, message)
+}
+
+func (r *reporter) ReportRuntimeError(line, column int, message string) {
+	r.CollectingErrorReporter.ReportRuntimeError(line, column, message)
+	r.writer.ReportRuntimeError(line, column, message)
+}
+
+func (r *reporter) ReportWarning(line, column int, where, message string) {
+	r.CollectingErrorReporter.ReportWarning(line, column, where, message)
+	r.writer.ReportWarning(line, column, where, message)
+}
+
+// result returns the result of a stage that ended with err.
+func (r *reporter) result(err error) *Result {
+	result := &Result{
+		Stdout:      r.stdout.String(),
+		Stderr:      r.stderr.String(),
+		Diagnostics: diagnostics(r.Diagnostics()),
+		Warnings:    diagnostics(r.Warnings()),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// newSession returns a session over source that captures what it prints,
+// reporting into reporter.
+func newSession(source string, reporter *reporter) *rottenlang.Rottenlang {
+	session := rottenlang.NewRottenlang(source, reporter)
+	session.Out = &reporter.stdout
+	return session
+}
+
+// Tokenize scans source into tokens.
+func Tokenize(source string) *Result {
+	reporter := newReporter()
+	tokens, err := newSession(source, reporter).Scan()
+	result := reporter.result(err)
+	for _, token := range tokens {
+		result.Tokens = append(result.Tokens, newToken(token))
+	}
+	return result
+}
+
+// Parse parses source into its syntax tree.
+func Parse(source string) *Result {
+	reporter := newReporter()
+	statements, err := newSession(source, reporter).Parse()
+	result := reporter.result(err)
+	for _, statement := range statements {
+		result.AST = append(result.AST, Node{statement})
+	}
+	return result
+}
+
+// Format parses source and prints it back formatted.
+func Format(source string) *Result {
+	reporter := newReporter()
+	statements, err := newSession(source, reporter).Parse()
+	result := reporter.result(err)
+	if err == nil && len(statements) > 0 {
+		result.Formatted = printer.NewASTPrinter().PrintProgram(statements) + "\n"
+	}
+	return result
+}
+
+// Options configure Run.
+type Options struct {
+	// Engine is "tree" or "vm", "tree" when empty
+	Engine string `json:"engine"`
+	// MaxSteps bounds the steps the program takes, DefaultMaxSteps when
+	// zero
+	MaxSteps int64 `json:"maxSteps"`
+	// Optimize runs the optimizer over the program first
+	Optimize bool `json:"optimize"`
+}
+
+// Run runs source, capturing what it prints.
+func Run(source string, options Options) *Result {
+	reporter := newReporter()
+	engine := rottenlang.EngineTree
+	if options.Engine != "" {
+		var err error
+		if engine, err = rottenlang.ParseEngine(options.Engine); err != nil {
+			return reporter.result(err)
+		}
+	}
+	session := newSession(source, reporter)
+	session.Optimize = options.Optimize
+	session.Limits.MaxSteps = options.MaxSteps
+	if session.Limits.MaxSteps == 0 {
+		session.Limits.MaxSteps = DefaultMaxSteps
+	}
+	return reporter.result(session.Execute(engine))
+}
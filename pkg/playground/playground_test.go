@@ -0,0 +1,83 @@
+package playground
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, result *Result) map[string]any {
+	t.Helper()
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(result.JSON()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestTokenize(t *testing.T) {
+	result := Tokenize("vibes x = 1;")
+	var names []string
+	for _, token := range result.Tokens {
+		names = append(names, token.Name)
+	}
+	if got := strings.Join(names, " "); got != "VAR IDENTIFIER EQUAL NUMBER SEMICOLON EOF" {
+		t.Errorf("got tokens %s", got)
+	}
+	if token := decode(t, result)["tokens"].([]any)[3]; token.(map[string]any)["literal"] != 1.0 {
+		t.Errorf("got number token %v", token)
+	}
+
+	result = Tokenize("@")
+	if result.Error == "" || len(result.Diagnostics) != 1 || result.Diagnostics[0].Kind != "scanner" {
+		t.Errorf("got %s", result.JSON())
+	}
+}
+
+func TestParse(t *testing.T) {
+	want := `{"ast":[{"type":"ExpressionStmt","expression":{"type":"BinaryExpr",` +
+		`"left":{"type":"UnaryExpr","operator":{"name":"MINUS","lexeme":"-","line":1,"column":1,"offset":0},"right":{"type":"LiteralExpr","value":1}},` +
+		`"operator":{"name":"PLUS","lexeme":"+","line":1,"column":4,"offset":3},` +
+		`"right":{"type":"VariableExpr","name":{"name":"IDENTIFIER","lexeme":"y","line":1,"column":6,"offset":5}}}}],` +
+		`"stdout":"","stderr":"","diagnostics":[],"warnings":[]}`
+	if got := Parse("-1 + y;").JSON(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	result := Parse("vibes = ;")
+	want = "[line=1 col=7] Error: Expect variable name. Snippet: 'at '=''\n"
+	if result.AST != nil || result.Stderr != want || len(result.Diagnostics) != 1 || result.Diagnostics[0].Where != "at '='" {
+		t.Errorf("got %s", result.JSON())
+	}
+}
+
+func TestFormat(t *testing.T) {
+	result := Format("vibes   x=1;chat is this real(x){print(x);}")
+	want := "vibes x = 1;\nchat is this real (x) {\n  print(x);\n}\n"
+	if result.Formatted != want {
+		t.Errorf("got:\n%s\nwant:\n%s", result.Formatted, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	for _, engine := range []string{"tree", "vm"} {
+		t.Run(engine, func(t *testing.T) {
+			result := Run("print(1);\nprint(1 - \"a\");", Options{Engine: engine})
+			if result.Stdout != "1\n" || result.Stderr != "[line=2 col=9] Runtime error: operands must be numbers\n" {
+				t.Errorf("got %s", result.JSON())
+			}
+			if len(result.Diagnostics) != 1 || result.Diagnostics[0].Kind != "runtime" {
+				t.Errorf("got diagnostics %+v", result.Diagnostics)
+			}
+
+			result = Run("skibidi (1) {}", Options{Engine: engine, MaxSteps: 1000})
+			if !strings.Contains(result.Error, "step") {
+				t.Errorf("endless loop wasn't stopped: %s", result.JSON())
+			}
+		})
+	}
+
+	if result := Run("print(1);", Options{Engine: "js"}); result.Error == "" || result.Stdout != "" {
+		t.Errorf("got %s", result.JSON())
+	}
+}
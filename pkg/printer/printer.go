@@ -2,43 +2,399 @@ package printer
 
 import (
 	"fmt"
-	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/value"
 )
 
-type ASTPrinter struct{}
+// ASTPrinter formats syntax trees back into source code.
+type ASTPrinter struct {
+	// depth is the block nesting level of the statement being printed
+	depth int
+}
+
+var (
+	_ ast.Visitor[string]        = (*ASTPrinter)(nil)
+	_ ast.StmtVisitor[string]    = (*ASTPrinter)(nil)
+	_ ast.PatternVisitor[string] = (*ASTPrinter)(nil)
+)
 
 func NewASTPrinter() *ASTPrinter {
 	return &ASTPrinter{}
 }
 
 func (p *ASTPrinter) Print(expr ast.Expr) string {
-	return expr.Accept(p).(string)
+	return ast.Accept[string](expr, p)
+}
+
+// PrintStmt formats stmt as source code, one statement per line.
+func (p *ASTPrinter) PrintStmt(stmt ast.Stmt) string {
+	return ast.AcceptStmt[string](stmt, p)
+}
+
+// PrintPattern formats the pattern of a match arm.
+func (p *ASTPrinter) PrintPattern(pattern ast.Pattern) string {
+	return ast.AcceptPattern[string](pattern, p)
+}
+
+// PrintProgram formats every statement of a program.
+func (p *ASTPrinter) PrintProgram(statements []ast.Stmt) string {
+	lines := make([]string, len(statements))
+	for i, stmt := range statements {
+		lines[i] = p.PrintStmt(stmt)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *ASTPrinter) indent() string {
+	return strings.Repeat("  ", p.depth)
+}
+
+func (p *ASTPrinter) block(statements []ast.Stmt) string {
+	p.depth++
+	lines := make([]string, len(statements))
+	for i, stmt := range statements {
+		lines[i] = p.indent() + p.PrintStmt(stmt)
+	}
+	p.depth--
+
+	if len(lines) == 0 {
+		return "{}"
+	}
+	return "{\n" + strings.Join(lines, "\n") + "\n" + p.indent() + "}"
+}
+
+func (p *ASTPrinter) VisitExpressionStmt(stmt *ast.ExpressionStmt) string {
+	// a statement starting with a brace would parse back as a block
+	if token := ast.LeadingToken(stmt.Expression()); token != nil && token.Type == ast.TokenLeftBrace {
+		return "(" + p.Print(stmt.Expression()) + ");"
+	}
+	return p.Print(stmt.Expression()) + ";"
+}
+
+func (p *ASTPrinter) VisitVarStmt(stmt *ast.VarStmt) string {
+	keyword := "vibes"
+	if stmt.Constant() {
+		keyword = "slay"
+	}
+	if stmt.Initializer() == nil {
+		return fmt.Sprintf("%s %s;", keyword, *stmt.Name().Lexeme)
+	}
+	return fmt.Sprintf("%s %s = %s;", keyword, *stmt.Name().Lexeme, p.Print(stmt.Initializer()))
+}
+
+func (p *ASTPrinter) VisitImportStmt(stmt *ast.ImportStmt) string {
+	return fmt.Sprintf("import %s as %s;", *stmt.Path().Lexeme, *stmt.Name().Lexeme)
+}
+
+func (p *ASTPrinter) VisitBlockStmt(stmt *ast.BlockStmt) string {
+	return p.block(stmt.Statements())
+}
+
+func (p *ASTPrinter) VisitIfStmt(stmt *ast.IfStmt) string {
+	s := fmt.Sprintf("chat is this real (%s) %s", p.Print(stmt.Condition()), p.PrintStmt(stmt.ThenBranch()))
+	if stmt.ElseBranch() != nil {
+		s += " else " + p.PrintStmt(stmt.ElseBranch())
+	}
+	return s
+}
+
+func (p *ASTPrinter) VisitWhileStmt(stmt *ast.WhileStmt) string {
+	// A for loop is parsed into a while loop that keeps the "for" keyword;
+	// printing it as a for loop without clauses parses back to the same tree.
+	if stmt.Keyword().Type == ast.TokenFor {
+		return fmt.Sprintf("%s (; %s;) %s", *stmt.Keyword().Lexeme, p.Print(stmt.Condition()), p.PrintStmt(stmt.Body()))
+	}
+	return fmt.Sprintf("skibidi (%s) %s", p.Print(stmt.Condition()), p.PrintStmt(stmt.Body()))
+}
+
+func (p *ASTPrinter) VisitForInStmt(stmt *ast.ForInStmt) string {
+	return fmt.Sprintf("%s (%s in %s) %s", *stmt.Keyword().Lexeme, *stmt.Name().Lexeme, p.Print(stmt.Iterable()), p.PrintStmt(stmt.Body()))
+}
+
+func (p *ASTPrinter) VisitFunctionStmt(stmt *ast.FunctionStmt) string {
+	return "func " + p.method(stmt)
+}
+
+// method formats a function without the "func" keyword, as a class
+// declares its methods.
+func (p *ASTPrinter) method(stmt *ast.FunctionStmt) string {
+	params := make([]string, len(stmt.Params()))
+	for i, param := range stmt.Params() {
+		params[i] = *param.Lexeme
+	}
+	return fmt.Sprintf("%s(%s) %s", *stmt.Name().Lexeme, strings.Join(params, ", "), p.block(stmt.Body()))
+}
+
+func (p *ASTPrinter) VisitClassStmt(stmt *ast.ClassStmt) string {
+	if len(stmt.Methods()) == 0 {
+		return "class " + *stmt.Name().Lexeme + " {}"
+	}
+	p.depth++
+	lines := make([]string, len(stmt.Methods()))
+	for i, method := range stmt.Methods() {
+		lines[i] = p.indent() + p.method(method.(*ast.FunctionStmt))
+	}
+	p.depth--
+	return "class " + *stmt.Name().Lexeme + " {\n" + strings.Join(lines, "\n") + "\n" + p.indent() + "}"
+}
+
+func (p *ASTPrinter) VisitReturnStmt(stmt *ast.ReturnStmt) string {
+	if stmt.Value() == nil {
+		return "purrr;"
+	}
+	return "purrr " + p.Print(stmt.Value()) + ";"
+}
+
+func (p *ASTPrinter) VisitYieldStmt(stmt *ast.YieldStmt) string {
+	return *stmt.Keyword().Lexeme + " " + p.Print(stmt.Value()) + ";"
+}
+
+func (p *ASTPrinter) VisitThrowStmt(stmt *ast.ThrowStmt) string {
+	return *stmt.Keyword().Lexeme + " " + p.Print(stmt.Value()) + ";"
+}
+
+func (p *ASTPrinter) VisitTryStmt(stmt *ast.TryStmt) string {
+	s := *stmt.Keyword().Lexeme + " " + p.block(stmt.Body())
+	if stmt.CatchName() != nil {
+		s += fmt.Sprintf(" catch (%s) %s", *stmt.CatchName().Lexeme, p.block(stmt.CatchBody()))
+	}
+	if stmt.Finally() != nil {
+		s += " " + *stmt.Finally().Lexeme + " " + p.block(stmt.FinallyBody())
+	}
+	return s
+}
+
+func (p *ASTPrinter) VisitSelectStmt(stmt *ast.SelectStmt) string {
+	p.depth++
+	lines := make([]string, 0, len(stmt.Cases())+1)
+	for i, c := range stmt.Cases() {
+		line := p.indent() + p.Print(c)
+		if name := stmt.Names()[i]; name != nil {
+			line += " as " + *name.Lexeme
+		}
+		lines = append(lines, line+" "+p.PrintStmt(stmt.Bodies()[i]))
+	}
+	if stmt.ElseBranch() != nil {
+		lines = append(lines, p.indent()+"else "+p.PrintStmt(stmt.ElseBranch()))
+	}
+	p.depth--
+	return *stmt.Keyword().Lexeme + " {\n" + strings.Join(lines, "\n") + "\n" + p.indent() + "}"
+}
+
+func (p *ASTPrinter) VisitBinaryExpr(expr *ast.BinaryExpr) string {
+	left := p.Print(expr.Left())
+	right := p.Print(expr.Right())
+	return left + " " + *expr.Operator().Lexeme + " " + right
+}
+
+func (p *ASTPrinter) VisitLiteralExpr(expr *ast.LiteralExpr) string {
+	return literal(expr.Value())
+}
+
+// literal formats the value of a literal. The scanner keeps escape
+// sequences as written, so quoting a string gives back the original
+// literal.
+func literal(v any) string {
+	if s, ok := v.(string); ok {
+		return `"` + s + `"`
+	}
+	return value.Stringify(v)
+}
+
+func (p *ASTPrinter) VisitGroupingExpr(expr *ast.GroupingExpr) string {
+	return fmt.Sprintf("(%v)", p.Print(expr.Expr()))
 }
 
-func (p *ASTPrinter) VisitBinaryExpr(expr *ast.BinaryExpr) any {
-	left := expr.Left().Accept(p).(string)
-	right := expr.Right().Accept(p).(string)
+func (p *ASTPrinter) VisitUnaryExpr(expr *ast.UnaryExpr) string {
+	return prefix(*expr.Operator().Lexeme, p.Print(expr.Right()))
+}
+
+func (p *ASTPrinter) VisitVariableExpr(expr *ast.VariableExpr) string {
+	return *expr.Name().Lexeme
+}
+
+func (p *ASTPrinter) VisitAssignExpr(expr *ast.AssignExpr) string {
+	return *expr.Name().Lexeme + " = " + p.Print(expr.Value())
+}
+
+func (p *ASTPrinter) VisitCompoundAssignExpr(expr *ast.CompoundAssignExpr) string {
+	return *expr.Name().Lexeme + " " + *expr.Operator().Lexeme + " " + p.Print(expr.Value())
+}
+
+func (p *ASTPrinter) VisitUpdateExpr(expr *ast.UpdateExpr) string {
+	return update(*expr.Name().Lexeme, expr.Operator(), expr.Prefix())
+}
+
+// update formats the "++" or "--" operator applied to target.
+func update(target string, operator *ast.Token, isPrefix bool) string {
+	if isPrefix {
+		return prefix(*operator.Lexeme, target)
+	}
+	if isWord(*operator.Lexeme) {
+		return target + " " + *operator.Lexeme
+	}
+	return target + *operator.Lexeme
+}
+
+func (p *ASTPrinter) VisitLogicalExpr(expr *ast.LogicalExpr) string {
+	left := p.Print(expr.Left())
+	right := p.Print(expr.Right())
 	return left + " " + *expr.Operator().Lexeme + " " + right
 }
 
-func (p *ASTPrinter) VisitLiteralExpr(expr *ast.LiteralExpr) any {
-	s := ""
-	v := expr.Value()
-	switch theV := v.(type) {
-	case float64:
-		s = strconv.FormatFloat(theV, 'f', 6, 64)
-	case string:
-		s = theV
+func (p *ASTPrinter) VisitConditionalExpr(expr *ast.ConditionalExpr) string {
+	return fmt.Sprintf("%s ? %s : %s", p.Print(expr.Condition()), p.Print(expr.ThenBranch()), p.Print(expr.ElseBranch()))
+}
+
+func (p *ASTPrinter) VisitCoalesceExpr(expr *ast.CoalesceExpr) string {
+	return p.Print(expr.Left()) + " ?? " + p.Print(expr.Right())
+}
+
+func (p *ASTPrinter) VisitGetExpr(expr *ast.GetExpr) string {
+	return p.Print(expr.Object()) + "." + *expr.Name().Lexeme
+}
+
+func (p *ASTPrinter) VisitOptionalGetExpr(expr *ast.OptionalGetExpr) string {
+	return p.Print(expr.Object()) + "?." + *expr.Name().Lexeme
+}
+
+func (p *ASTPrinter) VisitCallExpr(expr *ast.CallExpr) string {
+	arguments := make([]string, len(expr.Arguments()))
+	for i, argument := range expr.Arguments() {
+		arguments[i] = p.Print(argument)
+	}
+	return fmt.Sprintf("%s(%s)", p.Print(expr.Callee()), strings.Join(arguments, ", "))
+}
+
+func (p *ASTPrinter) VisitIndexExpr(expr *ast.IndexExpr) string {
+	return p.Print(expr.Object()) + "[" + p.Print(expr.Index()) + "]"
+}
+
+func (p *ASTPrinter) VisitSetExpr(expr *ast.SetExpr) string {
+	return p.Print(expr.Object()) + "." + *expr.Name().Lexeme + " = " + p.Print(expr.Value())
+}
+
+func (p *ASTPrinter) VisitSetIndexExpr(expr *ast.SetIndexExpr) string {
+	return p.Print(expr.Object()) + "[" + p.Print(expr.Index()) + "] = " + p.Print(expr.Value())
+}
+
+func (p *ASTPrinter) VisitCompoundSetExpr(expr *ast.CompoundSetExpr) string {
+	return p.Print(expr.Object()) + "." + *expr.Name().Lexeme + " " + *expr.Operator().Lexeme + " " + p.Print(expr.Value())
+}
+
+func (p *ASTPrinter) VisitCompoundSetIndexExpr(expr *ast.CompoundSetIndexExpr) string {
+	return p.Print(expr.Object()) + "[" + p.Print(expr.Index()) + "] " + *expr.Operator().Lexeme + " " + p.Print(expr.Value())
+}
+
+func (p *ASTPrinter) VisitUpdatePropertyExpr(expr *ast.UpdatePropertyExpr) string {
+	return update(p.Print(expr.Object())+"."+*expr.Name().Lexeme, expr.Operator(), expr.Prefix())
+}
+
+func (p *ASTPrinter) VisitUpdateIndexExpr(expr *ast.UpdateIndexExpr) string {
+	return update(p.Print(expr.Object())+"["+p.Print(expr.Index())+"]", expr.Operator(), expr.Prefix())
+}
+
+func (p *ASTPrinter) VisitThisExpr(expr *ast.ThisExpr) string {
+	return *expr.Keyword().Lexeme
+}
+
+func (p *ASTPrinter) VisitListExpr(expr *ast.ListExpr) string {
+	elements := make([]string, len(expr.Elements()))
+	for i, element := range expr.Elements() {
+		elements[i] = p.Print(element)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (p *ASTPrinter) VisitMapExpr(expr *ast.MapExpr) string {
+	if len(expr.Keys()) == 0 {
+		return "{}"
 	}
-	return fmt.Sprintf("%v", s)
+	entries := make([]string, len(expr.Keys()))
+	for i, key := range expr.Keys() {
+		entries[i] = p.Print(key) + ": " + p.Print(expr.Values()[i])
+	}
+	return "{ " + strings.Join(entries, ", ") + " }"
+}
+
+func (p *ASTPrinter) VisitMatchExpr(expr *ast.MatchExpr) string {
+	arms := make([]string, len(expr.Patterns()))
+	for i, pattern := range expr.Patterns() {
+		arms[i] = p.PrintPattern(pattern)
+		if guard := expr.Guards()[i]; guard != nil {
+			arms[i] += " chat is this real " + p.Print(guard)
+		}
+		arms[i] += " => " + p.Print(expr.Values()[i])
+	}
+	return fmt.Sprintf("%s (%s) { %s }", *expr.Keyword().Lexeme, p.Print(expr.Subject()), strings.Join(arms, ", "))
+}
+
+func (p *ASTPrinter) VisitSpawnExpr(expr *ast.SpawnExpr) string {
+	return *expr.Keyword().Lexeme + " " + p.Print(expr.Call())
+}
+
+// Patterns
+
+func (p *ASTPrinter) VisitLiteralPattern(pattern *ast.LiteralPattern) string {
+	return literal(pattern.Value())
 }
 
-func (p *ASTPrinter) VisitGroupingExpr(expr *ast.GroupingExpr) any {
-	return fmt.Sprintf("(%v)", expr.Expr().Accept(p).(string))
+func (p *ASTPrinter) VisitWildcardPattern(pattern *ast.WildcardPattern) string {
+	return "_"
+}
+
+func (p *ASTPrinter) VisitBindingPattern(pattern *ast.BindingPattern) string {
+	return *pattern.Name().Lexeme
+}
+
+func (p *ASTPrinter) VisitObjectPattern(pattern *ast.ObjectPattern) string {
+	return p.properties(pattern.Names(), pattern.Patterns())
+}
+
+func (p *ASTPrinter) VisitClassPattern(pattern *ast.ClassPattern) string {
+	return *pattern.Name().Lexeme + " " + p.properties(pattern.Names(), pattern.Patterns())
+}
+
+// properties formats the "{name: pattern, ...}" of an object or class
+// pattern.
+func (p *ASTPrinter) properties(names []*ast.Token, patterns []ast.Pattern) string {
+	if len(names) == 0 {
+		return "{}"
+	}
+	properties := make([]string, len(names))
+	for i, name := range names {
+		properties[i] = *name.Lexeme + ": " + p.PrintPattern(patterns[i])
+	}
+	return "{ " + strings.Join(properties, ", ") + " }"
+}
+
+func (p *ASTPrinter) VisitListPattern(pattern *ast.ListPattern) string {
+	elements := make([]string, len(pattern.Patterns()), len(pattern.Patterns())+1)
+	for i, element := range pattern.Patterns() {
+		elements[i] = p.PrintPattern(element)
+	}
+	if pattern.Rest() != nil {
+		elements = append(elements, "..."+*pattern.Rest().Lexeme)
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// prefix writes operator before operand, separated by a space where they
+// would otherwise scan as one token, as in "- -1" or "deadass x".
+func prefix(operator, operand string) string {
+	if isWord(operator) || operand != "" && operand[0] == operator[len(operator)-1] {
+		return operator + " " + operand
+	}
+	return operator + operand
 }
 
-func (p *ASTPrinter) VisitUnaryExpr(expr *ast.UnaryExpr) any {
-	return fmt.Sprintf("%s%v", *expr.Operator().Lexeme, expr.Operator().Lexeme)
+// isWord reports whether the lexeme of an operator is a keyword rather than
+// punctuation.
+func isWord(lexeme string) bool {
+	r, _ := utf8.DecodeRuneInString(lexeme)
+	return r == '_' || unicode.IsLetter(r)
 }
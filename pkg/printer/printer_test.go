@@ -10,12 +10,32 @@ import (
 func TestPrint_BinaryExpr(t *testing.T) {
 	expr := ast.NewBinaryExpr(ast.NewLiteralExpr(1), ast.NewToken(ast.TokenPlus, types.StrPtr("+"), nil, 0, 0), ast.NewLiteralExpr(2))
 	astPrinter := NewASTPrinter()
-	result := astPrinter.VisitBinaryExpr(expr).(string)
+	result := astPrinter.VisitBinaryExpr(expr)
 	if result != "1 + 2" {
 		t.Errorf("got %s, want %s", result, "1 + 2")
 	}
 }
 
+func TestPrintTree(t *testing.T) {
+	plus := ast.NewToken(ast.TokenPlus, types.StrPtr("+"), nil, 0, 0)
+	star := ast.NewToken(ast.TokenStar, types.StrPtr("*"), nil, 0, 0)
+	expr := ast.NewBinaryExpr(ast.NewLiteralExpr(1.0), plus, ast.NewBinaryExpr(ast.NewLiteralExpr(2.0), star, ast.NewLiteralExpr("x")))
+	want := `BinaryExpr
+  left: LiteralExpr
+    value: 1
+  operator: +
+  right: BinaryExpr
+    left: LiteralExpr
+      value: 2
+    operator: *
+    right: LiteralExpr
+      value: "x"
+`
+	if got := PrintTree(expr); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
 // func TestPrint_GroupingExpr(t *testing.T) {
 // 	expr := GroupingExpr{
 // 		expr: NewBinaryExpr(
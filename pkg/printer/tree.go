@@ -0,0 +1,86 @@
+package printer
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+// PrintTree formats the syntax tree rooted at node as an indented outline:
+// each node is named by its type and followed by its fields, one per line,
+// with the nodes a field holds nested under it.
+func PrintTree(node ast.Node) string {
+	var b strings.Builder
+	writeNode(&b, node, 0)
+	return b.String()
+}
+
+func writeNode(b *strings.Builder, node ast.Node, depth int) {
+	b.WriteString(strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.") + "\n")
+	for _, field := range ast.Fields(node) {
+		switch node.(type) {
+		case *ast.LiteralExpr, *ast.LiteralPattern:
+			if field.Name == "value" {
+				b.WriteString(strings.Repeat("  ", depth+1) + "value: " + literal(field.Value) + "\n")
+				continue
+			}
+		}
+		writeField(b, field.Name, field.Value, depth+1)
+	}
+}
+
+// writeField writes the field called name holding v, nested depth levels
+// deep. The elements of a list of nodes are fields named by their index.
+func writeField(b *strings.Builder, name string, v any, depth int) {
+	b.WriteString(strings.Repeat("  ", depth) + name + ":")
+	if node, ok := v.(ast.Node); ok && !isNil(node) {
+		b.WriteString(" ")
+		writeNode(b, node, depth)
+		return
+	}
+
+	list := reflect.ValueOf(v)
+	if list.Kind() != reflect.Slice || list.Type().Elem() == reflect.TypeOf((*ast.Token)(nil)) {
+		b.WriteString(" " + leaf(v) + "\n")
+		return
+	}
+	if list.Len() == 0 {
+		b.WriteString(" []\n")
+		return
+	}
+	b.WriteString("\n")
+	for i := 0; i < list.Len(); i++ {
+		writeField(b, strconv.Itoa(i), list.Index(i).Interface(), depth+1)
+	}
+}
+
+// leaf formats a field holding no nodes: a token, shown by its lexeme, a
+// list of tokens or a flag.
+func leaf(v any) string {
+	switch v := v.(type) {
+	case *ast.Token:
+		if v == nil {
+			return "nil"
+		}
+		return *v.Lexeme
+	case []*ast.Token:
+		lexemes := make([]string, len(v))
+		for i, token := range v {
+			lexemes[i] = leaf(token)
+		}
+		return "[" + strings.Join(lexemes, ", ") + "]"
+	case nil, ast.Node:
+		// a missing child, such as the guard of an arm without one
+		return "nil"
+	}
+	return fmt.Sprint(v)
+}
+
+// isNil reports whether node is a nil pointer held by the interface.
+func isNil(node ast.Node) bool {
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
@@ -0,0 +1,254 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Field numbers of the messages of the pprof profile format, see
+// https://github.com/google/pprof/blob/main/proto/profile.proto.
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileMapping           = 3
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileTimeNanos         = 9
+	profileDurationNanos     = 10
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	mappingID             = 1
+	mappingFilename       = 5
+	mappingHasFunctions   = 7
+	mappingHasFilenames   = 8
+	mappingHasLineNumbers = 9
+
+	locationID        = 1
+	locationMappingID = 2
+	locationLine      = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+)
+
+// sampleTypes are the values of each sample, in order.
+var sampleTypes = [...]struct{ name, unit string }{
+	{"steps", "count"},
+	{"allocations", "count"},
+	{"time", "nanoseconds"},
+}
+
+// WriteProfile writes what p recorded as a gzipped pprof profile. Each line
+// of each call in the call tree is a sample of its steps, allocations and
+// time, showing time unless another sample type is picked.
+func (p *Profiler) WriteProfile(w io.Writer) error {
+	b := &profileBuilder{
+		strings:   map[string]int{"": 0},
+		table:     []string{""},
+		functions: make(map[site]uint64),
+		locations: make(map[site]uint64),
+	}
+	for _, t := range sampleTypes {
+		b.message(profileSampleType, func(e *encoder) {
+			e.int(valueTypeType, int64(b.string(t.name)))
+			e.int(valueTypeUnit, int64(b.string(t.unit)))
+		})
+	}
+	// the one mapping tells pprof the locations are symbolized already
+	b.message(profileMapping, func(e *encoder) {
+		e.uint(mappingID, 1)
+		e.int(mappingFilename, int64(b.string("rottenlang")))
+		e.uint(mappingHasFunctions, 1)
+		e.uint(mappingHasFilenames, 1)
+		e.uint(mappingHasLineNumbers, 1)
+	})
+	b.samples(p.root)
+	b.int(profileTimeNanos, p.start.UnixNano())
+	b.int(profileDurationNanos, p.last.Sub(p.start).Nanoseconds())
+	b.int(profileDefaultSampleType, int64(b.string("time")))
+	// the string table goes last, once every string is in
+	for _, s := range b.table {
+		b.bytes(profileStringTable, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(b.buf); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// profileBuilder encodes a profile, numbering its strings, functions and
+// locations as they come.
+type profileBuilder struct {
+	encoder
+	strings   map[string]int
+	table     []string
+	functions map[site]uint64
+	// locations are keyed by function, file and line
+	locations map[site]uint64
+}
+
+func (b *profileBuilder) string(s string) int {
+	if i, ok := b.strings[s]; ok {
+		return i
+	}
+	b.strings[s] = len(b.table)
+	b.table = append(b.table, s)
+	return len(b.table) - 1
+}
+
+// samples encodes the samples of the calls under n, in a stable order.
+func (b *profileBuilder) samples(n *node) {
+	if n.parent != nil {
+		lines := make([]int, 0, len(n.lines))
+		for line := range n.lines {
+			lines = append(lines, line)
+		}
+		sort.Ints(lines)
+		for _, line := range lines {
+			c := n.lines[line]
+			if *c == (counts{}) {
+				continue
+			}
+			stack := []uint64{b.location(n, line)}
+			for caller := n; caller.parent.parent != nil; caller = caller.parent {
+				stack = append(stack, b.location(caller.parent, caller.line))
+			}
+			b.message(profileSample, func(e *encoder) {
+				e.packed(sampleLocationID, stack)
+				e.packed(sampleValue, []uint64{uint64(c.steps), uint64(c.allocations), uint64(c.nanoseconds)})
+			})
+		}
+	}
+
+	children := make([]site, 0, len(n.children))
+	for key := range n.children {
+		children = append(children, key)
+	}
+	sort.Slice(children, func(a, c int) bool {
+		x, y := children[a], children[c]
+		if x.line != y.line {
+			return x.line < y.line
+		}
+		if x.function != y.function {
+			return x.function < y.function
+		}
+		return x.file < y.file
+	})
+	for _, key := range children {
+		b.samples(n.children[key])
+	}
+}
+
+// location returns the id of the location of line of the function of n.
+func (b *profileBuilder) location(n *node, line int) uint64 {
+	key := site{function: n.function, file: n.file, line: line}
+	if id, ok := b.locations[key]; ok {
+		return id
+	}
+	function := b.function(n)
+	id := uint64(len(b.locations) + 1)
+	b.locations[key] = id
+	b.message(profileLocation, func(e *encoder) {
+		e.uint(locationID, id)
+		e.uint(locationMappingID, 1)
+		e.message(locationLine, func(e *encoder) {
+			e.uint(lineFunctionID, function)
+			e.int(lineLine, int64(line))
+		})
+	})
+	return id
+}
+
+// function returns the id of the function of n.
+func (b *profileBuilder) function(n *node) uint64 {
+	key := site{function: n.function, file: n.file}
+	if id, ok := b.functions[key]; ok {
+		return id
+	}
+	id := uint64(len(b.functions) + 1)
+	b.functions[key] = id
+	// pprof drops what looks like C++ template arguments from names, which
+	// is the whole of <script>
+	name := int64(b.string(strings.Trim(n.function, "<>")))
+	systemName, file := int64(b.string(n.function)), int64(b.string(n.file))
+	b.message(profileFunction, func(e *encoder) {
+		e.uint(functionID, id)
+		e.int(functionName, name)
+		e.int(functionSystemName, systemName)
+		e.int(functionFilename, file)
+	})
+	return id
+}
+
+// encoder writes the protocol buffer encoding of a message, leaving out
+// fields holding zero as proto3 does.
+type encoder struct {
+	buf []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (e *encoder) varint(x uint64) {
+	for x >= 0x80 {
+		e.buf = append(e.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	e.buf = append(e.buf, byte(x))
+}
+
+func (e *encoder) key(field, wire int) {
+	e.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (e *encoder) uint(field int, x uint64) {
+	if x == 0 {
+		return
+	}
+	e.key(field, wireVarint)
+	e.varint(x)
+}
+
+func (e *encoder) int(field int, x int64) {
+	e.uint(field, uint64(x))
+}
+
+// bytes writes a string or bytes field, even an empty one: the string
+// table starts with the empty string.
+func (e *encoder) bytes(field int, b []byte) {
+	e.key(field, wireBytes)
+	e.varint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *encoder) packed(field int, xs []uint64) {
+	var sub encoder
+	for _, x := range xs {
+		sub.varint(x)
+	}
+	e.bytes(field, sub.buf)
+}
+
+func (e *encoder) message(field int, encode func(e *encoder)) {
+	var sub encoder
+	encode(&sub)
+	e.bytes(field, sub.buf)
+}
@@ -0,0 +1,224 @@
+// Package profiler records where programs spend their steps, allocations
+// and time, by rottenlang function and source line. The engine running a
+// program reports its calls and steps to a Stack; the Profiler gathers what
+// the stacks of the program and of its tasks record, and writes it as a
+// pprof profile or as a summary.
+package profiler
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Profiler records one program at a time. The program and its tasks take
+// turns running, so its stacks are never used at the same time.
+type Profiler struct {
+	// root is the parent of the outermost calls
+	root *node
+	// calls counts the calls of each function, builtins included
+	calls              map[string]int64
+	steps, allocations int64
+	// start is when the profiler was created, and last when the latest
+	// time was charged
+	start, last time.Time
+	now         func() time.Time
+}
+
+// node is a function called from a given line of a given call in progress,
+// the way a call tree shows it.
+type node struct {
+	function, file string
+	// line is the line of the parent calling the function
+	line     int
+	parent   *node
+	children map[site]*node
+	// lines counts the work done on each line of the function
+	lines map[int]*counts
+}
+
+type site struct {
+	function, file string
+	line           int
+}
+
+type counts struct {
+	steps, allocations, nanoseconds int64
+}
+
+func newNode(parent *node, function, file string, line int) *node {
+	return &node{
+		function: function,
+		file:     file,
+		line:     line,
+		parent:   parent,
+		children: make(map[site]*node),
+		lines:    make(map[int]*counts),
+	}
+}
+
+func NewProfiler() *Profiler {
+	p := &Profiler{
+		root:  newNode(nil, "", "", 0),
+		calls: make(map[string]int64),
+		now:   time.Now,
+	}
+	p.start = p.now()
+	p.last = p.start
+	return p
+}
+
+// NewStack returns a stack following the calls of an engine running the
+// program, or one of its tasks or generators.
+func (p *Profiler) NewStack() *Stack {
+	return &Stack{p: p, path: []frame{{node: p.root}}}
+}
+
+// Stack follows the calls in progress on one engine, charging the work it
+// reports to the line running in the innermost one.
+//
+// Engines report the number of calls in progress along with each call and
+// step: the calls beyond it have returned, however they ended.
+type Stack struct {
+	p *Profiler
+	// path holds the root and then the calls in progress, innermost last
+	path []frame
+}
+
+type frame struct {
+	node *node
+	// line is the line running, 0 until the call takes its first step
+	line int
+}
+
+// Call records a call, made from the line running after depth calls, of
+// function, declared in file. An empty function continues the caller, for
+// code the engine compiles into a function of its own.
+func (s *Stack) Call(depth int, function, file string) {
+	s.tick()
+	s.truncate(depth)
+	caller := s.path[len(s.path)-1]
+	if function == "" {
+		s.path = append(s.path, caller)
+		return
+	}
+
+	s.p.calls[function]++
+	key := site{function: function, file: file, line: caller.line}
+	callee := caller.node.children[key]
+	if callee == nil {
+		callee = newNode(caller.node, function, file, caller.line)
+		caller.node.children[key] = callee
+	}
+	s.path = append(s.path, frame{node: callee})
+}
+
+// Builtin records a call of a builtin function, whose work is charged to
+// the line calling it.
+func (s *Stack) Builtin(function string) {
+	s.p.calls[function]++
+}
+
+// Step records a step taken on line, after depth calls. A line of 0 is the
+// line of the step before.
+func (s *Stack) Step(depth, line int) {
+	s.tick()
+	s.truncate(depth)
+	top := &s.path[len(s.path)-1]
+	if line > 0 {
+		top.line = line
+	}
+	s.counts().steps++
+	s.p.steps++
+}
+
+// Allocate records an allocation made on the line running.
+func (s *Stack) Allocate() {
+	s.counts().allocations++
+	s.p.allocations++
+}
+
+// Made records v, a value made by an operator or a builtin, as an
+// allocation unless it is nil, a boolean or a number.
+func (s *Stack) Made(v any) {
+	switch v.(type) {
+	case nil, bool, float64:
+	default:
+		s.Allocate()
+	}
+}
+
+// truncate drops the calls beyond depth, which have returned.
+func (s *Stack) truncate(depth int) {
+	if depth+1 < len(s.path) {
+		s.path = s.path[:depth+1]
+	}
+}
+
+// tick charges the time since the latest charge to the line running. A call
+// that hasn't taken a step yet leaves the time to its first line.
+func (s *Stack) tick() {
+	if s.path[len(s.path)-1].line == 0 {
+		return
+	}
+	now := s.p.now()
+	s.counts().nanoseconds += now.Sub(s.p.last).Nanoseconds()
+	s.p.last = now
+}
+
+func (s *Stack) counts() *counts {
+	top := s.path[len(s.path)-1]
+	c := top.node.lines[top.line]
+	if c == nil {
+		c = &counts{}
+		top.node.lines[top.line] = c
+	}
+	return c
+}
+
+// Steps returns the number of steps recorded.
+func (p *Profiler) Steps() int64 {
+	return p.steps
+}
+
+// Allocations returns the number of allocations recorded.
+func (p *Profiler) Allocations() int64 {
+	return p.allocations
+}
+
+// Calls returns the number of calls of each function.
+func (p *Profiler) Calls() map[string]int64 {
+	calls := make(map[string]int64, len(p.calls))
+	for function, n := range p.calls {
+		calls[function] = n
+	}
+	return calls
+}
+
+// WriteStats writes the number of steps and allocations recorded, then the
+// number of calls of each function, most called first.
+func (p *Profiler) WriteStats(w io.Writer) error {
+	functions := make([]string, 0, len(p.calls))
+	width := len("allocations")
+	for function := range p.calls {
+		functions = append(functions, function)
+		width = max(width, len(function)+2)
+	}
+	sort.Slice(functions, func(a, b int) bool {
+		if p.calls[functions[a]] != p.calls[functions[b]] {
+			return p.calls[functions[a]] > p.calls[functions[b]]
+		}
+		return functions[a] < functions[b]
+	})
+
+	if _, err := fmt.Fprintf(w, "%-*s %d\n%-*s %d\ncalls\n", width, "steps", p.steps, width, "allocations", p.allocations); err != nil {
+		return err
+	}
+	for _, function := range functions {
+		if _, err := fmt.Fprintf(w, "  %-*s %d\n", width-2, function, p.calls[function]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
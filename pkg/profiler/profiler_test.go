@@ -0,0 +1,135 @@
+package profiler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// record profiles a made-up run, as an engine reports it: the script calls
+// f from lines 2 and 3, and f runs code compiled into a function of its own
+// on line 6. The clock moves on by a millisecond each time it is read.
+func record() *Profiler {
+	p := NewProfiler()
+	clock := p.start
+	p.now = func() time.Time {
+		clock = clock.Add(time.Millisecond)
+		return clock
+	}
+
+	s := p.NewStack()
+	s.Call(0, "<script>", "main.rot")
+	s.Step(1, 1)
+	s.Allocate()
+	s.Step(1, 2)
+	s.Call(1, "f", "main.rot")
+	s.Step(2, 5)
+	s.Made("text")
+	s.Made(1.0)
+	s.Builtin("print")
+	s.Call(2, "", "")
+	s.Step(3, 6)
+	// f returns
+	s.Step(1, 3)
+	s.Step(1, 0)
+	s.Call(1, "f", "main.rot")
+	s.Step(2, 5)
+	s.Step(1, 3)
+	return p
+}
+
+func TestStack(t *testing.T) {
+	p := record()
+	if p.Steps() != 8 || p.Allocations() != 2 {
+		t.Errorf("got %d steps and %d allocations, want 8 and 2", p.Steps(), p.Allocations())
+	}
+
+	script := p.root.children[site{function: "<script>", file: "main.rot"}]
+	f := script.children[site{function: "f", file: "main.rot", line: 2}]
+	if f == nil || script.children[site{function: "f", file: "main.rot", line: 3}] == nil {
+		t.Fatalf("got calls %v, want f called from lines 2 and 3", script.children)
+	}
+	// line 5 runs from its step until the inline code's step on line 6,
+	// reading the clock on the inline call too; line 6 runs until the
+	// script's step on line 3
+	want := map[int]counts{
+		5: {steps: 1, allocations: 1, nanoseconds: int64(2 * time.Millisecond)},
+		6: {steps: 1, nanoseconds: int64(time.Millisecond)},
+	}
+	if len(f.lines) != len(want) {
+		t.Errorf("got lines %v of f", f.lines)
+	}
+	for line, c := range want {
+		if got := f.lines[line]; got == nil || *got != c {
+			t.Errorf("got %+v on line %d of f, want %+v", got, line, c)
+		}
+	}
+
+	var stats strings.Builder
+	if err := p.WriteStats(&stats); err != nil {
+		t.Fatal(err)
+	}
+	wantStats := `steps       8
+allocations 2
+calls
+  f         2
+  <script>  1
+  print     1
+`
+	if stats.String() != wantStats {
+		t.Errorf("got stats\n%s\nwant\n%s", stats.String(), wantStats)
+	}
+}
+
+// TestWriteProfile reads a profile back with go tool pprof.
+func TestWriteProfile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go tool pprof")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+
+	path := filepath.Join(t.TempDir(), "out.pb.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := record().WriteProfile(f); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for sampleIndex, want := range map[string]map[string][2]int{
+		// function: flat, cumulative
+		"steps":       {"f": {3, 3}, "script": {5, 8}},
+		"allocations": {"f": {1, 1}, "script": {1, 2}},
+	} {
+		out, err := exec.Command(gobin, "tool", "pprof", "-top", "-sample_index="+sampleIndex, path).CombinedOutput()
+		if err != nil {
+			t.Fatalf("go tool pprof: %v\n%s", err, out)
+		}
+		got := make(map[string][2]int)
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 6 {
+				continue
+			}
+			flat, err1 := strconv.Atoi(fields[0])
+			cum, err2 := strconv.Atoi(fields[3])
+			if err1 == nil && err2 == nil {
+				got[fields[5]] = [2]int{flat, cum}
+			}
+		}
+		for function, counts := range want {
+			if got[function] != counts {
+				t.Errorf("got %s %v of %s, want %v\n%s", sampleIndex, got[function], function, counts, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+)
+
+// History records the inputs entered in the REPL, persisted to a file so
+// that they survive between sessions. Multi-line inputs are stored as one
+// line with their newlines escaped.
+type History struct {
+	path    string
+	entries []string
+}
+
+// LoadHistory reads the history kept at path. A missing file is not an
+// error; it is created when the first entry is added.
+func LoadHistory(path string) (*History, error) {
+	history := &History{
+		path:    path,
+		entries: make([]string, 0),
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return history, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	lines := bufio.NewScanner(f)
+	for lines.Scan() {
+		history.entries = append(history.entries, unescapeEntry(lines.Text()))
+	}
+	return history, lines.Err()
+}
+
+// Add appends entry to the history and its file.
+func (h *History) Add(entry string) error {
+	h.entries = append(h.entries, entry)
+	if h.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString(escapeEntry(entry) + "\n")
+	return err
+}
+
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+var (
+	entryEscaper   = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	entryUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+)
+
+func escapeEntry(entry string) string {
+	return entryEscaper.Replace(entry)
+}
+
+func unescapeEntry(line string) string {
+	return entryUnescaper.Replace(line)
+}
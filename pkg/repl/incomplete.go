@@ -0,0 +1,81 @@
+package repl
+
+import (
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/parser"
+	"github.com/bagaswh/rottenlang/pkg/scanner"
+)
+
+// IsIncomplete reports whether source stops in the middle of something, such
+// as an unclosed parenthesis, block, string or comment, so that the REPL
+// should read another line before running it.
+func IsIncomplete(source string) bool {
+	s := scanner.NewScanner(strings.NewReader(source), 0)
+	tokens, err := s.ScanTokens()
+	if err != nil {
+		for _, lineErrs := range s.ScannerErrors() {
+			for _, lineErr := range lineErrs {
+				switch lineErr.Class() {
+				case scanner.ErrClassUnterminatedString, scanner.ErrClassUnterminatedComment:
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	depth := 0
+	for _, token := range tokens {
+		switch token.Type {
+		case ast.TokenLeftParen, ast.TokenLeftBrace, ast.TokenLeftBracket:
+			depth++
+		case ast.TokenRightParen, ast.TokenRightBrace, ast.TokenRightBracket:
+			depth--
+		}
+	}
+	if depth > 0 {
+		return true
+	}
+
+	if isExpression(tokens) {
+		return false
+	}
+
+	// a statement cut short, like "vibes a =", only fails at the end of input
+	p := parser.NewParser(&errorreporter.NopErrorReporter{})
+	p.SetTokens(tokens)
+	p.Parse()
+	if !p.HadError() {
+		return false
+	}
+	for _, parseErr := range p.Errors() {
+		if parseErr.Token().Type != ast.TokenEOF {
+			return false
+		}
+	}
+	return true
+}
+
+// IsExpression reports whether source is a single expression without the
+// trailing semicolon a statement needs. The REPL accepts those and shows
+// their value.
+func IsExpression(source string) bool {
+	s := scanner.NewScanner(strings.NewReader(source), 0)
+	tokens, err := s.ScanTokens()
+	if err != nil {
+		return false
+	}
+	return isExpression(tokens)
+}
+
+func isExpression(tokens []*ast.Token) bool {
+	p := parser.NewParser(&errorreporter.NopErrorReporter{})
+	p.SetTokens(tokens)
+	if p.ParseExpression() == nil {
+		return false
+	}
+	return !p.HadError()
+}
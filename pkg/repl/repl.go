@@ -0,0 +1,179 @@
+// Package repl implements the interactive rottenlang prompt.
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/parser"
+	"github.com/bagaswh/rottenlang/pkg/printer"
+	"github.com/bagaswh/rottenlang/pkg/rottenlang"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+const (
+	prompt             = "rot> "
+	continuationPrompt = "...> "
+)
+
+const help = `Enter statements or expressions; unfinished input continues on the next line,
+and an empty line runs it as is.
+
+Commands:
+  :tokens <code>  show the tokens code scans to
+  :ast <code>     show the syntax tree code parses to
+  :reset          forget every declaration made so far
+  :history        list previous inputs
+  :help           show this help
+  :quit           leave the REPL
+`
+
+type REPL struct {
+	in            io.Reader
+	out           io.Writer
+	errorReporter errorreporter.ErrorReporter
+	history       *History
+	session       *rottenlang.Rottenlang
+}
+
+func NewREPL(in io.Reader, out io.Writer, errorReporter errorreporter.ErrorReporter, engine rottenlang.Engine, history *History) *REPL {
+	session := rottenlang.NewRottenlang("", errorReporter)
+	session.Out = out
+	session.Engine = engine
+	return &REPL{
+		in:            in,
+		out:           out,
+		errorReporter: errorReporter,
+		history:       history,
+		session:       session,
+	}
+}
+
+// Run reads and executes input until end of input or ":quit".
+func (r *REPL) Run() error {
+	lines := bufio.NewScanner(r.in)
+	var buffer strings.Builder
+	currentPrompt := prompt
+
+	for {
+		fmt.Fprint(r.out, currentPrompt)
+		if !lines.Scan() {
+			fmt.Fprintln(r.out)
+			return lines.Err()
+		}
+		line := lines.Text()
+
+		if buffer.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			r.addHistory(line)
+			if quit := r.command(strings.TrimSpace(line)); quit {
+				return nil
+			}
+			continue
+		}
+
+		buffer.WriteString(line)
+		buffer.WriteString("\n")
+		source := buffer.String()
+		if strings.TrimSpace(line) != "" && IsIncomplete(source) {
+			currentPrompt = continuationPrompt
+			continue
+		}
+
+		buffer.Reset()
+		currentPrompt = prompt
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+		r.addHistory(strings.TrimRight(source, "\n"))
+		r.eval(source)
+	}
+}
+
+func (r *REPL) addHistory(entry string) {
+	if r.history == nil {
+		return
+	}
+	if err := r.history.Add(entry); err != nil {
+		fmt.Fprintf(r.out, "warning: failed saving history: %v\n", err)
+	}
+}
+
+func (r *REPL) eval(source string) {
+	if IsExpression(source) {
+		source = strings.TrimRight(source, " \t\r\n") + ";"
+	}
+	result, err := r.session.Run(source)
+	if err == nil && result != nil {
+		fmt.Fprintln(r.out, value.Stringify(result))
+	}
+}
+
+// command runs a meta-command and reports whether the REPL should stop.
+func (r *REPL) command(line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ":tokens":
+		r.tokens(arg)
+	case ":ast":
+		r.ast(arg)
+	case ":reset":
+		r.session.Reset()
+		fmt.Fprintln(r.out, "session reset")
+	case ":history":
+		if r.history != nil {
+			for i, entry := range r.history.Entries() {
+				fmt.Fprintf(r.out, "%4d  %s\n", i+1, entry)
+			}
+		}
+	case ":help":
+		fmt.Fprint(r.out, help)
+	case ":quit", ":q":
+		return true
+	default:
+		fmt.Fprintf(r.out, "unknown command %s, try :help\n", name)
+	}
+	return false
+}
+
+func (r *REPL) tokens(source string) {
+	tokens, err := rottenlang.NewRottenlang(source, r.errorReporter).Scan()
+	if err != nil {
+		return
+	}
+	for _, token := range tokens {
+		fmt.Fprintf(r.out, "%-16s %-20q line=%d col=%d\n", token.Name(), *token.Lexeme, token.Line, token.Column)
+	}
+}
+
+func (r *REPL) ast(source string) {
+	tokens, err := rottenlang.NewRottenlang(source, r.errorReporter).Scan()
+	if err != nil {
+		return
+	}
+
+	if isExpression(tokens) {
+		p := parser.NewParser(r.errorReporter)
+		p.SetTokens(tokens)
+		expr := p.ParseExpression()
+		if p.HadError() {
+			return
+		}
+		fmt.Fprint(r.out, printer.PrintTree(expr))
+		return
+	}
+
+	p := parser.NewParser(r.errorReporter)
+	p.SetTokens(tokens)
+	statements := p.Parse()
+	if p.HadError() {
+		return
+	}
+	for _, stmt := range statements {
+		fmt.Fprint(r.out, printer.PrintTree(stmt))
+	}
+}
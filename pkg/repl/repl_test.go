@@ -0,0 +1,32 @@
+package repl
+
+import "testing"
+
+func TestIsIncomplete(t *testing.T) {
+	tests := []struct {
+		source string
+		want   bool
+	}{
+		{"1 + 2", false},
+		{"1 + 2;", false},
+		{"print(1,", true},
+		{"func f() {", true},
+		{"func f() iykyk", true},
+		{"func f() iykyk purrr 1; periodt", false},
+		{"\"unterminated", true},
+		{"print(1); /* unterminated", true},
+		{"print(1); /* done */", false},
+		{"vibes a =", true},
+		{"vibes a = 1", true},
+		{"vibes a = 1;", false},
+		{"1 +", true},
+		{") oops", false},
+		{"vibes = 1;", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsIncomplete(tt.source); got != tt.want {
+			t.Errorf("IsIncomplete(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
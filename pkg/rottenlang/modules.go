@@ -0,0 +1,216 @@
+package rottenlang
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/compiler"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/interpreter"
+	"github.com/bagaswh/rottenlang/pkg/value"
+	"github.com/bagaswh/rottenlang/pkg/vm"
+)
+
+// moduleError is an error in an imported module, located in its file.
+type moduleError struct {
+	Path         string
+	Line, Column int
+	Message      string
+	Err          error
+}
+
+func (err *moduleError) Error() string {
+	if err.Line == 0 {
+		return fmt.Sprintf("%s: %s", err.Path, err.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", err.Path, err.Line, err.Column, err.Message)
+}
+
+func (err *moduleError) Unwrap() error {
+	return err.Err
+}
+
+// loader imports the modules of a program. Every file is loaded once; later
+// imports of it share the module.
+type loader struct {
+	session *Rottenlang
+	// execute runs the statements of a module with the engine of the
+	// program, returning a function looking up the module's globals
+	execute func(path string, statements []ast.Stmt, reporter errorreporter.ErrorReporter) (func(name string) (any, bool), error)
+	// modules are the modules loaded so far, by absolute path
+	modules map[string]*value.Module
+	// importing is the chain of modules being imported
+	importing []string
+}
+
+func newLoader(session *Rottenlang, execute func(string, []ast.Stmt, errorreporter.ErrorReporter) (func(string) (any, bool), error)) *loader {
+	return &loader{
+		session: session,
+		execute: execute,
+		modules: make(map[string]*value.Module),
+	}
+}
+
+// chain returns the files being imported, starting with the program itself
+// when its path is known. The path is read on every import, as a session
+// may run programs from several files.
+func (l *loader) chain() []string {
+	if l.session.Path == "" {
+		return l.importing
+	}
+	path, err := filepath.Abs(l.session.Path)
+	if err != nil {
+		return l.importing
+	}
+	return append([]string{path}, l.importing...)
+}
+
+// Import loads the module at path, running it the first time it is
+// imported.
+func (l *loader) Import(path string) (*value.Module, error) {
+	resolved, err := l.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	if module, ok := l.modules[resolved]; ok {
+		return module, nil
+	}
+	importing := l.chain()
+	for i, p := range importing {
+		if p == resolved {
+			cycle := make([]string, 0, len(importing)-i+1)
+			for _, p := range importing[i:] {
+				cycle = append(cycle, displayPath(p))
+			}
+			cycle = append(cycle, displayPath(resolved))
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	source, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, err
+	}
+	reporter := &errorreporter.CollectingErrorReporter{}
+	module := NewRottenlang(string(source), reporter)
+	module.KeywordPack = l.session.KeywordPack
+	module.Optimize = l.session.Optimize
+	statements, err := module.Parse()
+	if err != nil {
+		return nil, l.moduleError(resolved, reporter, err)
+	}
+
+	l.importing = append(l.importing, resolved)
+	lookup, err := l.execute(resolved, statements, reporter)
+	l.importing = l.importing[:len(l.importing)-1]
+	if err != nil {
+		// an error in a module imported by this one is already located
+		var inner *moduleError
+		if errors.As(err, &inner) {
+			return nil, inner
+		}
+		return nil, l.moduleError(resolved, reporter, err)
+	}
+
+	var exports []string
+	for _, stmt := range statements {
+		if name := declaredName(stmt); name != "" && value.IsExported(name) {
+			exports = append(exports, name)
+		}
+	}
+	l.modules[resolved] = value.NewModule(strings.TrimSuffix(filepath.Base(resolved), filepath.Ext(resolved)), exports, lookup)
+	return l.modules[resolved], nil
+}
+
+// resolve finds the file of an imported module: relative paths are looked
+// up next to the importing file, or in the working directory when it isn't
+// known, then in every directory of the search path.
+func (l *loader) resolve(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+
+	var dirs []string
+	if importing := l.chain(); len(importing) > 0 {
+		dirs = append(dirs, filepath.Dir(importing[len(importing)-1]))
+	} else {
+		dirs = append(dirs, ".")
+	}
+	dirs = append(dirs, l.session.SearchPath...)
+	for _, dir := range dirs {
+		candidate, err := filepath.Abs(filepath.Join(dir, path))
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("can't find module '%s'", path)
+}
+
+// moduleError locates err, which stopped the module at path, using the
+// first diagnostic reported for it.
+func (l *loader) moduleError(path string, reporter *errorreporter.CollectingErrorReporter, err error) error {
+	located := &moduleError{Path: displayPath(path), Message: err.Error(), Err: err}
+	var runtimeErr *value.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		located.Line, located.Column, located.Message = runtimeErr.Line, runtimeErr.Column, runtimeErr.Message
+	} else if diagnostics := reporter.Diagnostics(); len(diagnostics) > 0 {
+		first := diagnostics[0]
+		located.Line, located.Column = first.Line, first.Column
+		located.Message = fmt.Sprintf("%s error: %s", first.Kind, first.Message)
+	}
+	return located
+}
+
+// declaredName returns the name a top-level statement declares, or "".
+func declaredName(stmt ast.Stmt) string {
+	switch stmt := stmt.(type) {
+	case *ast.VarStmt:
+		return *stmt.Name().Lexeme
+	case *ast.FunctionStmt:
+		return *stmt.Name().Lexeme
+	case *ast.ImportStmt:
+		return *stmt.Name().Lexeme
+	}
+	return ""
+}
+
+// displayPath shortens path to be relative to the working directory when
+// it is below it.
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// treeLoader returns a loader running modules on the interpreter running the
+// program.
+func (d *Rottenlang) treeLoader(i *interpreter.Interpreter) *loader {
+	return newLoader(d, func(path string, statements []ast.Stmt, _ errorreporter.ErrorReporter) (func(string) (any, bool), error) {
+		return i.ExecuteModule(path, statements)
+	})
+}
+
+// vmLoader returns a loader compiling modules and running them on the vm
+// running the program.
+func (d *Rottenlang) vmLoader(machine *vm.VM) *loader {
+	return newLoader(d, func(path string, statements []ast.Stmt, reporter errorreporter.ErrorReporter) (func(string) (any, bool), error) {
+		function, err := compiler.NewCompiler(reporter).Compile(statements)
+		if err != nil {
+			return nil, err
+		}
+		return machine.ExecuteModule(path, function)
+	})
+}
@@ -1,19 +1,100 @@
 package rottenlang
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/compiler"
+	"github.com/bagaswh/rottenlang/pkg/debugger"
 	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/gogen"
+	"github.com/bagaswh/rottenlang/pkg/interpreter"
+	"github.com/bagaswh/rottenlang/pkg/jsgen"
+	"github.com/bagaswh/rottenlang/pkg/optimizer"
 	"github.com/bagaswh/rottenlang/pkg/parser"
 	"github.com/bagaswh/rottenlang/pkg/printer"
+	"github.com/bagaswh/rottenlang/pkg/profiler"
 	"github.com/bagaswh/rottenlang/pkg/scanner"
+	"github.com/bagaswh/rottenlang/pkg/value"
+	"github.com/bagaswh/rottenlang/pkg/vm"
 )
 
+// Engine selects how programs are executed.
+type Engine string
+
+const (
+	// EngineTree walks the syntax tree directly.
+	EngineTree Engine = "tree"
+	// EngineVM compiles to bytecode and runs it on the stack machine.
+	EngineVM Engine = "vm"
+)
+
+func ParseEngine(name string) (Engine, error) {
+	switch Engine(name) {
+	case EngineTree, EngineVM:
+		return Engine(name), nil
+	}
+	return "", fmt.Errorf("unknown engine '%s', expected '%s' or '%s'", name, EngineTree, EngineVM)
+}
+
+// Target selects the language Build translates programs into.
+type Target string
+
+const (
+	// TargetGo translates programs into Go packages.
+	TargetGo Target = "go"
+	// TargetJS translates programs into JavaScript modules with source
+	// maps.
+	TargetJS Target = "js"
+)
+
+func ParseTarget(name string) (Target, error) {
+	switch Target(name) {
+	case TargetGo, TargetJS:
+		return Target(name), nil
+	}
+	return "", fmt.Errorf("unknown target '%s', expected '%s' or '%s'", name, TargetGo, TargetJS)
+}
+
 type Rottenlang struct {
 	Scanner       *scanner.Scanner
 	Parser        *parser.Parser
 	ErrorReporter errorreporter.ErrorReporter
+	// Out receives the output of print
+	Out io.Writer
+	// Engine runs the lines given to Run
+	Engine Engine
+	// Limits bounds the resources programs may use
+	Limits value.Limits
+	// KeywordPack, when set, extends the keywords and operators of the
+	// language
+	KeywordPack *parser.KeywordPack
+	// Path is the file the source was read from, if any. Modules it
+	// imports are looked up relative to it.
+	Path string
+	// SearchPath lists more directories to look up imported modules in
+	SearchPath []string
+	// Optimize runs the passes of the optimizer over programs before they
+	// run
+	Optimize bool
+	// Debugger, when set, stops the programs run by Execute at its
+	// breakpoints and steps through them. Only the tree engine can be
+	// debugged.
+	Debugger *debugger.Debugger
+	// Profiler, when set, records where the programs run by Execute spend
+	// their steps, allocations and time
+	Profiler *profiler.Profiler
+
+	// session state kept between calls to Run
+	interpreter *interpreter.Interpreter
+	vm          *vm.VM
 }
 
 func NewRottenlang(source string, errorReporter errorreporter.ErrorReporter) *Rottenlang {
@@ -24,45 +105,278 @@ func NewRottenlang(source string, errorReporter errorreporter.ErrorReporter) *Ro
 		Scanner:       scanner,
 		Parser:        parser,
 		ErrorReporter: errorReporter,
+		Out:           os.Stdout,
+		Engine:        EngineTree,
+	}
+}
+
+// Run executes line in the session kept by d, so declarations made by one
+// line are visible to the next. It returns the value of the line's final
+// expression statement, or nil when it doesn't end with one.
+func (d *Rottenlang) Run(line string) (any, error) {
+	return d.RunContext(context.Background(), line)
+}
+
+// RunContext is like Run, but stops execution with a runtime error once ctx
+// is done.
+func (d *Rottenlang) RunContext(ctx context.Context, line string) (any, error) {
+	d.Scanner = scanner.NewScanner(strings.NewReader(line), 0)
+	statements, err := d.Parse()
+	if err != nil {
+		return nil, err
 	}
+
+	d.prepareSession(ctx)
+	switch d.Engine {
+	case EngineVM:
+		function, err := compiler.NewCompiler(d.ErrorReporter).CompileInteractive(statements)
+		if err != nil {
+			return nil, err
+		}
+		return d.vm.Interpret(function)
+	default:
+		return d.interpreter.InterpretInteractive(statements)
+	}
+}
+
+// startSession creates the engine kept between calls to Run, if needed.
+func (d *Rottenlang) startSession() {
+	switch d.Engine {
+	case EngineVM:
+		if d.vm == nil {
+			d.vm = vm.NewVM(d.ErrorReporter, d.Out)
+			d.vm.SetImporter(d.vmLoader(d.vm))
+		}
+	default:
+		if d.interpreter == nil {
+			d.interpreter = interpreter.NewInterpreter(d.ErrorReporter, d.Out)
+			d.interpreter.SetImporter(d.treeLoader(d.interpreter))
+		}
+	}
+}
+
+// prepareSession starts the session, applying the limits and ctx to the
+// program about to run.
+func (d *Rottenlang) prepareSession(ctx context.Context) {
+	d.startSession()
+	if d.Engine == EngineVM {
+		d.vm.SetLimits(d.Limits)
+		d.vm.SetContext(ctx)
+	} else {
+		d.interpreter.SetLimits(d.Limits)
+		d.interpreter.SetContext(ctx)
+	}
+}
+
+// DefineGlobal binds name to v in the session's global scope.
+func (d *Rottenlang) DefineGlobal(name string, v any) {
+	d.startSession()
+	if d.Engine == EngineVM {
+		d.vm.DefineGlobal(name, v)
+	} else {
+		d.interpreter.DefineGlobal(name, v)
+	}
+}
+
+// Global returns the value of a global variable of the session.
+func (d *Rottenlang) Global(name string) (any, bool) {
+	d.startSession()
+	if d.Engine == EngineVM {
+		return d.vm.Global(name)
+	}
+	return d.interpreter.Global(name)
+}
+
+// CallContext calls a function value of the session with arguments,
+// stopping execution with a runtime error once ctx is done.
+func (d *Rottenlang) CallContext(ctx context.Context, callee any, args []any) (any, error) {
+	d.prepareSession(ctx)
+	if d.Engine == EngineVM {
+		return d.vm.Call(callee, args)
+	}
+	return d.interpreter.Call(callee, args)
+}
+
+// Reset forgets the session state built up by Run.
+func (d *Rottenlang) Reset() {
+	d.interpreter = nil
+	d.vm = nil
 }
 
-func (d *Rottenlang) Run(line string) {}
+// Scan tokenizes the source, reporting the first error of every line that
+// has any.
+func (d *Rottenlang) Scan() ([]*ast.Token, error) {
+	if d.KeywordPack != nil {
+		keywords, err := d.KeywordPack.ScanKeywords()
+		if err != nil {
+			return nil, err
+		}
+		d.Scanner.SetKeywords(keywords)
+	}
 
-func (d *Rottenlang) Scan() {
-	_, err := d.Scanner.ScanTokens()
+	tokens, err := d.Scanner.ScanTokens()
 	if err != nil {
 		if err == scanner.ErrScanner {
-			for _, lineErrs := range d.Scanner.ScannerErrors() {
-				firstErr := lineErrs[0]
-				fmt.Println(firstErr.Error())
+			lineErrs := d.Scanner.ScannerErrors()
+			lines := make([]int, 0, len(lineErrs))
+			for line := range lineErrs {
+				lines = append(lines, line)
+			}
+			sort.Ints(lines)
+			for _, line := range lines {
+				firstErr := lineErrs[line][0]
+				d.ErrorReporter.ReportScannerError(firstErr.Line(), firstErr.Column(), firstErr.Lexeme(), firstErr.Message())
 			}
 		}
+		return nil, err
 	}
+	return tokens, nil
+}
 
-	if d.Scanner.HadError() {
-		return
+// Parse scans and parses the source into a program, optimized when
+// Optimize is set.
+func (d *Rottenlang) Parse() ([]ast.Stmt, error) {
+	tokens, err := d.Scan()
+	if err != nil {
+		return nil, err
 	}
 
-	tokens := d.Scanner.Tokens()
-	currentLine := -1
-	start := -1
-	astPrinter := printer.NewASTPrinter()
-	for i, token := range tokens {
-		if start == -1 {
-			start = i
+	if d.KeywordPack != nil {
+		operators := parser.NewOperators()
+		if err := d.KeywordPack.Register(operators); err != nil {
+			return nil, err
 		}
-		if currentLine != token.Line {
-			if currentLine != -1 {
-				fmt.Println("---------------")
-				d.Parser.SetTokens(tokens[start:i])
-				expr := d.Parser.Parse()
-				fmt.Printf("%d-%d; line %d: %#s\n", start, i, token.Line, astPrinter.Print(expr))
-				start = -1
-			}
-			currentLine = token.Line
+		d.Parser.SetOperators(operators)
+	}
+	d.Parser.SetTokens(tokens)
+	statements := d.Parser.Parse()
+	if d.Parser.HadError() {
+		return nil, parser.ErrParser
+	}
+	if d.Optimize {
+		statements = optimizer.Optimize(statements)
+	}
+	return statements, nil
+}
+
+func (d *Rottenlang) compile() (*compiler.Function, error) {
+	statements, err := d.Parse()
+	if err != nil {
+		return nil, err
+	}
+	return compiler.NewCompiler(d.ErrorReporter).Compile(statements)
+}
+
+// Execute runs the program with the given engine.
+func (d *Rottenlang) Execute(engine Engine) error {
+	return d.ExecuteContext(context.Background(), engine)
+}
+
+// ExecuteContext is like Execute, but stops execution with a runtime error
+// once ctx is done.
+func (d *Rottenlang) ExecuteContext(ctx context.Context, engine Engine) error {
+	switch engine {
+	case EngineVM:
+		if d.Debugger != nil {
+			return errors.New("the vm engine can't be debugged, use the tree engine")
+		}
+		function, err := d.compile()
+		if err != nil {
+			return err
+		}
+		machine := vm.NewVM(d.ErrorReporter, d.Out)
+		machine.SetImporter(d.vmLoader(machine))
+		machine.SetLimits(d.Limits)
+		machine.SetContext(ctx)
+		path, err := d.absPath()
+		if err != nil {
+			return err
+		}
+		machine.SetFile(path)
+		machine.SetProfiler(d.Profiler)
+		_, err = machine.Interpret(function)
+		return err
+	default:
+		statements, err := d.Parse()
+		if err != nil {
+			return err
+		}
+		interpreter := interpreter.NewInterpreter(d.ErrorReporter, d.Out)
+		interpreter.SetImporter(d.treeLoader(interpreter))
+		interpreter.SetLimits(d.Limits)
+		interpreter.SetContext(ctx)
+		path, err := d.absPath()
+		if err != nil {
+			return err
 		}
-		// fmt.Printf("str='%s' tok=%s line=%d literal=%w\n", *token.Lexeme, token.Name(), token.Line, token.Literal)
+		interpreter.SetFile(path)
+		interpreter.SetDebugger(d.Debugger)
+		interpreter.SetProfiler(d.Profiler)
+		return interpreter.Interpret(statements)
 	}
+}
+
+// absPath returns the absolute path of the file the source was read from,
+// or "" when there is none.
+func (d *Rottenlang) absPath() (string, error) {
+	if d.Path == "" {
+		return "", nil
+	}
+	return filepath.Abs(d.Path)
+}
+
+// Dump parses the program and writes it to w formatted as source code,
+// showing the optimizer's work when Optimize is set.
+func (d *Rottenlang) Dump(w io.Writer) error {
+	statements, err := d.Parse()
+	if err != nil {
+		return err
+	}
+	if len(statements) > 0 {
+		fmt.Fprintln(w, printer.NewASTPrinter().PrintProgram(statements))
+	}
+	return nil
+}
+
+// BuildOptions configures the translation made by Build.
+type BuildOptions struct {
+	// Package is the name of the generated Go package
+	Package string
+	// Source names the source file in the //line directives and source
+	// maps of the translation, relative to where its files are written; it
+	// defaults to Path
+	Source string
+}
+
+// Build translates the program into target, returning the files of the
+// translation keyed by file name.
+func (d *Rottenlang) Build(target Target, options BuildOptions) (map[string][]byte, error) {
+	statements, err := d.Parse()
+	if err != nil {
+		return nil, err
+	}
+	source := options.Source
+	if source == "" {
+		source = d.Path
+	}
+	if source == "" {
+		source = "script.rot"
+	}
+	switch target {
+	case TargetGo:
+		return gogen.NewGenerator(d.ErrorReporter).Generate(statements, source, options.Package)
+	case TargetJS:
+		return jsgen.NewGenerator(d.ErrorReporter).Generate(statements, source)
+	}
+	return nil, fmt.Errorf("unknown target '%s'", target)
+}
 
+// Disassemble compiles the program and writes its bytecode listing to w.
+func (d *Rottenlang) Disassemble(w io.Writer) error {
+	function, err := d.compile()
+	if err != nil {
+		return err
+	}
+	compiler.Disassemble(w, function)
+	return nil
 }
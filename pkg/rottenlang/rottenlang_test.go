@@ -0,0 +1,74 @@
+package rottenlang
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/golden"
+	"github.com/bagaswh/rottenlang/pkg/profiler"
+)
+
+// execute runs source, read from path, with engine, optimized when
+// optimize is set and recorded by profile when it isn't nil, returning what
+// it printed and the warnings and errors it reported.
+func execute(path, source string, engine Engine, optimize bool, profile *profiler.Profiler) (out, errs string) {
+	var b strings.Builder
+	reporter := &errorreporter.CollectingErrorReporter{}
+	d := NewRottenlang(source, reporter)
+	d.Path = path
+	d.Out = &b
+	d.Optimize = optimize
+	d.Profiler = profile
+	d.Execute(engine)
+
+	var messages strings.Builder
+	for _, diagnostic := range append(reporter.Warnings(), reporter.Diagnostics()...) {
+		messages.WriteString(diagnostic.String() + "\n")
+	}
+	return b.String(), messages.String()
+}
+
+// TestGolden runs the programs in testdata, comparing what they print with
+// the .out files and their errors with the .err files. Both engines must
+// behave the same, making the same calls, and so must the programs once
+// optimized.
+func TestGolden(t *testing.T) {
+	for _, program := range golden.Programs(t, "testdata") {
+		t.Run(filepath.Base(program), func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			treeProfile, vmProfile := profiler.NewProfiler(), profiler.NewProfiler()
+			out, errs := execute(program, string(source), EngineTree, false, treeProfile)
+			golden.Check(t, program, ".out", out)
+			golden.Check(t, program, ".err", errs)
+
+			vmOut, vmErrs := execute(program, string(source), EngineVM, false, vmProfile)
+			if vmOut != out {
+				t.Errorf("vm output differs from tree interpreter\ngot:  %q\nwant: %q", vmOut, out)
+			}
+			if vmErrs != errs {
+				t.Errorf("vm errors differ from tree interpreter\ngot:  %q\nwant: %q", vmErrs, errs)
+			}
+			if vmCalls, calls := vmProfile.Calls(), treeProfile.Calls(); !maps.Equal(vmCalls, calls) {
+				t.Errorf("vm calls differ from tree interpreter\ngot:  %v\nwant: %v", vmCalls, calls)
+			}
+
+			for _, engine := range []Engine{EngineTree, EngineVM} {
+				optOut, optErrs := execute(program, string(source), engine, true, nil)
+				if optOut != out {
+					t.Errorf("optimized %s output differs\ngot:  %q\nwant: %q", engine, optOut, out)
+				}
+				if optErrs != errs {
+					t.Errorf("optimized %s errors differ\ngot:  %q\nwant: %q", engine, optErrs, errs)
+				}
+			}
+		})
+	}
+}
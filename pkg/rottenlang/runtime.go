@@ -0,0 +1,193 @@
+package rottenlang
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// Runtime embeds rottenlang in a Go program. Globals defined by evaluated
+// code, and by Set and Register, persist for the lifetime of the runtime.
+// A Runtime is not safe for concurrent use.
+//
+//	rt := rottenlang.NewRuntime(rottenlang.EngineVM, io.Discard)
+//	rt.Register("shout", strings.ToUpper)
+//	rt.Eval(`func greet(name) { purrr shout("yo " + name); }`)
+//	greeting, err := rt.Call("greet", "bestie")
+type Runtime struct {
+	session  *Rottenlang
+	reporter *errorreporter.CollectingErrorReporter
+}
+
+// Error is returned when evaluation fails, carrying every diagnostic that
+// was reported. Err is the underlying error, so errors.Is(err,
+// value.ErrLimitExceeded) tells whether a limit stopped the program.
+type Error struct {
+	Diagnostics []errorreporter.Diagnostic
+	Err         error
+}
+
+func (err *Error) Error() string {
+	messages := make([]string, len(err.Diagnostics))
+	for i, diagnostic := range err.Diagnostics {
+		messages[i] = diagnostic.String()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (err *Error) Unwrap() error {
+	return err.Err
+}
+
+// NewRuntime creates a runtime executing code with engine. The output of
+// print goes to out.
+func NewRuntime(engine Engine, out io.Writer) *Runtime {
+	reporter := &errorreporter.CollectingErrorReporter{}
+	session := NewRottenlang("", reporter)
+	session.Engine = engine
+	session.Out = out
+	return &Runtime{
+		session:  session,
+		reporter: reporter,
+	}
+}
+
+// SetLimits bounds the resources used by the following calls to Eval and
+// Call.
+func (r *Runtime) SetLimits(limits value.Limits) {
+	r.session.Limits = limits
+}
+
+// SetSearchPath sets the directories imported modules are looked up in
+// when they aren't found next to the importing file.
+func (r *Runtime) SetSearchPath(dirs []string) {
+	r.session.SearchPath = dirs
+}
+
+// Set defines the global variable name, converting v with value.FromGo.
+func (r *Runtime) Set(name string, v any) error {
+	converted, err := value.FromGo(v)
+	if err != nil {
+		return err
+	}
+	if native, ok := converted.(*value.Native); ok && native.Name() == "" {
+		converted = value.NewNative(name, native.Arity(), native.Call)
+	}
+	r.session.DefineGlobal(name, converted)
+	return nil
+}
+
+// Register exposes fn, a Go function, as the global function name. See
+// value.NativeFromFunc for how arguments and results are converted.
+func (r *Runtime) Register(name string, fn any) error {
+	native, ok := fn.(*value.Native)
+	if !ok {
+		var err error
+		native, err = value.NativeFromFunc(name, fn)
+		if err != nil {
+			return err
+		}
+	}
+	r.session.DefineGlobal(name, native)
+	return nil
+}
+
+// Get returns the value of the global variable name.
+func (r *Runtime) Get(name string) (any, bool) {
+	return r.session.Global(name)
+}
+
+// Eval runs source and returns the value of its final expression statement,
+// or nil if it doesn't end with one.
+func (r *Runtime) Eval(source string) (any, error) {
+	return r.EvalContext(context.Background(), source)
+}
+
+// EvalContext is like Eval, but stops execution once ctx is done.
+func (r *Runtime) EvalContext(ctx context.Context, source string) (any, error) {
+	r.reporter.Reset()
+	result, err := r.session.RunContext(ctx, source)
+	return result, r.wrap(err)
+}
+
+// EvalFile runs the program in the file at path, like Eval. Its imports
+// are looked up next to the file.
+func (r *Runtime) EvalFile(path string) (any, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	previous := r.session.Path
+	r.session.Path = path
+	defer func() { r.session.Path = previous }()
+	return r.Eval(string(source))
+}
+
+// Call calls the global function name with args, converted with
+// value.FromGo.
+func (r *Runtime) Call(name string, args ...any) (any, error) {
+	return r.CallContext(context.Background(), name, args...)
+}
+
+// CallContext is like Call, but stops execution once ctx is done.
+func (r *Runtime) CallContext(ctx context.Context, name string, args ...any) (any, error) {
+	callee, ok := r.session.Global(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined function '%s'", name)
+	}
+	return r.callValue(ctx, callee, args)
+}
+
+// CallValue calls callee, a function value obtained from the runtime, with
+// args converted with value.FromGo.
+func (r *Runtime) CallValue(callee any, args ...any) (any, error) {
+	return r.callValue(context.Background(), callee, args)
+}
+
+func (r *Runtime) callValue(ctx context.Context, callee any, args []any) (any, error) {
+	converted := make([]any, len(args))
+	for i, arg := range args {
+		v, err := value.FromGo(arg)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		converted[i] = v
+	}
+
+	r.reporter.Reset()
+	result, err := r.session.CallContext(ctx, callee, converted)
+	return result, r.wrap(err)
+}
+
+// Diagnostics returns what was reported by the latest Eval or Call.
+func (r *Runtime) Diagnostics() []errorreporter.Diagnostic {
+	return r.reporter.Diagnostics()
+}
+
+func (r *Runtime) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	if len(r.reporter.Diagnostics()) == 0 {
+		return err
+	}
+	return &Error{Diagnostics: r.reporter.Diagnostics(), Err: err}
+}
+
+// As converts a value returned by the runtime to T, e.g. As[int](v).
+func As[T any](v any) (T, error) {
+	var zero T
+	rv, err := value.ToGo(v, reflect.TypeOf(&zero).Elem())
+	if err != nil {
+		return zero, err
+	}
+	// A nil interface value fails the assertion, leaving the zero T.
+	out, _ := rv.Interface().(T)
+	return out, nil
+}
@@ -0,0 +1,270 @@
+package rottenlang
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// point is an object given to scripts by the host.
+type point struct{ x, y float64 }
+
+func (p point) Property(name string) (any, bool) {
+	switch name {
+	case "x":
+		return p.x, true
+	case "y":
+		return p.y, true
+	}
+	return nil, false
+}
+
+func TestRuntime(t *testing.T) {
+	for _, engine := range []Engine{EngineTree, EngineVM} {
+		t.Run(string(engine), func(t *testing.T) {
+			var out bytes.Buffer
+			rt := NewRuntime(engine, &out)
+
+			if err := rt.Register("shout", strings.ToUpper); err != nil {
+				t.Fatal(err)
+			}
+			if err := rt.Set("answer", 42); err != nil {
+				t.Fatal(err)
+			}
+			if err := rt.Register("twice", func(fn any) (any, error) {
+				if _, err := rt.CallValue(fn); err != nil {
+					return nil, err
+				}
+				return rt.CallValue(fn)
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := rt.Eval(`
+vibes calls = 0;
+func greet(name) { purrr shout("yo " + name); }
+func bump() { calls = calls + 1; purrr calls; }
+print(answer + 1);
+answer;`)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if n, err := As[int](result); err != nil || n != 42 {
+				t.Errorf("Eval result = %v, %v; want 42", result, err)
+			}
+			for _, x := range []float64{1e30, -1e30, math.Inf(1), math.NaN()} {
+				if n, err := As[int](x); err == nil {
+					t.Errorf("As[int](%v) = %d, want an error", x, n)
+				}
+				if n, err := As[uint](x); err == nil {
+					t.Errorf("As[uint](%v) = %d, want an error", x, n)
+				}
+			}
+			if v, err := As[any](nil); err != nil || v != nil {
+				t.Errorf("As[any](nil) = %v, %v; want nil", v, err)
+			}
+			if v, err := As[error](nil); err != nil || v != nil {
+				t.Errorf("As[error](nil) = %v, %v; want nil", v, err)
+			}
+			list, err := rt.Eval(`[1, [2], {"a": 3}];`)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			want := []any{float64(1), []any{float64(2)}, map[any]any{"a": float64(3)}}
+			if v, err := As[any](list); err != nil || !reflect.DeepEqual(v, want) {
+				t.Errorf("As[any](list) = %#v, %v; want %#v", v, err, want)
+			}
+			if v, err := As[[]any](list); err != nil || !reflect.DeepEqual(v, want) {
+				t.Errorf("As[[]any](list) = %#v, %v; want %#v", v, err, want)
+			}
+			cyclic, err := rt.Eval(`vibes l = [1]; push(l, l); l;`)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			if v, err := As[any](cyclic); err == nil {
+				t.Errorf("As[any](cyclic) = %v, want an error", v)
+			}
+			if out.String() != "43\n" {
+				t.Errorf("output = %q, want %q", out.String(), "43\n")
+			}
+
+			greeting, err := rt.Call("greet", "bestie")
+			if err != nil || greeting != "YO BESTIE" {
+				t.Errorf("Call(greet) = %v, %v; want YO BESTIE", greeting, err)
+			}
+
+			if result, err := rt.Eval(`twice(bump);`); err != nil || result != float64(2) {
+				t.Errorf("twice(bump) = %v, %v; want 2", result, err)
+			}
+			if calls, _ := rt.Get("calls"); calls != float64(2) {
+				t.Errorf("calls = %v, want 2", calls)
+			}
+
+			if err := rt.Set("here", point{x: 3, y: 4}); err != nil {
+				t.Fatal(err)
+			}
+			if result, err := rt.Eval(`vibes nowhere; here.x * here?.y + (nowhere?.x ?? 0);`); err != nil || result != float64(12) {
+				t.Errorf("properties = %v, %v; want 12", result, err)
+			}
+			if _, err := rt.Eval(`here.z;`); err == nil || !strings.Contains(err.Error(), "undefined property 'z'") {
+				t.Errorf("undefined property error = %v", err)
+			}
+
+			_, err = rt.Eval("vibes x = 1;\nprint(x + nil);")
+			var rtErr *Error
+			if !errors.As(err, &rtErr) {
+				t.Fatalf("Eval error = %v, want *Error", err)
+			}
+			if len(rtErr.Diagnostics) != 1 || rtErr.Diagnostics[0].Line != 2 {
+				t.Errorf("diagnostics = %v, want one runtime error on line 2", rtErr.Diagnostics)
+			}
+
+			if err := rt.Register("fail", func() error { return fmt.Errorf("nope") }); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := rt.Call("fail"); err == nil || !strings.Contains(err.Error(), "nope") {
+				t.Errorf("Call(fail) error = %v, want nope", err)
+			}
+
+			// the call isn't in the program, so nothing in it is to blame
+			_, err = rt.Call("greet")
+			if !errors.As(err, &rtErr) {
+				t.Fatalf("Call(greet) error = %v, want *Error", err)
+			}
+			if len(rtErr.Diagnostics) != 1 || rtErr.Diagnostics[0].Line != 0 || rtErr.Diagnostics[0].Column != 0 {
+				t.Errorf("diagnostics = %v, want one runtime error with no position", rtErr.Diagnostics)
+			}
+
+			if _, err := rt.Call("missing"); err == nil {
+				t.Error("Call(missing) succeeded")
+			}
+		})
+	}
+}
+
+func TestRuntimeImports(t *testing.T) {
+	for _, engine := range []Engine{EngineTree, EngineVM} {
+		t.Run(string(engine), func(t *testing.T) {
+			// counter.rot imports lib.rot from next to it, not from the
+			// working directory
+			rt := NewRuntime(engine, io.Discard)
+			if _, err := rt.EvalFile("testdata/modules/counter.rot"); err != nil {
+				t.Fatalf("EvalFile: %v", err)
+			}
+			if result, err := rt.Call("twice"); err != nil || result != float64(2) {
+				t.Errorf("twice() = %v, %v; want 2", result, err)
+			}
+			if _, err := rt.Eval(`import "lib.rot" as again;`); err == nil {
+				t.Error("Eval found lib.rot after EvalFile returned")
+			}
+
+			rt = NewRuntime(engine, io.Discard)
+			rt.SetSearchPath([]string{"testdata/modules"})
+			if result, err := rt.Eval(`import "lib.rot" as lib; lib.bump();`); err != nil || result != float64(1) {
+				t.Errorf("lib.bump() = %v, %v; want 1", result, err)
+			}
+		})
+	}
+}
+
+func TestRuntimeLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits value.Limits
+		source string
+		limit  string
+		line   int
+	}{
+		{"steps", value.Limits{MaxSteps: 500}, "vibes n = 0;\nskibidi (nocap) {}", "step", 2},
+		// the catch clause fails on its first step
+		{"steps in try", value.Limits{MaxSteps: 500}, "try {\nskibidi (nocap) {}\n} catch (e) {\nprint(e.message);\n}", "step", 4},
+		{"call depth", value.Limits{MaxCallDepth: 8}, "func f(n) { purrr f(n + 1); }\nf(0);", "call depth", 1},
+		{"string length", value.Limits{MaxStringLength: 64}, "vibes s = \"ab\";\nskibidi (nocap) s = s + s;", "string length", 2},
+		{"push", value.Limits{MaxListLength: 8}, "vibes l = [];\nskibidi (nocap) push(l, 1);", "list length", 2},
+		{"list literal", value.Limits{MaxListLength: 2}, "vibes l = [1, 2];\nl = [1, 2, 3];", "list length", 2},
+		{"map literal", value.Limits{MaxListLength: 2}, "vibes m = {a: 1};\nm = {a: 1, b: 2, c: 3};", "list length", 2},
+		{"map entries", value.Limits{MaxListLength: 8}, "vibes m = {};\nvibes n = 0;\nskibidi (nocap) { m[n] = n; n = n + 1; }", "list length", 3},
+	}
+	for _, engine := range []Engine{EngineTree, EngineVM} {
+		for _, test := range tests {
+			t.Run(string(engine)+"/"+test.name, func(t *testing.T) {
+				rt := NewRuntime(engine, io.Discard)
+				rt.SetLimits(test.limits)
+
+				_, err := rt.Eval(test.source)
+				var limitErr *value.LimitError
+				if !errors.Is(err, value.ErrLimitExceeded) || !errors.As(err, &limitErr) {
+					t.Fatalf("Eval error = %v, want a limit error", err)
+				}
+				if limitErr.Limit != test.limit {
+					t.Errorf("limit = %q, want %q", limitErr.Limit, test.limit)
+				}
+				if diagnostics := rt.Diagnostics(); len(diagnostics) != 1 || diagnostics[0].Line != test.line {
+					t.Errorf("diagnostics = %v, want one on line %d", diagnostics, test.line)
+				}
+
+				// the runtime stays usable, with a fresh step budget
+				if result, err := rt.Eval("1 + 1;"); err != nil || result != float64(2) {
+					t.Errorf("Eval after limit = %v, %v; want 2", result, err)
+				}
+			})
+		}
+
+		t.Run(string(engine)+"/caught", func(t *testing.T) {
+			var out bytes.Buffer
+			rt := NewRuntime(engine, &out)
+			rt.SetLimits(value.Limits{MaxCallDepth: 8, MaxStringLength: 64, MaxListLength: 8})
+
+			_, err := rt.Eval(`
+func f(n) { purrr f(n + 1); }
+try { f(0); } catch (e) { print(e.message); }
+vibes s = "ab";
+try { skibidi (nocap) s = s + s; } catch (e) { print(e.message, len(s)); }
+vibes l = [];
+try { skibidi (nocap) push(l, 1); } catch (e) { print(e.message, len(l)); }`)
+			if err != nil {
+				t.Fatalf("Eval: %v", err)
+			}
+			want := "call depth limit of 8 exceeded\nstring length limit of 64 exceeded 64\nlist length limit of 8 exceeded 9\n"
+			if out.String() != want {
+				t.Errorf("output = %q, want %q", out.String(), want)
+			}
+		})
+
+		t.Run(string(engine)+"/canceled", func(t *testing.T) {
+			rt := NewRuntime(engine, io.Discard)
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_, err := rt.EvalContext(ctx, "skibidi (nocap) {}")
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("EvalContext error = %v, want context.Canceled", err)
+			}
+		})
+
+		// a catch clause can't keep a canceled program going
+		t.Run(string(engine)+"/canceled in try", func(t *testing.T) {
+			rt := NewRuntime(engine, io.Discard)
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err := rt.EvalContext(ctx, `
+vibes n = 0;
+skibidi (nocap) {
+  try { skibidi (nocap) { n = n + 1; } } catch (e) { print("caught", e.message); }
+}`)
+			if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, value.ErrExecutionCanceled) {
+				t.Errorf("EvalContext error = %v, want execution canceled", err)
+			}
+		})
+	}
+}
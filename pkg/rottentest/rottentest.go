@@ -0,0 +1,324 @@
+// Package rottentest runs tests written in rottenlang. A test file is named
+// *_test.rot; every top-level function in it whose name starts with "test"
+// is a test. A test fails when it stops with a runtime error, such as one
+// raised by the assertion builtins:
+//
+//	assert(condition[, message])
+//	assert_eq(got, want[, message])
+//	assert_throws(fn[, text])
+package rottentest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/rottenlang"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+// FileSuffix ends the names of test files.
+const FileSuffix = "_test.rot"
+
+// FindFiles returns the test files under dir, in lexical order. A path to a
+// file is returned as is.
+func FindFiles(dir string) ([]string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{dir}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() && strings.HasSuffix(path, FileSuffix) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Runner runs test files, reporting in the style of "go test".
+type Runner struct {
+	// Engine runs the tests
+	Engine rottenlang.Engine
+	// Out receives the report, and what the tests print
+	Out io.Writer
+	// Match, when set, selects the tests to run by name
+	Match *regexp.Regexp
+	// Verbose reports every test, not only those that fail
+	Verbose bool
+	// Prepare, when set, is called on the session of every file before it
+	// runs, e.g. to set a keyword pack
+	Prepare func(*rottenlang.Rottenlang)
+}
+
+func NewRunner(engine rottenlang.Engine, out io.Writer) *Runner {
+	return &Runner{
+		Engine: engine,
+		Out:    out,
+	}
+}
+
+// RunFiles runs the tests of every file and reports whether they all
+// passed. A summary line is written for each file.
+func (r *Runner) RunFiles(paths []string) (bool, error) {
+	passed := true
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		start := time.Now()
+		ok, ran := r.runFile(path, string(source))
+		elapsed := time.Since(start).Seconds()
+
+		switch {
+		case !ok:
+			passed = false
+			fmt.Fprintf(r.Out, "FAIL\t%s\t%.3fs\n", path, elapsed)
+		case ran == 0:
+			fmt.Fprintf(r.Out, "ok  \t%s\t%.3fs [no tests to run]\n", path, elapsed)
+		default:
+			fmt.Fprintf(r.Out, "ok  \t%s\t%.3fs\n", path, elapsed)
+		}
+	}
+	if !passed {
+		fmt.Fprintln(r.Out, "FAIL")
+	}
+	return passed, nil
+}
+
+// runFile runs the tests of one file, returning whether it passed and the
+// number of tests run.
+func (r *Runner) runFile(path, source string) (bool, int) {
+	reporter := &errorreporter.CollectingErrorReporter{}
+	session := rottenlang.NewRottenlang(source, reporter)
+	session.Engine = r.Engine
+	session.Out = r.Out
+	session.Path = path
+	if r.Prepare != nil {
+		r.Prepare(session)
+	}
+
+	// Run the file first, defining its functions, then call the tests one
+	// by one in the order they are declared.
+	statements, err := session.Parse()
+	if err == nil {
+		for _, native := range assertions(session) {
+			session.DefineGlobal(native.Name(), native)
+		}
+		_, err = session.Run(source)
+	}
+	if err != nil {
+		for _, diagnostic := range reporter.Diagnostics() {
+			fmt.Fprintf(r.Out, "%s:%d:%d: %s error: %s\n", filepath.Base(path), diagnostic.Line, diagnostic.Column, diagnostic.Kind, diagnostic.Message)
+		}
+		if len(reporter.Diagnostics()) == 0 {
+			fmt.Fprintf(r.Out, "%s: %v\n", filepath.Base(path), err)
+		}
+		return false, 0
+	}
+
+	passed, ran := true, 0
+	for _, name := range testNames(statements) {
+		if r.Match != nil && !r.Match.MatchString(name) {
+			continue
+		}
+		ran++
+		if !r.runTest(session, path, source, name) {
+			passed = false
+		}
+	}
+	return passed, ran
+}
+
+// runTest calls the test function name, reporting how it went.
+func (r *Runner) runTest(session *rottenlang.Rottenlang, path, source, name string) bool {
+	if r.Verbose {
+		fmt.Fprintf(r.Out, "=== RUN   %s\n", name)
+	}
+	start := time.Now()
+	fn, _ := session.Global(name)
+	var err error
+	if callable, ok := fn.(value.Callable); ok && callable.Arity() != 0 {
+		err = fmt.Errorf("test function %s must take no parameters", name)
+	} else {
+		_, err = session.CallContext(context.Background(), fn, nil)
+	}
+	elapsed := time.Since(start).Seconds()
+
+	if err == nil {
+		if r.Verbose {
+			fmt.Fprintf(r.Out, "--- PASS: %s (%.2fs)\n", name, elapsed)
+		}
+		return true
+	}
+	fmt.Fprintf(r.Out, "--- FAIL: %s (%.2fs)\n", name, elapsed)
+	var runtimeErr *value.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		fmt.Fprintf(r.Out, "    %s: %v\n", filepath.Base(path), err)
+		return false
+	}
+	fmt.Fprintf(r.Out, "    %s:%d:%d: %s\n", filepath.Base(path), runtimeErr.Line, runtimeErr.Column, runtimeErr.Message)
+	if excerpt := excerpt(source, runtimeErr.Line, runtimeErr.Column); excerpt != "" {
+		fmt.Fprint(r.Out, excerpt)
+	}
+	return false
+}
+
+// testNames returns the names of the tests declared by statements.
+func testNames(statements []ast.Stmt) []string {
+	var names []string
+	for _, stmt := range statements {
+		if function, ok := stmt.(*ast.FunctionStmt); ok && strings.HasPrefix(*function.Name().Lexeme, "test") {
+			names = append(names, *function.Name().Lexeme)
+		}
+	}
+	return names
+}
+
+// excerpt shows the source line line, underlining the call whose closing
+// parenthesis is at column when it starts on that line, or pointing at
+// column otherwise. It returns "" when the location is unknown.
+func excerpt(source string, line, column int) string {
+	lines := strings.Split(source, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	text := strings.TrimRight(lines[line-1], "\r")
+	if column < 1 || column > len(text) {
+		return "        " + text + "\n"
+	}
+	end := column - 1
+	start := callStart(text, end)
+
+	// keep tabs, so the marks line up however they are displayed
+	padding := strings.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, text[:start])
+	return "        " + text + "\n        " + padding + strings.Repeat("^", end-start+1) + "\n"
+}
+
+// callStart returns the index of the callee of the call whose closing
+// parenthesis is text[end], or end if the call doesn't start in text.
+func callStart(text string, end int) int {
+	if text[end] != ')' {
+		return end
+	}
+	depth, quoted := 0, false
+	for i := end; i >= 0; i-- {
+		switch {
+		case text[i] == '"' && (i == 0 || text[i-1] != '\\'):
+			quoted = !quoted
+		case quoted:
+		case text[i] == ')':
+			depth++
+		case text[i] == '(':
+			depth--
+			if depth == 0 {
+				start := i
+				for start > 0 && isWordByte(text[start-1]) {
+					start--
+				}
+				return start
+			}
+		}
+	}
+	return end
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// assertions returns the assertion builtins of the tests run by session.
+func assertions(session *rottenlang.Rottenlang) []*value.Native {
+	return []*value.Native{
+		value.NewNative("assert", -1, func(args []any) (any, error) {
+			if err := checkArgs("assert", args, 1); err != nil {
+				return nil, err
+			}
+			if !value.IsTruthy(args[0]) {
+				return nil, failure("assert", args[1:], "got %s", repr(args[0]))
+			}
+			return nil, nil
+		}),
+		value.NewNative("assert_eq", -1, func(args []any) (any, error) {
+			if err := checkArgs("assert_eq", args, 2); err != nil {
+				return nil, err
+			}
+			if !value.Equal(args[0], args[1]) {
+				return nil, failure("assert_eq", args[2:], "%s != %s", repr(args[0]), repr(args[1]))
+			}
+			return nil, nil
+		}),
+		value.NewNative("assert_throws", -1, func(args []any) (any, error) {
+			if err := checkArgs("assert_throws", args, 1); err != nil {
+				return nil, err
+			}
+			result, err := session.CallContext(context.Background(), args[0], nil)
+			var runtimeErr *value.RuntimeError
+			switch {
+			case err == nil:
+				return nil, fmt.Errorf("assert_throws failed: returned %s without an error", repr(result))
+			case errors.Is(err, value.ErrLimitExceeded) || !errors.As(err, &runtimeErr):
+				return nil, err
+			}
+			if len(args) == 2 {
+				text := value.Stringify(args[1])
+				if !strings.Contains(runtimeErr.Message, text) {
+					return nil, fmt.Errorf("assert_throws failed: error %s does not contain %s", repr(runtimeErr.Message), repr(text))
+				}
+			}
+			return nil, nil
+		}),
+	}
+}
+
+// checkArgs checks that an assertion got its required arguments and, at
+// most, one more.
+func checkArgs(name string, args []any, required int) error {
+	if len(args) < required || len(args) > required+1 {
+		return fmt.Errorf("%s expects %d or %d arguments but got %d", name, required, required+1, len(args))
+	}
+	return nil
+}
+
+// failure describes a failed assertion, followed by the message given to
+// it, if any.
+func failure(name string, message []any, format string, args ...any) error {
+	description := fmt.Sprintf(format, args...)
+	if len(message) > 0 {
+		return fmt.Errorf("%s failed: %s: %s", name, value.Stringify(message[0]), description)
+	}
+	return fmt.Errorf("%s failed: %s", name, description)
+}
+
+// repr formats v as it would be written in source, so strings are quoted.
+func repr(v any) string {
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return value.Stringify(v)
+}
@@ -0,0 +1,78 @@
+package rottentest
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/rottenlang"
+)
+
+func TestRunner(t *testing.T) {
+	files, err := FindFiles("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got files %v, want the two in testdata", files)
+	}
+
+	for _, engine := range []rottenlang.Engine{rottenlang.EngineTree, rottenlang.EngineVM} {
+		t.Run(string(engine), func(t *testing.T) {
+			var out strings.Builder
+			passed, err := NewRunner(engine, &out).RunFiles(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if passed {
+				t.Error("expected the failing tests to fail the run")
+			}
+
+			report := out.String()
+			for _, want := range []string{
+				"running broken\n",
+				"--- FAIL: test_broken",
+				`math_test.rot:10:48: assert_eq failed: string and number: 3 != "3"`,
+				"\n          ^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^^\n",
+				"assert_throws failed: returned 1 without an error",
+				"--- FAIL: testCrash",
+				"math_test.rot:27:5: can only call functions, got nil",
+				"FAIL\ttestdata/math_test.rot\t",
+				"ok  \ttestdata/strings_test.rot\t",
+			} {
+				if !strings.Contains(report, want) {
+					t.Errorf("report lacks %q:\n%s", want, report)
+				}
+			}
+			for _, unwanted := range []string{"test_add", "test_throws", "test_concat"} {
+				if strings.Contains(report, unwanted) {
+					t.Errorf("passing test %s is reported:\n%s", unwanted, report)
+				}
+			}
+		})
+	}
+}
+
+func TestRunnerMatch(t *testing.T) {
+	var out strings.Builder
+	runner := NewRunner(rottenlang.EngineTree, &out)
+	runner.Match = regexp.MustCompile("add|throws$")
+	runner.Verbose = true
+	passed, err := runner.RunFiles([]string{"testdata/math_test.rot", "testdata/strings_test.rot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !passed {
+		t.Errorf("expected the selected tests to pass:\n%s", out.String())
+	}
+
+	report := out.String()
+	for _, want := range []string{"--- PASS: test_add", "--- PASS: test_throws", "[no tests to run]"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "test_broken") {
+		t.Errorf("unselected test was run:\n%s", report)
+	}
+}
@@ -12,6 +12,7 @@ var (
 var (
 	ErrUnterminatedString        = &ScanErrorDescription{Message: "unterminated string", Class: ErrClassUnterminatedString}
 	ErrUnterminatedNumberLiteral = &ScanErrorDescription{Message: "unterminated number literal", Class: ErrClassUnterminatedNumberLiteral}
+	ErrUnterminatedComment       = &ScanErrorDescription{Message: "unterminated comment", Class: ErrClassUnterminatedComment}
 )
 
 var (
@@ -19,6 +20,7 @@ var (
 	ErrClassInvalidNumberLiteral      = "InvalidNumberLiteral"
 	ErrClassUnterminatedString        = "UnterminatedString"
 	ErrClassUnterminatedNumberLiteral = "UnterminatedNumberLiteral"
+	ErrClassUnterminatedComment       = "UnterminatedComment"
 )
 
 type ScanError interface {
@@ -33,6 +35,7 @@ const (
 type GenericScanError struct {
 	message   string
 	class     string
+	lexeme    string
 	line, col int
 }
 
@@ -53,6 +56,23 @@ func (err GenericScanError) Class() string {
 	return err.class
 }
 
+func (err GenericScanError) Message() string {
+	return err.message
+}
+
+// Lexeme returns the source text scanned when the error occurred.
+func (err GenericScanError) Lexeme() string {
+	return err.lexeme
+}
+
+func (err GenericScanError) Line() int {
+	return err.line
+}
+
+func (err GenericScanError) Column() int {
+	return err.col
+}
+
 type ScanErrorDescription struct {
 	Message string
 	Class   string
@@ -1,8 +1,11 @@
 package scanner
 
 import (
+	"bytes"
 	"io"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/bagaswh/rottenlang/pkg/ast"
 )
@@ -33,6 +36,38 @@ type Scanner struct {
 	tokens []*ast.Token
 
 	scannerErrors map[int][]*GenericScanError
+
+	// keywords extends the built-in keywords table, see SetKeywords
+	keywords map[string]ast.TokenType
+	// multiWordKeywords lists the multi-word keywords of both tables, or is
+	// nil when there are no extra keywords
+	multiWordKeywords []string
+}
+
+// SetKeywords adds keywords, such as those of a keyword pack, to the ones
+// the scanner recognizes. They take precedence over built-in keywords of the
+// same spelling.
+func (s *Scanner) SetKeywords(keywords []ast.Keyword) {
+	s.keywords = make(map[string]ast.TokenType, len(keywords))
+	s.multiWordKeywords = append([]string(nil), ast.MultiWordKeywords()...)
+	for _, keyword := range keywords {
+		spelling := strings.Join(strings.Fields(keyword.Spelling), " ")
+		s.keywords[spelling] = keyword.Type
+		if strings.Contains(spelling, " ") {
+			s.multiWordKeywords = append(s.multiWordKeywords, spelling)
+		}
+	}
+	// longest first, like the built-in list
+	sort.SliceStable(s.multiWordKeywords, func(i, j int) bool {
+		return len(s.multiWordKeywords[i]) > len(s.multiWordKeywords[j])
+	})
+}
+
+func (s *Scanner) lookupKeyword(word string) (ast.TokenType, bool) {
+	if tokenType, ok := s.keywords[word]; ok {
+		return tokenType, true
+	}
+	return ast.LookupKeyword(word)
 }
 
 func (s *Scanner) read() error {
@@ -72,7 +107,9 @@ func (s *Scanner) addToken(tokenType ast.TokenType, literal any) {
 	// if tokenType == TokenString {
 	// tokenStr += "\""
 	// }
-	s.tokens = append(s.tokens, ast.NewToken(tokenType, &tokenStr, literal, s.line, s.linecol()))
+	token := ast.NewToken(tokenType, &tokenStr, literal, s.line, s.linecol())
+	token.Offset = s.start
+	s.tokens = append(s.tokens, token)
 }
 
 func (s *Scanner) scanToken() error {
@@ -93,15 +130,65 @@ func (s *Scanner) scanToken() error {
 	case ",":
 		s.addToken(ast.TokenComma, nil)
 	case ".":
-		s.addToken(ast.TokenDot, nil)
+		token := ast.TokenDot
+		if s.peek() == "." && s.ahead() == "." {
+			s.advance()
+			s.advance()
+			token = ast.TokenDotDotDot
+		}
+		s.addToken(token, nil)
 	case "-":
-		s.addToken(ast.TokenMinus, nil)
+		token := ast.TokenMinus
+		if s.match("-") {
+			token = ast.TokenMinusMinus
+		} else if s.match("=") {
+			token = ast.TokenMinusEqual
+		}
+		s.addToken(token, nil)
 	case "+":
-		s.addToken(ast.TokenPlus, nil)
+		token := ast.TokenPlus
+		if s.match("+") {
+			token = ast.TokenPlusPlus
+		} else if s.match("=") {
+			token = ast.TokenPlusEqual
+		}
+		s.addToken(token, nil)
 	case ";":
 		s.addToken(ast.TokenSemicolon, nil)
 	case "*":
-		s.addToken(ast.TokenStar, nil)
+		token := ast.TokenStar
+		if s.match("*") {
+			token = ast.TokenStarStar
+		} else if s.match("=") {
+			token = ast.TokenStarEqual
+		}
+		s.addToken(token, nil)
+	case "%":
+		token := ast.TokenPercent
+		if s.match("=") {
+			token = ast.TokenPercentEqual
+		}
+		s.addToken(token, nil)
+	case "&":
+		s.addToken(ast.TokenAmpersand, nil)
+	case "|":
+		s.addToken(ast.TokenPipe, nil)
+	case "^":
+		s.addToken(ast.TokenCaret, nil)
+	case "~":
+		s.addToken(ast.TokenTilde, nil)
+	case ":":
+		s.addToken(ast.TokenColon, nil)
+	case "?":
+		token := ast.TokenQuestion
+		if s.match("?") {
+			token = ast.TokenQuestionQuestion
+		} else if s.peek() == "." && !s.isDigit(s.ahead()) {
+			// "a?.5:1" is a conditional, not an optional chain
+			s.advance()
+			token = ast.TokenQuestionDot
+		}
+		s.addToken(token, nil)
 	case "!":
 		token := ast.TokenBang
 		if s.match("=") {
@@ -112,18 +199,24 @@ func (s *Scanner) scanToken() error {
 		token := ast.TokenEqual
 		if s.match("=") {
 			token = ast.TokenEqualEqual
+		} else if s.match(">") {
+			token = ast.TokenEqualGreater
 		}
 		s.addToken(token, nil)
 	case "<":
 		token := ast.TokenLess
 		if s.match("=") {
 			token = ast.TokenLessEqual
+		} else if s.match("<") {
+			token = ast.TokenLessLess
 		}
 		s.addToken(token, nil)
 	case ">":
 		token := ast.TokenGreater
 		if s.match("=") {
 			token = ast.TokenGreaterEqual
+		} else if s.match(">") {
+			token = ast.TokenGreaterGreater
 		}
 		s.addToken(token, nil)
 	case "/":
@@ -131,11 +224,15 @@ func (s *Scanner) scanToken() error {
 			s.comment()
 		} else if s.match("*") {
 			s.cStyleComment()
+		} else if s.match("=") {
+			s.addToken(ast.TokenSlashEqual, nil)
 		} else {
 			s.addToken(ast.TokenSlash, nil)
 		}
 	case "\"":
 		s.string()
+	case " ", "\t", "\r":
+		// whitespace separates tokens but is otherwise ignored
 	case "\n":
 		s.newline()
 	case CharEOF:
@@ -143,6 +240,8 @@ func (s *Scanner) scanToken() error {
 	default:
 		if s.isDigit(c) {
 			s.number()
+		} else if s.isAlpha(c) {
+			s.identifier()
 		} else {
 			s.scanError(unexpectedCharacterError(c))
 		}
@@ -159,6 +258,7 @@ func (s *Scanner) scanError(errDesc *ScanErrorDescription) {
 		return
 	}
 	theError := NewGenericScanError(errDesc.Message, errDesc.Class, s.line, s.linecol())
+	theError.lexeme = string(s.buf[s.start:s.current])
 	s.scannerErrors[s.line] = append(s.scannerErrors[s.line], theError)
 }
 
@@ -179,7 +279,7 @@ func (s *Scanner) comment() {
 func (s *Scanner) cStyleComment() {
 	// nested comment is possible
 	level := 1
-	for {
+	for level > 0 && !s.isAtEnd() {
 		c := s.peek()
 		if c == "/" && s.ahead() == "*" {
 			// nested comment
@@ -188,57 +288,115 @@ func (s *Scanner) cStyleComment() {
 		} else if c == "*" && s.ahead() == "/" {
 			level--
 			s.advance()
-		} else if c == "\n" {
-			s.newline()
 		}
 
 		s.advance()
-
-		if level <= 0 {
-			break
+		if c == "\n" {
+			s.newline()
 		}
 	}
 
+	if level > 0 {
+		s.scanError(ErrUnterminatedComment)
+	}
 	s.addToken(ast.TokenCStyleComment, string(s.buf[s.start:s.current]))
-
 }
 
 func (s *Scanner) string() {
-	strValue := ""
-	for {
-		if s.isAtEnd() {
-			break
-		}
-
-		// escape char
+	for !s.isAtEnd() && s.peek() != "\"" {
 		if s.peek() == "\\" {
-			strValue += s.peek()
-			s.advance()
-			strValue += s.peek()
+			// the escaped character can't end the string; escapes are kept
+			// in the value as written
 			s.advance()
+			if s.isAtEnd() {
+				break
+			}
 		}
-
-		if s.peek() == "\"" {
-			break
-		}
-
-		if s.peek() == "\n" {
+		if s.advance() == "\n" {
 			s.newline()
 		}
-
-		strValue += s.peek()
-
-		s.advance()
 	}
 
 	if s.isAtEnd() {
 		s.scanError(ErrUnterminatedString)
+		s.addToken(ast.TokenString, string(s.buf[s.start+1:s.current]))
+		return
 	}
 
 	// the closing ""
 	s.advance()
+	s.addToken(ast.TokenString, string(s.buf[s.start+1:s.current-1]))
+}
+
+func (s *Scanner) isAlpha(ch string) bool {
+	if len(ch) == 0 {
+		return false
+	}
+	return (ch[0] >= 'a' && ch[0] <= 'z') || (ch[0] >= 'A' && ch[0] <= 'Z') || ch[0] == '_'
+}
+
+func (s *Scanner) isAlphaNumeric(ch string) bool {
+	return s.isAlpha(ch) || s.isDigit(ch)
+}
+
+func (s *Scanner) identifier() {
+	for s.isAlphaNumeric(s.peek()) {
+		s.advance()
+	}
+
+	if tokenType, ok := s.multiWordKeyword(); ok {
+		s.addToken(tokenType, nil)
+		return
+	}
+
+	tokenType, ok := s.lookupKeyword(string(s.buf[s.start:s.current]))
+	if !ok {
+		tokenType = ast.TokenIdentifier
+	}
+	s.addToken(tokenType, nil)
+}
+
+// multiWordKeyword extends the word just scanned into a keyword spelled with
+// several words, e.g. "chat is this real". Words may be separated by any run
+// of spaces or tabs, but not by newlines.
+func (s *Scanner) multiWordKeyword() (ast.TokenType, bool) {
+	word := string(s.buf[s.start:s.current])
+	multiWordKeywords := s.multiWordKeywords
+	if multiWordKeywords == nil {
+		multiWordKeywords = ast.MultiWordKeywords()
+	}
+	for _, keyword := range multiWordKeywords {
+		words := strings.Fields(keyword)
+		if words[0] != word {
+			continue
+		}
 
-	s.addToken(ast.TokenString, string(strValue))
+		pos := s.current
+		matched := true
+		for _, next := range words[1:] {
+			end := pos
+			for end < len(s.buf) && (s.buf[end] == ' ' || s.buf[end] == '\t') {
+				end++
+			}
+			if end == pos || !bytes.HasPrefix(s.buf[end:], []byte(next)) {
+				matched = false
+				break
+			}
+			end += len(next)
+			if end < len(s.buf) && s.isAlphaNumeric(string(s.buf[end])) {
+				matched = false
+				break
+			}
+			pos = end
+		}
+
+		if matched {
+			s.current = pos
+			tokenType, _ := s.lookupKeyword(keyword)
+			return tokenType, true
+		}
+	}
+	return 0, false
 }
 
 func (s *Scanner) isDigit(ch string) bool {
@@ -296,19 +454,20 @@ func (s *Scanner) match(ch string) bool {
 }
 
 func (s *Scanner) ScanTokens() ([]*ast.Token, error) {
-	tokens := make([]*ast.Token, 0)
 	for !s.isAtEnd() {
 		s.start = s.current
 		s.scanToken()
 	}
 
-	tokens = append(tokens, ast.NewToken(ast.TokenEOF, strPtr(""), nil, s.line, s.linecol()))
+	eof := ast.NewToken(ast.TokenEOF, strPtr(""), nil, s.line, s.linecol())
+	eof.Offset = len(s.buf)
+	s.tokens = append(s.tokens, eof)
 
 	if len(s.scannerErrors) > 0 {
 		return nil, ErrScanner
 	}
 
-	return tokens, nil
+	return s.tokens, nil
 }
 
 func (s *Scanner) Tokens() []*ast.Token {
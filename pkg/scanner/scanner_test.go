@@ -0,0 +1,131 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/golden"
+)
+
+// addSeeds adds every program under pkg/*/testdata to the corpus of f.
+func addSeeds(f *testing.F) {
+	paths, err := filepath.Glob("../*/testdata/*.rot")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(source))
+	}
+}
+
+// scan tokenizes source. Scanners that don't terminate are caught by the
+// test timeout, or by the fuzzing engine.
+func scan(source string) (*Scanner, []*ast.Token, error) {
+	s := NewScanner(strings.NewReader(source), 0)
+	tokens, err := s.ScanTokens()
+	return s, tokens, err
+}
+
+func FuzzScanTokens(f *testing.F) {
+	addSeeds(f)
+	for _, seed := range []string{"", "/*", "/* /* */", "/*/", "\"", "\"\\", "chat  is\tthis real", "a?.5:1", "1..2"} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, source string) {
+		s, tokens, err := scan(source)
+		if err != nil {
+			if err != ErrScanner || !s.HadError() {
+				t.Fatalf("unexpected error %v", err)
+			}
+			return
+		}
+
+		// The tokens cover the whole input in order, leaving out whitespace
+		// only, and end with EOF.
+		offset := 0
+		for i, token := range tokens {
+			if token.Offset < offset || token.Offset+len(*token.Lexeme) > len(source) {
+				t.Fatalf("token %d %s at offset %d overlaps the previous one or the end", i, token.Name(), token.Offset)
+			}
+			if gap := source[offset:token.Offset]; strings.Trim(gap, " \t\r\n") != "" {
+				t.Fatalf("text %q before token %d %s is not covered by any token", gap, i, token.Name())
+			}
+			if got := source[token.Offset : token.Offset+len(*token.Lexeme)]; got != *token.Lexeme {
+				t.Fatalf("token %d %s has lexeme %q, but the source at its offset is %q", i, token.Name(), *token.Lexeme, got)
+			}
+			offset = token.Offset + len(*token.Lexeme)
+		}
+		if last := tokens[len(tokens)-1]; last.Type != ast.TokenEOF || last.Offset != len(source) {
+			t.Fatalf("last token is %s at offset %d, want EOF at %d", last.Name(), last.Offset, len(source))
+		}
+	})
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	for _, source := range []string{"/*", "a /* b", "/* /* */", "/* */ /*\n\n"} {
+		s, _, err := scan(source)
+		if err != ErrScanner {
+			t.Fatalf("%q: got error %v, want %v", source, err, ErrScanner)
+		}
+		var classes []string
+		for _, errs := range s.ScannerErrors() {
+			for _, err := range errs {
+				classes = append(classes, err.Class())
+			}
+		}
+		if len(classes) != 1 || classes[0] != ErrClassUnterminatedComment {
+			t.Errorf("%q: got error classes %v, want [%s]", source, classes, ErrClassUnterminatedComment)
+		}
+	}
+}
+
+// TestGolden scans the programs in testdata, comparing their tokens with
+// the .tokens files and their errors with the .err files.
+func TestGolden(t *testing.T) {
+	for _, program := range golden.Programs(t, "testdata") {
+		t.Run(filepath.Base(program), func(t *testing.T) {
+			source, err := os.ReadFile(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s, _, _ := scan(string(source))
+
+			var b strings.Builder
+			for _, token := range s.Tokens() {
+				fmt.Fprintf(&b, "%d:%d %s %q", token.Line, token.Column, token.Name(), *token.Lexeme)
+				switch literal := token.Literal.(type) {
+				case nil:
+				case string:
+					if literal != *token.Lexeme {
+						fmt.Fprintf(&b, " %q", literal)
+					}
+				default:
+					fmt.Fprintf(&b, " %v", literal)
+				}
+				b.WriteByte('\n')
+			}
+			golden.Check(t, program, ".tokens", b.String())
+
+			var errs []*GenericScanError
+			for _, lineErrs := range s.ScannerErrors() {
+				errs = append(errs, lineErrs...)
+			}
+			sort.Slice(errs, func(i, j int) bool { return errs[i].Line() < errs[j].Line() })
+			var messages strings.Builder
+			for _, err := range errs {
+				fmt.Fprintf(&messages, "%s (%s)\n", err.Error(), err.Class())
+			}
+			golden.Check(t, program, ".err", messages.String())
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+)
+
+var errClosedSend = errors.New("send on closed channel")
+
+// Channel passes values between tasks, made by the channel builtin. Its
+// properties are the methods send, receive and close. A channel without
+// capacity hands each value from a sender straight to a receiver, blocking
+// whichever comes first; one with capacity buffers up to that many values.
+// Receiving from a closed channel gives the values left in its buffer, then
+// nil. A for-in loop over a channel receives from it until it is closed and
+// drained.
+type Channel struct {
+	scheduler *Scheduler
+	capacity  int
+	buffer    []any
+	closed    bool
+	// receivers and senders are the blocked operations on the channel, in
+	// the order they blocked
+	receivers []*waiter
+	senders   []*waiter
+}
+
+// waiter is a case of a blocked selection on a channel.
+type waiter struct {
+	sel   *selection
+	index int
+	// value is the value sent
+	value any
+}
+
+// SelectCase is one case of a select statement: receiving from Channel or,
+// when Send is set, sending Value to it.
+type SelectCase struct {
+	Channel *Channel
+	Send    bool
+	Value   any
+}
+
+func (ch *Channel) Property(name string) (any, bool) {
+	switch name {
+	case "send":
+		return NewNative("send", 1, func(args []any) (any, error) {
+			_, _, err := ch.scheduler.Select([]SelectCase{{Channel: ch, Send: true, Value: args[0]}}, false)
+			return nil, err
+		}), true
+	case "receive":
+		return NewNative("receive", 0, func(args []any) (any, error) {
+			_, v, err := ch.scheduler.Select([]SelectCase{{Channel: ch}}, false)
+			return v, err
+		}), true
+	case "close":
+		return NewNative("close", 0, func(args []any) (any, error) {
+			return nil, ch.close()
+		}), true
+	}
+	return nil, false
+}
+
+func (ch *Channel) String() string {
+	return fmt.Sprintf("<channel %d/%d>", len(ch.buffer), ch.capacity)
+}
+
+// Iterator returns an iterator receiving from ch.
+func (ch *Channel) Iterator() Iterator {
+	return channelIterator{ch}
+}
+
+type channelIterator struct {
+	ch *Channel
+}
+
+func (it channelIterator) Next() (any, bool, error) {
+	_, v, closed, err := it.ch.scheduler.selectCase([]SelectCase{{Channel: it.ch}}, false)
+	return v, !closed && err == nil, err
+}
+
+// Select performs the first of cases that can proceed without blocking,
+// returning its index and the value it received. When none can, it returns
+// -1 if the select has a default branch, and otherwise blocks the current
+// task until one can.
+func (s *Scheduler) Select(cases []SelectCase, hasDefault bool) (int, any, error) {
+	index, v, closed, err := s.selectCase(cases, hasDefault)
+	if closed && cases[index].Send {
+		return index, nil, errClosedSend
+	}
+	return index, v, err
+}
+
+// selectCase is Select, also reporting whether the channel of the case
+// performed was closed, for a receive from a closed and drained channel.
+func (s *Scheduler) selectCase(cases []SelectCase, hasDefault bool) (int, any, bool, error) {
+	for i, c := range cases {
+		ok, v, closed, err := c.Channel.try(c)
+		if err != nil {
+			return i, nil, false, err
+		}
+		if ok {
+			return i, v, closed, nil
+		}
+	}
+	if hasDefault {
+		return -1, nil, false, nil
+	}
+
+	sel := &selection{task: s.current}
+	for i, c := range cases {
+		w := &waiter{sel: sel, index: i, value: c.Value}
+		if c.Send {
+			c.Channel.senders = append(c.Channel.senders, w)
+		} else {
+			c.Channel.receivers = append(c.Channel.receivers, w)
+		}
+	}
+	operation := "selecting"
+	if len(cases) == 1 && cases[0].Send {
+		operation = "sending"
+	} else if len(cases) == 1 {
+		operation = "receiving"
+	}
+	if err := s.block(sel, operation); err != nil {
+		return 0, nil, false, err
+	}
+	return sel.index, sel.value, sel.closed, nil
+}
+
+// try performs c if it can proceed without blocking, reporting whether it
+// did, the value it received and whether it received from a closed and
+// drained channel.
+func (ch *Channel) try(c SelectCase) (bool, any, bool, error) {
+	s := ch.scheduler
+	if c.Send {
+		if ch.closed {
+			return false, nil, false, errClosedSend
+		}
+		if w := popWaiter(&ch.receivers); w != nil {
+			s.fire(w.sel, w.index, c.Value)
+			return true, nil, false, nil
+		}
+		if len(ch.buffer) < ch.capacity {
+			ch.buffer = append(ch.buffer, c.Value)
+			return true, nil, false, nil
+		}
+		return false, nil, false, nil
+	}
+
+	if len(ch.buffer) > 0 {
+		v := ch.buffer[0]
+		ch.buffer = ch.buffer[1:]
+		if w := popWaiter(&ch.senders); w != nil {
+			ch.buffer = append(ch.buffer, w.value)
+			s.fire(w.sel, w.index, nil)
+		}
+		return true, v, false, nil
+	}
+	if w := popWaiter(&ch.senders); w != nil {
+		s.fire(w.sel, w.index, nil)
+		return true, w.value, false, nil
+	}
+	if ch.closed {
+		return true, nil, true, nil
+	}
+	return false, nil, false, nil
+}
+
+// popWaiter removes and returns the first waiter of list whose selection
+// hasn't fired, or nil.
+func popWaiter(list *[]*waiter) *waiter {
+	for len(*list) > 0 {
+		w := (*list)[0]
+		*list = (*list)[1:]
+		if !w.sel.fired {
+			return w
+		}
+	}
+	return nil
+}
+
+// close closes ch, waking its blocked receivers with nil and failing its
+// blocked senders.
+func (ch *Channel) close() error {
+	if ch.closed {
+		return errors.New("close of closed channel")
+	}
+	ch.closed = true
+	for w := popWaiter(&ch.receivers); w != nil; w = popWaiter(&ch.receivers) {
+		w.sel.closed = true
+		ch.scheduler.fire(w.sel, w.index, nil)
+	}
+	for w := popWaiter(&ch.senders); w != nil; w = popWaiter(&ch.senders) {
+		w.sel.closed = true
+		ch.scheduler.fire(w.sel, w.index, nil)
+	}
+	return nil
+}
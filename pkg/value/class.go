@@ -0,0 +1,121 @@
+package value
+
+import "fmt"
+
+// InitMethod is the name of the method initializing the instances of a
+// class, which is called with the arguments the class is called with.
+const InitMethod = "init"
+
+// Class is a class of the program. Calling it makes an Instance. Its
+// methods are functions of the engine running the program, which binds
+// them to the instance they are called on.
+type Class struct {
+	name    string
+	methods map[string]Callable
+}
+
+// NewClass creates the class name with methods.
+func NewClass(name string, methods map[string]Callable) *Class {
+	return &Class{name: name, methods: methods}
+}
+
+func (c *Class) Name() string {
+	return c.name
+}
+
+// Arity is the arity of the init method, or 0 without one.
+func (c *Class) Arity() int {
+	if init, ok := c.methods[InitMethod]; ok {
+		return init.Arity()
+	}
+	return 0
+}
+
+// Method returns the method name of c.
+func (c *Class) Method(name string) (Callable, bool) {
+	method, ok := c.methods[name]
+	return method, ok
+}
+
+func (c *Class) String() string {
+	return fmt.Sprintf("<class %s>", c.name)
+}
+
+// Instance is an object made by calling a class. Its properties are its
+// fields, set by assigning to them, and the methods of its class, bound to
+// it.
+type Instance struct {
+	class  *Class
+	fields map[string]any
+}
+
+// NewInstance creates an instance of class without fields.
+func NewInstance(class *Class) *Instance {
+	return &Instance{class: class, fields: make(map[string]any)}
+}
+
+func (i *Instance) Class() *Class {
+	return i.class
+}
+
+func (i *Instance) Property(name string) (any, bool) {
+	if v, ok := i.fields[name]; ok {
+		return v, true
+	}
+	if method, ok := i.class.methods[name]; ok {
+		return &BoundMethod{receiver: i, method: method}, true
+	}
+	return nil, false
+}
+
+// SetField sets the field name of i to v.
+func (i *Instance) SetField(name string, v any) {
+	i.fields[name] = v
+}
+
+func (i *Instance) String() string {
+	return fmt.Sprintf("<%s instance>", i.class.name)
+}
+
+// BoundMethod is a method read from an instance, which calling calls with
+// the instance as "this".
+type BoundMethod struct {
+	receiver *Instance
+	method   Callable
+}
+
+// NewBoundMethod binds method to receiver.
+func NewBoundMethod(receiver *Instance, method Callable) *BoundMethod {
+	return &BoundMethod{receiver: receiver, method: method}
+}
+
+func (m *BoundMethod) Receiver() *Instance {
+	return m.receiver
+}
+
+func (m *BoundMethod) Method() Callable {
+	return m.method
+}
+
+func (m *BoundMethod) Arity() int {
+	return m.method.Arity()
+}
+
+func (m *BoundMethod) Name() string {
+	return m.method.Name()
+}
+
+func (m *BoundMethod) String() string {
+	return fmt.Sprintf("<fn %s>", m.method.Name())
+}
+
+// SetProperty assigns v to the property name of object, which must be an
+// instance.
+func SetProperty(object any, name string, v any) error {
+	instance, ok := object.(*Instance)
+	if !ok {
+		return fmt.Errorf("only instances have fields, got %s", TypeName(object))
+	}
+	instance.SetField(name, v)
+	return nil
+}
@@ -0,0 +1,235 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// List is a list of values, made by a list literal "[a, b]". Lists are
+// mutable and compare equal only to themselves.
+type List struct {
+	elements []any
+}
+
+// NewList creates a list holding elements, which it keeps.
+func NewList(elements []any) *List {
+	return &List{elements: elements}
+}
+
+// Elements returns the elements of l. Changing them changes l.
+func (l *List) Elements() []any {
+	return l.elements
+}
+
+// Append adds v to the end of l.
+func (l *List) Append(v any) {
+	l.elements = append(l.elements, v)
+}
+
+func (l *List) Iterator() Iterator {
+	return &listIterator{l: l}
+}
+
+func (l *List) String() string {
+	return l.format(make(map[any]bool))
+}
+
+// format writes l, printing "[...]" for l itself if it contains itself,
+// and the lists and maps in printing the same way.
+func (l *List) format(printing map[any]bool) string {
+	if printing[l] {
+		return "[...]"
+	}
+	printing[l] = true
+	defer delete(printing, l)
+	parts := make([]string, len(l.elements))
+	for i, element := range l.elements {
+		parts[i] = stringifyIn(element, printing)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// stringifyIn is Stringify for a value contained in the lists and maps
+// being printed.
+func stringifyIn(v any, printing map[any]bool) string {
+	switch v := v.(type) {
+	case *List:
+		return v.format(printing)
+	case *Map:
+		return v.format(printing)
+	}
+	return Stringify(v)
+}
+
+// listIterator goes through the elements of a list, including those added
+// while it does.
+type listIterator struct {
+	l     *List
+	index int
+}
+
+func (it *listIterator) Next() (any, bool, error) {
+	if it.index >= len(it.l.elements) {
+		return nil, false, nil
+	}
+	v := it.l.elements[it.index]
+	it.index++
+	return v, true, nil
+}
+
+// element returns the position of the element index refers to in l.
+func (l *List) element(index any) (int, error) {
+	n, ok := index.(float64)
+	if !ok || n != math.Trunc(n) {
+		return 0, fmt.Errorf("list index must be an integer, got %s", Stringify(index))
+	}
+	if n < 0 || n >= float64(len(l.elements)) {
+		return 0, fmt.Errorf("list index %s out of range for length %d", Stringify(n), len(l.elements))
+	}
+	return int(n), nil
+}
+
+// Map maps keys, which are nil, booleans, numbers or strings, to values. It
+// is made by a map literal "{name: value}" and keeps its keys in the order
+// they were added. Its entries with a string key are its properties, so
+// "m.name" reads the entry "name".
+type Map struct {
+	keys    []any
+	entries map[any]any
+}
+
+// NewMap creates an empty map.
+func NewMap() *Map {
+	return &Map{entries: make(map[any]any)}
+}
+
+// Get returns the entry of key, and whether m has one.
+func (m *Map) Get(key any) (any, bool) {
+	v, ok := m.entries[key]
+	return v, ok
+}
+
+// Set sets the entry of key to v, adding the key after the others if m
+// doesn't have it yet. NaN isn't a key, as it equals nothing.
+func (m *Map) Set(key, v any) error {
+	switch key := key.(type) {
+	case nil, bool, string:
+	case float64:
+		if math.IsNaN(key) {
+			return errors.New("map keys can't be NaN")
+		}
+	default:
+		return fmt.Errorf("map keys must be nil, booleans, numbers or strings, got %s", TypeName(key))
+	}
+	if _, ok := m.entries[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.entries[key] = v
+	return nil
+}
+
+// Keys returns the keys of m in the order they were added.
+func (m *Map) Keys() []any {
+	return m.keys
+}
+
+func (m *Map) Property(name string) (any, bool) {
+	return m.Get(name)
+}
+
+// Iterator goes through the keys of m, in the order they were added.
+func (m *Map) Iterator() Iterator {
+	return &mapIterator{m: m}
+}
+
+func (m *Map) String() string {
+	return m.format(make(map[any]bool))
+}
+
+// format writes m as List.format does, printing "{...}" for m itself.
+func (m *Map) format(printing map[any]bool) string {
+	if printing[m] {
+		return "{...}"
+	}
+	printing[m] = true
+	defer delete(printing, m)
+	parts := make([]string, len(m.keys))
+	for i, key := range m.keys {
+		parts[i] = Stringify(key) + ": " + stringifyIn(m.entries[key], printing)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+type mapIterator struct {
+	m     *Map
+	index int
+}
+
+func (it *mapIterator) Next() (any, bool, error) {
+	if it.index >= len(it.m.keys) {
+		return nil, false, nil
+	}
+	key := it.m.keys[it.index]
+	it.index++
+	return key, true, nil
+}
+
+// Index reads "object[index]": the element of a list at an integer index,
+// or the entry of a map, which is nil when the map has none.
+func Index(object, index any) (any, error) {
+	switch object := object.(type) {
+	case *List:
+		i, err := object.element(index)
+		if err != nil {
+			return nil, err
+		}
+		return object.elements[i], nil
+	case *Map:
+		v, _ := object.Get(index)
+		return v, nil
+	}
+	return nil, fmt.Errorf("can only index lists and maps, got %s", TypeName(object))
+}
+
+// SetIndex assigns v to "object[index]": the element of a list at an
+// existing index, or the entry of a map.
+func SetIndex(object, index, v any) error {
+	switch object := object.(type) {
+	case *List:
+		i, err := object.element(index)
+		if err != nil {
+			return err
+		}
+		object.elements[i] = v
+		return nil
+	case *Map:
+		return object.Set(index, v)
+	}
+	return fmt.Errorf("can only index lists and maps, got %s", TypeName(object))
+}
+
+// lenBuiltin is the len builtin, the number of characters of a string, of
+// elements of a list or of entries of a map.
+func lenBuiltin(args []any) (any, error) {
+	switch v := args[0].(type) {
+	case string:
+		return float64(len([]rune(v))), nil
+	case *List:
+		return float64(len(v.elements)), nil
+	case *Map:
+		return float64(len(v.keys)), nil
+	}
+	return nil, fmt.Errorf("len expects a string, list or map, got %s", TypeName(args[0]))
+}
+
+// pushBuiltin is the push builtin, adding a value to the end of a list.
+func pushBuiltin(args []any) (any, error) {
+	list, ok := args[0].(*List)
+	if !ok {
+		return nil, fmt.Errorf("push expects a list, got %s", TypeName(args[0]))
+	}
+	list.Append(args[1])
+	return nil, nil
+}
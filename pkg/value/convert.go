@@ -0,0 +1,290 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var (
+	anyType   = reflect.TypeOf((*any)(nil)).Elem()
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	sliceType = reflect.TypeOf([]any(nil))
+	mapType   = reflect.TypeOf(map[any]any(nil))
+)
+
+// FromGo converts a Go value to a rottenlang value: numeric types become
+// numbers, functions become natives, slices and arrays become lists, maps
+// become maps, and values that already are rottenlang values, including
+// Objects and iterators, pass through.
+func FromGo(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, bool, float64, string, Callable, Object, Iterator, Iterable:
+		return v, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Func:
+		return NativeFromFunc("", v)
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		elements := make([]any, rv.Len())
+		for i := range elements {
+			element, err := FromGo(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return NewList(elements), nil
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		m := NewMap()
+		iter := rv.MapRange()
+		for iter.Next() {
+			key, err := FromGo(iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+			entry, err := FromGo(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			if err := m.Set(key, entry); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %T to a rottenlang value", v)
+}
+
+// ToGo converts the rottenlang value v to Go type t. Numbers convert to any
+// numeric type that holds them exactly, lists to slices and maps to maps
+// whose elements they convert to. As an any, a list becomes a []any and a
+// map a map[any]any; a *List or *Map target keeps the value itself. A list
+// or map containing itself can't be converted.
+func ToGo(v any, t reflect.Type) (reflect.Value, error) {
+	return toGo(v, t, nil)
+}
+
+// toGo is ToGo, with the lists and maps being converted in converting.
+func toGo(v any, t reflect.Type, converting map[any]bool) (reflect.Value, error) {
+	if t == anyType {
+		switch v.(type) {
+		case nil:
+			return reflect.Zero(t), nil
+		case *List:
+			t = sliceType
+		case *Map:
+			t = mapType
+		default:
+			return reflect.ValueOf(v), nil
+		}
+	}
+
+	mismatch := fmt.Errorf("cannot use %s as %s", TypeName(v), t)
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := v.(bool)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		return reflect.ValueOf(b).Convert(t), nil
+	case reflect.String:
+		s, ok := v.(string)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		return reflect.ValueOf(s).Convert(t), nil
+	case reflect.Float32, reflect.Float64:
+		x, ok := v.(float64)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		return reflect.ValueOf(x).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, ok := v.(float64)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		rv := reflect.New(t).Elem()
+		n, ok := integer(x)
+		if !ok || rv.OverflowInt(n) {
+			return reflect.Value{}, fmt.Errorf("number %s doesn't fit in %s", Stringify(x), t)
+		}
+		rv.SetInt(n)
+		return rv, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, ok := v.(float64)
+		if !ok {
+			return reflect.Value{}, mismatch
+		}
+		rv := reflect.New(t).Elem()
+		// 1<<64 is the first float64 past math.MaxUint64; NaN fails the
+		// x == math.Trunc(x) check.
+		if x < 0 || x != math.Trunc(x) || x >= 1<<64 || rv.OverflowUint(uint64(x)) {
+			return reflect.Value{}, fmt.Errorf("number %s doesn't fit in %s", Stringify(x), t)
+		}
+		rv.SetUint(uint64(x))
+		return rv, nil
+	}
+
+	switch v := v.(type) {
+	case *List:
+		if t.Kind() != reflect.Slice {
+			break
+		}
+		if converting[v] {
+			return reflect.Value{}, errors.New("cannot convert a list containing itself")
+		}
+		converting = track(converting, v)
+		defer delete(converting, v)
+		rv := reflect.MakeSlice(t, len(v.elements), len(v.elements))
+		for i, element := range v.elements {
+			converted, err := toGo(element, t.Elem(), converting)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			rv.Index(i).Set(converted)
+		}
+		return rv, nil
+	case *Map:
+		if t.Kind() != reflect.Map {
+			break
+		}
+		if converting[v] {
+			return reflect.Value{}, errors.New("cannot convert a map containing itself")
+		}
+		converting = track(converting, v)
+		defer delete(converting, v)
+		rv := reflect.MakeMapWithSize(t, len(v.keys))
+		for _, key := range v.keys {
+			k, err := toGo(key, t.Key(), converting)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %s: %w", Stringify(key), err)
+			}
+			entry, err := toGo(v.entries[key], t.Elem(), converting)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("entry %s: %w", Stringify(key), err)
+			}
+			rv.SetMapIndex(k, entry)
+		}
+		return rv, nil
+	}
+
+	if v == nil {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Func, reflect.Map, reflect.Slice:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, mismatch
+	}
+	if rv := reflect.ValueOf(v); rv.Type().AssignableTo(t) {
+		return rv, nil
+	}
+	return reflect.Value{}, mismatch
+}
+
+// track adds v to converting, which it makes if needed.
+func track(converting map[any]bool, v any) map[any]bool {
+	if converting == nil {
+		converting = make(map[any]bool)
+	}
+	converting[v] = true
+	return converting
+}
+
+// NativeFromFunc wraps fn, a Go function, as a native function. Arguments
+// are converted with ToGo and results with FromGo. fn may return nothing, a
+// value, an error, or a value and an error; a non-nil error becomes a
+// runtime error. Variadic functions accept any number of trailing
+// arguments.
+func NativeFromFunc(name string, fn any) (*Native, error) {
+	fv := reflect.ValueOf(fn)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%T is not a function", fn)
+	}
+
+	switch ft.NumOut() {
+	case 0, 1:
+	case 2:
+		if ft.Out(1) != errorType {
+			return nil, errors.New("the second result of a native function must be an error")
+		}
+	default:
+		return nil, errors.New("native functions return at most a value and an error")
+	}
+
+	arity := ft.NumIn()
+	if ft.IsVariadic() {
+		arity = -1
+	}
+
+	return NewNative(name, arity, func(args []any) (any, error) {
+		in, err := goArguments(ft, args)
+		if err != nil {
+			return nil, err
+		}
+
+		out := fv.Call(in)
+		if len(out) == 0 {
+			return nil, nil
+		}
+		if last := out[len(out)-1]; last.Type() == errorType {
+			if !last.IsNil() {
+				return nil, last.Interface().(error)
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return nil, nil
+		}
+		return FromGo(out[0].Interface())
+	}), nil
+}
+
+func goArguments(ft reflect.Type, args []any) ([]reflect.Value, error) {
+	fixed := ft.NumIn()
+	if ft.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return nil, fmt.Errorf("expected at least %d arguments but got %d", fixed, len(args))
+		}
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		t := ft.In(min(i, ft.NumIn()-1))
+		if ft.IsVariadic() && i >= fixed {
+			t = ft.In(fixed).Elem()
+		}
+		v, err := ToGo(arg, t)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %w", i+1, err)
+		}
+		in[i] = v
+	}
+	return in, nil
+}
@@ -0,0 +1,150 @@
+package value
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuntimeError is an error raised while executing a program, positioned at
+// the source location that caused it.
+type RuntimeError struct {
+	Line, Column int
+	Message      string
+	// Err is the error that caused it, if any, such as a *LimitError, or
+	// the *Error thrown by a throw statement
+	Err error
+	// Stack is the call stack where the error was raised, innermost call
+	// first, ending with the top level code
+	Stack []StackFrame
+}
+
+// StackFrame is a call in progress: Line and Column locate what the
+// function was executing.
+type StackFrame struct {
+	Function     string
+	Line, Column int
+}
+
+func (frame StackFrame) String() string {
+	return fmt.Sprintf("at %s (line %d, column %d)", frame.Function, frame.Line, frame.Column)
+}
+
+func NewRuntimeError(line, column int, message string) *RuntimeError {
+	return &RuntimeError{
+		Line:    line,
+		Column:  column,
+		Message: message,
+	}
+}
+
+// WrapRuntimeError creates a runtime error caused by err.
+func WrapRuntimeError(line, column int, err error) *RuntimeError {
+	return &RuntimeError{
+		Line:    line,
+		Column:  column,
+		Message: err.Error(),
+		Err:     err,
+	}
+}
+
+func (err *RuntimeError) Error() string {
+	return fmt.Sprintf("runtime error at line %d, column %d: %s", err.Line, err.Column, err.Message)
+}
+
+func (err *RuntimeError) Unwrap() error {
+	return err.Err
+}
+
+// Trace formats the stack of an error raised inside a function, one
+// indented line per call starting with a newline, so that it can follow the
+// message. Errors raised by top level code have no trace.
+func (err *RuntimeError) Trace() string {
+	if len(err.Stack) < 2 {
+		return ""
+	}
+	var b strings.Builder
+	for _, frame := range err.Stack {
+		b.WriteString("\n  " + frame.String())
+	}
+	return b.String()
+}
+
+// ErrorClass is the class of errors thrown without one, such as by
+// "throw" with a value that isn't an error.
+const ErrorClass = "Error"
+
+// RuntimeErrorClass is the class of the errors raised by the language
+// itself, such as type errors, when they are caught.
+const RuntimeErrorClass = "RuntimeError"
+
+// Error is an error value, made by the error builtin or by catching an
+// error. Its properties are message, class and, once it has been raised,
+// stack.
+type Error struct {
+	class   string
+	message string
+	// raised is the runtime error raising it, which is raised again when
+	// the error is thrown again
+	raised *RuntimeError
+}
+
+func NewError(class, message string) *Error {
+	return &Error{
+		class:   class,
+		message: message,
+	}
+}
+
+// Raise returns the runtime error raising e at a location with stack, or
+// the one that raised it first.
+func (e *Error) Raise(line, column int, stack []StackFrame) *RuntimeError {
+	if e.raised == nil {
+		e.raised = &RuntimeError{
+			Line:    line,
+			Column:  column,
+			Message: e.Error(),
+			Err:     e,
+			Stack:   stack,
+		}
+	}
+	return e.raised
+}
+
+// Caught returns the error value handed to a catch clause for err.
+func Caught(err *RuntimeError) *Error {
+	if e, ok := err.Err.(*Error); ok {
+		return e
+	}
+	return &Error{
+		class:   RuntimeErrorClass,
+		message: err.Message,
+		raised:  err,
+	}
+}
+
+func (e *Error) Property(name string) (any, bool) {
+	switch name {
+	case "message":
+		return e.message, true
+	case "class":
+		return e.class, true
+	case "stack":
+		if e.raised == nil {
+			return nil, true
+		}
+		lines := make([]string, len(e.raised.Stack))
+		for i, frame := range e.raised.Stack {
+			lines[i] = frame.String()
+		}
+		return strings.Join(lines, "\n"), true
+	}
+	return nil, false
+}
+
+func (e *Error) Error() string {
+	return e.class + ": " + e.message
+}
+
+func (e *Error) String() string {
+	return e.Error()
+}
@@ -0,0 +1,164 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ErrGeneratorClosed is returned to the yield of a generator closed while
+// suspended, to unwind its body without running any more of its code.
+var ErrGeneratorClosed = errors.New("generator closed")
+
+// Generator is the value of a call of a generator function, a function
+// whose body yields. The body runs a piece at a time: each call of the
+// method next resumes it until it yields the next value, which next
+// returns, and next returns nil once the body has ended. Its property done
+// reports whether it has.
+//
+// The body runs on a goroutine of its own, which only runs while the code
+// resuming it waits, so the state of a program is never touched by two
+// goroutines at once. A generator dropped before its body ends is closed
+// once it is garbage collected.
+type Generator struct {
+	*generator
+}
+
+type generator struct {
+	name string
+	// body runs the body, calling yield for each value; it returns the
+	// error ending the body
+	body func(yield func(v any) error) error
+	// resume hands the baton to the body, and yielded hands it back
+	resume  chan struct{}
+	yielded chan struct{}
+	started bool
+	running bool
+	done    bool
+	closing bool
+	value   any
+	err     error
+}
+
+// NewGenerator creates the generator of a call of the generator function
+// name, whose body runs when it is first resumed.
+func NewGenerator(name string, body func(yield func(v any) error) error) *Generator {
+	g := &Generator{&generator{
+		name:    name,
+		body:    body,
+		resume:  make(chan struct{}),
+		yielded: make(chan struct{}),
+	}}
+	runtime.SetFinalizer(g, func(g *Generator) {
+		g.Close()
+	})
+	return g
+}
+
+// ResumeError is the error ending the body of a generator, returned to the
+// code resuming it. The stack of Err is the body's, which engines complete
+// with the stack of the resuming code, using Raise.
+type ResumeError struct {
+	Err *RuntimeError
+}
+
+func (err *ResumeError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *ResumeError) Unwrap() error {
+	return err.Err
+}
+
+// Raise returns Err with stack, the calls resuming the generator, added
+// below the calls of the body.
+func (err *ResumeError) Raise(stack []StackFrame) *RuntimeError {
+	raised := *err.Err
+	raised.Stack = append(append([]StackFrame(nil), err.Err.Stack...), stack...)
+	return &raised
+}
+
+func (g *Generator) Property(name string) (any, bool) {
+	switch name {
+	case "next":
+		return NewNative("next", 0, func(args []any) (any, error) {
+			v, _, err := g.Next()
+			return v, err
+		}), true
+	case "done":
+		return g.done, true
+	}
+	return nil, false
+}
+
+// Next resumes the body until it yields, returning the value yielded, or
+// false once the body has ended, with the error ending it.
+func (g *generator) Next() (any, bool, error) {
+	if g.done {
+		return nil, false, nil
+	}
+	if g.running {
+		return nil, false, fmt.Errorf("generator %s is already running", g.name)
+	}
+	g.running = true
+	g.switchToBody()
+	g.running = false
+	if g.done {
+		err := g.err
+		g.err = nil
+		if runtimeErr, ok := err.(*RuntimeError); ok {
+			return nil, false, &ResumeError{Err: runtimeErr}
+		}
+		return nil, false, err
+	}
+	v := g.value
+	g.value = nil
+	return v, true, nil
+}
+
+// Close ends the body of a suspended generator, whose yield returns
+// ErrGeneratorClosed.
+func (g *generator) Close() {
+	if !g.started || g.done || g.running {
+		g.done = true
+		return
+	}
+	g.closing = true
+	g.switchToBody()
+}
+
+// switchToBody runs the body until it yields or ends, starting it if it
+// hasn't run yet.
+func (g *generator) switchToBody() {
+	if !g.started {
+		g.started = true
+		go g.run()
+	} else {
+		g.resume <- struct{}{}
+	}
+	<-g.yielded
+}
+
+// run is the goroutine of the body.
+func (g *generator) run() {
+	err := g.body(g.yield)
+	g.done = true
+	if !errors.Is(err, ErrGeneratorClosed) {
+		g.err = err
+	}
+	g.yielded <- struct{}{}
+}
+
+func (g *generator) yield(v any) error {
+	g.value = v
+	g.yielded <- struct{}{}
+	<-g.resume
+	if g.closing {
+		return ErrGeneratorClosed
+	}
+	return nil
+}
+
+func (g *Generator) String() string {
+	return fmt.Sprintf("<generator %s>", g.name)
+}
@@ -0,0 +1,169 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// Iterator produces the values a for-in loop goes through.
+type Iterator interface {
+	// Next returns the next value, and false once there are none left.
+	Next() (any, bool, error)
+}
+
+// Iterable is a value a for-in loop can go through, each time with a new
+// Iterator.
+type Iterable interface {
+	Iterator() Iterator
+}
+
+// Iterate returns the iterator a for-in loop over v goes through. Besides
+// the values implementing Iterator or Iterable, such as generators, ranges
+// and channels, a loop goes through the characters of a string and through
+// the iterators of the program itself: a function returns the next value
+// each time it is called with no arguments, until it returns nil, and so
+// does the method next of an object. An object with a method iterator is
+// gone through by the iterator it returns. call calls the functions of the
+// program.
+func Iterate(v any, call func(callee any, args []any) (any, error)) (Iterator, error) {
+	switch v := v.(type) {
+	case Iterator:
+		return v, nil
+	case Iterable:
+		return v.Iterator(), nil
+	case string:
+		return &stringIterator{s: v}, nil
+	case Callable:
+		return &functionIterator{function: v, call: call}, nil
+	case Object:
+		if next, ok := v.Property("next"); ok {
+			return &functionIterator{function: next, call: call}, nil
+		}
+		if method, ok := v.Property("iterator"); ok {
+			it, err := call(method, nil)
+			if err != nil {
+				return nil, err
+			}
+			if object, ok := it.(Object); ok && !isIterator(it) {
+				if _, ok := object.Property("next"); !ok {
+					return nil, fmt.Errorf("method iterator must return an iterator, got %s", TypeName(it))
+				}
+			}
+			return Iterate(it, call)
+		}
+	}
+	return nil, fmt.Errorf("can't iterate over %s", TypeName(v))
+}
+
+// isIterator reports whether v is an Iterator or Iterable implemented in
+// Go.
+func isIterator(v any) bool {
+	switch v.(type) {
+	case Iterator, Iterable:
+		return true
+	}
+	return false
+}
+
+// stringIterator goes through the characters of a string.
+type stringIterator struct {
+	s      string
+	offset int
+}
+
+func (it *stringIterator) Next() (any, bool, error) {
+	if it.offset >= len(it.s) {
+		return nil, false, nil
+	}
+	_, size := utf8.DecodeRuneInString(it.s[it.offset:])
+	c := it.s[it.offset : it.offset+size]
+	it.offset += size
+	return c, true, nil
+}
+
+// functionIterator calls function for each value, until it returns nil.
+type functionIterator struct {
+	function any
+	call     func(callee any, args []any) (any, error)
+}
+
+func (it *functionIterator) Next() (any, bool, error) {
+	v, err := it.call(it.function, nil)
+	if err != nil || v == nil {
+		return nil, false, err
+	}
+	return v, true, nil
+}
+
+// Range is the sequence of numbers made by the range builtin, from start
+// up to but not including end, step apart. It doesn't hold the numbers, so
+// it can be as long as needed.
+type Range struct {
+	start, end, step float64
+}
+
+// NewRange creates the range from start to end with step, which must not be
+// zero.
+func NewRange(start, end, step float64) (*Range, error) {
+	if step == 0 {
+		return nil, errors.New("range step can't be 0")
+	}
+	return &Range{start: start, end: end, step: step}, nil
+}
+
+// count returns the number of numbers in r, which is infinite when its
+// end is.
+func (r *Range) count() float64 {
+	n := math.Ceil((r.end - r.start) / r.step)
+	if n <= 0 || math.IsNaN(n) {
+		return 0
+	}
+	return n
+}
+
+func (r *Range) Iterator() Iterator {
+	return &rangeIterator{r: r, count: r.count()}
+}
+
+func (r *Range) String() string {
+	return fmt.Sprintf("<range %s..%s step %s>", Stringify(r.start), Stringify(r.end), Stringify(r.step))
+}
+
+// rangeIterator computes each number of a range from its index, so that
+// long ranges don't pile up rounding errors.
+type rangeIterator struct {
+	r     *Range
+	index float64
+	count float64
+}
+
+func (it *rangeIterator) Next() (any, bool, error) {
+	if it.index >= it.count {
+		return nil, false, nil
+	}
+	v := it.r.start + it.index*it.r.step
+	it.index++
+	return v, true, nil
+}
+
+// rangeBuiltin is the range builtin: range(end), range(start, end) or
+// range(start, end, step).
+func rangeBuiltin(args []any) (any, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, fmt.Errorf("range expects 1 to 3 arguments but got %d", len(args))
+	}
+	bounds := []float64{0, 0, 1}
+	for i, arg := range args {
+		n, ok := arg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("range bounds must be numbers, got %s", TypeName(arg))
+		}
+		bounds[i] = n
+	}
+	if len(args) == 1 {
+		bounds[0], bounds[1] = 0, bounds[0]
+	}
+	return NewRange(bounds[0], bounds[1], bounds[2])
+}
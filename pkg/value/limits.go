@@ -0,0 +1,179 @@
+package value
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded matches, with errors.Is, the error of a program stopped
+// for exceeding one of its Limits.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// ErrExecutionCanceled matches, with errors.Is, the error of a program
+// stopped because its context is done. The error matches the context's
+// error too.
+var ErrExecutionCanceled = errors.New("execution canceled")
+
+// cancelCheckInterval is how many steps run between checks of the context,
+// so that polling it stays cheap.
+const cancelCheckInterval = 1024
+
+// Limits bounds the resources a program may use. The zero value sets no
+// limits besides the MaxCallDepth every program is subject to. A program
+// can catch the error of exceeding a limit, like any runtime error.
+type Limits struct {
+	// MaxSteps bounds the statements and expressions the tree interpreter
+	// evaluates, or the instructions the vm executes.
+	MaxSteps int64
+	// MaxCallDepth bounds the number of nested calls. It can only lower the
+	// package level MaxCallDepth.
+	MaxCallDepth int
+	// MaxStringLength bounds the length, in bytes, of the strings a program
+	// builds.
+	MaxStringLength int
+	// MaxListLength bounds the number of elements of the lists, and of
+	// entries of the maps, a program builds or grows.
+	MaxListLength int
+}
+
+// LimitError is the error of a program that exceeded one of its Limits.
+type LimitError struct {
+	// Limit names the limit, e.g. "step"
+	Limit string
+	Max   int64
+}
+
+func (err *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded", err.Limit, err.Max)
+}
+
+func (err *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// Meter enforces Limits and context cancellation while a program runs.
+// Engines call Step for every unit of work they do.
+type Meter struct {
+	limits Limits
+	ctx    context.Context
+	steps  int64
+}
+
+func (m *Meter) SetLimits(limits Limits) {
+	m.limits = limits
+}
+
+func (m *Meter) Limits() Limits {
+	return m.limits
+}
+
+// SetContext makes Step fail once ctx is done. A nil ctx is never done.
+func (m *Meter) SetContext(ctx context.Context) {
+	m.ctx = ctx
+}
+
+// Reset starts counting steps from zero, for the next program.
+func (m *Meter) Reset() {
+	m.steps = 0
+}
+
+// Steps returns the number of steps taken since the last Reset.
+func (m *Meter) Steps() int64 {
+	return m.steps
+}
+
+// Step records a step. It returns a *LimitError once there were too many,
+// or the context's error once it is done.
+func (m *Meter) Step() error {
+	m.steps++
+	if m.limits.MaxSteps > 0 && m.steps > m.limits.MaxSteps {
+		return &LimitError{Limit: "step", Max: m.limits.MaxSteps}
+	}
+	if m.ctx != nil && m.steps%cancelCheckInterval == 1 {
+		select {
+		case <-m.ctx.Done():
+			return fmt.Errorf("%w: %w", ErrExecutionCanceled, m.ctx.Err())
+		default:
+		}
+	}
+	return nil
+}
+
+// MaxCallDepth returns the number of nested calls allowed.
+func (m *Meter) MaxCallDepth() int {
+	if m.limits.MaxCallDepth > 0 && m.limits.MaxCallDepth < MaxCallDepth {
+		return m.limits.MaxCallDepth
+	}
+	return MaxCallDepth
+}
+
+// CheckCallDepth returns an error when a call at depth, the number of calls
+// already active, would exceed the allowed nesting.
+func (m *Meter) CheckCallDepth(depth int) error {
+	if depth < m.MaxCallDepth() {
+		return nil
+	}
+	if m.MaxCallDepth() < MaxCallDepth {
+		return &LimitError{Limit: "call depth", Max: int64(m.limits.MaxCallDepth)}
+	}
+	return errors.New("stack overflow")
+}
+
+// CheckValue returns a *LimitError when v is a string, list or map longer
+// than allowed.
+func (m *Meter) CheckValue(v any) error {
+	if s, ok := v.(string); ok {
+		if m.limits.MaxStringLength > 0 && len(s) > m.limits.MaxStringLength {
+			return &LimitError{Limit: "string length", Max: int64(m.limits.MaxStringLength)}
+		}
+		return nil
+	}
+	return m.checkLength(length(v))
+}
+
+// Lengths records the lengths of the lists and maps among values, for
+// CheckGrown. It returns nil when their length has no limit.
+func (m *Meter) Lengths(values []any) []int {
+	if m.limits.MaxListLength <= 0 {
+		return nil
+	}
+	lengths := make([]int, len(values))
+	for n, v := range values {
+		lengths[n] = length(v)
+	}
+	return lengths
+}
+
+// CheckGrown returns a *LimitError when one of values, a list or map that
+// grew since Lengths recorded lengths, is longer than allowed. A list left
+// too long fails again only when it grows again.
+func (m *Meter) CheckGrown(values []any, lengths []int) error {
+	for n, before := range lengths {
+		if after := length(values[n]); after > before {
+			if err := m.checkLength(after); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Meter) checkLength(length int) error {
+	if m.limits.MaxListLength > 0 && length > m.limits.MaxListLength {
+		return &LimitError{Limit: "list length", Max: int64(m.limits.MaxListLength)}
+	}
+	return nil
+}
+
+// length returns the number of elements of a list or entries of a map, and
+// 0 for other values.
+func length(v any) int {
+	switch v := v.(type) {
+	case *List:
+		return len(v.elements)
+	case *Map:
+		return len(v.keys)
+	}
+	return 0
+}
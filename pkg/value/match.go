@@ -0,0 +1,72 @@
+package value
+
+import (
+	"fmt"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+// Match reports whether v matches pattern. When it does, the values bound
+// by the pattern are returned in the order of ast.PatternBindings.
+func Match(pattern ast.Pattern, v any) ([]any, bool) {
+	var bound []any
+	if !match(pattern, v, &bound) {
+		return nil, false
+	}
+	return bound, true
+}
+
+func match(pattern ast.Pattern, v any, bound *[]any) bool {
+	switch pattern := pattern.(type) {
+	case *ast.LiteralPattern:
+		return Equal(pattern.Value(), v)
+	case *ast.WildcardPattern:
+		return true
+	case *ast.BindingPattern:
+		*bound = append(*bound, v)
+		return true
+	case *ast.ObjectPattern:
+		object, ok := v.(Object)
+		return ok && matchProperties(pattern.Names(), pattern.Patterns(), object, bound)
+	case *ast.ClassPattern:
+		instance, ok := v.(*Instance)
+		return ok && instance.class.name == *pattern.Name().Lexeme &&
+			matchProperties(pattern.Names(), pattern.Patterns(), instance, bound)
+	case *ast.ListPattern:
+		list, ok := v.(*List)
+		if !ok {
+			return false
+		}
+		n := len(pattern.Patterns())
+		if len(list.elements) < n || pattern.Rest() == nil && len(list.elements) != n {
+			return false
+		}
+		for i, element := range pattern.Patterns() {
+			if !match(element, list.elements[i], bound) {
+				return false
+			}
+		}
+		if rest := pattern.Rest(); rest != nil && *rest.Lexeme != "_" {
+			*bound = append(*bound, NewList(append([]any(nil), list.elements[n:]...)))
+		}
+		return true
+	}
+	panic(fmt.Sprintf("value.Match: unexpected pattern %T", pattern))
+}
+
+// matchProperties matches the property of object called each of names with
+// the pattern at the same index of patterns.
+func matchProperties(names []*ast.Token, patterns []ast.Pattern, object Object, bound *[]any) bool {
+	for i, name := range names {
+		property, ok := object.Property(*name.Lexeme)
+		if !ok || !match(patterns[i], property, bound) {
+			return false
+		}
+	}
+	return true
+}
+
+// NoMatch is the error of a match expression none of whose arms matches v.
+func NoMatch(v any) error {
+	return fmt.Errorf("no match arm matches %s", Stringify(v))
+}
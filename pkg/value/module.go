@@ -0,0 +1,55 @@
+package value
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Importer loads the modules of import statements. Engines call it with
+// the path as written in the statement.
+type Importer interface {
+	Import(path string) (*Module, error)
+}
+
+// Module is an imported module. Its exports, the top-level names of the
+// module not starting with "_", are read as properties. They are looked up
+// when read, so they reflect later assignments made by the module.
+type Module struct {
+	name    string
+	exports map[string]bool
+	lookup  func(name string) (any, bool)
+}
+
+// NewModule creates a module called name, exporting the names exports
+// whose values are given by lookup.
+func NewModule(name string, exports []string, lookup func(name string) (any, bool)) *Module {
+	m := &Module{
+		name:    name,
+		exports: make(map[string]bool, len(exports)),
+		lookup:  lookup,
+	}
+	for _, export := range exports {
+		m.exports[export] = true
+	}
+	return m
+}
+
+// IsExported reports whether a top-level name of a module is exported.
+func IsExported(name string) bool {
+	return !strings.HasPrefix(name, "_")
+}
+
+func (m *Module) Name() string {
+	return m.name
+}
+
+func (m *Module) Property(name string) (any, bool) {
+	if !m.exports[name] {
+		return nil, false
+	}
+	return m.lookup(name)
+}
+
+func (m *Module) String() string {
+	return fmt.Sprintf("<module %s>", m.name)
+}
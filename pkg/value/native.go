@@ -0,0 +1,80 @@
+package value
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// MaxCallDepth bounds the number of nested calls a program may make before
+// failing with a stack overflow.
+const MaxCallDepth = 256
+
+// Native is a function implemented in Go.
+type Native struct {
+	name  string
+	arity int
+	fn    func(args []any) (any, error)
+}
+
+// NewNative creates a native function. Pass -1 as arity to accept any number
+// of arguments.
+func NewNative(name string, arity int, fn func(args []any) (any, error)) *Native {
+	return &Native{
+		name:  name,
+		arity: arity,
+		fn:    fn,
+	}
+}
+
+func (n *Native) Name() string {
+	return n.name
+}
+
+func (n *Native) Arity() int {
+	return n.arity
+}
+
+func (n *Native) Call(args []any) (any, error) {
+	return n.fn(args)
+}
+
+func (n *Native) String() string {
+	return fmt.Sprintf("<native fn %s>", n.name)
+}
+
+// Builtins returns the native functions available to every program. print
+// writes to out.
+func Builtins(out io.Writer) []*Native {
+	return []*Native{
+		NewNative("print", -1, func(args []any) (any, error) {
+			parts := make([]string, len(args))
+			for i, arg := range args {
+				parts[i] = Stringify(arg)
+			}
+			_, err := fmt.Fprintln(out, strings.Join(parts, " "))
+			return nil, err
+		}),
+		NewNative("clock", 0, func(args []any) (any, error) {
+			return float64(time.Now().UnixNano()) / float64(time.Second), nil
+		}),
+		NewNative("error", -1, func(args []any) (any, error) {
+			if len(args) < 1 || len(args) > 2 {
+				return nil, fmt.Errorf("error expects 1 or 2 arguments but got %d", len(args))
+			}
+			class := ErrorClass
+			if len(args) == 2 {
+				name, ok := args[1].(string)
+				if !ok {
+					return nil, fmt.Errorf("error class must be a string, got %s", TypeName(args[1]))
+				}
+				class = name
+			}
+			return NewError(class, Stringify(args[0])), nil
+		}),
+		NewNative("range", -1, rangeBuiltin),
+		NewNative("len", 1, lenBuiltin),
+		NewNative("push", 2, pushBuiltin),
+	}
+}
@@ -0,0 +1,142 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+)
+
+var (
+	ErrOperandNumber   = errors.New("operand must be a number")
+	ErrOperandsNumber  = errors.New("operands must be numbers")
+	ErrOperandsAdd     = errors.New("operands must be two numbers or two strings")
+	ErrOperandInteger  = errors.New("operand must be an integer")
+	ErrOperandsInteger = errors.New("operands must be integers")
+	ErrNegativeShift   = errors.New("shift count must not be negative")
+)
+
+// Binary applies the arithmetic, bitwise, comparison or equality operator
+// op. Bitwise operators take numbers holding integers.
+func Binary(op ast.TokenType, a, b any) (any, error) {
+	switch op {
+	case ast.TokenEqualEqual:
+		return Equal(a, b), nil
+	case ast.TokenBangEqual:
+		return !Equal(a, b), nil
+	case ast.TokenPlus:
+		if a, ok := a.(string); ok {
+			if b, ok := b.(string); ok {
+				return a + b, nil
+			}
+		}
+		x, y, ok := numbers(a, b)
+		if !ok {
+			return nil, ErrOperandsAdd
+		}
+		return x + y, nil
+	}
+
+	x, y, ok := numbers(a, b)
+	if !ok {
+		return nil, ErrOperandsNumber
+	}
+	switch op {
+	case ast.TokenMinus:
+		return x - y, nil
+	case ast.TokenStar:
+		return x * y, nil
+	case ast.TokenSlash:
+		return x / y, nil
+	case ast.TokenPercent:
+		return math.Mod(x, y), nil
+	case ast.TokenStarStar:
+		return math.Pow(x, y), nil
+	case ast.TokenAmpersand, ast.TokenPipe, ast.TokenCaret, ast.TokenLessLess, ast.TokenGreaterGreater:
+		return bitwise(op, x, y)
+	case ast.TokenGreater:
+		return x > y, nil
+	case ast.TokenGreaterEqual:
+		return x >= y, nil
+	case ast.TokenLess:
+		return x < y, nil
+	case ast.TokenLessEqual:
+		return x <= y, nil
+	}
+	return nil, fmt.Errorf("unknown binary operator %d", op)
+}
+
+// Unary applies the prefix operator op.
+func Unary(op ast.TokenType, v any) (any, error) {
+	switch op {
+	case ast.TokenBang:
+		return !IsTruthy(v), nil
+	case ast.TokenMinus:
+		x, ok := v.(float64)
+		if !ok {
+			return nil, ErrOperandNumber
+		}
+		return -x, nil
+	case ast.TokenPlus:
+		x, ok := v.(float64)
+		if !ok {
+			return nil, ErrOperandNumber
+		}
+		return x, nil
+	case ast.TokenTilde:
+		x, ok := v.(float64)
+		if !ok {
+			return nil, ErrOperandNumber
+		}
+		n, ok := integer(x)
+		if !ok {
+			return nil, ErrOperandInteger
+		}
+		return float64(^n), nil
+	}
+	return nil, fmt.Errorf("unknown unary operator %d", op)
+}
+
+func bitwise(op ast.TokenType, x, y float64) (any, error) {
+	a, aok := integer(x)
+	b, bok := integer(y)
+	if !aok || !bok {
+		return nil, ErrOperandsInteger
+	}
+	switch op {
+	case ast.TokenAmpersand:
+		return float64(a & b), nil
+	case ast.TokenPipe:
+		return float64(a | b), nil
+	case ast.TokenCaret:
+		return float64(a ^ b), nil
+	}
+	if b < 0 {
+		return nil, ErrNegativeShift
+	}
+	if op == ast.TokenLessLess {
+		return float64(a << b), nil
+	}
+	return float64(a >> b), nil
+}
+
+// integer returns x as an int64 if it holds an integer in its range.
+func integer(x float64) (int64, bool) {
+	if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(x), true
+}
+
+func numbers(a, b any) (float64, float64, bool) {
+	x, ok := a.(float64)
+	if !ok {
+		return 0, 0, false
+	}
+	y, ok := b.(float64)
+	if !ok {
+		return 0, 0, false
+	}
+	return x, y, true
+}
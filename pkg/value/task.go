@@ -0,0 +1,373 @@
+package value
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDeadlock matches, with errors.Is, the error of a program whose tasks
+// are all blocked.
+var ErrDeadlock = errors.New("deadlock")
+
+// ErrTaskFailed matches, with errors.Is, the error of a program stopped by
+// an error a task didn't catch.
+var ErrTaskFailed = errors.New("task failed")
+
+// ErrCanceled is returned to the blocked operations of the tasks left when
+// a program stops.
+var ErrCanceled = errors.New("task canceled")
+
+// ErrTerminated is the error of a program terminated from outside, such as
+// by a debugger.
+var ErrTerminated = errors.New("program terminated")
+
+// Catchable reports whether a catch clause may handle err. The errors
+// stopping a program, such as deadlocks and cancellation, can't be caught,
+// and finally clauses don't run for them either. Neither can those
+// unwinding the body of a closed generator. Exceeded limits can be caught,
+// though a program past its step limit fails again on its next step.
+func Catchable(err error) bool {
+	return !errors.Is(err, ErrDeadlock) &&
+		!errors.Is(err, ErrTaskFailed) && !errors.Is(err, ErrCanceled) &&
+		!errors.Is(err, ErrGeneratorClosed) && !errors.Is(err, ErrTerminated) &&
+		!errors.Is(err, ErrExecutionCanceled)
+}
+
+// Scheduler runs the tasks of a program, started by spawn expressions.
+// Tasks are cooperative: one runs at a time, and it only gives way to the
+// others when it blocks, on a channel or waiting for a task, or ends. The
+// tasks waiting for their turn run in the order they became able to, so a
+// program's tasks interleave the same way every time it runs.
+//
+// Every task runs on a goroutine of its own, but the scheduler hands a
+// single baton from one to the next, so the state of a program is never
+// touched by two goroutines at once.
+type Scheduler struct {
+	// main is the task running the program itself
+	main    *Task
+	current *Task
+	// tasks are the spawned tasks that haven't ended, in the order they
+	// were spawned
+	tasks []*Task
+	// runnable are the tasks waiting for their turn, first in first out
+	runnable []*Task
+	nextID   int
+	// waiting is the selection of the main task waiting for every task to
+	// end, if it is
+	waiting *selection
+	// failure is the error stopping the program, handed to the main task
+	failure *RuntimeError
+}
+
+// NewScheduler creates the scheduler of a program. position returns the
+// source location the program itself is at, reported when it blocks.
+func NewScheduler(position func() (line, column int)) *Scheduler {
+	main := &Task{
+		name:     "main",
+		position: position,
+		wake:     make(chan struct{}, 1),
+		started:  true,
+	}
+	s := &Scheduler{main: main, current: main, nextID: 1}
+	main.scheduler = s
+	return s
+}
+
+// Builtins returns the native functions making channels.
+func (s *Scheduler) Builtins() []*Native {
+	return []*Native{
+		NewNative("channel", -1, func(args []any) (any, error) {
+			if len(args) > 1 {
+				return nil, fmt.Errorf("channel expects 0 or 1 arguments but got %d", len(args))
+			}
+			capacity := 0
+			if len(args) == 1 {
+				n, ok := args[0].(float64)
+				if !ok || n < 0 || n != float64(int(n)) {
+					return nil, fmt.Errorf("channel capacity must be a non-negative integer, got %s", Stringify(args[0]))
+				}
+				capacity = int(n)
+			}
+			return &Channel{scheduler: s, capacity: capacity}, nil
+		}),
+	}
+}
+
+// Task is a function running concurrently with the rest of the program,
+// the value of a spawn expression. Its property join waits for it to end
+// and returns what it returned.
+type Task struct {
+	scheduler *Scheduler
+	id        int
+	name      string
+	// fn runs the task, returning its result or the error ending it
+	fn       func() (any, error)
+	position func() (line, column int)
+	// wake hands the baton to the task
+	wake     chan struct{}
+	started  bool
+	done     bool
+	canceled bool
+	result   any
+	// blocked describes the operation the task is blocked in, if it is,
+	// located at line and column
+	blocked      string
+	line, column int
+	// joiners are the selections of the tasks waiting for this one to end
+	joiners []*selection
+}
+
+// Spawn creates a task running fn, named after the function it calls, and
+// puts it last in line to run. position returns the source location the
+// task is at, reported when it blocks.
+func (s *Scheduler) Spawn(name string, position func() (line, column int), fn func() (any, error)) *Task {
+	t := &Task{
+		scheduler: s,
+		id:        s.nextID,
+		name:      name,
+		fn:        fn,
+		position:  position,
+		wake:      make(chan struct{}, 1),
+	}
+	s.nextID++
+	s.tasks = append(s.tasks, t)
+	s.runnable = append(s.runnable, t)
+	return t
+}
+
+func (t *Task) Property(name string) (any, bool) {
+	switch name {
+	case "join":
+		return NewNative("join", 0, func(args []any) (any, error) {
+			return t.join()
+		}), true
+	case "done":
+		return t.done, true
+	}
+	return nil, false
+}
+
+// join blocks the current task until t ends, returning its result.
+func (t *Task) join() (any, error) {
+	s := t.scheduler
+	if t.done {
+		return t.result, nil
+	}
+	if t == s.current {
+		return nil, errors.New("a task can't join itself")
+	}
+	sel := &selection{task: s.current}
+	t.joiners = append(t.joiners, sel)
+	if err := s.block(sel, "joining "+t.label()); err != nil {
+		return nil, err
+	}
+	return sel.value, nil
+}
+
+func (t *Task) String() string {
+	return fmt.Sprintf("<task %s>", t.name)
+}
+
+// label names t in error messages.
+func (t *Task) label() string {
+	if t.id == 0 {
+		return "main task"
+	}
+	return fmt.Sprintf("task %d (%s)", t.id, t.name)
+}
+
+// selection is a blocked operation: a select, a single channel operation
+// or a join. The first of its cases to proceed fires it, making its task
+// runnable again.
+type selection struct {
+	task  *Task
+	fired bool
+	// index is the case that fired, and value what it received
+	index int
+	value any
+	// closed is set when the channel of the case that fired was closed
+	closed bool
+}
+
+// fire completes sel with case index, receiving v.
+func (s *Scheduler) fire(sel *selection, index int, v any) {
+	sel.fired = true
+	sel.index = index
+	sel.value = v
+	s.runnable = append(s.runnable, sel.task)
+}
+
+// block parks the current task in sel until another task fires it. It
+// returns the error stopping the program, if the task is woken for that
+// instead.
+func (s *Scheduler) block(sel *selection, operation string) error {
+	t := s.current
+	t.blocked = operation
+	t.line, t.column = t.position()
+	s.switchTo(s.next())
+	<-t.wake
+	// a task woken to stop leaves sel registered with the channels it
+	// waits for, to be skipped from now on
+	sel.fired = true
+	t.blocked = ""
+	if t.canceled {
+		return ErrCanceled
+	}
+	if t == s.main && s.failure != nil {
+		return s.failure
+	}
+	return nil
+}
+
+// next returns the task to run next: the first runnable one or, when there
+// is none, the main task, which fails with a deadlock.
+func (s *Scheduler) next() *Task {
+	if len(s.runnable) == 0 {
+		if s.failure == nil {
+			s.failure = s.deadlock()
+		}
+		return s.main
+	}
+	t := s.runnable[0]
+	s.runnable = s.runnable[1:]
+	return t
+}
+
+// switchTo hands the baton to t, starting it if it hasn't run yet.
+func (s *Scheduler) switchTo(t *Task) {
+	s.current = t
+	if !t.started {
+		t.started = true
+		go s.run(t)
+		return
+	}
+	t.wake <- struct{}{}
+}
+
+// run is the goroutine of the spawned task t.
+func (s *Scheduler) run(t *Task) {
+	result, err := t.fn()
+	t.done = true
+	t.result = result
+	for i, task := range s.tasks {
+		if task == t {
+			s.tasks = append(s.tasks[:i], s.tasks[i+1:]...)
+			break
+		}
+	}
+	if t.canceled {
+		// Stop is waiting for the task to end
+		s.switchTo(s.main)
+		return
+	}
+	if err != nil {
+		s.failure = s.taskFailed(t, err)
+		s.switchTo(s.main)
+		return
+	}
+
+	for _, sel := range t.joiners {
+		if !sel.fired {
+			s.fire(sel, 0, result)
+		}
+	}
+	t.joiners = nil
+	if len(s.tasks) == 0 && s.waiting != nil {
+		s.fire(s.waiting, 0, nil)
+		s.waiting = nil
+	}
+	s.switchTo(s.next())
+}
+
+// taskFailed returns the error stopping the program because t ended with
+// err.
+func (s *Scheduler) taskFailed(t *Task, err error) *RuntimeError {
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		runtimeErr = WrapRuntimeError(0, 0, err)
+	}
+	return &RuntimeError{
+		Line:    runtimeErr.Line,
+		Column:  runtimeErr.Column,
+		Message: fmt.Sprintf("%s failed: %s", t.label(), runtimeErr.Message),
+		Err:     fmt.Errorf("%w: %w", ErrTaskFailed, runtimeErr),
+		Stack:   runtimeErr.Stack,
+	}
+}
+
+// Wait runs the spawned tasks until every one has ended, once the program
+// itself has. It returns the error stopping the program when a task fails
+// or every task is blocked.
+func (s *Scheduler) Wait() *RuntimeError {
+	if len(s.tasks) == 0 {
+		return nil
+	}
+	s.waiting = &selection{task: s.main}
+	if err := s.block(s.waiting, ""); err != nil {
+		return err.(*RuntimeError)
+	}
+	return nil
+}
+
+// Stop ends the tasks left when a program stops with an error, so that the
+// next program starts with none. The blocked operations of the tasks that
+// started return ErrCanceled, which unwinds them without running any more
+// of their code.
+func (s *Scheduler) Stop() {
+	for len(s.tasks) > 0 {
+		t := s.tasks[0]
+		s.tasks = s.tasks[1:]
+		if !t.started {
+			continue
+		}
+		t.canceled = true
+		s.switchTo(t)
+		<-s.main.wake
+	}
+	s.current = s.main
+	s.runnable = nil
+	s.waiting = nil
+	s.failure = nil
+}
+
+// BlockedTask is a task blocked in a deadlock.
+type BlockedTask struct {
+	Task string
+	// Operation is what the task waits for, such as "receiving"
+	Operation    string
+	Line, Column int
+}
+
+// DeadlockError is the error of a program whose tasks are all blocked.
+type DeadlockError struct {
+	Blocked []BlockedTask
+}
+
+func (err *DeadlockError) Error() string {
+	var b strings.Builder
+	b.WriteString("deadlock, every task is blocked")
+	for _, task := range err.Blocked {
+		fmt.Fprintf(&b, "\n  %s %s at line %d, column %d", task.Task, task.Operation, task.Line, task.Column)
+	}
+	return b.String()
+}
+
+func (err *DeadlockError) Is(target error) bool {
+	return target == ErrDeadlock
+}
+
+// deadlock returns the error of a program whose tasks are all blocked,
+// located where the first of them is.
+func (s *Scheduler) deadlock() *RuntimeError {
+	err := &DeadlockError{}
+	for _, t := range append([]*Task{s.main}, s.tasks...) {
+		if t.blocked != "" {
+			err.Blocked = append(err.Blocked, BlockedTask{Task: t.label(), Operation: t.blocked, Line: t.line, Column: t.column})
+		}
+	}
+	if len(err.Blocked) == 0 {
+		return WrapRuntimeError(0, 0, err)
+	}
+	return WrapRuntimeError(err.Blocked[0].Line, err.Blocked[0].Column, err)
+}
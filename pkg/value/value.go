@@ -0,0 +1,134 @@
+// Package value defines the runtime representation of rottenlang values and
+// the semantics shared by every execution engine.
+//
+// Values are plain Go values: nil, bool, float64 and string. Functions are
+// represented by engine specific types implementing Callable, plus *Native
+// for functions implemented in Go. Values with properties implement Object.
+// Lists, maps and the classes of the program and their instances are *List,
+// *Map, *Class and *Instance.
+package value
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type Callable interface {
+	// Arity returns the number of parameters, or -1 for variadic functions.
+	Arity() int
+	Name() string
+}
+
+// Object is a value with named properties, read with "object.name".
+type Object interface {
+	// Property returns the value of the property name, and whether the
+	// object has it.
+	Property(name string) (any, bool)
+}
+
+// GetProperty reads the property name of v.
+func GetProperty(v any, name string) (any, error) {
+	object, ok := v.(Object)
+	if !ok {
+		return nil, fmt.Errorf("only objects have properties, got %s", TypeName(v))
+	}
+	property, ok := object.Property(name)
+	if !ok {
+		return nil, fmt.Errorf("undefined property '%s'", name)
+	}
+	return property, nil
+}
+
+// IsTruthy reports whether v counts as true in a condition: everything but
+// nil and cap (false) does.
+func IsTruthy(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	default:
+		return true
+	}
+}
+
+// Equal compares two values. Values of different types are never equal, and
+// functions are equal only to themselves.
+func Equal(a, b any) bool {
+	switch a := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		b, ok := b.(bool)
+		return ok && a == b
+	case float64:
+		b, ok := b.(float64)
+		return ok && a == b
+	case string:
+		b, ok := b.(string)
+		return ok && a == b
+	default:
+		return a == b
+	}
+}
+
+// Stringify formats v the way print shows it.
+func Stringify(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		if v {
+			return "nocap"
+		}
+		return "cap"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// TypeName returns the name of v's type as shown in error messages.
+func TypeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *Class:
+		return "class"
+	case Callable:
+		return "function"
+	case *Module:
+		return "module"
+	case *Error:
+		return "error"
+	case *Channel:
+		return "channel"
+	case *Task:
+		return "task"
+	case *Generator:
+		return "generator"
+	case *Range:
+		return "range"
+	case *List:
+		return "list"
+	case *Map:
+		return "map"
+	case *Instance:
+		return "instance"
+	case Object:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
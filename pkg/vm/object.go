@@ -0,0 +1,52 @@
+package vm
+
+import (
+	"github.com/bagaswh/rottenlang/pkg/compiler"
+)
+
+// Closure is a compiled function paired with the variables it captured.
+type Closure struct {
+	function *compiler.Function
+	upvalues []*Upvalue
+	// globals is the global scope of the program or module defining the
+	// function
+	globals *globals
+	// file holds the code of the function, as shown to the profiler
+	file string
+}
+
+func (c *Closure) Arity() int {
+	return c.function.Arity
+}
+
+func (c *Closure) Name() string {
+	return c.function.Name
+}
+
+func (c *Closure) String() string {
+	return c.function.String()
+}
+
+// Upvalue is a variable captured by a closure. While the variable is still
+// on the stack, location points at its slot; once the slot goes away the
+// value is moved into closed and location points there instead.
+type Upvalue struct {
+	location *any
+	closed   any
+	slot     int
+	next     *Upvalue
+}
+
+// globals is the global scope of a program or of a module.
+type globals struct {
+	values    map[string]any
+	constants map[string]bool
+}
+
+// handler is a try statement in effect: errors raised in it resume the
+// frame at ip, with the stack cut back to stackTop.
+type handler struct {
+	frame    int
+	stackTop int
+	ip       int
+}
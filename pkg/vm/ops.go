@@ -0,0 +1,26 @@
+package vm
+
+import (
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/compiler"
+)
+
+// tokenTypes maps binary opcodes back to their operator so that the slow
+// path can share value.Binary with the tree interpreter.
+var tokenTypes = map[compiler.OpCode]ast.TokenType{
+	compiler.OpGreater:      ast.TokenGreater,
+	compiler.OpGreaterEqual: ast.TokenGreaterEqual,
+	compiler.OpLess:         ast.TokenLess,
+	compiler.OpLessEqual:    ast.TokenLessEqual,
+	compiler.OpAdd:          ast.TokenPlus,
+	compiler.OpSubtract:     ast.TokenMinus,
+	compiler.OpMultiply:     ast.TokenStar,
+	compiler.OpDivide:       ast.TokenSlash,
+	compiler.OpModulo:       ast.TokenPercent,
+	compiler.OpPower:        ast.TokenStarStar,
+	compiler.OpBitAnd:       ast.TokenAmpersand,
+	compiler.OpBitOr:        ast.TokenPipe,
+	compiler.OpBitXor:       ast.TokenCaret,
+	compiler.OpShiftLeft:    ast.TokenLessLess,
+	compiler.OpShiftRight:   ast.TokenGreaterGreater,
+}
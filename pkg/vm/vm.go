@@ -0,0 +1,1003 @@
+// Package vm executes bytecode produced by the compiler package on a value
+// stack.
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bagaswh/rottenlang/pkg/ast"
+	"github.com/bagaswh/rottenlang/pkg/compiler"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/profiler"
+	"github.com/bagaswh/rottenlang/pkg/value"
+)
+
+const (
+	FramesMax = value.MaxCallDepth
+	StackMax  = FramesMax * 256
+)
+
+type callFrame struct {
+	closure *Closure
+	ip      int
+	// slots is the stack index of the frame's slot zero
+	slots int
+}
+
+type VM struct {
+	frames []callFrame
+	// stack never grows past StackMax so that upvalues can point into it
+	stack    []any
+	stackTop int
+	// globals is the global scope of the program; modules have their own
+	globals      *globals
+	builtins     []*value.Native
+	importer     value.Importer
+	openUpvalues *Upvalue
+	// handlers are the try statements in effect, innermost last
+	handlers []handler
+	// meter is shared with the vms of the program's tasks
+	meter     *value.Meter
+	scheduler *value.Scheduler
+	// yield suspends the generator whose body runs on the vm
+	yield func(v any) error
+	// file is the file the program is read from, as shown to the profiler
+	file string
+	// profile follows the calls on the vm for the profiler, when the
+	// program is profiled
+	profiler *profiler.Profiler
+	profile  *profiler.Stack
+
+	errorReporter errorreporter.ErrorReporter
+}
+
+// NewVM creates a virtual machine whose builtins, such as print, write to
+// out. Globals persist across calls to Interpret.
+func NewVM(errorReporter errorreporter.ErrorReporter, out io.Writer) *VM {
+	vm := &VM{
+		frames:        make([]callFrame, 0, FramesMax),
+		stack:         make([]any, StackMax),
+		meter:         &value.Meter{},
+		errorReporter: errorReporter,
+	}
+	vm.scheduler = value.NewScheduler(vm.taskPosition)
+	vm.builtins = append(value.Builtins(out), vm.scheduler.Builtins()...)
+	vm.globals = vm.newGlobals()
+	return vm
+}
+
+// newGlobals creates a global scope holding the builtins.
+func (vm *VM) newGlobals() *globals {
+	g := &globals{
+		values:    make(map[string]any),
+		constants: make(map[string]bool),
+	}
+	for _, native := range vm.builtins {
+		g.values[native.Name()] = native
+	}
+	return g
+}
+
+// SetImporter sets the importer loading the modules of import statements.
+func (vm *VM) SetImporter(importer value.Importer) {
+	vm.importer = importer
+}
+
+// SetLimits bounds the resources used by the following programs.
+func (vm *VM) SetLimits(limits value.Limits) {
+	vm.meter.SetLimits(limits)
+}
+
+// SetContext makes the following programs stop with a runtime error once
+// ctx is done.
+func (vm *VM) SetContext(ctx context.Context) {
+	vm.meter.SetContext(ctx)
+}
+
+// SetFile names the file the following programs are read from.
+func (vm *VM) SetFile(file string) {
+	vm.file = file
+}
+
+// SetProfiler makes profiler record the following programs.
+func (vm *VM) SetProfiler(profiler *profiler.Profiler) {
+	vm.profiler = profiler
+	vm.profile = nil
+	if profiler != nil {
+		vm.profile = profiler.NewStack()
+	}
+}
+
+// Steps returns the number of instructions executed by the latest program.
+func (vm *VM) Steps() int64 {
+	return vm.meter.Steps()
+}
+
+// Interpret runs the top level function of a compiled program and returns
+// the value it returns, which is nil unless it was compiled with
+// CompileInteractive. A runtime error stops execution; it is reported to the
+// error reporter and returned.
+func (vm *VM) Interpret(function *compiler.Function) (any, error) {
+	return vm.Call(&Closure{function: function, globals: vm.globals, file: vm.file}, nil)
+}
+
+// ExecuteModule runs the top level function of a compiled module, read from
+// file, in a global scope of its own, returning a function looking up the
+// module's globals. Importers use it while the import instruction runs.
+func (vm *VM) ExecuteModule(file string, function *compiler.Function) (func(name string) (any, bool), error) {
+	g := vm.newGlobals()
+	if _, err := vm.Call(&Closure{function: function, globals: g, file: file}, nil); err != nil {
+		return nil, err
+	}
+	return func(name string) (any, bool) {
+		v, ok := g.values[name]
+		return v, ok
+	}, nil
+}
+
+// Call calls callee, a function value, with arguments given from Go. Native
+// functions may use it to call back into the running program.
+func (vm *VM) Call(callee any, args []any) (any, error) {
+	base := len(vm.frames)
+	stackBase := vm.stackTop
+	if base == 0 {
+		vm.meter.Reset()
+	}
+
+	vm.push(callee)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+
+	result, err := vm.callFromGo(callee, len(args), base)
+	if err != nil {
+		if base == 0 {
+			return nil, vm.fail(err)
+		}
+		// the outermost call reports the error once the native function
+		// that called back into the vm returns it
+		vm.unwind(base, stackBase)
+		return nil, err
+	}
+	if base == 0 {
+		// run the tasks the program spawned until they all end
+		if err := vm.scheduler.Wait(); err != nil {
+			return nil, vm.fail(err)
+		}
+	}
+	return result, nil
+}
+
+// fork returns a vm for a task of the program, sharing its globals and
+// resources but with a stack of its own.
+func (vm *VM) fork() *VM {
+	f := &VM{
+		frames:        make([]callFrame, 0, FramesMax),
+		stack:         make([]any, StackMax),
+		globals:       vm.globals,
+		builtins:      vm.builtins,
+		importer:      vm.importer,
+		meter:         vm.meter,
+		scheduler:     vm.scheduler,
+		errorReporter: vm.errorReporter,
+		file:          vm.file,
+	}
+	f.SetProfiler(vm.profiler)
+	return f
+}
+
+// runTask calls callee as the body of a task, returning the runtime error
+// ending it instead of reporting it: the program reports it, if it stops
+// the program.
+func (vm *VM) runTask(callee any, args []any) (any, error) {
+	vm.push(callee)
+	for _, arg := range args {
+		vm.push(arg)
+	}
+	result, err := vm.callFromGo(callee, len(args), 0)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// generator returns the generator of a call of closure, a generator
+// function, whose body runs on a vm of its own. receiver is the value of
+// slot zero of the call: the closure, or the instance a method is called
+// on.
+func (vm *VM) generator(closure *Closure, receiver any, args []any) *value.Generator {
+	return value.NewGenerator(closure.Name(), func(yield func(v any) error) error {
+		body := vm.fork()
+		body.yield = yield
+		body.push(receiver)
+		for _, arg := range args {
+			body.push(arg)
+		}
+		if err := body.call(closure, len(args)); err != nil {
+			return err
+		}
+		if _, err := body.run(0); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// taskPosition returns where execution is, reported when the task running
+// on vm blocks.
+func (vm *VM) taskPosition() (line, column int) {
+	if len(vm.frames) == 0 {
+		return 0, 0
+	}
+	pos := vm.position()
+	return pos.Line, pos.Column
+}
+
+func (vm *VM) callFromGo(callee any, argCount, base int) (any, *value.RuntimeError) {
+	if err := vm.callValue(callee, argCount); err != nil {
+		return nil, err
+	}
+	if len(vm.frames) == base {
+		// a native function, which already left its result on the stack
+		return vm.pop(), nil
+	}
+	return vm.run(base)
+}
+
+// DefineGlobal binds name to v in the global scope.
+func (vm *VM) DefineGlobal(name string, v any) {
+	vm.globals.values[name] = v
+	delete(vm.globals.constants, name)
+}
+
+// Global returns the value of the global variable name.
+func (vm *VM) Global(name string) (any, bool) {
+	v, ok := vm.globals.values[name]
+	return v, ok
+}
+
+func (vm *VM) fail(err *value.RuntimeError) error {
+	vm.resetStack()
+	vm.scheduler.Stop()
+	vm.errorReporter.ReportRuntimeError(err.Line, err.Column, err.Message+err.Trace())
+	return err
+}
+
+// unwind drops the frames and stack slots above the given heights, with the
+// handlers of the frames dropped.
+func (vm *VM) unwind(frames, stackTop int) {
+	vm.closeUpvalues(stackTop)
+	for vm.stackTop > stackTop {
+		vm.pop()
+	}
+	vm.frames = vm.frames[:frames]
+	vm.dropHandlers(frames)
+}
+
+// dropHandlers drops the handlers of the frames at or above height frames.
+func (vm *VM) dropHandlers(frames int) {
+	for len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frame >= frames {
+		vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	}
+}
+
+func (vm *VM) resetStack() {
+	for i := 0; i < vm.stackTop; i++ {
+		vm.stack[i] = nil
+	}
+	vm.stackTop = 0
+	vm.frames = vm.frames[:0]
+	vm.handlers = vm.handlers[:0]
+	vm.openUpvalues = nil
+}
+
+func (vm *VM) push(v any) {
+	vm.stack[vm.stackTop] = v
+	vm.stackTop++
+}
+
+func (vm *VM) pop() any {
+	vm.stackTop--
+	v := vm.stack[vm.stackTop]
+	vm.stack[vm.stackTop] = nil
+	return v
+}
+
+func (vm *VM) peek(distance int) any {
+	return vm.stack[vm.stackTop-1-distance]
+}
+
+func (vm *VM) runtimeError(format string, args ...any) *value.RuntimeError {
+	if len(vm.frames) == 0 {
+		return value.NewRuntimeError(0, 0, fmt.Sprintf(format, args...))
+	}
+	pos := vm.position()
+	err := value.NewRuntimeError(pos.Line, pos.Column, fmt.Sprintf(format, args...))
+	err.Stack = vm.stackTrace()
+	return err
+}
+
+// position returns the location of the instruction being executed.
+func (vm *VM) position() compiler.Position {
+	frame := &vm.frames[len(vm.frames)-1]
+	return frame.closure.function.Chunk.Position(frame.ip - 1)
+}
+
+// checkValue stops execution when v exceeds the memory limits. Values are
+// checked as operators and builtins make them, so the profiler counts the
+// allocations here.
+func (vm *VM) checkValue(v any) *value.RuntimeError {
+	if vm.profile != nil {
+		vm.profile.Made(v)
+	}
+	if err := vm.meter.CheckValue(v); err != nil {
+		return vm.wrapError(err)
+	}
+	return nil
+}
+
+// checkGrown stops execution when one of values, lists and maps a builtin
+// or an assignment may have added to, grew past the memory limits since
+// their lengths were recorded. Unlike checkValue it doesn't count an
+// allocation.
+func (vm *VM) checkGrown(values []any, lengths []int) *value.RuntimeError {
+	if err := vm.meter.CheckGrown(values, lengths); err != nil {
+		return vm.wrapError(err)
+	}
+	return nil
+}
+
+// profiledName returns the name the profiler shows function under: empty
+// for inline functions, part of their caller.
+func profiledName(function *compiler.Function) string {
+	switch {
+	case function.Inline:
+		return ""
+	case function.Name == "":
+		return "<script>"
+	}
+	return function.Name
+}
+
+// stackTrace returns the calls in progress, innermost first. Inline
+// functions are part of their caller, which is shown at their position.
+func (vm *VM) stackTrace() []value.StackFrame {
+	stack := make([]value.StackFrame, 0, len(vm.frames))
+	var inline *compiler.Position
+	for i := len(vm.frames) - 1; i >= 0; i-- {
+		function := vm.frames[i].closure.function
+		pos := function.Chunk.Position(vm.frames[i].ip - 1)
+		if inline != nil {
+			pos, inline = *inline, nil
+		}
+		if function.Inline {
+			inline = &pos
+			continue
+		}
+		name := function.Name
+		if name == "" {
+			name = "<script>"
+		}
+		stack = append(stack, value.StackFrame{Function: name, Line: pos.Line, Column: pos.Column})
+	}
+	return stack
+}
+
+// throw raises v, which is made an error value unless it is one.
+func (vm *VM) throw(v any) *value.RuntimeError {
+	e, ok := v.(*value.Error)
+	if !ok {
+		e = value.NewError(value.ErrorClass, value.Stringify(v))
+	}
+	pos := vm.position()
+	return e.Raise(pos.Line, pos.Column, vm.stackTrace())
+}
+
+// catch hands err to the innermost handler of the frames run since base,
+// and reports whether there was one.
+func (vm *VM) catch(err *value.RuntimeError, base int) bool {
+	if len(vm.handlers) == 0 || !value.Catchable(err) {
+		return false
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	if h.frame < base {
+		// the run that the frame belongs to catches it
+		return false
+	}
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.unwind(h.frame+1, h.stackTop)
+	vm.frames[h.frame].ip = h.ip
+	vm.push(value.Caught(err))
+	return true
+}
+
+// wrapError creates a runtime error caused by err at the current
+// instruction.
+func (vm *VM) wrapError(err error) *value.RuntimeError {
+	runtimeErr := vm.runtimeError("%s", err.Error())
+	runtimeErr.Err = err
+	return runtimeErr
+}
+
+// raise returns the runtime error for err, raised at the current
+// instruction unless it is one already: an error raised by a call back into
+// the vm, or the error of a task stopping the program. The error ending the
+// body of a generator gets the stack of the calls resuming it.
+func (vm *VM) raise(err error) *value.RuntimeError {
+	var resumeErr *value.ResumeError
+	if errors.As(err, &resumeErr) {
+		return resumeErr.Raise(vm.stackTrace())
+	}
+	var runtimeErr *value.RuntimeError
+	if errors.As(err, &runtimeErr) {
+		return runtimeErr
+	}
+	return vm.wrapError(err)
+}
+
+func (vm *VM) call(closure *Closure, argCount int) *value.RuntimeError {
+	if argCount != closure.function.Arity {
+		return vm.runtimeError("expected %d arguments but got %d", closure.function.Arity, argCount)
+	}
+	if err := vm.meter.CheckCallDepth(len(vm.frames)); err != nil {
+		return vm.wrapError(err)
+	}
+	if vm.profile != nil {
+		vm.profile.Call(len(vm.frames), profiledName(closure.function), closure.file)
+	}
+	vm.frames = append(vm.frames, callFrame{
+		closure: closure,
+		slots:   vm.stackTop - argCount - 1,
+	})
+	return nil
+}
+
+func (vm *VM) callValue(callee any, argCount int) *value.RuntimeError {
+	switch callee := callee.(type) {
+	case *Closure:
+		if !callee.function.Generator {
+			return vm.call(callee, argCount)
+		}
+		if argCount != callee.function.Arity {
+			return vm.runtimeError("expected %d arguments but got %d", callee.function.Arity, argCount)
+		}
+		receiver := vm.peek(argCount)
+		args := make([]any, argCount)
+		copy(args, vm.stack[vm.stackTop-argCount:vm.stackTop])
+		for i := 0; i < argCount+1; i++ {
+			vm.pop()
+		}
+		if vm.profile != nil {
+			vm.profile.Allocate()
+		}
+		vm.push(vm.generator(callee, receiver, args))
+		return nil
+	case *value.Class:
+		// the instance takes the place of the class, as the result of the
+		// call or slot zero of init
+		instance := value.NewInstance(callee)
+		if vm.profile != nil {
+			vm.profile.Allocate()
+		}
+		vm.stack[vm.stackTop-argCount-1] = instance
+		if init, ok := callee.Method(value.InitMethod); ok {
+			return vm.callValue(init, argCount)
+		}
+		if argCount != 0 {
+			return vm.runtimeError("expected 0 arguments but got %d", argCount)
+		}
+		return nil
+	case *value.BoundMethod:
+		vm.stack[vm.stackTop-argCount-1] = callee.Receiver()
+		return vm.callValue(callee.Method(), argCount)
+	case *value.Native:
+		if callee.Arity() >= 0 && callee.Arity() != argCount {
+			return vm.runtimeError("expected %d arguments but got %d", callee.Arity(), argCount)
+		}
+		args := make([]any, argCount)
+		copy(args, vm.stack[vm.stackTop-argCount:vm.stackTop])
+		if vm.profile != nil {
+			vm.profile.Builtin(callee.Name())
+		}
+		lengths := vm.meter.Lengths(args)
+		result, err := callee.Call(args)
+		if err != nil {
+			return vm.raise(err)
+		}
+		if err := vm.checkValue(result); err != nil {
+			return err
+		}
+		// push grows the list it is given
+		if err := vm.checkGrown(args, lengths); err != nil {
+			return err
+		}
+		for i := 0; i < argCount+1; i++ {
+			vm.pop()
+		}
+		vm.push(result)
+		return nil
+	}
+	return vm.runtimeError("can only call functions, got %s", value.TypeName(callee))
+}
+
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var prev *Upvalue
+	upvalue := vm.openUpvalues
+	for upvalue != nil && upvalue.slot > slot {
+		prev = upvalue
+		upvalue = upvalue.next
+	}
+	if upvalue != nil && upvalue.slot == slot {
+		return upvalue
+	}
+
+	created := &Upvalue{location: &vm.stack[slot], slot: slot, next: upvalue}
+	if prev == nil {
+		vm.openUpvalues = created
+	} else {
+		prev.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves every captured variable at or above slot off the
+// stack.
+func (vm *VM) closeUpvalues(slot int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= slot {
+		upvalue := vm.openUpvalues
+		upvalue.closed = *upvalue.location
+		upvalue.location = &upvalue.closed
+		vm.openUpvalues = upvalue.next
+	}
+}
+
+// run executes instructions until the frame at height base returns,
+// handing the errors raised to the handlers in effect.
+func (vm *VM) run(base int) (any, *value.RuntimeError) {
+	for {
+		result, err := vm.execute(base)
+		if err == nil || !vm.catch(err, base) {
+			return result, err
+		}
+	}
+}
+
+// execute executes instructions until the frame at height base returns or
+// an error is raised.
+func (vm *VM) execute(base int) (any, *value.RuntimeError) {
+	frame := &vm.frames[len(vm.frames)-1]
+	code := frame.closure.function.Chunk.Code
+	constants := frame.closure.function.Chunk.Constants
+	globals := frame.closure.globals
+
+	readByte := func() byte {
+		b := code[frame.ip]
+		frame.ip++
+		return b
+	}
+	readShort := func() int {
+		frame.ip += 2
+		return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+	}
+	loadFrame := func() {
+		frame = &vm.frames[len(vm.frames)-1]
+		code = frame.closure.function.Chunk.Code
+		constants = frame.closure.function.Chunk.Constants
+		globals = frame.closure.globals
+	}
+
+	for {
+		op := compiler.OpCode(readByte())
+		if err := vm.meter.Step(); err != nil {
+			return nil, vm.wrapError(err)
+		}
+		if vm.profile != nil {
+			vm.profile.Step(len(vm.frames), frame.closure.function.Chunk.Position(frame.ip-1).Line)
+		}
+		switch op {
+		case compiler.OpConstant:
+			vm.push(constants[readShort()])
+		case compiler.OpNil:
+			vm.push(nil)
+		case compiler.OpTrue:
+			vm.push(true)
+		case compiler.OpFalse:
+			vm.push(false)
+		case compiler.OpPop:
+			vm.pop()
+		case compiler.OpDup:
+			vm.push(vm.peek(int(readByte())))
+		case compiler.OpBury:
+			top := vm.stackTop - 1
+			below := top - int(readByte())
+			v := vm.stack[top]
+			copy(vm.stack[below+1:top+1], vm.stack[below:top])
+			vm.stack[below] = v
+
+		case compiler.OpGetLocal:
+			vm.push(vm.stack[frame.slots+int(readByte())])
+		case compiler.OpSetLocal:
+			vm.stack[frame.slots+int(readByte())] = vm.peek(0)
+		case compiler.OpGetGlobal:
+			name := constants[readShort()].(string)
+			v, ok := globals.values[name]
+			if !ok {
+				return nil, vm.runtimeError("undefined variable '%s'", name)
+			}
+			vm.push(v)
+		case compiler.OpDefineGlobal, compiler.OpDefineGlobalConst:
+			name := constants[readShort()].(string)
+			globals.values[name] = vm.pop()
+			if op == compiler.OpDefineGlobalConst {
+				globals.constants[name] = true
+			} else {
+				delete(globals.constants, name)
+			}
+		case compiler.OpSetGlobal:
+			name := constants[readShort()].(string)
+			if _, ok := globals.values[name]; !ok {
+				return nil, vm.runtimeError("undefined variable '%s'", name)
+			}
+			if globals.constants[name] {
+				return nil, vm.runtimeError("cannot assign to constant '%s'", name)
+			}
+			globals.values[name] = vm.peek(0)
+		case compiler.OpGetUpvalue:
+			vm.push(*frame.closure.upvalues[readByte()].location)
+		case compiler.OpSetUpvalue:
+			*frame.closure.upvalues[readByte()].location = vm.peek(0)
+		case compiler.OpGetProperty:
+			name := constants[readShort()].(string)
+			v, err := value.GetProperty(vm.peek(0), name)
+			if err != nil {
+				return nil, vm.runtimeError("%s", err.Error())
+			}
+			vm.stack[vm.stackTop-1] = v
+		case compiler.OpImport:
+			path := constants[readShort()].(string)
+			if vm.importer == nil {
+				return nil, vm.runtimeError("can't import modules here")
+			}
+			module, err := vm.importer.Import(path)
+			if err != nil {
+				return nil, vm.wrapError(err)
+			}
+			vm.push(module)
+
+		case compiler.OpEqual:
+			b := vm.pop()
+			a := vm.pop()
+			vm.push(value.Equal(a, b))
+		case compiler.OpNotEqual:
+			b := vm.pop()
+			a := vm.pop()
+			vm.push(!value.Equal(a, b))
+		case compiler.OpGreater, compiler.OpGreaterEqual, compiler.OpLess, compiler.OpLessEqual,
+			compiler.OpAdd, compiler.OpSubtract, compiler.OpMultiply, compiler.OpDivide,
+			compiler.OpModulo, compiler.OpPower, compiler.OpBitAnd, compiler.OpBitOr, compiler.OpBitXor,
+			compiler.OpShiftLeft, compiler.OpShiftRight:
+			if err := vm.binaryOp(op); err != nil {
+				return nil, err
+			}
+		case compiler.OpNot:
+			vm.push(!value.IsTruthy(vm.pop()))
+		case compiler.OpNegate, compiler.OpPositive:
+			x, ok := vm.peek(0).(float64)
+			if !ok {
+				return nil, vm.runtimeError("%s", value.ErrOperandNumber.Error())
+			}
+			if op == compiler.OpNegate {
+				x = -x
+			}
+			vm.stack[vm.stackTop-1] = x
+		case compiler.OpBitNot:
+			result, err := value.Unary(ast.TokenTilde, vm.peek(0))
+			if err != nil {
+				return nil, vm.runtimeError("%s", err.Error())
+			}
+			vm.stack[vm.stackTop-1] = result
+
+		case compiler.OpJump:
+			offset := readShort()
+			frame.ip += offset
+		case compiler.OpJumpIfFalse:
+			offset := readShort()
+			if !value.IsTruthy(vm.peek(0)) {
+				frame.ip += offset
+			}
+		case compiler.OpJumpIfNil:
+			offset := readShort()
+			if vm.peek(0) == nil {
+				frame.ip += offset
+			}
+		case compiler.OpJumpIfNotNil:
+			offset := readShort()
+			if vm.peek(0) != nil {
+				frame.ip += offset
+			}
+		case compiler.OpLoop:
+			offset := readShort()
+			frame.ip -= offset
+
+		case compiler.OpCall:
+			argCount := int(readByte())
+			if err := vm.callValue(vm.peek(argCount), argCount); err != nil {
+				return nil, err
+			}
+			loadFrame()
+		case compiler.OpClosure:
+			function := constants[readShort()].(*compiler.Function)
+			closure := &Closure{
+				function: function,
+				upvalues: make([]*Upvalue, function.UpvalueCount),
+				globals:  globals,
+				file:     frame.closure.file,
+			}
+			if vm.profile != nil {
+				vm.profile.Allocate()
+			}
+			for i := range closure.upvalues {
+				isLocal := readByte()
+				index := int(readByte())
+				if isLocal == 1 {
+					closure.upvalues[i] = vm.captureUpvalue(frame.slots + index)
+				} else {
+					closure.upvalues[i] = frame.closure.upvalues[index]
+				}
+			}
+			vm.push(closure)
+		case compiler.OpCloseUpvalue:
+			vm.closeUpvalues(vm.stackTop - 1)
+			vm.pop()
+		case compiler.OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.slots)
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			vm.dropHandlers(len(vm.frames))
+			for vm.stackTop > frame.slots {
+				vm.pop()
+			}
+			if len(vm.frames) == base {
+				return result, nil
+			}
+			vm.push(result)
+			loadFrame()
+
+		case compiler.OpTry:
+			offset := readShort()
+			vm.handlers = append(vm.handlers, handler{
+				frame:    len(vm.frames) - 1,
+				stackTop: vm.stackTop,
+				ip:       frame.ip + offset,
+			})
+		case compiler.OpEndTry:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case compiler.OpThrow:
+			return nil, vm.throw(vm.pop())
+
+		case compiler.OpMatch:
+			pattern := constants[readShort()].(ast.Pattern)
+			count := int(readByte())
+			bound, ok := value.Match(pattern, vm.pop())
+			for i := 0; i < count; i++ {
+				if ok {
+					vm.push(bound[i])
+				} else {
+					vm.push(nil)
+				}
+			}
+			vm.push(ok)
+		case compiler.OpNoMatch:
+			return nil, vm.wrapError(value.NoMatch(vm.pop()))
+
+		case compiler.OpSpawn:
+			argCount := int(readByte())
+			task, err := vm.spawn(argCount)
+			if err != nil {
+				return nil, err
+			}
+			if vm.profile != nil {
+				vm.profile.Allocate()
+			}
+			vm.push(task)
+		case compiler.OpSelect:
+			count := int(readByte())
+			hasElse := readByte() == 1
+			cases := make([]value.SelectCase, count)
+			for i := range cases {
+				cases[i].Send = readByte() == 1
+			}
+			base := vm.stackTop - 2*count
+			for i := range cases {
+				channel := vm.stack[base+2*i]
+				ch, ok := channel.(*value.Channel)
+				if !ok {
+					return nil, vm.runtimeError("select case needs a channel, got %s", value.TypeName(channel))
+				}
+				cases[i].Channel = ch
+				cases[i].Value = vm.stack[base+2*i+1]
+			}
+			index, received, err := vm.scheduler.Select(cases, hasElse)
+			if err != nil {
+				return nil, vm.raise(err)
+			}
+			for vm.stackTop > base {
+				vm.pop()
+			}
+			vm.push(received)
+			vm.push(float64(index))
+
+		case compiler.OpIterate:
+			iterator, err := value.Iterate(vm.pop(), vm.Call)
+			if err != nil {
+				return nil, vm.raise(err)
+			}
+			vm.push(iterator)
+		case compiler.OpForNext:
+			offset := readShort()
+			v, ok, err := vm.peek(0).(value.Iterator).Next()
+			if err != nil {
+				return nil, vm.raise(err)
+			}
+			if ok {
+				vm.push(v)
+			} else {
+				frame.ip += offset
+			}
+		case compiler.OpYield:
+			if err := vm.yield(vm.pop()); err != nil {
+				return nil, vm.wrapError(err)
+			}
+
+		case compiler.OpList:
+			count := readShort()
+			elements := make([]any, count)
+			copy(elements, vm.stack[vm.stackTop-count:vm.stackTop])
+			for i := 0; i < count; i++ {
+				vm.pop()
+			}
+			list := value.NewList(elements)
+			if err := vm.checkValue(list); err != nil {
+				return nil, err
+			}
+			vm.push(list)
+		case compiler.OpMap:
+			count := readShort()
+			m := value.NewMap()
+			start := vm.stackTop - 2*count
+			for i := start; i < vm.stackTop; i += 2 {
+				if err := m.Set(vm.stack[i], vm.stack[i+1]); err != nil {
+					return nil, vm.runtimeError("%s", err.Error())
+				}
+			}
+			for vm.stackTop > start {
+				vm.pop()
+			}
+			if err := vm.checkValue(m); err != nil {
+				return nil, err
+			}
+			vm.push(m)
+		case compiler.OpIndex:
+			index := vm.pop()
+			v, err := value.Index(vm.peek(0), index)
+			if err != nil {
+				return nil, vm.runtimeError("%s", err.Error())
+			}
+			vm.stack[vm.stackTop-1] = v
+		case compiler.OpSetIndex:
+			v := vm.pop()
+			index := vm.pop()
+			objects := []any{vm.peek(0)}
+			lengths := vm.meter.Lengths(objects)
+			if err := value.SetIndex(objects[0], index, v); err != nil {
+				return nil, vm.runtimeError("%s", err.Error())
+			}
+			if err := vm.checkGrown(objects, lengths); err != nil {
+				return nil, err
+			}
+			vm.stack[vm.stackTop-1] = v
+		case compiler.OpSetProperty:
+			name := constants[readShort()].(string)
+			v := vm.pop()
+			if err := value.SetProperty(vm.peek(0), name, v); err != nil {
+				return nil, vm.runtimeError("%s", err.Error())
+			}
+			vm.stack[vm.stackTop-1] = v
+		case compiler.OpClass:
+			name := constants[readShort()].(string)
+			count := int(readByte())
+			methods := make(map[string]value.Callable, count)
+			for _, method := range vm.stack[vm.stackTop-count : vm.stackTop] {
+				closure := method.(*Closure)
+				methods[closure.Name()] = closure
+			}
+			for i := 0; i < count; i++ {
+				vm.pop()
+			}
+			if vm.profile != nil {
+				vm.profile.Allocate()
+			}
+			vm.push(value.NewClass(name, methods))
+
+		default:
+			return nil, vm.runtimeError("unknown opcode %d", op)
+		}
+	}
+}
+
+// spawn pops the callee and the argCount arguments on top of the stack,
+// returning a task calling the callee on a vm of its own.
+func (vm *VM) spawn(argCount int) (*value.Task, *value.RuntimeError) {
+	callee := vm.peek(argCount)
+	callable, ok := callee.(value.Callable)
+	if !ok {
+		return nil, vm.runtimeError("can only spawn functions, got %s", value.TypeName(callee))
+	}
+	args := make([]any, argCount)
+	copy(args, vm.stack[vm.stackTop-argCount:vm.stackTop])
+	for i := 0; i < argCount+1; i++ {
+		vm.pop()
+	}
+
+	// the task's vm, with its stack, is only made once it runs
+	var task *VM
+	position := func() (line, column int) {
+		return task.taskPosition()
+	}
+	return vm.scheduler.Spawn(callable.Name(), position, func() (any, error) {
+		task = vm.fork()
+		return task.runTask(callee, args)
+	}), nil
+}
+
+func (vm *VM) binaryOp(op compiler.OpCode) *value.RuntimeError {
+	x, xok := vm.peek(1).(float64)
+	y, yok := vm.peek(0).(float64)
+	if !xok || !yok {
+		// slow path: string concatenation and type errors
+		b := vm.pop()
+		a := vm.pop()
+		result, err := value.Binary(tokenTypes[op], a, b)
+		if err != nil {
+			return vm.runtimeError("%s", err.Error())
+		}
+		if err := vm.checkValue(result); err != nil {
+			return err
+		}
+		vm.push(result)
+		return nil
+	}
+
+	var result any
+	switch op {
+	case compiler.OpGreater:
+		result = x > y
+	case compiler.OpGreaterEqual:
+		result = x >= y
+	case compiler.OpLess:
+		result = x < y
+	case compiler.OpLessEqual:
+		result = x <= y
+	case compiler.OpAdd:
+		result = x + y
+	case compiler.OpSubtract:
+		result = x - y
+	case compiler.OpMultiply:
+		result = x * y
+	case compiler.OpDivide:
+		result = x / y
+	default:
+		// modulo, power and the bitwise operators
+		var err error
+		if result, err = value.Binary(tokenTypes[op], x, y); err != nil {
+			return vm.runtimeError("%s", err.Error())
+		}
+	}
+	vm.stackTop--
+	vm.stack[vm.stackTop] = nil
+	vm.stack[vm.stackTop-1] = result
+	return nil
+}
@@ -0,0 +1,186 @@
+package vm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bagaswh/rottenlang/pkg/compiler"
+	"github.com/bagaswh/rottenlang/pkg/errorreporter"
+	"github.com/bagaswh/rottenlang/pkg/interpreter"
+	"github.com/bagaswh/rottenlang/pkg/parser"
+	"github.com/bagaswh/rottenlang/pkg/scanner"
+)
+
+var programs = map[string]string{
+	"arithmetic": `print(1 + 2 * 3, (1 + 2) * 3, 10 / 4, -(3), +2, 1 no_tea_no_shade 1);`,
+	"strings":    `slay s = "rotten" + "lang"; print(s, s == "rottenlang");`,
+	"logic":      `print(nil or "x", cap and 1, !nil, 1 mid 2, 2 lowkey 2, 3 bussin 4);`,
+	"scopes": `
+vibes a = "global";
+iykyk
+  vibes a = "block";
+  print(a);
+periodt
+print(a);`,
+	"control": `
+vibes total = 0;
+for (vibes i = 0; i < 10; i = i + 1) {
+  chat is this real (i / 2 == 2) total = total + 100; else total = total + i;
+}
+vibes n = 3;
+skibidi (n > 0) n = n - 1;
+print(total, n);`,
+	"closures": `
+func counter() {
+  vibes count = 0;
+  func inc() { count = count + 1; purrr count; }
+  purrr inc;
+}
+vibes c = counter();
+c(); c();
+print(c(), counter()());`,
+	"recursion": `
+func fib(n) { chat is this real (n < 2) purrr n; purrr fib(n - 1) + fib(n - 2); }
+print(fib(15));`,
+	"conditional": `
+func loud(x) { print("evaluated", x); purrr x; }
+print(nocap ? 1 : cap ? 2 : 3, 1 > 2 ? "big" : "small");
+print(nil ?? "default", cap ?? loud("skipped"), nil ?? nil ?? 3);
+print(nocap ? loud("then") : loud("else"));
+vibes missing;
+print(missing?.name, missing ?? 1 ? "a" : "b");`,
+	"compound": `
+vibes x = 10;
+x += 5; x -= 3; x *= 2; x /= 4; x %= 4;
+print(x, x stacks 1, x hypes 3);
+func counter() {
+  vibes n = 0;
+  func next() { purrr n++; }
+  purrr next;
+}
+vibes c = counter();
+print(c(), c(), c());
+vibes i = 5;
+print(i++, i, ++i, i--, --i, i stonks, flopped i);`,
+	"exponent and bitwise": `
+print(2 ** 3 ** 2, -2 ** 2, 2 glowup 10, 7 % 3, -7 ratio 3);
+print(6 & 3, 6 | 3, 6 ^ 3, ~5, 1 << 4, 256 >> 2);
+print(6 collab 3, 6 squad 3, 6 beef 3, flip 0, 1 up_only 3, 8 down_bad 1);
+print(1 | 2 & 3, 4 == 4 & 1, 1 + 2 << 1);`,
+	"bitwise error":  `print(1.5 & 1);`,
+	"property error": `vibes n = 1; print(n?.name);`,
+	"runtime error":  `print("before"); print(1 + "a"); print("after");`,
+	"const":          `slay k = 1; k = 2;`,
+	"arity":          `func f(a) {} f(1, 2);`,
+	"stack overflow": `func f() { purrr f(); } f();`,
+	"try": `
+func f(n) {
+  try {
+    chat is this real (n > 0) throw n;
+    purrr "none";
+  } catch (e) {
+    purrr e.message;
+  } finally {
+    print("finally", n);
+  }
+}
+print(f(0), f(2));
+func g() { try { purrr 1; } finally { purrr 2; } }
+print(g());`,
+	"uncaught": `func f() { throw error("boom", "Boom"); } print("before"); f(); print("after");`,
+	"match": `
+func classify(x) {
+  vibes seen = 0;
+  vibes kind = match (x) {
+    0 => "zero",
+    { message } => message,
+    n chat is this real n > 10 => match (n) { 11 => "eleven", _ => "big" },
+    n chat is this real (seen = n) < 0 => "negative",
+    _ => "other",
+  };
+  print(kind, seen);
+}
+classify(0); classify(11); classify(50); classify(-2); classify(error("oops"));
+print(1, match (2) { 1 => "one", two => two * 10 }, 3);`,
+	"no match": `func f(b) { purrr match (b) { nocap => 1 }; } print(f(nocap)); print(f(cap));`,
+	"channels": `
+func produce(out, n) {
+  for (vibes i = 0; i < n; i++) out.send(i);
+  out.close();
+  purrr "produced";
+}
+vibes numbers = channel();
+vibes producer = spawn produce(numbers, 3);
+vibes n = numbers.receive();
+skibidi (n != nil) { print("received", n); n = numbers.receive(); }
+print(producer.join(), producer.done);`,
+	"select": `
+vibes a = channel(1);
+vibes b = channel(1);
+func send(c, v) { c.send(v); }
+spawn send(b, "bee");
+select { a.receive() as v { print("a", v); } else { print("none"); } }
+select { a.receive() as v { print("a", v); } b.receive() as v { print("b", v); } }
+select { a.send(1) { print("sent"); } }
+print(a.receive());`,
+	"deadlock": `func f(c) { c.receive(); } spawn f(channel()); print("end");`,
+	"for in": `
+func squares(n) { for (i in range(1, n + 1)) yield i * i; }
+vibes total = 0;
+for (s in squares(4)) total += s;
+func letters() { vibes word = "abc"; for (c in word) yield c + c; }
+for (l in letters()) print(l);
+vibes g = squares(2);
+print(total, g.next(), g.next(), g.next(), g.done);`,
+	"iterate error":   `for (x in 42) print(x);`,
+	"generator error": `func g() { yield 1; print(1 + nil); } for (v in g()) print(v);`,
+	"iterator error":  `func next() { throw error("bad", "BadError"); } for (v in next) print(v);`,
+	"task failed":     `func f() { throw "boom"; } vibes t = spawn f(); try { t.join(); } catch (e) { print("caught"); }`,
+}
+
+func run(t *testing.T, source string, useVM bool) string {
+	t.Helper()
+
+	s := scanner.NewScanner(strings.NewReader(source), 0)
+	tokens, err := s.ScanTokens()
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	p := parser.NewParser(&errorreporter.NopErrorReporter{})
+	p.SetTokens(tokens)
+	statements := p.Parse()
+	if p.HadError() {
+		t.Fatalf("parse: %v", p.Errors()[0])
+	}
+
+	var out bytes.Buffer
+	if useVM {
+		function, err := compiler.NewCompiler(&errorreporter.NopErrorReporter{}).Compile(statements)
+		if err != nil {
+			return "compile error"
+		}
+		_, err = NewVM(&errorreporter.NopErrorReporter{}, &out).Interpret(function)
+		if err != nil {
+			out.WriteString("error: " + err.Error())
+		}
+	} else {
+		err := interpreter.NewInterpreter(&errorreporter.NopErrorReporter{}, &out).Interpret(statements)
+		if err != nil {
+			out.WriteString("error: " + err.Error())
+		}
+	}
+	return out.String()
+}
+
+func TestVM_MatchesTreeInterpreter(t *testing.T) {
+	for name, source := range programs {
+		t.Run(name, func(t *testing.T) {
+			want := run(t, source, false)
+			got := run(t, source, true)
+			if got != want {
+				t.Errorf("vm output differs from tree interpreter\ngot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}